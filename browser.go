@@ -0,0 +1,569 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/db/redis"
+	"github.com/gdamore/tcell"
+)
+
+const (
+	// scanCount is the COUNT hint of each SCAN step.
+	scanCount = 200
+	// scanBatch is how many keys are scanned before showing them, more are
+	// scanned once the selection reaches the end of the list.
+	scanBatch = 500
+	// viewerRange is how many items of a value are loaded at once.
+	viewerRange = 200
+)
+
+// keyRow is a line of the key tree, either a key or a namespace grouping
+// the keys that share a "prefix:".
+type keyRow struct {
+	label     string
+	path      string // the key, or the namespace prefix with its ":"
+	depth     int
+	namespace bool
+	count     int // keys in the namespace
+}
+
+// KeyBrowser is a popup listing the keys of a Redis connection as a tree of
+// namespaces. Opening a key shows its value in the results pane, where it
+// can be edited.
+type KeyBrowser struct {
+	Pattern  string
+	Selected int
+
+	keys     []string // sorted
+	info     map[string]redis.KeyInfo
+	expanded map[string]bool
+	rows     []keyRow
+	offset   int
+
+	cursor     string // SCAN cursor to continue from
+	done       bool
+	scanning   bool
+	generation int // bumped by rescans, so stale scans are dropped
+
+	value *redis.KeyValue
+
+	style    tcell.Style
+	selStyle tcell.Style
+	dimStyle tcell.Style
+
+	screen tcell.Screen
+	editor *Editor
+}
+
+func NewKeyBrowser(editor *Editor, pattern string) *KeyBrowser {
+	b := &KeyBrowser{
+		Pattern:  pattern,
+		style:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		selStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+		dimStyle: tcell.StyleDefault.Foreground(tcell.ColorGray).Background(tcell.ColorBlack),
+		screen:   editor.screen,
+		editor:   editor,
+	}
+	b.reset()
+
+	return b
+}
+
+// OpenKeyBrowser shows the keys of the buffer's Redis connection matching
+// pattern.
+func (e *Editor) OpenKeyBrowser(pattern string) {
+	if e.Connection == nil || e.Connection.Type != "redis" {
+		e.notify("The key browser needs a Redis connection")
+		return
+	}
+
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	b := NewKeyBrowser(e, pattern)
+	e.OpenPopup(b)
+	b.scan()
+}
+
+func (b *KeyBrowser) reset() {
+	b.keys = nil
+	b.info = map[string]redis.KeyInfo{}
+	b.expanded = map[string]bool{}
+	b.rows = nil
+	b.Selected = 0
+	b.offset = 0
+	b.cursor = "0"
+	b.done = false
+	b.scanning = false
+	b.generation++
+}
+
+// withSession runs fn with a Redis session of the buffer's connection,
+// taken from the pool for the time of the call.
+func (b *KeyBrowser) withSession(ctx context.Context, fn func(*redis.Session) error) error {
+	c := b.editor.Connection
+	if c == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	sessions := b.editor.app.sessions
+	s, err := sessions.Acquire(ctx, *c)
+	if err != nil {
+		return err
+	}
+
+	rs, ok := s.(*redis.Session)
+	if !ok {
+		sessions.Release(s)
+		return fmt.Errorf("%s isn't a Redis connection", c.Name)
+	}
+
+	err = fn(rs)
+	if err != nil && sessionBroken(s, err) {
+		sessions.Discard(s)
+		return err
+	}
+
+	sessions.Release(s)
+	return err
+}
+
+// scan fetches the next batch of keys in the background.
+func (b *KeyBrowser) scan() {
+	if b.scanning || b.done {
+		return
+	}
+	b.scanning = true
+
+	cursor, generation := b.cursor, b.generation
+	go func() {
+		ctx := context.Background()
+
+		var found []string
+		var infos []redis.KeyInfo
+		err := b.withSession(ctx, func(session *redis.Session) error {
+			for len(found) < scanBatch {
+				next, keys, err := session.Scan(ctx, cursor, b.Pattern, scanCount)
+				if err != nil {
+					return err
+				}
+				cursor = next
+				found = append(found, keys...)
+
+				if cursor == "0" {
+					break
+				}
+			}
+
+			var err error
+			infos, err = session.KeyInfos(ctx, found)
+			return err
+		})
+
+		b.editor.app.post(func() {
+			if generation != b.generation {
+				return
+			}
+			b.scanning = false
+
+			if err != nil {
+				b.editor.fail("Error: %s", err)
+				return
+			}
+
+			b.cursor = cursor
+			b.done = cursor == "0"
+			b.addKeys(found, infos)
+		})
+	}()
+}
+
+// addKeys merges scanned keys into the tree, keeping the selection on the
+// same row. SCAN may return a key more than once.
+func (b *KeyBrowser) addKeys(keys []string, infos []redis.KeyInfo) {
+	for i, k := range keys {
+		if _, ok := b.info[k]; !ok {
+			b.keys = append(b.keys, k)
+		}
+		b.info[k] = infos[i]
+	}
+	sort.Strings(b.keys)
+
+	b.rebuild()
+}
+
+// rebuild lays out the rows of the tree, keeping the selection on the same
+// path.
+func (b *KeyBrowser) rebuild() {
+	var selected string
+	if b.Selected < len(b.rows) {
+		selected = b.rows[b.Selected].path
+	}
+
+	b.rows = b.appendRows(nil, "", b.keys, 0)
+
+	b.Selected = min(b.Selected, max(len(b.rows)-1, 0))
+	for i, row := range b.rows {
+		if row.path == selected {
+			b.Selected = i
+			break
+		}
+	}
+}
+
+// appendRows appends the rows of keys, which all start with prefix. Keys
+// with another ":" after the prefix are grouped into namespaces, their
+// keys only get rows once the namespace is expanded.
+func (b *KeyBrowser) appendRows(rows []keyRow, prefix string, keys []string, depth int) []keyRow {
+	for i := 0; i < len(keys); {
+		rest := keys[i][len(prefix):]
+
+		name, _, ok := strings.Cut(rest, ":")
+		if !ok {
+			rows = append(rows, keyRow{label: rest, path: keys[i], depth: depth})
+			i++
+			continue
+		}
+
+		// Sorted keys of a namespace are next to each other.
+		ns := prefix + name + ":"
+		j := i
+		for j < len(keys) && strings.HasPrefix(keys[j], ns) {
+			j++
+		}
+
+		rows = append(rows, keyRow{label: name, path: ns, depth: depth, namespace: true, count: j - i})
+		if b.expanded[ns] {
+			rows = b.appendRows(rows, ns, keys[i:j], depth+1)
+		}
+		i = j
+	}
+
+	return rows
+}
+
+func (b *KeyBrowser) HandleEventKey(ek *tcell.EventKey) {
+	e := b.editor
+
+	switch ek.Key() {
+	case tcell.KeyEscape:
+		e.ClosePopup()
+		return
+	case tcell.KeyCtrlW:
+		if e.Results.Visible() {
+			e.SetEditorMode(ResultsMode)
+		}
+		return
+	case tcell.KeyUp:
+		b.move(-1)
+		return
+	case tcell.KeyDown:
+		b.move(1)
+		return
+	case tcell.KeyEnter, tcell.KeyRight:
+		b.activate()
+		return
+	case tcell.KeyLeft:
+		b.collapse()
+		return
+	case tcell.KeyRune:
+	default:
+		return
+	}
+
+	switch ek.Rune() {
+	case 'q':
+		e.ClosePopup()
+	case ':':
+		e.enterCommandLine(':')
+	case 'j':
+		b.move(1)
+	case 'k':
+		b.move(-1)
+	case 'g':
+		b.move(-len(b.rows))
+	case 'G':
+		b.move(len(b.rows))
+	case 'l':
+		b.activate()
+	case 'h':
+		b.collapse()
+	case 'r':
+		b.reset()
+		b.scan()
+	}
+}
+
+func (b *KeyBrowser) move(delta int) {
+	b.Selected = max(0, min(b.Selected+delta, len(b.rows)-1))
+
+	if b.Selected >= len(b.rows)-1 {
+		b.scan()
+	}
+}
+
+// activate expands or collapses the selected namespace, or opens the
+// selected key.
+func (b *KeyBrowser) activate() {
+	if b.Selected >= len(b.rows) {
+		return
+	}
+
+	row := b.rows[b.Selected]
+	if row.namespace {
+		b.expanded[row.path] = !b.expanded[row.path]
+		b.rebuild()
+		return
+	}
+
+	b.load(row.path, 0)
+}
+
+// collapse closes the selected namespace, or selects the namespace of the
+// selected key.
+func (b *KeyBrowser) collapse() {
+	if b.Selected >= len(b.rows) {
+		return
+	}
+
+	row := b.rows[b.Selected]
+	if row.namespace && b.expanded[row.path] {
+		b.expanded[row.path] = false
+		b.rebuild()
+		return
+	}
+
+	for i := b.Selected - 1; i >= 0; i-- {
+		if b.rows[i].depth < row.depth {
+			b.Selected = i
+			return
+		}
+	}
+}
+
+// load loads the range of key starting at start and shows it.
+func (b *KeyBrowser) load(key string, start int64) {
+	info := b.info[key]
+	b.editor.notify("Loading %s...", key)
+	go func() {
+		begin := time.Now()
+		ctx := context.Background()
+
+		var v *redis.KeyValue
+		err := b.withSession(ctx, func(session *redis.Session) error {
+			var err error
+			v, err = session.LoadValue(ctx, key, info.Type, start, viewerRange)
+			return err
+		})
+		elapsed := time.Since(begin)
+
+		b.editor.app.post(func() {
+			if err != nil {
+				b.editor.fail("Error: %s", err)
+				return
+			}
+
+			b.show(v, elapsed)
+		})
+	}()
+}
+
+// show fills the results pane with v.
+func (b *KeyBrowser) show(v *redis.KeyValue, elapsed time.Duration) {
+	e := b.editor
+	b.value = v
+
+	e.Results.SetResult(v.Key, v.Result, elapsed)
+	e.Results.Result.Tag = v.Describe()
+	e.Results.OnEdit = b.edit
+
+	if v.Type == "list" || v.Type == "zset" {
+		e.Results.OnPage = func(delta int) {
+			start := v.Start + int64(delta)*viewerRange
+			if start < 0 || start >= v.Len {
+				return
+			}
+
+			b.load(v.Key, start)
+		}
+	}
+
+	e.SetEditorMode(ResultsMode)
+	e.notify("%s", e.Results.Summary())
+}
+
+// edit asks for a new value of the cell at row and col and writes it back
+// once confirmed.
+func (b *KeyBrowser) edit(row, col int) {
+	e, v := b.editor, b.value
+	if v == nil {
+		return
+	}
+
+	if c := e.Connection; c != nil && c.ReadOnly {
+		e.warn("Refused on read-only %s", c.Name)
+		return
+	}
+
+	current := v.Raw
+	if v.Type != "string" && v.Type != "ReJSON-RL" {
+		if row >= len(v.Result.Rows) || col >= len(v.Result.Columns) {
+			return
+		}
+		current = db.FormatValue(v.Result.Rows[row][col])
+	}
+
+	// Check the cell can be edited before asking for its value.
+	if _, err := v.Edit(row, col, current); err != nil {
+		e.fail("Error: %s", err)
+		return
+	}
+
+	e.StatusBar.PromptWith(v.Key+": ", current, func(answer string) {
+		cmds, err := v.Edit(row, col, answer)
+		if err != nil {
+			e.fail("Error: %s", err)
+			return
+		}
+
+		b.confirmWrite(v, cmds)
+	})
+}
+
+// confirmWrite shows the commands that will run and runs them once
+// confirmed. Production connections need the connection name typed out.
+func (b *KeyBrowser) confirmWrite(v *redis.KeyValue, cmds [][]string) {
+	e, c := b.editor, b.editor.Connection
+
+	lines := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		lines[i] = redis.Quote(cmd)
+	}
+	run := snippet(strings.Join(lines, "; "), 60)
+
+	e.confirm(c, "Run "+run, fmt.Sprintf("Run %s? [y/N]: ", run), func() {
+		b.write(v, cmds)
+	})
+}
+
+// write runs cmds in the background and shows the value as written.
+func (b *KeyBrowser) write(v *redis.KeyValue, cmds [][]string) {
+	b.editor.notify("Saving %s...", v.Key)
+
+	go func() {
+		ctx := context.Background()
+
+		begin := time.Now()
+		var written *redis.KeyValue
+		err := b.withSession(ctx, func(session *redis.Session) error {
+			if err := session.Exec(ctx, cmds); err != nil {
+				return err
+			}
+
+			var err error
+			written, err = session.LoadValue(ctx, v.Key, v.Type, v.Start, viewerRange)
+			return err
+		})
+		elapsed := time.Since(begin)
+
+		b.editor.app.post(func() {
+			if err != nil {
+				b.editor.fail("Error: %s", err)
+				return
+			}
+
+			b.show(written, elapsed)
+			b.editor.notify("Saved %s", v.Key)
+		})
+	}()
+}
+
+func (b *KeyBrowser) Draw() {
+	width, height := b.editor.Width, b.editor.Height
+	if width < 20 || height < 3 {
+		return
+	}
+
+	title := fmt.Sprintf("Keys %s  %d keys", b.Pattern, len(b.keys))
+	if b.scanning {
+		title += ", scanning..."
+	} else if !b.done {
+		title += ", more below"
+	}
+	drawBox(b.screen, 0, 0, width, height, title, b.editor.AccentStyle(), b.style)
+
+	rows := height - 2
+	if b.Selected < b.offset {
+		b.offset = b.Selected
+	}
+	if b.Selected >= b.offset+rows {
+		b.offset = b.Selected - rows + 1
+	}
+
+	for i := 0; i < rows && b.offset+i < len(b.rows); i++ {
+		row := b.rows[b.offset+i]
+
+		style := b.style
+		if b.offset+i == b.Selected {
+			style = b.selStyle
+		}
+
+		y := 1 + i
+		fillRow(b.screen, 1, y, width-2, style)
+
+		label := strings.Repeat("  ", row.depth)
+		switch {
+		case row.namespace && b.expanded[row.path]:
+			label += "▾ " + row.label + ":"
+		case row.namespace:
+			label += "▸ " + row.label + ":"
+		default:
+			label += "  " + row.label
+		}
+
+		detailStyle := b.dimStyle
+		if b.offset+i == b.Selected {
+			detailStyle = style
+		}
+
+		detail := fmt.Sprintf("(%d)", row.count)
+		if !row.namespace {
+			info := b.info[row.path]
+			detail = fmt.Sprintf("%-9s %9s %9s", info.Type, formatTTL(info.TTL), formatSize(info.Memory))
+		}
+
+		n := drawText(b.screen, 2, y, width-4, label, style)
+		if x := width - 2 - len(detail); x > n+3 {
+			drawText(b.screen, x, y, len(detail), detail, detailStyle)
+		}
+	}
+}
+
+// formatTTL shows the time left before a key expires.
+func formatTTL(ttl time.Duration) string {
+	if ttl < 0 {
+		return "-"
+	}
+
+	return ttl.Round(time.Second).String()
+}
+
+// formatSize shows a number of bytes, "-" if unknown.
+func formatSize(n int64) string {
+	switch {
+	case n < 0:
+		return "-"
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db/redis"
+	"github.com/gdamore/tcell"
+)
+
+func TestKeyBrowserTree(t *testing.T) {
+	e := newTestEditor(t, nil)
+	e.Connect(&config.Connection{Name: "Cache", Type: "redis"})
+
+	b := NewKeyBrowser(e, "*")
+	e.OpenPopup(b)
+
+	keys := []string{"user:2", "tags", "user:1", "user:1:sessions", "queue:jobs"}
+	infos := make([]redis.KeyInfo, len(keys))
+	for i := range infos {
+		infos[i] = redis.KeyInfo{Type: "hash", TTL: -1, Memory: 72}
+	}
+	infos[1] = redis.KeyInfo{Type: "set", TTL: 90 * time.Second, Memory: 2048}
+	b.addKeys(keys, infos)
+	b.done = true
+
+	labels := func() string {
+		var got []string
+		for _, row := range b.rows {
+			got = append(got, strings.Repeat(" ", row.depth)+row.label)
+		}
+		return strings.Join(got, ",")
+	}
+
+	if got, want := labels(), "queue,tags,user"; got != want {
+		t.Fatalf("got rows %s, want %s", got, want)
+	}
+
+	// Expand user:, then its user:1: namespace.
+	typeKeys(e, "jjl")
+	if got, want := labels(), "queue,tags,user, 1, 1, 2"; got != want {
+		t.Fatalf("got rows %s, want %s", got, want)
+	}
+	typeKeys(e, "jjl")
+	if got, want := labels(), "queue,tags,user, 1, 1,  sessions, 2"; got != want {
+		t.Fatalf("got rows %s, want %s", got, want)
+	}
+
+	// h on a key selects its namespace, on an expanded namespace collapses it.
+	typeKeys(e, "jh")
+	if b.Selected != 4 {
+		t.Errorf("got selection %d, want the user:1: namespace", b.Selected)
+	}
+	typeKeys(e, "h")
+	if got, want := labels(), "queue,tags,user, 1, 1, 2"; got != want {
+		t.Errorf("got rows %s, want %s", got, want)
+	}
+
+	// New keys keep the selection on the same row.
+	b.addKeys([]string{"a"}, []redis.KeyInfo{{Type: "string", TTL: -1, Memory: -1}})
+	if row := b.rows[b.Selected]; row.path != "user:1:" {
+		t.Errorf("got selection on %s, want user:1:", row.path)
+	}
+
+	e.Draw()
+	if got := screenRow(e, 0); !strings.Contains(got, "Keys *  6 keys") {
+		t.Errorf("got title %q", got)
+	}
+	if got := screenRow(e, 3); !strings.Contains(got, "tags") || !strings.Contains(got, "set") || !strings.Contains(got, "1m30s") || !strings.Contains(got, "2.0 KB") {
+		t.Errorf("got row %q, want the type, TTL and size of tags", got)
+	}
+
+	pressKey(e, tcell.KeyEscape)
+	if e.Popup != nil {
+		t.Error("expected escape to close the browser")
+	}
+}
+
+func TestKeysCommandNeedsRedis(t *testing.T) {
+	e := newTestEditor(t, testConnections)
+
+	typeKeys(e, ":keys user:*")
+	pressKey(e, tcell.KeyEnter)
+
+	if e.Popup != nil {
+		t.Error("expected no browser without a connection")
+	}
+	if want := "The key browser needs a Redis connection"; e.StatusBar.Command != want {
+		t.Errorf("got %q, want %q", e.StatusBar.Command, want)
+	}
+}
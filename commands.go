@@ -1,6 +1,9 @@
 package main
 
-import "context"
+import (
+	"context"
+	"strings"
+)
 
 type CommandHandler func(context.Context, *Editor)
 
@@ -12,7 +15,9 @@ type HotkeyCommand struct {
 	Handler     CommandHandler // What the command does
 }
 
-var HotkeyCommandRegistry = map[string]*HotkeyCommand{}
+// HotkeyCommandRegistry maps keys to the commands bound to them. A key can
+// be bound once per mode, e.g. "j" in the editor and in the results pane.
+var HotkeyCommandRegistry = map[string][]*HotkeyCommand{}
 
 func newHotkeyCommand(name string, description string, editorModes []EditorMode, keys []string, handler CommandHandler) *HotkeyCommand {
 	return &HotkeyCommand{
@@ -26,14 +31,44 @@ func newHotkeyCommand(name string, description string, editorModes []EditorMode,
 
 func registerHotkeyCommand(command *HotkeyCommand) {
 	for _, key := range command.Keys {
-		HotkeyCommandRegistry[key] = command
+		HotkeyCommandRegistry[key] = appendHotkeyCommand(HotkeyCommandRegistry[key], command)
 	}
 }
 
+// appendHotkeyCommand adds command to cmds, replacing a command registered
+// earlier under the same name.
+func appendHotkeyCommand(cmds []*HotkeyCommand, command *HotkeyCommand) []*HotkeyCommand {
+	for i, c := range cmds {
+		if c.Name == command.Name {
+			cmds[i] = command
+			return cmds
+		}
+	}
+
+	return append(cmds, command)
+}
+
+// hasMode reports if the command is active in mode. Commands without modes
+// are active in every mode.
+func (c *HotkeyCommand) hasMode(mode EditorMode) bool {
+	if len(c.EditorModes) == 0 {
+		return true
+	}
+
+	for _, m := range c.EditorModes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
 type Command struct {
 	Name        string
 	Description string
 	Command     string
+	Usage       string         // how it is typed with arguments, e.g. "connect [name]"
 	Handler     CommandHandler // What the command does
 }
 
@@ -48,6 +83,46 @@ func newCommand(name string, description string, command string, handler Command
 	}
 }
 
+// withUsage sets how the command is typed with its arguments.
+func (c *Command) withUsage(usage string) *Command {
+	c.Usage = usage
+	return c
+}
+
 func registerCommand(command *Command) {
 	CommandRegistry[command.Command] = command
 }
+
+type commandArgsKey struct{}
+
+// CommandArgs returns everything typed after the command name.
+func CommandArgs(ctx context.Context) string {
+	args, _ := ctx.Value(commandArgsKey{}).(string)
+	return args
+}
+
+func withCommandArgs(ctx context.Context, args string) context.Context {
+	return context.WithValue(ctx, commandArgsKey{}, args)
+}
+
+type commandBangKey struct{}
+
+// CommandBang reports if the command was typed with a trailing "!", like
+// :q! to quit without asking.
+func CommandBang(ctx context.Context) bool {
+	bang, _ := ctx.Value(commandBangKey{}).(bool)
+	return bang
+}
+
+func withCommandBang(ctx context.Context) context.Context {
+	return context.WithValue(ctx, commandBangKey{}, true)
+}
+
+// splitCommandLine splits ":name args" into the command name and its raw
+// arguments.
+func splitCommandLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	name, args, _ := strings.Cut(line, " ")
+
+	return name, strings.TrimSpace(args)
+}
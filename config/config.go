@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,12 +16,18 @@ const DefaultConfigName = "dbvi.yaml"
 type Config struct {
 	Connections          []Connection
 	UseConnection        string
+	Theme                Theme
+	Results              Results
+	History              History
 	HasUnmaskedPasswords bool
 }
 
 type config struct {
 	Connections   []Connection `yaml:"connections"`
-	UseConnection string       `yaml:"use_connection"`
+	UseConnection string       `yaml:"use_connection" help:"Connection the buffer is bound to at startup"`
+	Theme         Theme        `yaml:"theme"`
+	Results       Results      `yaml:"results"`
+	History       History      `yaml:"history"`
 }
 
 func Load(path string) (*Config, error) {
@@ -59,13 +66,39 @@ func Load(path string) (*Config, error) {
 		}
 	}
 
+	if err := validateTheme(cfg.Theme); err != nil {
+		return nil, err
+	}
+
+	if err := validateResults(cfg.Results); err != nil {
+		return nil, err
+	}
+
+	if err := validateHistory(cfg.History, cfg.Connections); err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		Connections:          cfg.Connections,
 		UseConnection:        cfg.UseConnection,
+		Theme:                cfg.Theme,
+		Results:              cfg.Results,
+		History:              cfg.History,
 		HasUnmaskedPasswords: hasUnmaskedPasswords,
 	}, nil
 }
 
+// Connection looks up a connection by name, ignoring case.
+func (c *Config) Connection(name string) (*Connection, bool) {
+	for i := range c.Connections {
+		if strings.EqualFold(c.Connections[i].Name, name) {
+			return &c.Connections[i], true
+		}
+	}
+
+	return nil, false
+}
+
 func FindDefault() (string, error) {
 	home, _ := os.UserHomeDir()
 	cwd, _ := os.Getwd()
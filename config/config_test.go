@@ -79,3 +79,98 @@ func TestLoad(t *testing.T) {
 	}
 
 }
+
+func TestConfigConnection(t *testing.T) {
+	c := &Config{
+		Connections: []Connection{
+			{Name: "Local", Type: "postgres", Host: "localhost"},
+			{Name: "Cache", Type: "redis", Host: "localhost"},
+		},
+	}
+
+	got, ok := c.Connection("cache")
+	if !ok || got.Name != "Cache" {
+		t.Errorf("got %+v (found=%v), want Cache", got, ok)
+	}
+
+	if _, ok := c.Connection("missing"); ok {
+		t.Errorf("expected no connection named missing")
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	c, err := Load(filepath.Join("testdata", "theme.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Theme{ModeColors: ModeColors{Normal: "navy", Insert: "#00aa00"}}
+	if !reflect.DeepEqual(c.Theme, want) {
+		t.Errorf("got %+v, want %+v", c.Theme, want)
+	}
+
+	if _, err := Load(filepath.Join("testdata", "invalid_theme.yaml")); err == nil {
+		t.Errorf("expected invalid mode color to fail loading")
+	}
+}
+
+func TestLoadResults(t *testing.T) {
+	c, err := Load(filepath.Join("testdata", "results.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.Results.Page() != 200 || c.Results.RowCap() != DefaultMaxRows {
+		t.Errorf("got page %d and cap %d, want 200 and the default cap", c.Results.Page(), c.Results.RowCap())
+	}
+
+	if _, err := Load(filepath.Join("testdata", "invalid_results.yaml")); err == nil {
+		t.Errorf("expected a negative max_rows to fail loading")
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	c, err := Load(filepath.Join("testdata", "history.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.History.Entries() != 50 {
+		t.Errorf("got %d entries, want 50", c.History.Entries())
+	}
+
+	if c.History.Records("Production") || !c.History.Records("Local") {
+		t.Errorf("expected only Production to be left out of the history")
+	}
+
+	if _, err := Load(filepath.Join("testdata", "invalid_history.yaml")); err == nil {
+		t.Errorf("expected excluding an unknown connection to fail loading")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	opts := map[string]Option{}
+	for _, opt := range Options() {
+		if opt.Description == "" {
+			t.Errorf("option %s has no help", opt.Key)
+		}
+		opts[opt.Key] = opt
+	}
+
+	for key, typ := range map[string]string{
+		"use_connection":                             "string",
+		"connections[].port":                         "int",
+		"connections[].confirm_destructive":          "bool",
+		"connections[].ssh.host":                     "string",
+		"connections[].ssh.agent":                    "bool",
+		"connections[].ssh.known_hosts":              "string",
+		"connections[].ssh.insecure_ignore_host_key": "bool",
+		"connections[].ssh.jump[].key_file":          "string",
+		"theme.mode_colors.insert":                   "string",
+		"history.exclude":                            "[]string",
+	} {
+		if opts[key].Type != typ {
+			t.Errorf("got option %s of type %q, want %q", key, opts[key].Type, typ)
+		}
+	}
+}
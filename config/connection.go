@@ -1,24 +1,103 @@
 package config
 
 import (
+	"net"
 	"slices"
+	"strconv"
+	"strings"
 )
 
 var ConnectionTypes = []string{
 	"postgres",
 	"mysql",
 	"redis",
+	"sqlite",
+}
+
+// fileTypes are the connection types that open a local file instead of
+// connecting to a server.
+var fileTypes = []string{
+	"sqlite",
 }
 
 type Connection struct {
-	Name     string `yaml:"name"`
-	Type     string `yaml:"type"`
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	Database string `yaml:"database"`
-	Password string `yaml:"password"`
-	Username string `yaml:"username"`
-	ReadOnly bool   `yaml:"read_only"`
+	Name     string `yaml:"name" help:"Name the connection is picked by"`
+	Type     string `yaml:"type" help:"Database type: postgres, mysql, sqlite or redis"`
+	Host     string `yaml:"host" help:"Host of the database server"`
+	Port     int    `yaml:"port" help:"Port of the database server, the default of the type when unset"`
+	Database string `yaml:"database" help:"Database to connect to"`
+	Password string `yaml:"password" help:"Password to connect with"`
+	Username string `yaml:"username" help:"User to connect as"`
+	ReadOnly bool   `yaml:"read_only" help:"Refuses statements that write, and puts the session in read-only mode"`
+	SSH      *SSH   `yaml:"ssh"`
+
+	// Path is the database file of file based types, or :memory: for a
+	// database that lives as long as the session.
+	Path string `yaml:"path" help:"Database file of sqlite connections, :memory: for one that lives as long as the session"`
+
+	// Charset and Collation set the MySQL connection character set, the
+	// charset defaults to utf8mb4 and the collation to its server default.
+	Charset   string `yaml:"charset" help:"MySQL connection character set, utf8mb4 by default"`
+	Collation string `yaml:"collation" help:"MySQL connection collation, the server default of the charset by default"`
+
+	Environment        string   `yaml:"environment" help:"Environment like development or production, production asks more before writing"`
+	Color              string   `yaml:"color" help:"Color of the status line while connected, by environment when unset"`
+	Tags               []string `yaml:"tags" help:"Free form tags, production counts as the production environment"`
+	ConfirmDestructive *bool    `yaml:"confirm_destructive" help:"Asks before running destructive statements, on unless set to false"`
+
+	// AutoRollback rolls back the open transaction when one of its
+	// statements fails.
+	AutoRollback bool `yaml:"auto_rollback" help:"Rolls back the open transaction when one of its statements fails"`
+}
+
+// ConfirmsDestructive reports if destructive statements need confirmation
+// before running. It is on unless confirm_destructive is set to false.
+func (c Connection) ConfirmsDestructive() bool {
+	return c.ConfirmDestructive == nil || *c.ConfirmDestructive
+}
+
+// IsProduction reports if the connection is in the production environment
+// or tagged production.
+func (c Connection) IsProduction() bool {
+	if strings.EqualFold(c.Environment, "production") {
+		return true
+	}
+
+	for _, tag := range c.Tags {
+		if strings.EqualFold(tag, "production") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsFile reports if the connection opens a local database file rather than
+// connecting to a server.
+func (c Connection) IsFile() bool {
+	return slices.Contains(fileTypes, c.Type)
+}
+
+var defaultPorts = map[string]int{
+	"postgres": 5432,
+	"mysql":    3306,
+	"redis":    6379,
+}
+
+// Address returns the host:port the database listens on, falling back to
+// the default port of the connection type when none is configured. File
+// based connections return their path.
+func (c Connection) Address() string {
+	if c.IsFile() {
+		return c.Path
+	}
+
+	port := c.Port
+	if port == 0 {
+		port = defaultPorts[c.Type]
+	}
+
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
 }
 
 func validateConnection(c Connection) error {
@@ -30,10 +109,36 @@ func validateConnection(c Connection) error {
 		return &ConnectionValidationError{Field: "type", Desc: "invalid: " + c.Type}
 	}
 
-	if c.Host == "" {
+	if c.IsFile() {
+		if c.Path == "" {
+			return &ConnectionValidationError{Field: "path", Desc: "missing field"}
+		}
+
+		if c.SSH != nil {
+			return &ConnectionValidationError{Field: "ssh", Desc: "not supported for " + c.Type}
+		}
+	} else if c.Host == "" {
 		return &ConnectionValidationError{Field: "host", Desc: "missing field"}
 	}
 
+	if c.Color != "" && !IsValidColor(c.Color) {
+		return &ConnectionValidationError{Field: "color", Desc: "invalid: " + c.Color}
+	}
+
+	if c.Charset != "" && !isIdentifier(c.Charset) {
+		return &ConnectionValidationError{Field: "charset", Desc: "invalid: " + c.Charset}
+	}
+
+	if c.Collation != "" && !isIdentifier(c.Collation) {
+		return &ConnectionValidationError{Field: "collation", Desc: "invalid: " + c.Collation}
+	}
+
+	if c.SSH != nil {
+		if err := validateSSH(c.SSH); err != nil {
+			return err
+		}
+	}
+
 	if !isFieldMasked(c.Password) {
 		return ErrPasswordUnmasked
 	}
@@ -41,3 +146,15 @@ func validateConnection(c Connection) error {
 	// TODO: Do more validation here based on the type provided.
 	return nil
 }
+
+// isIdentifier reports if s is a plain name like utf8mb4_0900_ai_ci, safe to
+// use unquoted in a statement.
+func isIdentifier(s string) bool {
+	for _, r := range s {
+		if r != '_' && (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -39,6 +39,39 @@ func TestValidateConnection(t *testing.T) {
 			},
 			want: nil,
 		},
+		{
+			c: Connection{
+				Name: "SQLite",
+				Type: "sqlite",
+				Path: "testdata/app.db",
+			},
+			want: nil,
+		},
+		{
+			c: Connection{
+				Name: "SQLite In Memory",
+				Type: "sqlite",
+				Path: ":memory:",
+			},
+			want: nil,
+		},
+		{
+			c: Connection{
+				Name: "SQLite No Path",
+				Type: "sqlite",
+				Host: "localhost",
+			},
+			want: &ConnectionValidationError{Field: "path", Desc: "missing field"},
+		},
+		{
+			c: Connection{
+				Name: "SQLite SSH",
+				Type: "sqlite",
+				Path: "app.db",
+				SSH:  &SSH{SSHHost: SSHHost{Host: "bastion", User: "deploy", Agent: true}},
+			},
+			want: &ConnectionValidationError{Field: "ssh", Desc: "not supported for sqlite"},
+		},
 		{
 			c: Connection{
 				Name: "Mongo - Invalid Type",
@@ -63,6 +96,77 @@ func TestValidateConnection(t *testing.T) {
 			},
 			want: &ConnectionValidationError{Field: "host", Desc: "missing field"},
 		},
+		{
+			c: Connection{
+				Name:      "MariaDB",
+				Type:      "mysql",
+				Host:      "localhost",
+				Charset:   "utf8mb4",
+				Collation: "utf8mb4_uca1400_ai_ci",
+			},
+			want: nil,
+		},
+		{
+			c: Connection{
+				Name:    "Bad Charset",
+				Type:    "mysql",
+				Host:    "localhost",
+				Charset: "utf8; DROP TABLE users",
+			},
+			want: &ConnectionValidationError{Field: "charset", Desc: "invalid: utf8; DROP TABLE users"},
+		},
+		{
+			c: Connection{
+				Name:        "Colored",
+				Type:        "postgres",
+				Host:        "localhost",
+				Environment: "production",
+				Color:       "#ff8800",
+			},
+			want: nil,
+		},
+		{
+			c: Connection{
+				Name:  "Bad Color",
+				Type:  "postgres",
+				Host:  "localhost",
+				Color: "reddish",
+			},
+			want: &ConnectionValidationError{Field: "color", Desc: "invalid: reddish"},
+		},
+		{
+			c: Connection{
+				Name: "SSH",
+				Type: "postgres",
+				Host: "db.internal",
+				SSH: &SSH{
+					SSHHost: SSHHost{Host: "bastion", User: "deploy", Agent: true},
+					Jump:    []SSHHost{{Host: "gateway", User: "deploy", KeyFile: "~/.ssh/id_ed25519"}},
+				},
+			},
+			want: nil,
+		},
+		{
+			c: Connection{
+				Name: "SSH No User",
+				Type: "postgres",
+				Host: "db.internal",
+				SSH:  &SSH{SSHHost: SSHHost{Host: "bastion", Agent: true}},
+			},
+			want: &ConnectionValidationError{Field: "ssh.user", Desc: "missing field"},
+		},
+		{
+			c: Connection{
+				Name: "SSH Jump No Auth",
+				Type: "postgres",
+				Host: "db.internal",
+				SSH: &SSH{
+					SSHHost: SSHHost{Host: "bastion", User: "deploy", Agent: true},
+					Jump:    []SSHHost{{Host: "gateway", User: "deploy"}},
+				},
+			},
+			want: &ConnectionValidationError{Field: "ssh.jump[0].key_file", Desc: "missing field or agent"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,3 +192,79 @@ func TestValidateConnection(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectionAddress(t *testing.T) {
+	tests := []struct {
+		c    Connection
+		want string
+	}{
+		{c: Connection{Type: "postgres", Host: "localhost"}, want: "localhost:5432"},
+		{c: Connection{Type: "redis", Host: "localhost", Port: 6380}, want: "localhost:6380"},
+		{c: Connection{Type: "mysql", Host: "::1"}, want: "[::1]:3306"},
+		{c: Connection{Type: "sqlite", Path: "/var/lib/app/cache.db"}, want: "/var/lib/app/cache.db"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.Address(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestConnectionConfirmsDestructive(t *testing.T) {
+	off := false
+	on := true
+
+	tests := []struct {
+		c    Connection
+		want bool
+	}{
+		{c: Connection{}, want: true},
+		{c: Connection{ConfirmDestructive: &on}, want: true},
+		{c: Connection{ConfirmDestructive: &off}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.ConfirmsDestructive(); got != tt.want {
+			t.Errorf("got %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestConnectionIsProduction(t *testing.T) {
+	tests := []struct {
+		c    Connection
+		want bool
+	}{
+		{c: Connection{}, want: false},
+		{c: Connection{Tags: []string{"analytics"}}, want: false},
+		{c: Connection{Tags: []string{"analytics", "Production"}}, want: true},
+		{c: Connection{Environment: "staging"}, want: false},
+		{c: Connection{Environment: "production"}, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.IsProduction(); got != tt.want {
+			t.Errorf("%+v: got %v, want %v", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionAccentColor(t *testing.T) {
+	tests := []struct {
+		c    Connection
+		want string
+	}{
+		{c: Connection{}, want: ""},
+		{c: Connection{Environment: "local"}, want: ""},
+		{c: Connection{Environment: "Production"}, want: "red"},
+		{c: Connection{Environment: "staging"}, want: "orange"},
+		{c: Connection{Environment: "production", Color: "purple"}, want: "purple"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.AccentColor(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.c, got, tt.want)
+		}
+	}
+}
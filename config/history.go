@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const DefaultHistorySize = 1000
+
+// History controls the record kept of the statements run. Statements run on
+// the connections named in Exclude are left out of it.
+type History struct {
+	Size    int      `yaml:"size" help:"Most statements the history keeps, 1000 by default"`
+	Exclude []string `yaml:"exclude" help:"Connections whose statements are left out of the history"`
+}
+
+// Entries is the most statements the history keeps.
+func (h History) Entries() int {
+	if h.Size == 0 {
+		return DefaultHistorySize
+	}
+
+	return h.Size
+}
+
+// Records reports if statements run on the connection named name are kept
+// in the history.
+func (h History) Records(name string) bool {
+	return !slices.ContainsFunc(h.Exclude, func(excluded string) bool {
+		return strings.EqualFold(excluded, name)
+	})
+}
+
+func validateHistory(h History, connections []Connection) error {
+	if h.Size < 0 {
+		return fmt.Errorf("error at history.size: must be positive: %d", h.Size)
+	}
+
+	for i, name := range h.Exclude {
+		known := slices.ContainsFunc(connections, func(c Connection) bool {
+			return strings.EqualFold(c.Name, name)
+		})
+		if !known {
+			return fmt.Errorf("error at history.exclude[%d]: no connection named %q", i, name)
+		}
+	}
+
+	return nil
+}
+
+// DataDir is where dbvi keeps what it writes itself, such as the history:
+// $XDG_DATA_HOME/dbvi, falling back to ~/.local/share/dbvi, or %AppData%\dbvi
+// on Windows.
+func DataDir() (string, error) {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "dbvi"), nil
+	}
+
+	if os.PathSeparator == '\\' {
+		if appdata := os.Getenv("APPDATA"); appdata != "" {
+			return filepath.Join(appdata, "dbvi"), nil
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share", "dbvi"), nil
+}
@@ -76,5 +76,5 @@ func isSensitiveKey(key string) bool {
 }
 
 func isFieldMasked(value string) bool {
-	return MaskStr != value || value == ""
+	return MaskStr == value || value == ""
 }
@@ -30,3 +30,20 @@ func TestMaskPasswords(t *testing.T) {
 		os.Remove(outputFile)
 	}
 }
+
+func TestIsFieldMasked(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: MaskStr, want: true},
+		{value: "", want: true},
+		{value: "hunter2", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isFieldMasked(tt.value); got != tt.want {
+			t.Errorf("isFieldMasked(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Option is a setting of the config file.
+type Option struct {
+	Key         string // e.g. "connections[].ssh.port"
+	Type        string // e.g. "int" or "[]string"
+	Description string
+}
+
+// Options lists the settings the config file takes. They are read from the
+// yaml and help tags of the types it is decoded into, so they can't drift
+// from what Load accepts.
+func Options() []Option {
+	return options(reflect.TypeOf(config{}), "")
+}
+
+func options(t reflect.Type, prefix string) []Option {
+	var opts []Option
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, flags, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+
+		typ := f.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+
+		switch {
+		case flags == "inline":
+			opts = append(opts, options(typ, prefix)...)
+		case name == "" || name == "-":
+		case typ.Kind() == reflect.Struct:
+			opts = append(opts, options(typ, prefix+name+".")...)
+		case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Struct:
+			opts = append(opts, options(typ.Elem(), prefix+name+"[].")...)
+		default:
+			opts = append(opts, Option{Key: prefix + name, Type: typ.String(), Description: f.Tag.Get("help")})
+		}
+	}
+
+	return opts
+}
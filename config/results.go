@@ -0,0 +1,46 @@
+package config
+
+import "fmt"
+
+const (
+	DefaultPageSize = 500
+	DefaultMaxRows  = 100000
+)
+
+// Results controls how much of a result is read. Rows are fetched a page at
+// a time as the results pane scrolls, up to MaxRows, which also caps
+// :fetchall.
+type Results struct {
+	PageSize int `yaml:"page_size" help:"Rows fetched at a time as the results scroll, 500 by default"`
+	MaxRows  int `yaml:"max_rows" help:"Most rows a result holds, 100000 by default"`
+}
+
+// Page is the number of rows fetched at a time.
+func (r Results) Page() int {
+	if r.PageSize == 0 {
+		return DefaultPageSize
+	}
+
+	return r.PageSize
+}
+
+// RowCap is the most rows a result holds.
+func (r Results) RowCap() int {
+	if r.MaxRows == 0 {
+		return DefaultMaxRows
+	}
+
+	return r.MaxRows
+}
+
+func validateResults(r Results) error {
+	if r.PageSize < 0 {
+		return fmt.Errorf("error at results.page_size: must be positive: %d", r.PageSize)
+	}
+
+	if r.MaxRows < 0 {
+		return fmt.Errorf("error at results.max_rows: must be positive: %d", r.MaxRows)
+	}
+
+	return nil
+}
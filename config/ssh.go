@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const DefaultSSHPort = 22
+
+// SSHHost is a single SSH server dbvi authenticates against, either the
+// bastion in front of the database or one of the jump hosts leading to it.
+type SSHHost struct {
+	Host    string `yaml:"host" help:"Host of the SSH server"`
+	Port    int    `yaml:"port" help:"Port of the SSH server, 22 when unset"`
+	User    string `yaml:"user" help:"User to log in to the SSH server as"`
+	KeyFile string `yaml:"key_file" help:"Private key to authenticate with"`
+	Agent   bool   `yaml:"agent" help:"Authenticates with the keys of the SSH agent"`
+}
+
+// SSH describes the tunnel opened before connecting to the database.
+// Jump hosts are dialed in order, the last one reaching Host.
+type SSH struct {
+	SSHHost               `yaml:",inline"`
+	KnownHosts            string    `yaml:"known_hosts" help:"Known hosts file checked for the host keys, ~/.ssh/known_hosts by default"`
+	InsecureIgnoreHostKey bool      `yaml:"insecure_ignore_host_key" help:"Skips checking host keys"`
+	Jump                  []SSHHost `yaml:"jump" help:"SSH servers dialed in order to reach the host"`
+}
+
+// Address returns the host:port of the SSH server.
+func (h SSHHost) Address() string {
+	port := h.Port
+	if port == 0 {
+		port = DefaultSSHPort
+	}
+
+	return net.JoinHostPort(h.Host, strconv.Itoa(port))
+}
+
+func validateSSH(s *SSH) error {
+	for i, hop := range s.Jump {
+		if err := validateSSHHost(hop, fmt.Sprintf("ssh.jump[%d].", i)); err != nil {
+			return err
+		}
+	}
+
+	return validateSSHHost(s.SSHHost, "ssh.")
+}
+
+func validateSSHHost(h SSHHost, prefix string) error {
+	if h.Host == "" {
+		return &ConnectionValidationError{Field: prefix + "host", Desc: "missing field"}
+	}
+
+	if h.User == "" {
+		return &ConnectionValidationError{Field: prefix + "user", Desc: "missing field"}
+	}
+
+	if h.KeyFile == "" && !h.Agent {
+		return &ConnectionValidationError{Field: prefix + "key_file", Desc: "missing field or agent"}
+	}
+
+	return nil
+}
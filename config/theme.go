@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModeColors overrides the color of the mode indicator in the status line.
+type ModeColors struct {
+	Normal  string `yaml:"normal" help:"Color of the mode indicator in normal mode"`
+	Insert  string `yaml:"insert" help:"Color of the mode indicator in insert mode"`
+	Visual  string `yaml:"visual" help:"Color of the mode indicator in visual mode"`
+	Command string `yaml:"command" help:"Color of the mode indicator in command mode"`
+}
+
+type Theme struct {
+	ModeColors ModeColors `yaml:"mode_colors"`
+}
+
+// environmentColors are used for connections in an environment that don't
+// set their own color.
+var environmentColors = map[string]string{
+	"production": "red",
+	"staging":    "orange",
+}
+
+// AccentColor is the color the UI adopts while a buffer is pointed at the
+// connection, "" when it has none.
+func (c Connection) AccentColor() string {
+	if c.Color != "" {
+		return c.Color
+	}
+
+	return environmentColors[strings.ToLower(c.Environment)]
+}
+
+// colorNames are the named colors of CSS, which the UI knows how to draw.
+var colorNames = map[string]bool{
+	"black": true, "maroon": true, "green": true, "olive": true,
+	"navy": true, "purple": true, "teal": true, "silver": true,
+	"gray": true, "red": true, "lime": true, "yellow": true, "blue": true,
+	"fuchsia": true, "aqua": true, "white": true, "aliceblue": true,
+	"antiquewhite": true, "aquamarine": true, "azure": true, "beige": true,
+	"bisque": true, "blanchedalmond": true, "blueviolet": true,
+	"brown": true, "burlywood": true, "cadetblue": true, "chartreuse": true,
+	"chocolate": true, "coral": true, "cornflowerblue": true,
+	"cornsilk": true, "crimson": true, "darkblue": true, "darkcyan": true,
+	"darkgoldenrod": true, "darkgray": true, "darkgreen": true,
+	"darkkhaki": true, "darkmagenta": true, "darkolivegreen": true,
+	"darkorange": true, "darkorchid": true, "darkred": true,
+	"darksalmon": true, "darkseagreen": true, "darkslateblue": true,
+	"darkslategray": true, "darkturquoise": true, "darkviolet": true,
+	"deeppink": true, "deepskyblue": true, "dimgray": true,
+	"dodgerblue": true, "firebrick": true, "floralwhite": true,
+	"forestgreen": true, "gainsboro": true, "ghostwhite": true,
+	"gold": true, "goldenrod": true, "greenyellow": true, "honeydew": true,
+	"hotpink": true, "indianred": true, "indigo": true, "ivory": true,
+	"khaki": true, "lavender": true, "lavenderblush": true,
+	"lawngreen": true, "lemonchiffon": true, "lightblue": true,
+	"lightcoral": true, "lightcyan": true, "lightgoldenrodyellow": true,
+	"lightgray": true, "lightgreen": true, "lightpink": true,
+	"lightsalmon": true, "lightseagreen": true, "lightskyblue": true,
+	"lightslategray": true, "lightsteelblue": true, "lightyellow": true,
+	"limegreen": true, "linen": true, "mediumaquamarine": true,
+	"mediumblue": true, "mediumorchid": true, "mediumpurple": true,
+	"mediumseagreen": true, "mediumslateblue": true,
+	"mediumspringgreen": true, "mediumturquoise": true,
+	"mediumvioletred": true, "midnightblue": true, "mintcream": true,
+	"mistyrose": true, "moccasin": true, "navajowhite": true,
+	"oldlace": true, "olivedrab": true, "orange": true, "orangered": true,
+	"orchid": true, "palegoldenrod": true, "palegreen": true,
+	"paleturquoise": true, "palevioletred": true, "papayawhip": true,
+	"peachpuff": true, "peru": true, "pink": true, "plum": true,
+	"powderblue": true, "rebeccapurple": true, "rosybrown": true,
+	"royalblue": true, "saddlebrown": true, "salmon": true,
+	"sandybrown": true, "seagreen": true, "seashell": true, "sienna": true,
+	"skyblue": true, "slateblue": true, "slategray": true, "snow": true,
+	"springgreen": true, "steelblue": true, "tan": true, "thistle": true,
+	"tomato": true, "turquoise": true, "violet": true, "wheat": true,
+	"whitesmoke": true, "yellowgreen": true, "grey": true, "dimgrey": true,
+	"darkgrey": true, "darkslategrey": true, "lightgrey": true,
+	"lightslategrey": true, "slategrey": true,
+}
+
+// IsValidColor reports if name is a color name or #rrggbb value.
+func IsValidColor(name string) bool {
+	name = strings.ToLower(name)
+	if hex, ok := strings.CutPrefix(name, "#"); ok {
+		return len(hex) == 6 && strings.Trim(hex, "0123456789abcdef") == ""
+	}
+
+	return colorNames[name]
+}
+
+func validateTheme(t Theme) error {
+	colors := []struct {
+		field string
+		value string
+	}{
+		{"normal", t.ModeColors.Normal},
+		{"insert", t.ModeColors.Insert},
+		{"visual", t.ModeColors.Visual},
+		{"command", t.ModeColors.Command},
+	}
+
+	for _, c := range colors {
+		if c.value != "" && !IsValidColor(c.value) {
+			return fmt.Errorf("error at theme.mode_colors.%s: invalid color: %s", c.field, c.value)
+		}
+	}
+
+	return nil
+}
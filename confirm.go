@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ajm113/dbvi/config"
+)
+
+// confirm runs then once the user agreed to it going ahead on c. Production
+// connections need the connection name typed out, what says what runs there,
+// e.g. "Apply 3 statements". Elsewhere question is asked for a y, an empty
+// question runs then right away.
+func (e *Editor) confirm(c *config.Connection, what, question string, then func()) {
+	if c.IsProduction() {
+		e.StatusBar.Prompt(fmt.Sprintf("%s on production %s, type the connection name to run: ", what, c.Name), func(answer string) {
+			if answer != c.Name {
+				e.notify("Cancelled, %q doesn't match %s", answer, c.Name)
+				return
+			}
+
+			then()
+		})
+		return
+	}
+
+	if question == "" {
+		then()
+		return
+	}
+
+	e.confirmYes(question, then)
+}
+
+// confirmYes asks question and runs then if the answer is y or yes.
+func (e *Editor) confirmYes(question string, then func()) {
+	e.StatusBar.Prompt(question, func(answer string) {
+		if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+			e.notify("Cancelled")
+			return
+		}
+
+		then()
+	})
+}
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ajm113/dbvi/config"
+)
+
+var ErrNoDriver = errors.New("no driver registered")
+
+type Column struct {
+	Name string
+	Type string
+}
+
+// Rows iterates over the rows returned by a statement. Values are already
+// converted to types that are safe to display: nil for NULL, string, []byte,
+// bool, int64, uint64, float64, time.Time or one of the types of this
+// package.
+type Rows interface {
+	Columns() []Column
+	Next() bool
+	Values() []any
+	Err() error
+	Close() error
+
+	// Tag is the command tag (e.g. "INSERT 0 1") and RowsAffected the
+	// number of rows changed. Both are only valid once Next returned false.
+	Tag() string
+	RowsAffected() int64
+}
+
+// ResultSets is implemented by rows that can hold more than one result set,
+// like those of a MySQL stored procedure. Once Next returned false,
+// NextResultSet moves on to the next set if there is one, Columns then
+// describes it.
+type ResultSets interface {
+	NextResultSet() bool
+}
+
+// Notice is an informational message sent by the server alongside a
+// statement's result, like a Postgres RAISE NOTICE.
+type Notice struct {
+	Severity string
+	Message  string
+	Detail   string
+	Hint     string
+}
+
+func (n Notice) String() string {
+	if n.Severity == "" {
+		return n.Message
+	}
+
+	return n.Severity + ": " + n.Message
+}
+
+// ServerError is the error a server returned for a statement, with what
+// the driver could tell of where in the statement it points.
+type ServerError struct {
+	Err     error  // as the driver returned it
+	Code    string // SQLSTATE, e.g. "42601"
+	Message string
+	Detail  string
+	Hint    string
+
+	// Offset is the byte offset in the statement the error points at, -1
+	// when it points nowhere.
+	Offset int
+}
+
+func (e *ServerError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
+
+// NoticeHandler receives notices as they arrive.
+type NoticeHandler func(Notice)
+
+// NoticeReporter is implemented by sessions that can deliver notices.
+type NoticeReporter interface {
+	OnNotice(NoticeHandler)
+}
+
+// Canceler is implemented by sessions that can ask the server to cancel the
+// statement they are running without closing the session.
+type Canceler interface {
+	Cancel(ctx context.Context) error
+}
+
+// Session is a single connection to a database.
+type Session interface {
+	Query(ctx context.Context, stmt string, args ...any) (Rows, error)
+	Close() error
+}
+
+// Driver opens sessions for a connection type. addr is the host:port to
+// dial, which is a local port when the connection goes through an SSH
+// tunnel. File based drivers get the path of the database instead.
+type Driver interface {
+	Open(ctx context.Context, c config.Connection, addr string) (Session, error)
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// Register makes a driver available for a connection type. Drivers call it
+// from their init function.
+func Register(connectionType string, d Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if _, dup := drivers[connectionType]; dup {
+		panic("db: Register called twice for driver " + connectionType)
+	}
+
+	drivers[connectionType] = d
+}
+
+// Drivers returns the connection types with a registered driver.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	var names []string
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// readOnlyStatements switch a session into read-only transaction mode, so
+// the server refuses writes even if the client side check misses one.
+var readOnlyStatements = map[string]string{
+	"postgres": "SET default_transaction_read_only = on",
+	"mysql":    "SET SESSION TRANSACTION READ ONLY",
+	"sqlite":   "PRAGMA query_only = ON",
+}
+
+// Open opens a session for c using the driver registered for its type.
+// Sessions on read_only connections are put in read-only transaction mode
+// where the server supports it.
+func Open(ctx context.Context, c config.Connection, addr string) (Session, error) {
+	driversMu.RLock()
+	d, ok := drivers[c.Type]
+	driversMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNoDriver, c.Type)
+	}
+
+	s, err := d.Open(ctx, c, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt, ok := readOnlyStatements[c.Type]; ok && c.ReadOnly {
+		if _, err := Exec(ctx, s, stmt); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("enabling read-only mode: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Result is a fully fetched result set.
+type Result struct {
+	Columns      []Column
+	Rows         [][]any
+	Tag          string
+	RowsAffected int64
+
+	// More is set when the rows were read from a stream that has rows
+	// left.
+	More bool
+}
+
+// Collect reads up to limit rows (all of them when limit <= 0) and closes
+// rows.
+func Collect(rows Rows, limit int) (*Result, error) {
+	defer rows.Close()
+
+	res := &Result{Columns: rows.Columns()}
+	for (limit <= 0 || len(res.Rows) < limit) && rows.Next() {
+		res.Rows = append(res.Rows, rows.Values())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	res.Tag = rows.Tag()
+	res.RowsAffected = rows.RowsAffected()
+
+	return res, nil
+}
+
+// Exec runs stmt and fetches its whole result.
+func Exec(ctx context.Context, s Session, stmt string, args ...any) (*Result, error) {
+	rows, err := s.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return Collect(rows, 0)
+}
+
+// Reply returns the reply held by results of key-value stores, which come
+// back as a single value rather than a table.
+func (r *Result) Reply() (Reply, bool) {
+	if len(r.Rows) != 1 || len(r.Rows[0]) != 1 {
+		return Reply{}, false
+	}
+
+	reply, ok := r.Rows[0][0].(Reply)
+	return reply, ok
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+)
+
+// fakeDriver records the statements sent to its sessions and answers every
+// query with the same rows.
+type fakeDriver struct {
+	statements []string
+}
+
+func (d *fakeDriver) Open(_ context.Context, _ config.Connection, _ string) (Session, error) {
+	return &fakeSession{driver: d}, nil
+}
+
+type fakeSession struct {
+	driver *fakeDriver
+}
+
+func (s *fakeSession) Query(_ context.Context, stmt string, _ ...any) (Rows, error) {
+	s.driver.statements = append(s.driver.statements, stmt)
+	return &fakeRows{values: [][]any{{int64(1), "a"}, {int64(2), nil}}}, nil
+}
+
+func (s *fakeSession) Close() error {
+	return nil
+}
+
+type fakeRows struct {
+	values [][]any
+	i      int
+}
+
+func (r *fakeRows) Columns() []Column {
+	return []Column{{Name: "id", Type: "int8"}, {Name: "name", Type: "text"}}
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i <= len(r.values)
+}
+
+func (r *fakeRows) Values() []any       { return r.values[r.i-1] }
+func (r *fakeRows) Err() error          { return nil }
+func (r *fakeRows) Close() error        { return nil }
+func (r *fakeRows) Tag() string         { return "SELECT 2" }
+func (r *fakeRows) RowsAffected() int64 { return 2 }
+
+var testDriver = &fakeDriver{}
+
+func init() {
+	Register("postgres", testDriver)
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	tests := []struct {
+		readOnly bool
+		want     []string
+	}{
+		{readOnly: false, want: nil},
+		{readOnly: true, want: []string{"SET default_transaction_read_only = on"}},
+	}
+
+	for _, tt := range tests {
+		testDriver.statements = nil
+
+		c := config.Connection{Name: "Test", Type: "postgres", Host: "localhost", ReadOnly: tt.readOnly}
+		s, err := Open(context.Background(), c, c.Address())
+		if err != nil {
+			t.Fatalf("unexpected error opening session: %v", err)
+		}
+		s.Close()
+
+		if !reflect.DeepEqual(testDriver.statements, tt.want) {
+			t.Errorf("read_only=%v: got %q, want %q", tt.readOnly, testDriver.statements, tt.want)
+		}
+	}
+}
+
+func TestOpenNoDriver(t *testing.T) {
+	c := config.Connection{Name: "Test", Type: "mysql", Host: "localhost"}
+
+	if _, err := Open(context.Background(), c, c.Address()); !errors.Is(err, ErrNoDriver) {
+		t.Errorf("got %v, want %v", err, ErrNoDriver)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	res, err := Collect(&fakeRows{values: [][]any{{int64(1), "a"}, {int64(2), nil}}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Result{
+		Columns:      []Column{{Name: "id", Type: "int8"}, {Name: "name", Type: "text"}},
+		Rows:         [][]any{{int64(1), "a"}},
+		Tag:          "SELECT 2",
+		RowsAffected: 2,
+	}
+
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("got %+v, want %+v", res, want)
+	}
+}
+
+func TestStream(t *testing.T) {
+	st, err := OpenStream(context.Background(), &fakeSession{driver: &fakeDriver{}}, "SELECT", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows, err := st.Fetch(1)
+	if err != nil || len(rows) != 1 || st.Done() {
+		t.Fatalf("got rows %v and done %v, want the first row only", rows, st.Done())
+	}
+
+	rows, err = st.Fetch(5)
+	if err != nil || len(rows) != 1 || !st.Done() {
+		t.Fatalf("got rows %v and done %v, want the last row", rows, st.Done())
+	}
+
+	if st.Tag() != "SELECT 2" || st.RowsAffected() != 2 {
+		t.Errorf("got tag %q and %d rows affected", st.Tag(), st.RowsAffected())
+	}
+
+	if rows, _ := st.Fetch(1); rows != nil {
+		t.Errorf("got rows %v after the end", rows)
+	}
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// primaryKeyQueries list the primary key columns of a table in key order.
+// They take the table's schema, "" for the default one, and its name.
+var primaryKeyQueries = map[string]string{
+	"postgres": `SELECT a.attname::text FROM pg_index i
+JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+WHERE i.indisprimary AND i.indrelid = (CASE WHEN $1 = '' THEN quote_ident($2) ELSE quote_ident($1) || '.' || quote_ident($2) END)::regclass
+ORDER BY array_position(i.indkey::int2[], a.attnum)`,
+	"mysql": `SELECT column_name FROM information_schema.key_column_usage
+WHERE constraint_name = 'PRIMARY' AND table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?
+ORDER BY ordinal_position`,
+	"sqlite": `SELECT name FROM pragma_table_info(?2, COALESCE(NULLIF(?1, ''), 'main')) WHERE pk > 0 ORDER BY pk`,
+}
+
+// PrimaryKey returns the primary key columns of table on a connection of
+// type connectionType, in key order. It returns no columns for a table
+// without a primary key.
+func PrimaryKey(ctx context.Context, s Session, connectionType, schema, table string) ([]string, error) {
+	stmt, ok := primaryKeyQueries[connectionType]
+	if !ok {
+		return nil, fmt.Errorf("primary keys of %s tables can't be looked up", connectionType)
+	}
+
+	res, err := Exec(ctx, s, stmt, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("looking up the primary key of %s: %w", table, err)
+	}
+
+	columns := make([]string, len(res.Rows))
+	for i, row := range res.Rows {
+		columns[i] = FormatValue(row[0])
+	}
+
+	return columns, nil
+}
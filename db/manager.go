@@ -0,0 +1,287 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+)
+
+var ErrManagerClosed = errors.New("session manager closed")
+
+// ErrBroken is returned by sessions whose connection is gone. IsBroken
+// recognizes it along with the usual network errors.
+var ErrBroken = errors.New("connection broken")
+
+const (
+	// maxIdle is how many unused sessions are kept per connection.
+	maxIdle = 2
+
+	// pingAfter is how long a session may sit idle before it is pinged,
+	// either by the health check or before being handed out again.
+	pingAfter = 30 * time.Second
+
+	// pingTimeout bounds a single health check ping.
+	pingTimeout = 5 * time.Second
+)
+
+// Pinger is implemented by sessions that can check their connection is
+// still alive.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// IsBroken reports if err means the connection of a session is gone, as
+// opposed to the statement failing.
+func IsBroken(err error) bool {
+	return errors.Is(err, ErrBroken) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// Alive pings s, sessions that can't be pinged are assumed to be alive.
+func Alive(ctx context.Context, s Session) bool {
+	p, ok := s.(Pinger)
+	if !ok {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	return p.Ping(ctx) == nil
+}
+
+// OpenFunc opens a new session for a connection.
+type OpenFunc func(ctx context.Context, c config.Connection) (Session, error)
+
+type idleSession struct {
+	session Session
+	since   time.Time
+}
+
+// Manager keeps a small pool of sessions per connection, keyed by
+// connection name. A buffer acquires a session for its first statement and
+// releases it when it disconnects, so the state a statement leaves on the
+// session is there for the next. Idle sessions are pinged in the background
+// so a dead one is never handed out.
+type Manager struct {
+	open OpenFunc
+
+	mu     sync.Mutex
+	idle   map[string][]idleSession
+	active map[Session]string
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewManager(open OpenFunc) *Manager {
+	m := &Manager{
+		open:   open,
+		idle:   map[string][]idleSession{},
+		active: map[Session]string{},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go m.healthCheck()
+	return m
+}
+
+// Acquire returns an idle session of c, or opens a new one. Sessions that
+// sat idle for a while are pinged first.
+func (m *Manager) Acquire(ctx context.Context, c config.Connection) (Session, error) {
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return nil, ErrManagerClosed
+		}
+
+		idle := m.idle[c.Name]
+		if len(idle) == 0 {
+			m.mu.Unlock()
+			break
+		}
+
+		// The most recently used session is the most likely to be alive.
+		is := idle[len(idle)-1]
+		m.idle[c.Name] = idle[:len(idle)-1]
+		m.active[is.session] = c.Name
+		m.mu.Unlock()
+
+		if time.Since(is.since) < pingAfter || Alive(ctx, is.session) {
+			return is.session, nil
+		}
+
+		m.Discard(is.session)
+	}
+
+	s, err := m.open(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		s.Close()
+		return nil, ErrManagerClosed
+	}
+
+	m.active[s] = c.Name
+	return s, nil
+}
+
+// Release gives s back to the pool once the caller is done with it. It is
+// closed if the pool of its connection is full.
+func (m *Manager) Release(s Session) {
+	m.mu.Lock()
+	name, ok := m.active[s]
+	delete(m.active, s)
+
+	// Close already closed the sessions it took over.
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+
+	if m.closed || len(m.idle[name]) >= maxIdle {
+		m.mu.Unlock()
+		s.Close()
+		return
+	}
+
+	m.idle[name] = append(m.idle[name], idleSession{session: s, since: time.Now()})
+	m.mu.Unlock()
+}
+
+// Discard closes s instead of giving it back, for sessions whose
+// connection broke or that are left in a state nobody else should inherit.
+func (m *Manager) Discard(s Session) {
+	m.mu.Lock()
+	_, ok := m.active[s]
+	delete(m.active, s)
+	m.mu.Unlock()
+
+	if ok {
+		s.Close()
+	}
+}
+
+// Idle is the number of idle sessions of the connection named name.
+func (m *Manager) Idle(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.idle[name])
+}
+
+func (m *Manager) healthCheck() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(pingAfter)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.checkIdle(context.Background(), pingAfter)
+		}
+	}
+}
+
+// checkIdle pings the sessions idle for longer than after and closes the
+// dead ones.
+func (m *Manager) checkIdle(ctx context.Context, after time.Duration) {
+	m.mu.Lock()
+	var due []idleSession
+	var names []string
+	for name, idle := range m.idle {
+		kept := idle[:0]
+		for _, is := range idle {
+			if time.Since(is.since) < after {
+				kept = append(kept, is)
+				continue
+			}
+
+			due = append(due, is)
+			names = append(names, name)
+		}
+		m.idle[name] = kept
+	}
+	m.mu.Unlock()
+
+	for i, is := range due {
+		if !Alive(ctx, is.session) {
+			is.session.Close()
+			continue
+		}
+
+		is.since = time.Now()
+
+		m.mu.Lock()
+		if m.closed || len(m.idle[names[i]]) >= maxIdle {
+			m.mu.Unlock()
+			is.session.Close()
+			continue
+		}
+		m.idle[names[i]] = append(m.idle[names[i]], is)
+		m.mu.Unlock()
+	}
+}
+
+// Close closes every session, idle or not. Acquire fails with
+// ErrManagerClosed afterwards.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+
+	var sessions []Session
+	var names []string
+	for name, idle := range m.idle {
+		for _, is := range idle {
+			sessions = append(sessions, is.session)
+			names = append(names, name)
+		}
+		delete(m.idle, name)
+	}
+	for s, name := range m.active {
+		sessions = append(sessions, s)
+		names = append(names, name)
+		delete(m.active, s)
+	}
+	m.mu.Unlock()
+
+	close(m.stop)
+	<-m.done
+
+	var errs []error
+	for i, s := range sessions {
+		if err := s.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", names[i], err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
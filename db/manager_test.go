@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+)
+
+// pooledSession is a session whose liveness the test controls.
+type pooledSession struct {
+	id int
+
+	mu     sync.Mutex
+	dead   bool
+	closed bool
+}
+
+func (s *pooledSession) Query(context.Context, string, ...any) (Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *pooledSession) Ping(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dead {
+		return io.EOF
+	}
+	return nil
+}
+
+func (s *pooledSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func (s *pooledSession) kill() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dead = true
+}
+
+func (s *pooledSession) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// newPool returns a manager whose sessions are numbered in the order they
+// were opened.
+func newPool(t *testing.T) (*Manager, *[]*pooledSession) {
+	t.Helper()
+
+	var opened []*pooledSession
+	m := NewManager(func(_ context.Context, c config.Connection) (Session, error) {
+		if c.Name == "down" {
+			return nil, fmt.Errorf("dial %s: connection refused", c.Name)
+		}
+
+		s := &pooledSession{id: len(opened) + 1}
+		opened = append(opened, s)
+		return s, nil
+	})
+	t.Cleanup(func() { m.Close() })
+
+	return m, &opened
+}
+
+func acquire(t *testing.T, m *Manager, name string) *pooledSession {
+	t.Helper()
+
+	s, err := m.Acquire(context.Background(), config.Connection{Name: name})
+	if err != nil {
+		t.Fatalf("acquiring %s: %v", name, err)
+	}
+
+	return s.(*pooledSession)
+}
+
+func TestManagerReusesSessions(t *testing.T) {
+	m, opened := newPool(t)
+
+	s := acquire(t, m, "Local")
+	m.Release(s)
+
+	if again := acquire(t, m, "Local"); again != s {
+		t.Errorf("got session %d, want the released session %d", again.id, s.id)
+	}
+
+	// Sessions are kept per connection.
+	if other := acquire(t, m, "Staging"); other == s {
+		t.Error("expected another connection to get its own session")
+	}
+
+	if _, err := m.Acquire(context.Background(), config.Connection{Name: "down"}); err == nil {
+		t.Error("expected the open error")
+	}
+
+	if len(*opened) != 2 {
+		t.Errorf("opened %d sessions, want 2", len(*opened))
+	}
+}
+
+func TestManagerKeepsFewIdle(t *testing.T) {
+	m, _ := newPool(t)
+
+	var sessions []*pooledSession
+	for range maxIdle + 1 {
+		sessions = append(sessions, acquire(t, m, "Local"))
+	}
+	for _, s := range sessions {
+		m.Release(s)
+	}
+
+	if n := m.Idle("Local"); n != maxIdle {
+		t.Errorf("got %d idle sessions, want %d", n, maxIdle)
+	}
+	if !sessions[maxIdle].isClosed() {
+		t.Error("expected the session over the limit to be closed")
+	}
+}
+
+func TestManagerHealthCheck(t *testing.T) {
+	m, _ := newPool(t)
+
+	alive, dead := acquire(t, m, "Local"), acquire(t, m, "Local")
+	m.Release(alive)
+	m.Release(dead)
+	dead.kill()
+
+	m.checkIdle(context.Background(), 0)
+
+	if !dead.isClosed() || alive.isClosed() {
+		t.Fatalf("got dead closed %v and alive closed %v, want only the dead one closed", dead.isClosed(), alive.isClosed())
+	}
+	if s := acquire(t, m, "Local"); s != alive {
+		t.Errorf("got session %d, want the live session %d", s.id, alive.id)
+	}
+}
+
+func TestManagerPingsStaleSessions(t *testing.T) {
+	m, _ := newPool(t)
+
+	s := acquire(t, m, "Local")
+	m.Release(s)
+	s.kill()
+
+	// Age the idle session past the ping threshold.
+	m.mu.Lock()
+	m.idle["Local"][0].since = time.Now().Add(-pingAfter)
+	m.mu.Unlock()
+
+	if again := acquire(t, m, "Local"); again == s {
+		t.Error("expected the dead session to be replaced")
+	}
+	if !s.isClosed() {
+		t.Error("expected the dead session to be closed")
+	}
+}
+
+func TestManagerClose(t *testing.T) {
+	m, _ := newPool(t)
+
+	idle, active := acquire(t, m, "Local"), acquire(t, m, "Local")
+	m.Release(idle)
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !idle.isClosed() || !active.isClosed() {
+		t.Error("expected every session to be closed")
+	}
+
+	if _, err := m.Acquire(context.Background(), config.Connection{Name: "Local"}); !errors.Is(err, ErrManagerClosed) {
+		t.Errorf("got %v, want ErrManagerClosed", err)
+	}
+
+	// Releasing after Close is harmless.
+	m.Release(active)
+}
+
+func TestIsBroken(t *testing.T) {
+	if !IsBroken(fmt.Errorf("reading reply: %w", io.ErrUnexpectedEOF)) {
+		t.Error("expected a cut connection to be broken")
+	}
+	if IsBroken(errors.New(`relation "users" does not exist`)) {
+		t.Error("expected a statement error not to be broken")
+	}
+}
@@ -0,0 +1,295 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+const defaultCharset = "utf8mb4"
+
+func init() {
+	db.Register("mysql", Driver{})
+}
+
+type Driver struct{}
+
+func (Driver) Open(ctx context.Context, c config.Connection, addr string) (db.Session, error) {
+	cfg := gomysql.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = addr
+	cfg.User = c.Username
+	cfg.Passwd = c.Password
+	cfg.DBName = c.Database
+	cfg.ConnectionAttributes = "program_name:dbvi"
+	// Count the rows an UPDATE matched rather than changed, so one setting
+	// a row to what it holds isn't mistaken for a row that's gone.
+	cfg.ClientFoundRows = true
+
+	connector, err := gomysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pool only ever holds the session's connection, plus a short lived
+	// one to kill a running query.
+	pool := sql.OpenDB(connector)
+	pool.SetMaxIdleConns(0)
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	s := &Session{pool: pool, conn: conn}
+
+	// The handshake only knows the collations compiled into the client,
+	// setting them afterwards also allows newer ones like MariaDB's
+	// utf8mb4_uca1400_ai_ci.
+	if err := s.exec(ctx, setNames(c.Charset, c.Collation)); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("setting charset: %w", err)
+	}
+
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&s.id); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func setNames(charset, collation string) string {
+	if charset == "" {
+		charset = defaultCharset
+	}
+
+	if collation == "" {
+		return "SET NAMES " + charset
+	}
+
+	return "SET NAMES " + charset + " COLLATE " + collation
+}
+
+// Session is a single MySQL or MariaDB connection.
+type Session struct {
+	pool *sql.DB
+	conn *sql.Conn
+	id   int64 // server side connection id, used to kill queries
+
+	mu       sync.Mutex
+	onNotice db.NoticeHandler
+}
+
+// Query runs the statements in stmt one after another and returns the result
+// of the last one. The warnings of each statement are reported as notices.
+func (s *Session) Query(ctx context.Context, stmt string, args ...any) (db.Rows, error) {
+	stmts := query.Split(stmt, query.MySQL)
+	if len(stmts) == 0 {
+		stmts = []query.Statement{{Text: stmt}}
+	}
+
+	if len(stmts) > 1 && len(args) > 0 {
+		return nil, errors.New("bind parameters need a single statement")
+	}
+
+	for _, st := range stmts[:len(stmts)-1] {
+		rows, err := s.query(ctx, st.Text)
+		if err != nil {
+			return nil, serverError(err, st)
+		}
+
+		if _, err := db.Collect(rows, 0); err != nil {
+			return nil, serverError(err, st)
+		}
+	}
+
+	last := stmts[len(stmts)-1]
+	rows, err := s.query(ctx, last.Text, args...)
+	if err != nil {
+		return nil, serverError(err, last)
+	}
+
+	return rows, nil
+}
+
+// nearPattern matches the end of a syntax error, which quotes the text
+// following the error, up to 80 characters, and its line.
+var nearPattern = regexp.MustCompile(`(?s)near '(.*)' at line (\d+)$`)
+
+// serverError adds what the server said about err, if it came from the
+// server, pointing it at the part of st a syntax error is near.
+func serverError(err error, st query.Statement) error {
+	var myErr *gomysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return err
+	}
+
+	serverErr := &db.ServerError{Err: err, Message: myErr.Message, Offset: -1}
+	if myErr.SQLState != [5]byte{} {
+		serverErr.Code = string(myErr.SQLState[:])
+	}
+	if at, ok := nearOffset(st.Text, myErr.Message); ok {
+		serverErr.Offset = st.Offset + at
+	}
+
+	return serverErr
+}
+
+// nearOffset finds the byte offset in stmt of the text a syntax error
+// message says the error is near.
+func nearOffset(stmt, message string) (int, bool) {
+	m := nearPattern.FindStringSubmatch(message)
+	if m == nil {
+		return 0, false
+	}
+
+	line, _ := strconv.Atoi(m[2])
+	start := 0
+	for range line - 1 {
+		i := strings.IndexByte(stmt[start:], '\n')
+		if i < 0 {
+			return 0, false
+		}
+		start += i + 1
+	}
+
+	// Nothing is near an error at the end of the statement.
+	near := m[1]
+	if near == "" {
+		return len(strings.TrimRight(stmt, "; \t\n")), true
+	}
+
+	first, _, _ := strings.Cut(near, "\n")
+	if i := strings.Index(stmt[start:], first); i >= 0 {
+		return start + i, true
+	}
+
+	return start, true
+}
+
+func (s *Session) query(ctx context.Context, stmt string, args ...any) (db.Rows, error) {
+	if !returnsRows(stmt) {
+		res, err := s.conn.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			return nil, err
+		}
+		s.reportWarnings(ctx)
+
+		affected, _ := res.RowsAffected()
+		return &execRows{affected: affected}, nil
+	}
+
+	r, err := s.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRows(ctx, s, r)
+}
+
+// returnsRows guesses if stmt produces a result set. Statements that don't
+// are executed so their affected row count is known.
+func returnsRows(stmt string) bool {
+	c := query.Classify(stmt, query.MySQL)
+
+	switch c.Kind {
+	case query.KindWrite:
+		// MariaDB's INSERT/DELETE ... RETURNING
+		for _, tok := range query.Lex(stmt, query.MySQL) {
+			if tok.Keyword() == "RETURNING" {
+				return true
+			}
+		}
+		return false
+	case query.KindTransaction, query.KindSession:
+		return false
+	case query.KindDDL:
+		// ANALYZE, OPTIMIZE, REPAIR and friends report a table of results.
+		switch c.Keyword {
+		case "ANALYZE", "OPTIMIZE", "REPAIR":
+			return true
+		}
+		return false
+	}
+
+	return true
+}
+
+func (s *Session) exec(ctx context.Context, stmt string) error {
+	_, err := s.conn.ExecContext(ctx, stmt)
+	return err
+}
+
+// reportWarnings sends the warnings of the last statement to the notice
+// handler.
+func (s *Session) reportWarnings(ctx context.Context) {
+	s.mu.Lock()
+	h := s.onNotice
+	s.mu.Unlock()
+
+	if h == nil {
+		return
+	}
+
+	rows, err := s.conn.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var level, message string
+		var code int
+		if err := rows.Scan(&level, &code, &message); err != nil {
+			return
+		}
+
+		h(db.Notice{
+			Severity: strings.ToUpper(level),
+			Message:  message,
+			Detail:   fmt.Sprintf("code %d", code),
+		})
+	}
+}
+
+// Cancel kills the running query over a second connection, leaving this
+// session usable.
+func (s *Session) Cancel(ctx context.Context) error {
+	conn, err := s.pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", s.id))
+	return err
+}
+
+// Ping checks the connection is still alive.
+func (s *Session) Ping(ctx context.Context) error {
+	return s.conn.PingContext(ctx)
+}
+
+func (s *Session) OnNotice(h db.NoticeHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onNotice = h
+}
+
+func (s *Session) Close() error {
+	s.conn.Close()
+	return s.pool.Close()
+}
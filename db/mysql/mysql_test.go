@@ -0,0 +1,745 @@
+package mysql
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+)
+
+// Column types and flags of the MySQL protocol used by the fake server.
+const (
+	typeLong       = 0x03
+	typeFloat      = 0x04
+	typeLongLong   = 0x08
+	typeDate       = 0x0a
+	typeDateTime   = 0x0c
+	typeBit        = 0x10
+	typeJSON       = 0xf5
+	typeNewDecimal = 0xf6
+	typeBlob       = 0xfc
+	typeVarString  = 0xfd
+
+	flagUnsigned = 0x20
+
+	charsetUTF8   = 45
+	charsetBinary = 63
+)
+
+type fakeColumn struct {
+	name    string
+	typ     byte
+	flags   uint16
+	charset uint16
+}
+
+func column(name string, typ byte) fakeColumn {
+	return fakeColumn{name: name, typ: typ, charset: charsetUTF8}
+}
+
+type fakeWarning struct {
+	level   string
+	code    int
+	message string
+}
+
+// fakeResult is what the fake server answers to one query. Queries without
+// columns get an OK packet. Values are sent in the text protocol, nil for
+// NULL.
+type fakeResult struct {
+	columns  []fakeColumn
+	rows     [][]any
+	affected uint64
+	warnings []fakeWarning
+
+	next *fakeResult // another result set follows, as from a CALL
+}
+
+// fakeServer speaks just enough of the MySQL protocol to connect and answer
+// text protocol queries. Every query it receives is recorded.
+type fakeServer struct {
+	listener net.Listener
+	results  map[string]fakeResult
+
+	mu          sync.Mutex
+	nextID      uint32
+	queries     []string
+	clientFlags uint32 // capabilities of the last client to connect
+}
+
+func newFakeServer(t *testing.T, results map[string]fakeResult) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeServer{listener: l, results: results, nextID: 100}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) connection() (config.Connection, string) {
+	c := config.Connection{Name: "Fake", Type: "mysql", Host: "127.0.0.1", Username: "dbvi", Database: "dbvi"}
+	return c, s.listener.Addr().String()
+}
+
+// received returns the queries received so far.
+func (s *fakeServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.queries...)
+}
+
+type packetConn struct {
+	conn net.Conn
+	seq  byte
+}
+
+func (p *packetConn) read() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(p.conn, header[:]); err != nil {
+		return nil, err
+	}
+
+	n := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	p.seq = header[3] + 1
+
+	data := make([]byte, n)
+	_, err := io.ReadFull(p.conn, data)
+	return data, err
+}
+
+func (p *packetConn) write(data []byte) {
+	header := []byte{byte(len(data)), byte(len(data) >> 8), byte(len(data) >> 16), p.seq}
+	p.seq++
+	p.conn.Write(append(header, data...))
+}
+
+func lenEncInt(n uint64) []byte {
+	switch {
+	case n < 251:
+		return []byte{byte(n)}
+	case n < 1<<16:
+		return []byte{0xfc, byte(n), byte(n >> 8)}
+	default:
+		b := make([]byte, 9)
+		b[0] = 0xfe
+		binary.LittleEndian.PutUint64(b[1:], n)
+		return b
+	}
+}
+
+func lenEncString(s string) []byte {
+	return append(lenEncInt(uint64(len(s))), s...)
+}
+
+// Server status flags.
+const (
+	statusAutocommit  = 0x02
+	statusMoreResults = 0x08
+)
+
+func okPacket(affected uint64, warnings uint16) []byte {
+	return okPacketStatus(affected, warnings, statusAutocommit)
+}
+
+func okPacketStatus(affected uint64, warnings, status uint16) []byte {
+	b := append([]byte{0x00}, lenEncInt(affected)...)
+	b = append(b, 0) // last insert id
+	b = binary.LittleEndian.AppendUint16(b, status)
+	return binary.LittleEndian.AppendUint16(b, warnings)
+}
+
+func eofPacket() []byte {
+	return eofPacketStatus(statusAutocommit)
+}
+
+func eofPacketStatus(status uint16) []byte {
+	return binary.LittleEndian.AppendUint16([]byte{0xfe, 0, 0}, status)
+}
+
+func errPacket(code uint16, state, message string) []byte {
+	b := binary.LittleEndian.AppendUint16([]byte{0xff}, code)
+	b = append(b, '#')
+	b = append(b, state...)
+	return append(b, message...)
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.mu.Unlock()
+
+	p := &packetConn{conn: conn}
+
+	// protocol 41, secure connection, plugin auth and connect attributes
+	capabilities := uint32(0x1 | 0x8 | 0x200 | 0x2000 | 0x8000 | 0x80000 | 0x100000)
+
+	greeting := []byte{10}
+	greeting = append(greeting, "8.0.36-fake\x00"...)
+	greeting = binary.LittleEndian.AppendUint32(greeting, id)
+	greeting = append(greeting, "abcdefgh"...)
+	greeting = append(greeting, 0)
+	greeting = binary.LittleEndian.AppendUint16(greeting, uint16(capabilities))
+	greeting = append(greeting, charsetUTF8)
+	greeting = append(greeting, 0x02, 0x00)
+	greeting = binary.LittleEndian.AppendUint16(greeting, uint16(capabilities>>16))
+	greeting = append(greeting, 21)
+	greeting = append(greeting, make([]byte, 10)...)
+	greeting = append(greeting, "ijklmnopqrst\x00"...)
+	greeting = append(greeting, "mysql_native_password\x00"...)
+	p.write(greeting)
+
+	response, err := p.read()
+	if err != nil || len(response) < 4 {
+		return
+	}
+	s.mu.Lock()
+	s.clientFlags = binary.LittleEndian.Uint32(response)
+	s.mu.Unlock()
+	p.write(okPacket(0, 0))
+
+	var warnings []fakeWarning
+
+	for {
+		data, err := p.read()
+		if err != nil || len(data) == 0 {
+			return
+		}
+
+		switch data[0] {
+		case 0x01: // COM_QUIT
+			return
+		case 0x0e: // COM_PING
+			p.write(okPacket(0, 0))
+			continue
+		case 0x03: // COM_QUERY
+		default:
+			p.write(errPacket(1047, "08S01", "Unknown command"))
+			continue
+		}
+
+		q := string(data[1:])
+		s.mu.Lock()
+		s.queries = append(s.queries, q)
+		s.mu.Unlock()
+
+		res, ok := s.results[q]
+		switch {
+		case q == "SELECT CONNECTION_ID()":
+			res = fakeResult{
+				columns: []fakeColumn{{name: "CONNECTION_ID()", typ: typeLongLong, flags: flagUnsigned, charset: charsetBinary}},
+				rows:    [][]any{{strconv.FormatUint(uint64(id), 10)}},
+			}
+		case q == "SHOW WARNINGS":
+			res = fakeResult{columns: []fakeColumn{column("Level", typeVarString), column("Code", typeLong), column("Message", typeVarString)}}
+			for _, w := range warnings {
+				res.rows = append(res.rows, []any{w.level, strconv.Itoa(w.code), w.message})
+			}
+			s.sendResult(p, res)
+			continue
+		case strings.HasPrefix(q, "SET ") || strings.HasPrefix(q, "KILL QUERY "):
+			res = fakeResult{}
+		case !ok:
+			p.write(errPacket(1064, "42000", fmt.Sprintf("You have an error in your SQL syntax near '%s' at line 1", q)))
+			continue
+		}
+
+		warnings = res.warnings
+		s.sendResult(p, res)
+	}
+}
+
+func (s *fakeServer) sendResult(p *packetConn, res fakeResult) {
+	status := uint16(statusAutocommit)
+	if res.next != nil {
+		status |= statusMoreResults
+		defer s.sendResult(p, *res.next)
+	}
+
+	if len(res.columns) == 0 {
+		p.write(okPacketStatus(res.affected, uint16(len(res.warnings)), status))
+		return
+	}
+
+	p.write(lenEncInt(uint64(len(res.columns))))
+	for _, c := range res.columns {
+		var b []byte
+		for _, s := range []string{"def", "dbvi", "t", "t", c.name, c.name} {
+			b = append(b, lenEncString(s)...)
+		}
+		b = append(b, 0x0c)
+		b = binary.LittleEndian.AppendUint16(b, c.charset)
+		b = binary.LittleEndian.AppendUint32(b, 255)
+		b = append(b, c.typ)
+		b = binary.LittleEndian.AppendUint16(b, c.flags)
+		b = append(b, 0, 0, 0)
+		p.write(b)
+	}
+	p.write(eofPacket())
+
+	for _, row := range res.rows {
+		var b []byte
+		for _, v := range row {
+			if v == nil {
+				b = append(b, 0xfb)
+				continue
+			}
+			b = append(b, lenEncString(v.(string))...)
+		}
+		p.write(b)
+	}
+	p.write(eofPacketStatus(status))
+}
+
+func openFake(t *testing.T, s *fakeServer, c config.Connection) db.Session {
+	t.Helper()
+
+	_, addr := s.connection()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := Driver{}.Open(ctx, c, addr)
+	if err != nil {
+		t.Fatalf("connecting to fake server: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+
+	return session
+}
+
+func TestOpenCharset(t *testing.T) {
+	s := newFakeServer(t, nil)
+
+	c, _ := s.connection()
+	openFake(t, s, c)
+
+	c.Charset = "latin1"
+	c.Collation = "latin1_swedish_ci"
+	openFake(t, s, c)
+
+	var setNames []string
+	for _, q := range s.received() {
+		if strings.HasPrefix(q, "SET NAMES") {
+			setNames = append(setNames, q)
+		}
+	}
+
+	want := []string{"SET NAMES utf8mb4", "SET NAMES latin1 COLLATE latin1_swedish_ci"}
+	if !reflect.DeepEqual(setNames, want) {
+		t.Errorf("got %q, want %q", setNames, want)
+	}
+}
+
+func TestOpenFoundRows(t *testing.T) {
+	s := newFakeServer(t, nil)
+
+	c, _ := s.connection()
+	openFake(t, s, c)
+
+	// An UPDATE writing the values a row already has still counts it, so
+	// edits of the results can tell it from a row that's gone.
+	const clientFoundRows = 0x2
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clientFlags&clientFoundRows == 0 {
+		t.Errorf("got client flags %#x, want CLIENT_FOUND_ROWS", s.clientFlags)
+	}
+}
+
+func TestQueryTypeMapping(t *testing.T) {
+	s := newFakeServer(t, map[string]fakeResult{
+		"SELECT types": {
+			columns: []fakeColumn{
+				column("price", typeNewDecimal),
+				column("created", typeDateTime),
+				column("zero", typeDateTime),
+				column("day", typeDate),
+				{name: "flags", typ: typeBit, charset: charsetBinary},
+				{name: "avatar", typ: typeBlob, charset: charsetBinary},
+				{name: "doc", typ: typeJSON, charset: charsetBinary},
+				{name: "big", typ: typeLongLong, flags: flagUnsigned, charset: charsetBinary},
+				{name: "ratio", typ: typeFloat, charset: charsetBinary},
+				column("name", typeVarString),
+				column("missing", typeVarString),
+			},
+			rows: [][]any{{
+				"12345.678901234567890",
+				"2024-03-01 12:34:56.789",
+				"0000-00-00 00:00:00",
+				"2024-03-01",
+				"\x01\x05",
+				"\x01\x02\xff",
+				`{"b": 1, "a": [1.50]}`,
+				"18446744073709551615",
+				"1.1",
+				"héllo",
+				nil,
+			}},
+		},
+	})
+
+	c, _ := s.connection()
+	session := openFake(t, s, c)
+
+	res, err := db.Exec(context.Background(), session, "SELECT types")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantTypes := []string{"DECIMAL", "DATETIME", "DATETIME", "DATE", "BIT", "BLOB", "JSON", "UNSIGNED BIGINT", "FLOAT", "VARCHAR", "VARCHAR"}
+	for i, c := range res.Columns {
+		if c.Type != wantTypes[i] {
+			t.Errorf("%s: got type %q, want %q", c.Name, c.Type, wantTypes[i])
+		}
+	}
+
+	if len(res.Rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(res.Rows))
+	}
+
+	want := []string{
+		"12345.678901234567890",
+		"2024-03-01 12:34:56.789",
+		"0000-00-00 00:00:00",
+		"2024-03-01",
+		"b'100000101'",
+		`\x0102ff`,
+		`{"b": 1, "a": [1.50]}`,
+		"18446744073709551615",
+		"1.1",
+		"héllo",
+		"NULL",
+	}
+
+	for i, v := range res.Rows[0] {
+		if got := db.FormatValue(v); got != want[i] {
+			t.Errorf("%s: got %q (%T), want %q", res.Columns[i].Name, got, v, want[i])
+		}
+	}
+
+	if _, ok := res.Rows[0][0].(db.Decimal); !ok {
+		t.Errorf("expected DECIMAL to be a db.Decimal, got %T", res.Rows[0][0])
+	}
+
+	if _, ok := res.Rows[0][7].(uint64); !ok {
+		t.Errorf("expected UNSIGNED BIGINT to be a uint64, got %T", res.Rows[0][7])
+	}
+
+	if res.RowsAffected != 1 {
+		t.Errorf("got %d rows affected, want 1", res.RowsAffected)
+	}
+}
+
+func TestQueryWarnings(t *testing.T) {
+	s := newFakeServer(t, map[string]fakeResult{
+		"INSERT INTO t VALUES ('too long')": {
+			affected: 1,
+			warnings: []fakeWarning{{level: "Warning", code: 1265, message: "Data truncated for column 'name' at row 1"}},
+		},
+		"SELECT 1": {
+			columns: []fakeColumn{column("1", typeLong)},
+			rows:    [][]any{{"1"}},
+		},
+	})
+
+	c, _ := s.connection()
+	session := openFake(t, s, c)
+
+	var notices []db.Notice
+	session.(db.NoticeReporter).OnNotice(func(n db.Notice) {
+		notices = append(notices, n)
+	})
+
+	res, err := db.Exec(context.Background(), session, "INSERT INTO t VALUES ('too long')")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.RowsAffected != 1 || len(res.Columns) != 0 {
+		t.Errorf("got %d rows affected and %d columns, want 1 and none", res.RowsAffected, len(res.Columns))
+	}
+
+	want := []db.Notice{{Severity: "WARNING", Message: "Data truncated for column 'name' at row 1", Detail: "code 1265"}}
+	if !reflect.DeepEqual(notices, want) {
+		t.Errorf("got notices %+v, want %+v", notices, want)
+	}
+
+	// Statements returning rows are checked once drained.
+	notices = nil
+	if _, err := db.Exec(context.Background(), session, "SELECT 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(notices) != 0 {
+		t.Errorf("got notices %+v, want none", notices)
+	}
+
+	queries := s.received()
+	if got := queries[len(queries)-1]; got != "SHOW WARNINGS" {
+		t.Errorf("last query %q, want SHOW WARNINGS", got)
+	}
+}
+
+func TestQueryMultiStatement(t *testing.T) {
+	s := newFakeServer(t, map[string]fakeResult{
+		"UPDATE t SET n = n + 1": {affected: 3},
+		"SELECT @a": {
+			columns: []fakeColumn{column("@a", typeLong)},
+			rows:    [][]any{{"1"}},
+		},
+	})
+
+	c, _ := s.connection()
+	session := openFake(t, s, c)
+
+	res, err := db.Exec(context.Background(), session, "SET @a = 1;\nUPDATE t SET n = n + 1;\nSELECT @a;")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(res.Rows) != 1 || res.Rows[0][0] != int64(1) {
+		t.Errorf("got rows %+v, want the result of the last statement", res.Rows)
+	}
+
+	var got []string
+	for _, q := range s.received() {
+		if q != "SHOW WARNINGS" && q != "SELECT CONNECTION_ID()" && !strings.HasPrefix(q, "SET NAMES") {
+			got = append(got, q)
+		}
+	}
+
+	want := []string{"SET @a = 1", "UPDATE t SET n = n + 1", "SELECT @a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got queries %q, want %q", got, want)
+	}
+}
+
+func TestQueryResultSets(t *testing.T) {
+	s := newFakeServer(t, map[string]fakeResult{
+		"CALL report()": {
+			columns: []fakeColumn{column("n", typeLong)},
+			rows:    [][]any{{"1"}, {"2"}},
+			next: &fakeResult{
+				columns: []fakeColumn{column("name", typeVarString)},
+				rows:    [][]any{{"a"}},
+				next:    &fakeResult{},
+			},
+		},
+	})
+
+	c, _ := s.connection()
+	session := openFake(t, s, c)
+
+	st, err := db.OpenStream(context.Background(), session, "CALL report()", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows, err := st.Fetch(10)
+	if err != nil || len(rows) != 2 || !st.Done() || st.Columns()[0].Name != "n" {
+		t.Fatalf("got rows %v, columns %v and error %v, want the first result set", rows, st.Columns(), err)
+	}
+
+	if !st.NextResultSet() {
+		t.Fatal("expected a second result set")
+	}
+	rows, err = st.Fetch(10)
+	if err != nil || !reflect.DeepEqual(rows, [][]any{{"a"}}) || st.Columns()[0].Name != "name" {
+		t.Fatalf("got rows %v, columns %v and error %v, want the second result set", rows, st.Columns(), err)
+	}
+
+	if st.NextResultSet() {
+		t.Error("expected no more result sets, the status of the CALL has no columns")
+	}
+	if err := st.Close(); err != nil {
+		t.Errorf("unexpected error closing: %v", err)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	s := newFakeServer(t, nil)
+	c, _ := s.connection()
+	session := openFake(t, s, c)
+
+	_, err := db.Exec(context.Background(), session, "SELEC 1")
+	if err == nil || !strings.Contains(err.Error(), "1064") {
+		t.Fatalf("got %v, want a syntax error", err)
+	}
+
+	// The session stays usable after an error, which points into the
+	// statement that failed.
+	_, err = db.Exec(context.Background(), session, "SET a = 1;\nSELEC 2")
+	var serverErr *db.ServerError
+	if !errors.As(err, &serverErr) {
+		t.Fatalf("got %v, want a server error", err)
+	}
+	if serverErr.Code != "42000" || serverErr.Offset != 11 {
+		t.Errorf("got code %s at %d, want 42000 at 11", serverErr.Code, serverErr.Offset)
+	}
+}
+
+func TestNearOffset(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		message string
+		want    int
+		ok      bool
+	}{
+		{"SELEC 1", "You have an error in your SQL syntax; check the manual that corresponds to your MySQL server version for the right syntax to use near 'SELEC 1' at line 1", 0, true},
+		{"SELECT *\nFROM t\nWHERE a = = 1", "... to use near '= 1' at line 3", 26, true},
+		{"SELECT *\nFROM t WHERE\n a = 1 AND", "... to use near '' at line 3", 32, true},
+		{"SELECT a,\n  FROM t", "... to use near 'FROM t\n' at line 2", 12, true},
+		{"SELECT nope FROM t", "Unknown column 'nope' in 'field list'", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := nearOffset(tt.stmt, tt.message)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("nearOffset(%q, %q) = %d, %v, want %d, %v", tt.stmt, tt.message, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := newFakeServer(t, nil)
+	c, _ := s.connection()
+	session := openFake(t, s, c)
+
+	if err := session.(db.Canceler).Cancel(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The session's connection was the first one, with id 100.
+	queries := s.received()
+	if got := queries[len(queries)-1]; got != "KILL QUERY 100" {
+		t.Errorf("last query %q, want KILL QUERY 100", got)
+	}
+}
+
+func TestCloseEarly(t *testing.T) {
+	s := newFakeServer(t, map[string]fakeResult{
+		"SELECT n FROM t": {
+			columns: []fakeColumn{column("n", typeLong)},
+			rows:    [][]any{{"1"}, {"2"}, {"3"}},
+		},
+	})
+	c, _ := s.connection()
+	session := openFake(t, s, c)
+
+	st, err := db.OpenStream(context.Background(), session, "SELECT n FROM t", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows, err := st.Fetch(1); err != nil || len(rows) != 1 {
+		t.Fatalf("got rows %v and error %v, want the first row", rows, err)
+	}
+
+	if err := st.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	// The query is killed rather than read to the end.
+	queries := s.received()
+	if got := queries[len(queries)-1]; got != "KILL QUERY 100" {
+		t.Errorf("last query %q, want KILL QUERY 100", got)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want string
+	}{
+		{b: []byte{0}, want: "0"},
+		{b: []byte{0x05}, want: "101"},
+		{b: []byte{0x01, 0x00}, want: "100000000"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBits(tt.b); got != tt.want {
+			t.Errorf("formatBits(%x): got %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestIntegration runs against a real server when DBVI_TEST_MYSQL_ADDR
+// (host:port) is set, using DBVI_TEST_MYSQL_USER, _PASSWORD and _DATABASE.
+func TestIntegration(t *testing.T) {
+	addr := os.Getenv("DBVI_TEST_MYSQL_ADDR")
+	if addr == "" {
+		t.Skip("DBVI_TEST_MYSQL_ADDR not set")
+	}
+
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	c := config.Connection{
+		Name:     "Integration",
+		Type:     "mysql",
+		Host:     host,
+		Port:     p,
+		Username: os.Getenv("DBVI_TEST_MYSQL_USER"),
+		Password: os.Getenv("DBVI_TEST_MYSQL_PASSWORD"),
+		Database: os.Getenv("DBVI_TEST_MYSQL_DATABASE"),
+	}
+
+	ctx := context.Background()
+	session, err := Driver{}.Open(ctx, c, c.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	var notices []db.Notice
+	session.(db.NoticeReporter).OnNotice(func(n db.Notice) { notices = append(notices, n) })
+
+	res, err := db.Exec(ctx, session, `SELECT CAST(1.50 AS DECIMAL(4,2)), CAST(18446744073709551615 AS UNSIGNED), b'101', x'01', JSON_OBJECT('a', 1), NULL`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"1.50", "18446744073709551615", "b'101'", `\x01`, `{"a": 1}`, "NULL"}
+	for i, v := range res.Rows[0] {
+		if got := db.FormatValue(v); got != want[i] {
+			t.Errorf("column %d: got %q, want %q", i, got, want[i])
+		}
+	}
+
+	if _, err := db.Exec(ctx, session, "SELECT CAST('x' AS SIGNED)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(fmt.Sprint(notices), "Truncated") {
+		t.Errorf("got notices %+v, want a truncation warning", notices)
+	}
+}
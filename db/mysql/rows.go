@@ -0,0 +1,238 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+// killTimeout bounds the KILL QUERY sent when rows are closed early.
+const killTimeout = 5 * time.Second
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05.999999"
+)
+
+type rows struct {
+	ctx     context.Context
+	session *Session
+	rows    *sql.Rows
+	columns []db.Column
+	count   int64
+	err     error
+	done    bool // every row was read
+	closed  bool
+}
+
+func newRows(ctx context.Context, s *Session, r *sql.Rows) (*rows, error) {
+	columns, err := columnsOf(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return &rows{ctx: ctx, session: s, rows: r, columns: columns}, nil
+}
+
+func columnsOf(r *sql.Rows) ([]db.Column, error) {
+	types, err := r.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]db.Column, len(types))
+	for i, t := range types {
+		columns[i] = db.Column{Name: t.Name(), Type: t.DatabaseTypeName()}
+	}
+
+	return columns, nil
+}
+
+func (r *rows) Columns() []db.Column {
+	return r.columns
+}
+
+func (r *rows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	if !r.rows.Next() {
+		r.done = true
+		return false
+	}
+
+	r.count++
+	return true
+}
+
+func (r *rows) Values() []any {
+	values := make([]any, len(r.columns))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := r.rows.Scan(dest...); err != nil {
+		r.err = err
+		return values
+	}
+
+	for i, v := range values {
+		values[i] = convertValue(r.columns[i].Type, v)
+	}
+
+	return values
+}
+
+// NextResultSet moves on to the next result set of a CALL, which can return
+// one per SELECT of the procedure.
+func (r *rows) NextResultSet() bool {
+	if r.err != nil || !r.rows.NextResultSet() {
+		return false
+	}
+
+	columns, err := columnsOf(r.rows)
+	if err != nil {
+		r.err = err
+		return false
+	}
+
+	r.columns, r.count, r.done = columns, 0, false
+	return true
+}
+
+func (r *rows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return r.rows.Err()
+}
+
+// Close reports the statement's warnings once its result set is drained.
+// Closing early kills the query, so the rest of a large result isn't read
+// off the connection only to be thrown away.
+func (r *rows) Close() error {
+	if r.closed {
+		return r.Err()
+	}
+	r.closed = true
+
+	if !r.done && r.err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+		defer cancel()
+
+		r.session.Cancel(ctx)
+		r.rows.Close()
+		return nil
+	}
+
+	if err := r.rows.Close(); err != nil {
+		return err
+	}
+
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	r.session.reportWarnings(r.ctx)
+	return nil
+}
+
+func (r *rows) Tag() string {
+	return ""
+}
+
+func (r *rows) RowsAffected() int64 {
+	return r.count
+}
+
+// execRows is the empty result of a statement that doesn't return rows.
+type execRows struct {
+	affected int64
+}
+
+func (r *execRows) Columns() []db.Column { return nil }
+func (r *execRows) Next() bool           { return false }
+func (r *execRows) Values() []any        { return nil }
+func (r *execRows) Err() error           { return nil }
+func (r *execRows) Close() error         { return nil }
+func (r *execRows) Tag() string          { return "" }
+func (r *execRows) RowsAffected() int64  { return r.affected }
+
+// convertValue maps the values the driver returns, mostly raw bytes in the
+// text protocol, to the displayable types documented on db.Rows based on the
+// column's type name.
+func convertValue(typeName string, v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return convertBytes(typeName, v)
+	case float32:
+		// Go through the shortest representation so 1.1 doesn't turn into
+		// 1.100000023841858.
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+		return f
+	default:
+		return v
+	}
+}
+
+func convertBytes(typeName string, b []byte) any {
+	switch typeName {
+	case "DECIMAL":
+		return db.Decimal(b)
+	case "DATETIME", "TIMESTAMP":
+		// Zero dates like 0000-00-00 00:00:00 aren't valid times, show them
+		// as the server sent them.
+		if t, err := time.Parse(dateTimeLayout, string(b)); err == nil {
+			return t
+		}
+		return string(b)
+	case "DATE":
+		if t, err := time.Parse(dateLayout, string(b)); err == nil {
+			return db.Date{Time: t}
+		}
+		return string(b)
+	case "BIT":
+		return db.Bits(formatBits(b))
+	case "JSON":
+		return db.JSON(b)
+	case "BINARY", "VARBINARY", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "GEOMETRY":
+		return b
+	case "UNSIGNED BIGINT":
+		if n, err := strconv.ParseUint(string(b), 10, 64); err == nil {
+			return n
+		}
+		return string(b)
+	case "BIGINT", "INT", "UNSIGNED INT", "MEDIUMINT", "UNSIGNED MEDIUMINT",
+		"SMALLINT", "UNSIGNED SMALLINT", "TINYINT", "UNSIGNED TINYINT", "YEAR":
+		if n, err := strconv.ParseInt(string(b), 10, 64); err == nil {
+			return n
+		}
+		return string(b)
+	default:
+		return string(b)
+	}
+}
+
+// formatBits renders a BIT value without its leading zero bits, which the
+// server pads to whole bytes.
+func formatBits(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		sb.WriteString(strconv.FormatUint(uint64(c)|0x100, 2)[1:])
+	}
+
+	s := strings.TrimLeft(sb.String(), "0")
+	if s == "" {
+		return "0"
+	}
+
+	return s
+}
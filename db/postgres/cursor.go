@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// cursorName is the server side cursor QueryPages reads through. A session
+// only ever has one stream open.
+const cursorName = "dbvi_cursor"
+
+// QueryPages runs a read through a cursor, FETCHing pageSize rows at a time.
+// Cursors live in a transaction, one is opened for it when none is and
+// committed once the rows are closed. Other statements, and reads in a
+// transaction that already failed, go through Query.
+func (s *Session) QueryPages(ctx context.Context, stmt string, pageSize int) (db.Rows, error) {
+	status := s.conn.PgConn().TxStatus()
+	if !cursorable(stmt) || status == 'E' {
+		return s.Query(ctx, stmt)
+	}
+
+	r := &cursorRows{ctx: ctx, session: s, pageSize: pageSize, ownTxn: status == 'I'}
+	if r.ownTxn {
+		if _, err := s.conn.Exec(ctx, "BEGIN", pgx.QueryExecModeSimpleProtocol); err != nil {
+			return nil, err
+		}
+	}
+
+	prefix := fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR ", cursorName)
+	body := strings.TrimRight(stmt, "; \t\n")
+	if _, err := s.conn.Exec(ctx, prefix+body, pgx.QueryExecModeSimpleProtocol); err != nil {
+		r.end()
+
+		// Point error positions at the statement rather than the DECLARE.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Position > int32(len(prefix)) {
+			pgErr.Position -= int32(len(prefix))
+		}
+		return nil, serverError(err, body)
+	}
+
+	if err := r.fetch(); err != nil {
+		r.end()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// cursorable reports if stmt is a single read DECLARE accepts.
+func cursorable(stmt string) bool {
+	if len(query.Split(stmt, query.Postgres)) != 1 {
+		return false
+	}
+
+	class := query.Classify(stmt, query.Postgres)
+	if class.Kind != query.KindRead {
+		return false
+	}
+
+	switch class.Keyword {
+	case "SELECT", "WITH", "VALUES", "TABLE":
+		return true
+	}
+
+	return false
+}
+
+// cursorRows reads the pages of a cursor as one result.
+type cursorRows struct {
+	ctx      context.Context
+	session  *Session
+	pageSize int
+	ownTxn   bool
+
+	page    *rows
+	columns []db.Column
+	inPage  int
+	total   int64
+	err     error
+	closed  bool
+}
+
+// fetch reads the next page from the cursor.
+func (r *cursorRows) fetch() error {
+	stmt := fmt.Sprintf("FETCH FORWARD %d FROM %s", r.pageSize, cursorName)
+	rows, err := r.session.conn.Query(r.ctx, stmt, pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		return err
+	}
+
+	r.page = newRows(rows, r.session.conn.TypeMap(), stmt)
+	r.inPage = 0
+	if r.columns == nil {
+		r.columns = r.page.Columns()
+	}
+
+	return nil
+}
+
+func (r *cursorRows) Columns() []db.Column {
+	return r.columns
+}
+
+func (r *cursorRows) Next() bool {
+	for r.err == nil {
+		if r.page.Next() {
+			r.inPage++
+			r.total++
+			return true
+		}
+
+		if err := r.page.Close(); err != nil {
+			r.err = err
+			return false
+		}
+
+		// A short page was the last one.
+		if r.inPage < r.pageSize {
+			return false
+		}
+
+		r.err = r.fetch()
+	}
+
+	return false
+}
+
+func (r *cursorRows) Values() []any {
+	return r.page.Values()
+}
+
+func (r *cursorRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return r.page.Err()
+}
+
+// Close drops the cursor, committing the transaction opened for it.
+func (r *cursorRows) Close() error {
+	if r.closed {
+		return r.Err()
+	}
+	r.closed = true
+
+	r.page.Close()
+	r.end()
+
+	return r.Err()
+}
+
+// end closes the cursor, or ends the transaction opened for it which
+// closes it too.
+func (r *cursorRows) end() {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	stmt := "CLOSE " + cursorName
+	if r.ownTxn {
+		stmt = "COMMIT"
+	}
+
+	r.session.conn.Exec(ctx, stmt, pgx.QueryExecModeSimpleProtocol)
+}
+
+func (r *cursorRows) Tag() string {
+	return fmt.Sprintf("SELECT %d", r.total)
+}
+
+func (r *cursorRows) RowsAffected() int64 {
+	return r.total
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const closeTimeout = 5 * time.Second
+
+func init() {
+	db.Register("postgres", Driver{})
+}
+
+type Driver struct{}
+
+func (Driver) Open(ctx context.Context, c config.Connection, addr string) (db.Session, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q: %w", port, err)
+	}
+
+	cfg, err := pgx.ParseConfig("")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Host = host
+	cfg.Port = uint16(p)
+	cfg.User = c.Username
+	cfg.Password = c.Password
+	cfg.Database = c.Database
+	cfg.RuntimeParams["application_name"] = "dbvi"
+
+	// TLS fallbacks dial the same host, keep them pointed at the tunnel.
+	for _, fb := range cfg.Fallbacks {
+		fb.Host = host
+		fb.Port = uint16(p)
+	}
+
+	s := &Session{}
+	cfg.OnNotice = func(_ *pgconn.PgConn, n *pgconn.Notice) {
+		s.notice(db.Notice{
+			Severity: n.Severity,
+			Message:  n.Message,
+			Detail:   n.Detail,
+			Hint:     n.Hint,
+		})
+	}
+
+	s.conn, err = pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Session is a single Postgres connection.
+type Session struct {
+	conn *pgx.Conn
+
+	mu       sync.Mutex
+	onNotice db.NoticeHandler
+}
+
+// Query runs stmt with the simple protocol, which allows several statements
+// in one string, unless args are given in which case the extended protocol
+// sends them as bind parameters.
+func (s *Session) Query(ctx context.Context, stmt string, args ...any) (db.Rows, error) {
+	var rows pgx.Rows
+	var err error
+
+	if len(args) == 0 {
+		rows, err = s.conn.Query(ctx, stmt, pgx.QueryExecModeSimpleProtocol)
+	} else {
+		rows, err = s.conn.Query(ctx, stmt, args...)
+	}
+	if err != nil {
+		return nil, serverError(err, stmt)
+	}
+
+	return newRows(rows, s.conn.TypeMap(), stmt), nil
+}
+
+// serverError adds what the server said about err, if it came from the
+// server, converting its position in stmt to a byte offset.
+func serverError(err error, stmt string) error {
+	var pgErr *pgconn.PgError
+	var known *db.ServerError
+	if !errors.As(err, &pgErr) || errors.As(err, &known) {
+		return err
+	}
+
+	offset := -1
+	if pgErr.Position > 0 {
+		// Positions count characters from 1.
+		n := int(pgErr.Position) - 1
+		for i := range stmt {
+			if n == 0 {
+				offset = i
+				break
+			}
+			n--
+		}
+	}
+
+	return &db.ServerError{
+		Err:     err,
+		Code:    pgErr.Code,
+		Message: pgErr.Message,
+		Detail:  pgErr.Detail,
+		Hint:    pgErr.Hint,
+		Offset:  offset,
+	}
+}
+
+// Cancel asks the server to cancel the running statement over a separate
+// connection, leaving this session usable.
+func (s *Session) Cancel(ctx context.Context) error {
+	return s.conn.PgConn().CancelRequest(ctx)
+}
+
+// Ping checks the connection is still alive.
+func (s *Session) Ping(ctx context.Context) error {
+	return s.conn.Ping(ctx)
+}
+
+func (s *Session) OnNotice(h db.NoticeHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onNotice = h
+}
+
+func (s *Session) notice(n db.Notice) {
+	s.mu.Lock()
+	h := s.onNotice
+	s.mu.Unlock()
+
+	if h != nil {
+		h(n)
+	}
+}
+
+func (s *Session) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	err := s.conn.Close(ctx)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,549 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeResult is what the fake server answers to one query. Values are sent
+// in the text format.
+type fakeResult struct {
+	fields  []pgproto3.FieldDescription
+	rows    [][][]byte
+	tag     string
+	notices []string
+}
+
+func field(name string, oid uint32) pgproto3.FieldDescription {
+	return pgproto3.FieldDescription{Name: []byte(name), DataTypeOID: oid, DataTypeSize: -1, TypeModifier: -1}
+}
+
+// fakeServer speaks just enough of the Postgres wire protocol to connect,
+// run simple and extended protocol queries and receive cancel requests.
+type fakeServer struct {
+	listener net.Listener
+	results  map[string]fakeResult
+	cancels  chan pgproto3.CancelRequest
+
+	mu      sync.Mutex
+	queries []string // simple protocol queries, in the order received
+}
+
+const (
+	fakePID    = 4242
+	fakeSecret = 777
+)
+
+func newFakeServer(t *testing.T, results map[string]fakeResult) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeServer{listener: l, results: results, cancels: make(chan pgproto3.CancelRequest, 1)}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) connection() (config.Connection, string) {
+	c := config.Connection{Name: "Fake", Type: "postgres", Host: "127.0.0.1", Username: "dbvi", Database: "dbvi"}
+	return c, s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	backend := pgproto3.NewBackend(conn, conn)
+
+	for {
+		msg, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			return
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.SSLRequest, *pgproto3.GSSEncRequest:
+			conn.Write([]byte("N"))
+			continue
+		case *pgproto3.CancelRequest:
+			s.cancels <- *msg
+			return
+		}
+
+		break
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	for name, value := range map[string]string{
+		"server_version":              "16.0",
+		"client_encoding":             "UTF8",
+		"standard_conforming_strings": "on",
+		"DateStyle":                   "ISO, MDY",
+		"integer_datetimes":           "on",
+		"TimeZone":                    "UTC",
+	} {
+		backend.Send(&pgproto3.ParameterStatus{Name: name, Value: value})
+	}
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: fakePID, SecretKey: fakeSecret})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	statements := map[string]string{}
+	var portalQuery string
+	var portalParams [][]byte
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			s.mu.Lock()
+			s.queries = append(s.queries, msg.String)
+			s.mu.Unlock()
+
+			res, ok := s.results[msg.String]
+			if !ok {
+				backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "42601", Message: "syntax error at or near \"" + msg.String + "\"", Position: 1})
+			} else {
+				s.sendResult(backend, res, true)
+			}
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.Parse:
+			statements[msg.Name] = msg.Query
+			backend.Send(&pgproto3.ParseComplete{})
+		case *pgproto3.Describe:
+			query := statements[msg.Name]
+			if msg.ObjectType == 'P' {
+				query = portalQuery
+			}
+
+			res := s.results[query]
+			if msg.ObjectType == 'S' {
+				oids := make([]uint32, len(res.fields))
+				for i := range oids {
+					oids[i] = pgtype.TextOID
+				}
+				backend.Send(&pgproto3.ParameterDescription{ParameterOIDs: oids})
+			}
+
+			if len(res.fields) == 0 {
+				backend.Send(&pgproto3.NoData{})
+			} else {
+				backend.Send(&pgproto3.RowDescription{Fields: res.fields})
+			}
+		case *pgproto3.Bind:
+			portalQuery = statements[msg.PreparedStatement]
+			portalParams = nil
+			for _, p := range msg.Parameters {
+				portalParams = append(portalParams, append([]byte(nil), p...))
+			}
+			backend.Send(&pgproto3.BindComplete{})
+		case *pgproto3.Execute:
+			// Parameterized queries echo their parameters back as the row.
+			res := s.results[portalQuery]
+			res.rows = [][][]byte{portalParams}
+			s.sendResult(backend, res, false)
+		case *pgproto3.Sync:
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.Terminate:
+			return
+		}
+
+		if err := backend.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.queries...)
+}
+
+func (s *fakeServer) sendResult(backend *pgproto3.Backend, res fakeResult, describe bool) {
+	for _, n := range res.notices {
+		backend.Send(&pgproto3.NoticeResponse{Severity: "NOTICE", Message: n})
+	}
+
+	if describe && len(res.fields) > 0 {
+		backend.Send(&pgproto3.RowDescription{Fields: res.fields})
+	}
+
+	for _, row := range res.rows {
+		backend.Send(&pgproto3.DataRow{Values: row})
+	}
+
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(res.tag)})
+}
+
+func openFake(t *testing.T, s *fakeServer) db.Session {
+	t.Helper()
+
+	c, addr := s.connection()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := Driver{}.Open(ctx, c, addr)
+	if err != nil {
+		t.Fatalf("connecting to fake server: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+
+	return session
+}
+
+func TestQueryTypeMapping(t *testing.T) {
+	s := newFakeServer(t, map[string]fakeResult{
+		"SELECT types": {
+			fields: []pgproto3.FieldDescription{
+				field("numeric", pgtype.NumericOID),
+				field("timestamptz", pgtype.TimestamptzOID),
+				field("date", pgtype.DateOID),
+				field("interval", pgtype.IntervalOID),
+				field("ints", pgtype.Int4ArrayOID),
+				field("doc", pgtype.JSONBOID),
+				field("bytes", pgtype.ByteaOID),
+				field("id", pgtype.UUIDOID),
+				field("mood", 90001),
+				field("flags", pgtype.BitOID),
+				field("missing", pgtype.TextOID),
+			},
+			rows: [][][]byte{{
+				[]byte("12345.678901234567890"),
+				[]byte("2024-03-01 12:34:56.789+00"),
+				[]byte("2024-03-01"),
+				[]byte("1 year 2 mons 3 days 04:05:06.5"),
+				[]byte("{1,NULL,3}"),
+				[]byte(`{"b": 1, "a": [1.50]}`),
+				[]byte(`\x0102ff`),
+				[]byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"),
+				[]byte("happy"),
+				[]byte("101"),
+				nil,
+			}},
+			tag: "SELECT 1",
+		},
+	})
+
+	session := openFake(t, s)
+
+	res, err := db.Exec(context.Background(), session, "SELECT types")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantColumns := []db.Column{
+		{Name: "numeric", Type: "numeric"},
+		{Name: "timestamptz", Type: "timestamptz"},
+		{Name: "date", Type: "date"},
+		{Name: "interval", Type: "interval"},
+		{Name: "ints", Type: "_int4"},
+		{Name: "doc", Type: "jsonb"},
+		{Name: "bytes", Type: "bytea"},
+		{Name: "id", Type: "uuid"},
+		{Name: "mood", Type: "90001"},
+		{Name: "flags", Type: "bit"},
+		{Name: "missing", Type: "text"},
+	}
+	if !reflect.DeepEqual(res.Columns, wantColumns) {
+		t.Errorf("got columns %+v, want %+v", res.Columns, wantColumns)
+	}
+
+	if len(res.Rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(res.Rows))
+	}
+
+	want := []string{
+		"12345.678901234567890",
+		"2024-03-01 12:34:56.789",
+		"2024-03-01",
+		"1 year 2 mons 3 days 04:05:06.5",
+		"{1,NULL,3}",
+		`{"b": 1, "a": [1.50]}`,
+		`\x0102ff`,
+		"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"happy",
+		"b'101'",
+		"NULL",
+	}
+
+	for i, v := range res.Rows[0] {
+		got := db.FormatValue(v)
+		if tm, ok := v.(time.Time); ok {
+			got = db.FormatValue(tm.UTC())
+		}
+
+		if got != want[i] {
+			t.Errorf("%s: got %q (%T), want %q", res.Columns[i].Name, got, v, want[i])
+		}
+	}
+
+	if _, ok := res.Rows[0][0].(db.Decimal); !ok {
+		t.Errorf("expected numeric to be a db.Decimal, got %T", res.Rows[0][0])
+	}
+
+	if res.Tag != "SELECT 1" {
+		t.Errorf("got tag %q, want SELECT 1", res.Tag)
+	}
+}
+
+func TestQueryCommandTagAndNotices(t *testing.T) {
+	s := newFakeServer(t, map[string]fakeResult{
+		"INSERT INTO t SELECT 1": {
+			tag:     "INSERT 0 3",
+			notices: []string{"hello from RAISE"},
+		},
+	})
+
+	session := openFake(t, s)
+
+	notices := make(chan db.Notice, 1)
+	session.(db.NoticeReporter).OnNotice(func(n db.Notice) {
+		notices <- n
+	})
+
+	res, err := db.Exec(context.Background(), session, "INSERT INTO t SELECT 1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.Tag != "INSERT 0 3" || res.RowsAffected != 3 {
+		t.Errorf("got tag %q affecting %d rows, want INSERT 0 3 affecting 3", res.Tag, res.RowsAffected)
+	}
+
+	select {
+	case n := <-notices:
+		if n.String() != "NOTICE: hello from RAISE" {
+			t.Errorf("got notice %q", n)
+		}
+	default:
+		t.Error("expected a notice")
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	session := openFake(t, newFakeServer(t, nil))
+
+	_, err := db.Exec(context.Background(), session, "SELEC 1")
+	var serverErr *db.ServerError
+	if !errors.As(err, &serverErr) {
+		t.Fatalf("got %v, want a server error", err)
+	}
+	if serverErr.Code != "42601" || serverErr.Offset != 0 {
+		t.Errorf("got code %s at %d, want 42601 at 0", serverErr.Code, serverErr.Offset)
+	}
+
+	// The session stays usable after an error.
+	if _, err := db.Exec(context.Background(), session, "SELEC 2"); err == nil {
+		t.Fatal("expected a syntax error")
+	}
+}
+
+func TestQueryExtendedProtocol(t *testing.T) {
+	s := newFakeServer(t, map[string]fakeResult{
+		"SELECT $1::text": {
+			fields: []pgproto3.FieldDescription{field("text", pgtype.TextOID)},
+			tag:    "SELECT 1",
+		},
+	})
+
+	session := openFake(t, s)
+
+	res, err := db.Exec(context.Background(), session, "SELECT $1::text", "bound value")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(res.Rows) != 1 || res.Rows[0][0] != "bound value" {
+		t.Errorf("got %+v, want the bound parameter echoed back", res.Rows)
+	}
+}
+
+// cursorServer answers every FETCH with rows, one int4 per row.
+func cursorServer(t *testing.T, rows ...string) *fakeServer {
+	t.Helper()
+
+	page := fakeResult{fields: []pgproto3.FieldDescription{field("n", pgtype.Int4OID)}, tag: fmt.Sprintf("FETCH %d", len(rows))}
+	for _, n := range rows {
+		page.rows = append(page.rows, [][]byte{[]byte(n)})
+	}
+
+	return newFakeServer(t, map[string]fakeResult{
+		"BEGIN": {tag: "BEGIN"},
+		"DECLARE dbvi_cursor NO SCROLL CURSOR FOR SELECT n FROM t": {tag: "DECLARE CURSOR"},
+		"FETCH FORWARD 2 FROM dbvi_cursor":                         page,
+		"COMMIT":                                                   {tag: "COMMIT"},
+		"INSERT INTO t VALUES (1)":                                 {tag: "INSERT 0 1"},
+	})
+}
+
+func TestQueryPages(t *testing.T) {
+	// A short page is the last one.
+	s := cursorServer(t, "1")
+	st, err := db.OpenStream(context.Background(), openFake(t, s), "SELECT n FROM t;", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows, err := st.Fetch(2)
+	if err != nil || len(rows) != 1 || !st.Done() || st.Tag() != "SELECT 1" {
+		t.Fatalf("got rows %v, done %v and tag %q, want the single row", rows, st.Done(), st.Tag())
+	}
+
+	want := []string{"BEGIN", "DECLARE dbvi_cursor NO SCROLL CURSOR FOR SELECT n FROM t", "FETCH FORWARD 2 FROM dbvi_cursor", "COMMIT"}
+	if got := s.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got queries %q, want %q", got, want)
+	}
+
+	// Full pages keep fetching until the stream is closed.
+	s = cursorServer(t, "1", "2")
+	session := openFake(t, s)
+	if st, err = db.OpenStream(context.Background(), session, "SELECT n FROM t", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rows, err := st.Fetch(5); err != nil || len(rows) != 5 || st.Done() {
+		t.Fatalf("got %d rows and done %v, want 5 rows and more to come", len(rows), st.Done())
+	}
+	if err := st.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	got := s.received()
+	if fetches := len(got) - 3; fetches != 3 || got[len(got)-1] != "COMMIT" {
+		t.Errorf("got queries %q, want 3 fetches and a commit", got)
+	}
+
+	// Writes don't go through a cursor.
+	if st, err = db.OpenStream(context.Background(), session, "INSERT INTO t VALUES (1)", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := st.Fetch(2); err != nil || st.Tag() != "INSERT 0 1" {
+		t.Errorf("got tag %q and error %v, want the insert run as is", st.Tag(), err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := newFakeServer(t, nil)
+	session := openFake(t, s)
+
+	if err := session.(db.Canceler).Cancel(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case req := <-s.cancels:
+		if req.ProcessID != fakePID || req.SecretKey != fakeSecret {
+			t.Errorf("got cancel for %d/%d, want %d/%d", req.ProcessID, req.SecretKey, fakePID, fakeSecret)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server never received the cancel request")
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	tests := []struct {
+		iv   pgtype.Interval
+		want string
+	}{
+		{iv: pgtype.Interval{}, want: "00:00:00"},
+		{iv: pgtype.Interval{Months: 14, Days: 1}, want: "1 year 2 mons 1 day"},
+		{iv: pgtype.Interval{Days: -3, Microseconds: -3723000001}, want: "-3 days -01:02:03.000001"},
+	}
+
+	for _, tt := range tests {
+		if got := formatInterval(tt.iv); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// TestIntegration runs against a real server when DBVI_TEST_POSTGRES_ADDR
+// (host:port) is set, using DBVI_TEST_POSTGRES_USER, _PASSWORD and _DATABASE.
+func TestIntegration(t *testing.T) {
+	addr := os.Getenv("DBVI_TEST_POSTGRES_ADDR")
+	if addr == "" {
+		t.Skip("DBVI_TEST_POSTGRES_ADDR not set")
+	}
+
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	c := config.Connection{
+		Name:     "Integration",
+		Type:     "postgres",
+		Host:     host,
+		Port:     p,
+		Username: os.Getenv("DBVI_TEST_POSTGRES_USER"),
+		Password: os.Getenv("DBVI_TEST_POSTGRES_PASSWORD"),
+		Database: os.Getenv("DBVI_TEST_POSTGRES_DATABASE"),
+	}
+
+	ctx := context.Background()
+	session, err := Driver{}.Open(ctx, c, c.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	notices := make(chan db.Notice, 1)
+	session.(db.NoticeReporter).OnNotice(func(n db.Notice) { notices <- n })
+
+	res, err := db.Exec(ctx, session, `SELECT 1.50::numeric, '{"a": 1}'::jsonb, '\x01'::bytea, interval '1 day 2 hours', ARRAY[1,2], NULL::text`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"1.50", `{"a": 1}`, `\x01`, "1 day 02:00:00", "{1,2}", "NULL"}
+	for i, v := range res.Rows[0] {
+		if got := db.FormatValue(v); got != want[i] {
+			t.Errorf("column %d: got %q, want %q", i, got, want[i])
+		}
+	}
+
+	if _, err := db.Exec(ctx, session, `DO $$ BEGIN RAISE NOTICE 'hi'; END $$`); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case n := <-notices:
+		if n.Message != "hi" {
+			t.Errorf("got notice %q, want hi", n.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected a notice from RAISE")
+	}
+}
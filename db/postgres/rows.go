@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type rows struct {
+	rows    pgx.Rows
+	typeMap *pgtype.Map
+	stmt    string // error positions point into it
+	columns []db.Column
+	err     error
+}
+
+func newRows(r pgx.Rows, typeMap *pgtype.Map, stmt string) *rows {
+	return &rows{rows: r, typeMap: typeMap, stmt: stmt}
+}
+
+func (r *rows) Columns() []db.Column {
+	if r.columns != nil {
+		return r.columns
+	}
+
+	fields := r.rows.FieldDescriptions()
+	r.columns = make([]db.Column, len(fields))
+	for i, f := range fields {
+		r.columns[i] = db.Column{Name: f.Name, Type: typeName(r.typeMap, f.DataTypeOID)}
+	}
+
+	return r.columns
+}
+
+func (r *rows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	return r.rows.Next()
+}
+
+func (r *rows) Values() []any {
+	fields := r.rows.FieldDescriptions()
+	raw := r.rows.RawValues()
+
+	values, err := r.rows.Values()
+	if err != nil {
+		r.err = err
+		return make([]any, len(fields))
+	}
+
+	for i, f := range fields {
+		if raw[i] == nil {
+			values[i] = nil
+			continue
+		}
+
+		switch f.DataTypeOID {
+		case pgtype.JSONOID, pgtype.JSONBOID:
+			// Keep the document as the server sent it rather than a
+			// decoded map, so key order and number precision survive.
+			doc := raw[i]
+			if f.DataTypeOID == pgtype.JSONBOID && f.Format == pgtype.BinaryFormatCode && len(doc) > 0 {
+				doc = doc[1:] // jsonb version byte
+			}
+			values[i] = db.JSON(append([]byte(nil), doc...))
+		case pgtype.DateOID:
+			if t, ok := values[i].(time.Time); ok {
+				values[i] = db.Date{Time: t}
+			} else {
+				values[i] = convertValue(values[i])
+			}
+		case pgtype.UUIDOID:
+			if u, ok := values[i].([16]byte); ok {
+				values[i] = formatUUID(u)
+			} else {
+				values[i] = convertValue(values[i])
+			}
+		default:
+			values[i] = convertValue(values[i])
+		}
+	}
+
+	return values
+}
+
+func (r *rows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return serverError(r.rows.Err(), r.stmt)
+}
+
+func (r *rows) Close() error {
+	r.rows.Close()
+	return r.Err()
+}
+
+func (r *rows) Tag() string {
+	return r.rows.CommandTag().String()
+}
+
+func (r *rows) RowsAffected() int64 {
+	return r.rows.CommandTag().RowsAffected()
+}
+
+// convertValue maps the values pgx decodes to the displayable types
+// documented on db.Rows.
+func convertValue(v any) any {
+	switch v := v.(type) {
+	case nil, string, []byte, bool, int64, float64, time.Time:
+		return v
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case uint32:
+		return uint64(v)
+	case float32:
+		return float64(v)
+	case pgtype.Numeric:
+		n, err := v.Value()
+		if err != nil || n == nil {
+			return nil
+		}
+		return db.Decimal(n.(string))
+	case pgtype.Interval:
+		return db.Interval(formatInterval(v))
+	case pgtype.Time:
+		return formatTimeOfDay(v)
+	case pgtype.Bits:
+		return db.Bits(formatBits(v))
+	case pgtype.InfinityModifier:
+		return v.String()
+	case [16]byte:
+		return formatUUID(v)
+	case []any:
+		values := make([]any, len(v))
+		for i, x := range v {
+			values[i] = convertValue(x)
+		}
+		return values
+	case pgtype.Hstore:
+		return formatHstore(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func typeName(m *pgtype.Map, oid uint32) string {
+	if t, ok := m.TypeForOID(oid); ok {
+		return t.Name
+	}
+
+	return strconv.FormatUint(uint64(oid), 10)
+}
+
+// formatInterval renders an interval the way Postgres does with its default
+// intervalstyle, e.g. "1 year 2 mons 3 days 04:05:06.5".
+func formatInterval(iv pgtype.Interval) string {
+	var parts []string
+
+	plural := func(n int64, unit string) {
+		if n == 0 {
+			return
+		}
+		if n == 1 || n == -1 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, unit))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %ss", n, unit))
+		}
+	}
+
+	plural(int64(iv.Months/12), "year")
+	plural(int64(iv.Months%12), "mon")
+	plural(int64(iv.Days), "day")
+
+	if iv.Microseconds != 0 || len(parts) == 0 {
+		us := iv.Microseconds
+		sign := ""
+		if us < 0 {
+			sign = "-"
+			us = -us
+		}
+
+		clock := fmt.Sprintf("%s%02d:%02d:%02d", sign, us/3600000000, us/60000000%60, us/1000000%60)
+		if frac := us % 1000000; frac != 0 {
+			clock += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+		}
+		parts = append(parts, clock)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func formatTimeOfDay(t pgtype.Time) string {
+	us := t.Microseconds
+	clock := fmt.Sprintf("%02d:%02d:%02d", us/3600000000, us/60000000%60, us/1000000%60)
+	if frac := us % 1000000; frac != 0 {
+		clock += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+	}
+
+	return clock
+}
+
+func formatBits(b pgtype.Bits) string {
+	var sb strings.Builder
+	for i := int32(0); i < b.Len; i++ {
+		if b.Bytes[i/8]&(0x80>>(i%8)) != 0 {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	}
+
+	return sb.String()
+}
+
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+func formatHstore(h pgtype.Hstore) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if h[k] == nil {
+			parts[i] = fmt.Sprintf("%q=>NULL", k)
+		} else {
+			parts[i] = fmt.Sprintf("%q=>%q", k, *h[k])
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
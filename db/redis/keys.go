@@ -0,0 +1,526 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+// Do runs a single command given as separate arguments, so nothing needs
+// quoting.
+func (s *Session) Do(ctx context.Context, args ...string) (db.Reply, error) {
+	return s.do(ctx, args)
+}
+
+// Pipeline sends cmds in one round trip and returns their replies in order.
+func (s *Session) Pipeline(ctx context.Context, cmds [][]string) ([]db.Reply, error) {
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	return s.pipeline(ctx, cmds)
+}
+
+// replyError turns error replies into Go errors.
+func replyError(r db.Reply) error {
+	if r.Kind == db.ReplyError {
+		return errors.New(r.Str)
+	}
+
+	return nil
+}
+
+// Scan runs one step of SCAN, returning the cursor to continue from, "0"
+// once the whole keyspace was visited.
+func (s *Session) Scan(ctx context.Context, cursor, match string, count int) (string, []string, error) {
+	reply, err := s.do(ctx, []string{"SCAN", cursor, "MATCH", match, "COUNT", strconv.Itoa(count)})
+	if err != nil {
+		return "", nil, err
+	}
+	if err := replyError(reply); err != nil {
+		return "", nil, err
+	}
+
+	if len(reply.Elems) != 2 {
+		return "", nil, errProtocol
+	}
+
+	keys := make([]string, len(reply.Elems[1].Elems))
+	for i, k := range reply.Elems[1].Elems {
+		keys[i] = k.Str
+	}
+
+	return reply.Elems[0].Str, keys, nil
+}
+
+// KeyInfo describes a key in the browser.
+type KeyInfo struct {
+	Type string
+	// TTL is the time left before the key expires, or -1 if it never
+	// does.
+	TTL time.Duration
+	// Memory is the number of bytes used by the key and its value, or -1
+	// if the server doesn't tell.
+	Memory int64
+}
+
+// KeyInfos fetches the type, TTL and memory usage of keys in one round trip.
+func (s *Session) KeyInfos(ctx context.Context, keys []string) ([]KeyInfo, error) {
+	var cmds [][]string
+	for _, k := range keys {
+		cmds = append(cmds,
+			[]string{"TYPE", k},
+			[]string{"PTTL", k},
+			[]string{"MEMORY", "USAGE", k, "SAMPLES", "0"},
+		)
+	}
+
+	replies, err := s.Pipeline(ctx, cmds)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]KeyInfo, len(keys))
+	for i := range keys {
+		typ, ttl, mem := replies[3*i], replies[3*i+1], replies[3*i+2]
+
+		infos[i] = KeyInfo{Type: typ.Str, TTL: -1, Memory: -1}
+		if ttl.Kind == db.ReplyInteger && ttl.Int >= 0 {
+			infos[i].TTL = time.Duration(ttl.Int) * time.Millisecond
+		}
+		if mem.Kind == db.ReplyInteger {
+			infos[i].Memory = mem.Int
+		}
+	}
+
+	return infos, nil
+}
+
+// KeyValue is a range of a key's value, loaded for viewing and editing.
+type KeyValue struct {
+	Key  string
+	Type string
+	// Start is the offset of the first item for lists and sorted sets.
+	Start int64
+	// Len is the total number of items, or the length of a string.
+	Len int64
+	// Raw is the value of strings as stored.
+	Raw string
+
+	Result *db.Result
+}
+
+// Describe is a one-line description like "list queue 1-100 of 2500".
+func (v *KeyValue) Describe() string {
+	desc := v.Type + " " + v.Key
+	if v.Result == nil || v.Type == "string" || v.Type == "ReJSON-RL" {
+		return desc
+	}
+
+	n := int64(len(v.Result.Rows))
+	if n == 0 {
+		return desc + " (empty)"
+	}
+
+	if v.Type == "list" || v.Type == "zset" {
+		return fmt.Sprintf("%s %d-%d of %d", desc, v.Start+1, v.Start+n, v.Len)
+	}
+
+	if n < v.Len {
+		return fmt.Sprintf("%s first %d of %d", desc, n, v.Len)
+	}
+
+	return desc
+}
+
+var ErrNoViewer = errors.New("no viewer for this type")
+
+// LoadValue loads up to count items of key starting at start. Hashes and
+// sets aren't ordered server side, they load their first count members
+// sorted.
+func (s *Session) LoadValue(ctx context.Context, key, typ string, start, count int64) (*KeyValue, error) {
+	v := &KeyValue{Key: key, Type: typ, Start: start}
+
+	var err error
+	switch typ {
+	case "string":
+		err = s.loadString(ctx, v)
+	case "ReJSON-RL":
+		err = s.loadJSON(ctx, v)
+	case "hash":
+		err = s.loadHash(ctx, v, count)
+	case "list":
+		err = s.loadList(ctx, v, count)
+	case "set":
+		err = s.loadSet(ctx, v, count)
+	case "zset":
+		err = s.loadZSet(ctx, v, count)
+	case "stream":
+		err = s.loadStream(ctx, v, count)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNoViewer, typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// textResult shows a text as a verbatim reply, JSON documents pretty
+// printed. Binary strings are shown quoted instead.
+func textResult(s string) *db.Result {
+	reply := db.Reply{Kind: db.ReplyVerbatim, Str: s}
+
+	var pretty bytes.Buffer
+	if json.Valid([]byte(s)) && json.Indent(&pretty, []byte(s), "", "  ") == nil {
+		reply.Str = pretty.String()
+	} else if !utf8.ValidString(s) {
+		reply = db.Reply{Kind: db.ReplyBulk, Str: s}
+	}
+
+	return &db.Result{
+		Columns: []db.Column{{Name: "reply", Type: "reply"}},
+		Rows:    [][]any{{reply}},
+	}
+}
+
+func (s *Session) loadString(ctx context.Context, v *KeyValue) error {
+	reply, err := s.do(ctx, []string{"GET", v.Key})
+	if err != nil {
+		return err
+	}
+	if err := replyError(reply); err != nil {
+		return err
+	}
+
+	v.Raw = reply.Str
+	v.Len = int64(len(reply.Str))
+	v.Result = textResult(reply.Str)
+	return nil
+}
+
+func (s *Session) loadJSON(ctx context.Context, v *KeyValue) error {
+	reply, err := s.do(ctx, []string{"JSON.GET", v.Key})
+	if err != nil {
+		return err
+	}
+	if err := replyError(reply); err != nil {
+		return err
+	}
+
+	v.Raw = reply.Str
+	v.Len = int64(len(reply.Str))
+	v.Result = textResult(reply.Str)
+	return nil
+}
+
+// scanMembers collects up to count members of a hash, set or sorted set with
+// the HSCAN family. Hashes return field and value pairs.
+func (s *Session) scanMembers(ctx context.Context, cmd, key string, count int64) ([]string, error) {
+	var members []string
+
+	cursor := "0"
+	for {
+		reply, err := s.do(ctx, []string{cmd, key, cursor, "COUNT", "500"})
+		if err != nil {
+			return nil, err
+		}
+		if err := replyError(reply); err != nil {
+			return nil, err
+		}
+		if len(reply.Elems) != 2 {
+			return nil, errProtocol
+		}
+
+		for _, m := range reply.Elems[1].Elems {
+			members = append(members, m.Str)
+		}
+
+		cursor = reply.Elems[0].Str
+		if cursor == "0" || int64(len(members)) >= count {
+			return members, nil
+		}
+	}
+}
+
+func (s *Session) length(ctx context.Context, cmd, key string) (int64, error) {
+	reply, err := s.do(ctx, []string{cmd, key})
+	if err != nil {
+		return 0, err
+	}
+	if err := replyError(reply); err != nil {
+		return 0, err
+	}
+
+	return reply.Int, nil
+}
+
+func (s *Session) loadHash(ctx context.Context, v *KeyValue, count int64) error {
+	var err error
+	if v.Len, err = s.length(ctx, "HLEN", v.Key); err != nil {
+		return err
+	}
+
+	pairs, err := s.scanMembers(ctx, "HSCAN", v.Key, 2*count)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]any, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs) && int64(len(rows)) < count; i += 2 {
+		rows = append(rows, []any{pairs[i], pairs[i+1]})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0].(string) < rows[j][0].(string) })
+
+	v.Result = &db.Result{Columns: []db.Column{{Name: "field"}, {Name: "value"}}, Rows: rows}
+	return nil
+}
+
+func (s *Session) loadSet(ctx context.Context, v *KeyValue, count int64) error {
+	var err error
+	if v.Len, err = s.length(ctx, "SCARD", v.Key); err != nil {
+		return err
+	}
+
+	members, err := s.scanMembers(ctx, "SSCAN", v.Key, count)
+	if err != nil {
+		return err
+	}
+	sort.Strings(members)
+
+	rows := make([][]any, 0, len(members))
+	for _, m := range members {
+		if int64(len(rows)) == count {
+			break
+		}
+		rows = append(rows, []any{m})
+	}
+
+	v.Result = &db.Result{Columns: []db.Column{{Name: "member"}}, Rows: rows}
+	return nil
+}
+
+func (s *Session) loadList(ctx context.Context, v *KeyValue, count int64) error {
+	var err error
+	if v.Len, err = s.length(ctx, "LLEN", v.Key); err != nil {
+		return err
+	}
+
+	reply, err := s.do(ctx, []string{"LRANGE", v.Key, strconv.FormatInt(v.Start, 10), strconv.FormatInt(v.Start+count-1, 10)})
+	if err != nil {
+		return err
+	}
+	if err := replyError(reply); err != nil {
+		return err
+	}
+
+	rows := make([][]any, len(reply.Elems))
+	for i, e := range reply.Elems {
+		rows[i] = []any{v.Start + int64(i), e.Str}
+	}
+
+	v.Result = &db.Result{Columns: []db.Column{{Name: "index"}, {Name: "value"}}, Rows: rows}
+	return nil
+}
+
+func (s *Session) loadZSet(ctx context.Context, v *KeyValue, count int64) error {
+	var err error
+	if v.Len, err = s.length(ctx, "ZCARD", v.Key); err != nil {
+		return err
+	}
+
+	reply, err := s.do(ctx, []string{"ZRANGE", v.Key, strconv.FormatInt(v.Start, 10), strconv.FormatInt(v.Start+count-1, 10), "WITHSCORES"})
+	if err != nil {
+		return err
+	}
+	if err := replyError(reply); err != nil {
+		return err
+	}
+
+	// RESP3 nests member and score pairs, RESP2 flattens them.
+	var flat []db.Reply
+	for _, e := range reply.Elems {
+		if e.Kind == db.ReplyArray {
+			flat = append(flat, e.Elems...)
+		} else {
+			flat = append(flat, e)
+		}
+	}
+
+	var rows [][]any
+	for i := 0; i+1 < len(flat); i += 2 {
+		score, err := strconv.ParseFloat(flat[i+1].Str, 64)
+		if err != nil {
+			return errProtocol
+		}
+		rows = append(rows, []any{v.Start + int64(len(rows)), flat[i].Str, score})
+	}
+
+	v.Result = &db.Result{Columns: []db.Column{{Name: "rank"}, {Name: "member"}, {Name: "score"}}, Rows: rows}
+	return nil
+}
+
+// loadStream shows entries with one column per field, in the order fields
+// first appear.
+func (s *Session) loadStream(ctx context.Context, v *KeyValue, count int64) error {
+	var err error
+	if v.Len, err = s.length(ctx, "XLEN", v.Key); err != nil {
+		return err
+	}
+
+	reply, err := s.do(ctx, []string{"XRANGE", v.Key, "-", "+", "COUNT", strconv.FormatInt(count, 10)})
+	if err != nil {
+		return err
+	}
+	if err := replyError(reply); err != nil {
+		return err
+	}
+
+	columns := []db.Column{{Name: "id"}}
+	index := map[string]int{}
+
+	var entries []map[int]string
+	var ids []string
+	for _, e := range reply.Elems {
+		if len(e.Elems) != 2 {
+			return errProtocol
+		}
+
+		fields := map[int]string{}
+		kv := e.Elems[1].Elems
+		for i := 0; i+1 < len(kv); i += 2 {
+			col, ok := index[kv[i].Str]
+			if !ok {
+				col = len(columns)
+				index[kv[i].Str] = col
+				columns = append(columns, db.Column{Name: kv[i].Str})
+			}
+			fields[col] = kv[i+1].Str
+		}
+
+		ids = append(ids, e.Elems[0].Str)
+		entries = append(entries, fields)
+	}
+
+	rows := make([][]any, len(entries))
+	for i, fields := range entries {
+		row := make([]any, len(columns))
+		row[0] = ids[i]
+		for col, val := range fields {
+			row[col] = val
+		}
+		rows[i] = row
+	}
+
+	v.Result = &db.Result{Columns: columns, Rows: rows}
+	return nil
+}
+
+// Edit returns the commands that set the cell at row and col, or the whole
+// value of strings, to value.
+func (v *KeyValue) Edit(row, col int, value string) ([][]string, error) {
+	switch v.Type {
+	case "string":
+		return [][]string{{"SET", v.Key, value, "KEEPTTL"}}, nil
+	case "ReJSON-RL":
+		if !json.Valid([]byte(value)) {
+			return nil, errors.New("not a valid JSON document")
+		}
+		return [][]string{{"JSON.SET", v.Key, "$", value}}, nil
+	}
+
+	if row < 0 || row >= len(v.Result.Rows) {
+		return nil, errors.New("no row selected")
+	}
+	cells := v.Result.Rows[row]
+
+	switch v.Type {
+	case "hash":
+		if col != 1 {
+			return nil, errors.New("only values can be edited, not fields")
+		}
+		return [][]string{{"HSET", v.Key, cells[0].(string), value}}, nil
+	case "list":
+		if col != 1 {
+			return nil, errors.New("only values can be edited, not indexes")
+		}
+		return [][]string{{"LSET", v.Key, strconv.FormatInt(cells[0].(int64), 10), value}}, nil
+	case "set":
+		old := cells[0].(string)
+		return [][]string{{"SREM", v.Key, old}, {"SADD", v.Key, value}}, nil
+	case "zset":
+		member := cells[1].(string)
+		switch col {
+		case 1:
+			score := strconv.FormatFloat(cells[2].(float64), 'g', -1, 64)
+			return [][]string{{"ZREM", v.Key, member}, {"ZADD", v.Key, score, value}}, nil
+		case 2:
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("invalid score: %s", value)
+			}
+			return [][]string{{"ZADD", v.Key, "XX", value, member}}, nil
+		}
+		return nil, errors.New("only members and scores can be edited, not ranks")
+	case "stream":
+		return nil, errors.New("stream entries can't be edited")
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNoViewer, v.Type)
+}
+
+// Quote formats args as a redis-cli command line, quoting the ones that need
+// it.
+func Quote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsFunc(a, func(r rune) bool {
+			return r <= ' ' || r == '"' || r == '\'' || r == '\\' || r > '~'
+		}) {
+			quoted[i] = db.QuoteReply(a)
+		} else {
+			quoted[i] = a
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// Exec runs cmds, wrapped in MULTI/EXEC when there are several so they apply
+// together.
+func (s *Session) Exec(ctx context.Context, cmds [][]string) error {
+	if len(cmds) > 1 {
+		cmds = append(append([][]string{{"MULTI"}}, cmds...), []string{"EXEC"})
+	}
+
+	replies, err := s.Pipeline(ctx, cmds)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range replies {
+		if err := replyError(r); err != nil {
+			return err
+		}
+	}
+
+	// Errors of queued commands come back inside EXEC's reply.
+	if len(cmds) > 1 {
+		for _, r := range replies[len(replies)-1].Elems {
+			if err := replyError(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,419 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/query"
+)
+
+type streamEntry struct {
+	id     string
+	fields []string
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func integer(n int) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+func array(elems ...string) string {
+	return fmt.Sprintf("*%d\r\n%s", len(elems), strings.Join(elems, ""))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// command answers the keyspace commands of the key browser from s.data.
+// MULTI starts queueing commands in *queued.
+func (s *fakeServer) command(args []string, proto int, queued *[][]string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmd := strings.ToUpper(args[0])
+	if cmd == "MULTI" {
+		*queued = [][]string{}
+		return "+OK\r\n"
+	}
+
+	if cmd == "SCAN" {
+		cursor, _ := strconv.Atoi(args[1])
+		count, _ := strconv.Atoi(args[5])
+
+		var keys []string
+		all := sortedKeys(s.data)
+		for _, k := range all[cursor:min(cursor+count, len(all))] {
+			if matchPattern(args[3], k) {
+				keys = append(keys, bulk(k))
+			}
+		}
+
+		next := cursor + count
+		if next >= len(all) {
+			next = 0
+		}
+
+		return array(bulk(strconv.Itoa(next)), array(keys...))
+	}
+
+	if len(args) < 2 {
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+	v, exists := s.data[args[1]]
+
+	switch cmd {
+	case "TYPE":
+		switch v.(type) {
+		case string:
+			return "+string\r\n"
+		case map[string]string:
+			return "+hash\r\n"
+		case []string:
+			return "+list\r\n"
+		case map[string]bool:
+			return "+set\r\n"
+		case map[string]float64:
+			return "+zset\r\n"
+		case []streamEntry:
+			return "+stream\r\n"
+		}
+		return "+none\r\n"
+	case "PTTL":
+		if !exists {
+			return integer(-2)
+		}
+		if args[1] == "session:1" {
+			return integer(90500)
+		}
+		return integer(-1)
+	case "MEMORY":
+		if args[2] == "session:1" {
+			return "-ERR MEMORY USAGE is disabled\r\n"
+		}
+		return integer(72)
+	case "HLEN", "SCARD", "ZCARD", "LLEN", "XLEN":
+		switch v := v.(type) {
+		case map[string]string:
+			return integer(len(v))
+		case map[string]bool:
+			return integer(len(v))
+		case map[string]float64:
+			return integer(len(v))
+		case []string:
+			return integer(len(v))
+		case []streamEntry:
+			return integer(len(v))
+		}
+		return integer(0)
+	case "HSCAN":
+		var elems []string
+		h := v.(map[string]string)
+		for _, f := range sortedKeys(h) {
+			elems = append(elems, bulk(f), bulk(h[f]))
+		}
+		return array(bulk("0"), array(elems...))
+	case "SSCAN":
+		var elems []string
+		for _, m := range sortedKeys(v.(map[string]bool)) {
+			elems = append(elems, bulk(m))
+		}
+		return array(bulk("0"), array(elems...))
+	case "LRANGE":
+		l := v.([]string)
+		start, _ := strconv.Atoi(args[2])
+		stop, _ := strconv.Atoi(args[3])
+		var elems []string
+		for i := start; i <= stop && i < len(l); i++ {
+			elems = append(elems, bulk(l[i]))
+		}
+		return array(elems...)
+	case "ZRANGE":
+		z := v.(map[string]float64)
+		members := sortedKeys(z)
+		sort.SliceStable(members, func(i, j int) bool { return z[members[i]] < z[members[j]] })
+
+		start, _ := strconv.Atoi(args[2])
+		stop, _ := strconv.Atoi(args[3])
+		var elems []string
+		for i := start; i <= stop && i < len(members); i++ {
+			score := strconv.FormatFloat(z[members[i]], 'g', -1, 64)
+			if proto == 3 {
+				elems = append(elems, array(bulk(members[i]), ","+score+"\r\n"))
+			} else {
+				elems = append(elems, bulk(members[i]), bulk(score))
+			}
+		}
+		return array(elems...)
+	case "XRANGE":
+		var elems []string
+		for _, e := range v.([]streamEntry) {
+			var fields []string
+			for _, f := range e.fields {
+				fields = append(fields, bulk(f))
+			}
+			elems = append(elems, array(bulk(e.id), array(fields...)))
+		}
+		return array(elems...)
+	case "SET":
+		s.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "HSET":
+		v.(map[string]string)[args[2]] = args[3]
+		return integer(0)
+	case "LSET":
+		i, _ := strconv.Atoi(args[2])
+		v.([]string)[i] = args[3]
+		return "+OK\r\n"
+	case "SREM":
+		delete(v.(map[string]bool), args[2])
+		return integer(1)
+	case "SADD":
+		v.(map[string]bool)[args[2]] = true
+		return integer(1)
+	case "ZREM":
+		delete(v.(map[string]float64), args[2])
+		return integer(1)
+	case "ZADD":
+		scoreArg, member := args[2], args[3]
+		if strings.EqualFold(scoreArg, "XX") {
+			scoreArg, member = args[3], args[4]
+		}
+		score, _ := strconv.ParseFloat(scoreArg, 64)
+		v.(map[string]float64)[member] = score
+		return integer(0)
+	}
+
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+// matchPattern supports the "*" globs the tests use.
+func matchPattern(pattern, key string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(key, prefix)
+	}
+
+	return pattern == key
+}
+
+func newKeyspaceServer(t *testing.T) *fakeServer {
+	s := newFakeServer(t, true, "")
+	s.data = map[string]any{
+		"user:1":      map[string]string{"name": "Ada", "role": "admin"},
+		"user:2":      map[string]string{"name": "Grace"},
+		"session:1":   `{"user":1,"roles":["admin"]}`,
+		"queue:jobs":  []string{"a", "b", "c"},
+		"tags":        map[string]bool{"go": true, "redis": true},
+		"leaderboard": map[string]float64{"ada": 10, "grace": 12.5},
+		"events": []streamEntry{
+			{id: "1-0", fields: []string{"type", "login", "user", "1"}},
+			{id: "2-0", fields: []string{"type", "logout", "reason", "idle"}},
+		},
+	}
+
+	return s
+}
+
+func TestScan(t *testing.T) {
+	s := newKeyspaceServer(t)
+	session := s.open(t, config.Connection{Type: "redis"}).(*Session)
+
+	var keys []string
+	cursor := "0"
+	for steps := 0; ; steps++ {
+		next, page, err := session.Scan(context.Background(), cursor, "user:*", 3)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		keys = append(keys, page...)
+
+		if cursor = next; cursor == "0" {
+			break
+		}
+		if steps > 10 {
+			t.Fatal("scan never finished")
+		}
+	}
+
+	if want := []string{"user:1", "user:2"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %q, want %q", keys, want)
+	}
+}
+
+func TestKeyInfos(t *testing.T) {
+	s := newKeyspaceServer(t)
+	session := s.open(t, config.Connection{Type: "redis"}).(*Session)
+
+	infos, err := session.KeyInfos(context.Background(), []string{"user:1", "session:1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []KeyInfo{
+		{Type: "hash", TTL: -1, Memory: 72},
+		{Type: "string", TTL: 90500 * time.Millisecond, Memory: -1},
+	}
+	if !reflect.DeepEqual(infos, want) {
+		t.Errorf("got %+v, want %+v", infos, want)
+	}
+}
+
+func TestLoadValue(t *testing.T) {
+	tests := []struct {
+		key, typ string
+		start    int64
+		describe string
+		rows     [][]any
+		text     string
+	}{
+		{key: "user:1", typ: "hash", describe: "hash user:1", rows: [][]any{{"name", "Ada"}, {"role", "admin"}}},
+		{key: "queue:jobs", typ: "list", start: 1, describe: "list queue:jobs 2-3 of 3", rows: [][]any{{int64(1), "b"}, {int64(2), "c"}}},
+		{key: "tags", typ: "set", describe: "set tags", rows: [][]any{{"go"}, {"redis"}}},
+		{key: "leaderboard", typ: "zset", describe: "zset leaderboard 1-2 of 2", rows: [][]any{{int64(0), "ada", 10.0}, {int64(1), "grace", 12.5}}},
+		{key: "events", typ: "stream", describe: "stream events", rows: [][]any{{"1-0", "login", "1", nil}, {"2-0", "logout", nil, "idle"}}},
+		{key: "session:1", typ: "string", describe: "string session:1", text: "{\n  \"user\": 1,\n  \"roles\": [\n    \"admin\"\n  ]\n}"},
+	}
+
+	s := newKeyspaceServer(t)
+	session := s.open(t, config.Connection{Type: "redis"}).(*Session)
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			v, err := session.LoadValue(context.Background(), tt.key, tt.typ, tt.start, 100)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := v.Describe(); got != tt.describe {
+				t.Errorf("got description %q, want %q", got, tt.describe)
+			}
+
+			if tt.text != "" {
+				reply, ok := v.Result.Reply()
+				if !ok || reply.String() != tt.text {
+					t.Errorf("got %+v, want the pretty printed document", v.Result)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(v.Result.Rows, tt.rows) {
+				t.Errorf("got rows %v, want %v", v.Result.Rows, tt.rows)
+			}
+		})
+	}
+
+	if _, err := session.LoadValue(context.Background(), "geo", "vectorset", 0, 100); err == nil {
+		t.Error("expected an error for a type without viewer")
+	}
+}
+
+func TestEdit(t *testing.T) {
+	s := newKeyspaceServer(t)
+	session := s.open(t, config.Connection{Type: "redis"}).(*Session)
+	ctx := context.Background()
+
+	tests := []struct {
+		key, typ string
+		row, col int
+		value    string
+		want     [][]string
+		check    func() any
+		wantData any
+	}{
+		{
+			key: "user:1", typ: "hash", row: 0, col: 1, value: "Ada Lovelace",
+			want:     [][]string{{"HSET", "user:1", "name", "Ada Lovelace"}},
+			check:    func() any { return s.data["user:1"].(map[string]string)["name"] },
+			wantData: "Ada Lovelace",
+		},
+		{
+			key: "queue:jobs", typ: "list", row: 2, col: 1, value: "z",
+			want:     [][]string{{"LSET", "queue:jobs", "2", "z"}},
+			check:    func() any { return s.data["queue:jobs"] },
+			wantData: []string{"a", "b", "z"},
+		},
+		{
+			key: "tags", typ: "set", row: 0, col: 0, value: "golang",
+			want:     [][]string{{"SREM", "tags", "go"}, {"SADD", "tags", "golang"}},
+			check:    func() any { return s.data["tags"] },
+			wantData: map[string]bool{"golang": true, "redis": true},
+		},
+		{
+			key: "leaderboard", typ: "zset", row: 0, col: 2, value: "20",
+			want:     [][]string{{"ZADD", "leaderboard", "XX", "20", "ada"}},
+			check:    func() any { return s.data["leaderboard"] },
+			wantData: map[string]float64{"ada": 20, "grace": 12.5},
+		},
+		{
+			key: "session:1", typ: "string", value: "plain",
+			want:     [][]string{{"SET", "session:1", "plain", "KEEPTTL"}},
+			check:    func() any { return s.data["session:1"] },
+			wantData: "plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			v, err := session.LoadValue(ctx, tt.key, tt.typ, 0, 100)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cmds, err := v.Edit(tt.row, tt.col, tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(cmds, tt.want) {
+				t.Fatalf("got %q, want %q", cmds, tt.want)
+			}
+
+			if err := session.Exec(ctx, cmds); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := tt.check(); !reflect.DeepEqual(got, tt.wantData) {
+				t.Errorf("got %v after writing, want %v", got, tt.wantData)
+			}
+		})
+	}
+
+	v, _ := session.LoadValue(ctx, "events", "stream", 0, 100)
+	if _, err := v.Edit(0, 1, "x"); err == nil {
+		t.Error("expected streams to refuse edits")
+	}
+
+	v, _ = session.LoadValue(ctx, "user:2", "hash", 0, 100)
+	if _, err := v.Edit(0, 0, "x"); err == nil {
+		t.Error("expected hash fields to refuse edits")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	got := Quote([]string{"HSET", "user:1", "name", "Ada Lovelace", "", `say "hi"`})
+	want := `HSET user:1 name "Ada Lovelace" "" "say \"hi\""`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// What Quote produces parses back to the same arguments.
+	args, err := query.SplitRedisArgs(got)
+	if err != nil || !reflect.DeepEqual(args, []string{"HSET", "user:1", "name", "Ada Lovelace", "", `say "hi"`}) {
+		t.Errorf("got %q, %v parsing %s back", args, err, got)
+	}
+}
@@ -0,0 +1,254 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+var ErrSessionBroken = errors.New("connection lost after a cancelled command")
+
+func init() {
+	db.Register("redis", Driver{})
+}
+
+type Driver struct{}
+
+func (Driver) Open(ctx context.Context, c config.Connection, addr string) (db.Session, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+
+	if err := s.handshake(ctx, c); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Session is a single Redis connection.
+type Session struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+	broken bool
+}
+
+// handshake switches to RESP3 so hashes come back as maps, falling back to
+// RESP2 on servers older than Redis 6, authenticates and selects the
+// database.
+func (s *Session) handshake(ctx context.Context, c config.Connection) error {
+	hello := []string{"HELLO", "3"}
+	if c.Password != "" {
+		user := c.Username
+		if user == "" {
+			user = "default"
+		}
+		hello = append(hello, "AUTH", user, c.Password)
+	}
+	hello = append(hello, "SETNAME", "dbvi")
+
+	reply, err := s.do(ctx, hello)
+	if err != nil {
+		return err
+	}
+
+	if reply.Kind == db.ReplyError {
+		if !strings.HasPrefix(reply.Str, "ERR unknown command") && !strings.HasPrefix(reply.Str, "NOPROTO") {
+			return errors.New(reply.Str)
+		}
+
+		if c.Password != "" {
+			auth := []string{"AUTH", c.Password}
+			if c.Username != "" {
+				auth = []string{"AUTH", c.Username, c.Password}
+			}
+
+			if err := s.expectOK(ctx, auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.Database != "" {
+		if err := s.expectOK(ctx, []string{"SELECT", c.Database}); err != nil {
+			return fmt.Errorf("selecting database %s: %w", c.Database, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Session) expectOK(ctx context.Context, args []string) error {
+	reply, err := s.do(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	if reply.Kind == db.ReplyError {
+		return errors.New(reply.Str)
+	}
+
+	return nil
+}
+
+// Ping checks the connection is still alive. Sessions broken by a cancelled
+// command fail right away.
+func (s *Session) Ping(ctx context.Context) error {
+	return s.expectOK(ctx, []string{"PING"})
+}
+
+// Query runs stmt as a redis-cli command line. args are appended to the
+// command's arguments. The reply comes back as a single db.Reply value,
+// error replies included.
+func (s *Session) Query(ctx context.Context, stmt string, args ...any) (db.Rows, error) {
+	cmd, err := query.SplitRedisArgs(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range args {
+		cmd = append(cmd, fmt.Sprint(a))
+	}
+
+	if len(cmd) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	reply, err := s.do(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rows{command: strings.ToUpper(cmd[0]), reply: reply}, nil
+}
+
+// do sends a command and reads its reply.
+func (s *Session) do(ctx context.Context, args []string) (db.Reply, error) {
+	replies, err := s.pipeline(ctx, [][]string{args})
+	if err != nil {
+		return db.Reply{}, err
+	}
+
+	return replies[0], nil
+}
+
+// pipeline sends cmds in one go and reads their replies. Cancelling ctx
+// interrupts the read, which leaves the connection unusable.
+func (s *Session) pipeline(ctx context.Context, cmds [][]string) ([]db.Reply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.broken {
+		return nil, ErrSessionBroken
+	}
+
+	s.conn.SetDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() {
+		s.conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	for _, args := range cmds {
+		writeCommand(s.w, args)
+	}
+
+	if err := s.w.Flush(); err != nil {
+		s.broken = true
+		return nil, s.contextError(ctx, err)
+	}
+
+	replies := make([]db.Reply, 0, len(cmds))
+	for len(replies) < len(cmds) {
+		reply, err := readReply(s.r)
+		if err != nil {
+			s.broken = true
+			return nil, s.contextError(ctx, err)
+		}
+
+		// Out of band pushes, like client side caching invalidations, aren't
+		// the reply to this command.
+		if reply.Kind == db.ReplyPush && !isSubscribe(cmds[len(replies)][0]) {
+			continue
+		}
+
+		replies = append(replies, reply)
+	}
+
+	return replies, nil
+}
+
+func (s *Session) contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+func isSubscribe(cmd string) bool {
+	switch strings.ToUpper(cmd) {
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE":
+		return true
+	}
+
+	return false
+}
+
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.broken {
+		s.conn.SetDeadline(time.Now().Add(time.Second))
+		writeCommand(s.w, []string{"QUIT"})
+		s.w.Flush()
+	}
+
+	return s.conn.Close()
+}
+
+// rows holds a command's reply as a single value.
+type rows struct {
+	command string
+	reply   db.Reply
+	read    bool
+}
+
+func (r *rows) Columns() []db.Column {
+	return []db.Column{{Name: "reply", Type: "reply"}}
+}
+
+func (r *rows) Next() bool {
+	if r.read {
+		return false
+	}
+
+	r.read = true
+	return true
+}
+
+func (r *rows) Values() []any       { return []any{r.reply} }
+func (r *rows) Err() error          { return nil }
+func (r *rows) Close() error        { return nil }
+func (r *rows) Tag() string         { return r.command }
+func (r *rows) RowsAffected() int64 { return 0 }
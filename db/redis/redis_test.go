@@ -0,0 +1,315 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+// fakeServer is an in-process stand-in for Redis. It answers the handful of
+// commands the tests use, in RESP2 or, after HELLO 3, RESP3.
+type fakeServer struct {
+	listener net.Listener
+	resp3    bool // whether HELLO is supported
+	password string
+
+	mu       sync.Mutex
+	commands [][]string
+	data     map[string]any // keyspace for the key browser commands
+}
+
+func newFakeServer(t *testing.T, resp3 bool, password string) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeServer{listener: l, resp3: resp3, password: password}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) received() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([][]string(nil), s.commands...)
+}
+
+func (s *fakeServer) open(t *testing.T, c config.Connection) db.Session {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := Driver{}.Open(ctx, c, s.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("connecting to fake server: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+
+	return session
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	proto := 2
+	var queued [][]string // commands since MULTI
+
+	for {
+		req, err := readReply(r)
+		if err != nil {
+			return
+		}
+
+		args := make([]string, len(req.Elems))
+		for i, e := range req.Elems {
+			args[i] = e.Str
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, args)
+		s.mu.Unlock()
+
+		if queued != nil && !strings.EqualFold(args[0], "EXEC") {
+			queued = append(queued, args)
+			conn.Write([]byte("+QUEUED\r\n"))
+			continue
+		}
+
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "HELLO":
+			if !s.resp3 {
+				reply = "-ERR unknown command 'HELLO', with args beginning with: '3'\r\n"
+				break
+			}
+			if s.password != "" && (len(args) < 5 || args[4] != s.password) {
+				reply = "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+				break
+			}
+			proto = 3
+			reply = "%1\r\n$5\r\nproto\r\n:3\r\n"
+		case "AUTH":
+			if args[len(args)-1] != s.password {
+				reply = "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+				break
+			}
+			reply = "+OK\r\n"
+		case "SELECT":
+			reply = "+OK\r\n"
+		case "QUIT":
+			conn.Write([]byte("+OK\r\n"))
+			return
+		case "GET":
+			if v, ok := s.data[args[1]]; ok {
+				reply = bulk(v.(string))
+				break
+			}
+			if args[1] == "missing" {
+				reply = "$-1\r\n"
+				if proto == 3 {
+					reply = "_\r\n"
+				}
+				break
+			}
+			reply = fmt.Sprintf("$%d\r\n%s\r\n", len(args[1]), args[1])
+		case "HGETALL":
+			reply = "*4\r\n$4\r\nname\r\n$3\r\nAda\r\n$4\r\nrole\r\n$5\r\nadmin\r\n"
+			if proto == 3 {
+				reply = "%2\r\n$4\r\nname\r\n$3\r\nAda\r\n$4\r\nrole\r\n$5\r\nadmin\r\n"
+			}
+		case "EXEC":
+			if queued != nil {
+				var replies []string
+				for _, q := range queued {
+					replies = append(replies, s.command(q, proto, nil))
+				}
+				queued = nil
+				reply = array(replies...)
+				break
+			}
+			reply = ">2\r\n$10\r\ninvalidate\r\n*0\r\n" + // out of band push
+				"*3\r\n:1\r\n*2\r\n+QUEUED\r\n$1\r\nx\r\n-ERR value is not an integer\r\n"
+		case "BLPOP":
+			// Never answers, like a blocking command waiting for data.
+			continue
+		default:
+			reply = s.command(args, proto, &queued)
+		}
+
+		conn.Write([]byte(reply))
+	}
+}
+
+func exec(t *testing.T, session db.Session, line string) (db.Reply, string) {
+	t.Helper()
+
+	res, err := db.Exec(context.Background(), session, line)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", line, err)
+	}
+
+	reply, ok := res.Reply()
+	if !ok {
+		t.Fatalf("%s: result %+v isn't a reply", line, res)
+	}
+
+	return reply, res.Tag
+}
+
+func TestQueryRESP3(t *testing.T) {
+	s := newFakeServer(t, true, "secret")
+	session := s.open(t, config.Connection{Type: "redis", Password: "secret", Database: "2"})
+
+	reply, tag := exec(t, session, `get "hello world"`)
+	if tag != "GET" {
+		t.Errorf("got tag %q, want GET", tag)
+	}
+	if want := (db.Reply{Kind: db.ReplyBulk, Str: "hello world"}); !reflect.DeepEqual(reply, want) {
+		t.Errorf("got %+v, want %+v", reply, want)
+	}
+
+	reply, _ = exec(t, session, "HGETALL user:1")
+	if reply.Kind != db.ReplyMap {
+		t.Errorf("got %v, want a map", reply.Kind)
+	}
+	if got, want := reply.String(), "1# \"name\" => \"Ada\"\n2# \"role\" => \"admin\""; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	reply, _ = exec(t, session, "GET missing")
+	if reply.Kind != db.ReplyNil {
+		t.Errorf("got %v, want nil", reply.Kind)
+	}
+
+	reply, _ = exec(t, session, "EXEC")
+	want := "1) (integer) 1\n2) 1) QUEUED\n   2) \"x\"\n3) (error) ERR value is not an integer"
+	if got := reply.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	reply, _ = exec(t, session, "NOPE")
+	if reply.Kind != db.ReplyError || reply.Str != "ERR unknown command 'NOPE'" {
+		t.Errorf("got %+v, want an error reply", reply)
+	}
+
+	wantHandshake := [][]string{
+		{"HELLO", "3", "AUTH", "default", "secret", "SETNAME", "dbvi"},
+		{"SELECT", "2"},
+	}
+	if got := s.received()[:2]; !reflect.DeepEqual(got, wantHandshake) {
+		t.Errorf("got handshake %q, want %q", got, wantHandshake)
+	}
+}
+
+func TestQueryRESP2Fallback(t *testing.T) {
+	s := newFakeServer(t, false, "secret")
+	session := s.open(t, config.Connection{Type: "redis", Password: "secret"})
+
+	reply, _ := exec(t, session, "HGETALL user:1")
+	if reply.Kind != db.ReplyArray || len(reply.Elems) != 4 {
+		t.Errorf("got %+v, want a flat array", reply)
+	}
+
+	reply, _ = exec(t, session, "GET missing")
+	if reply.Kind != db.ReplyNil {
+		t.Errorf("got %v, want nil", reply.Kind)
+	}
+
+	if got := s.received()[1]; !reflect.DeepEqual(got, []string{"AUTH", "secret"}) {
+		t.Errorf("got %q, want AUTH after the failed HELLO", got)
+	}
+}
+
+func TestOpenWrongPassword(t *testing.T) {
+	s := newFakeServer(t, true, "secret")
+
+	_, err := Driver{}.Open(context.Background(), config.Connection{Type: "redis", Password: "nope"}, s.listener.Addr().String())
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGPASS") {
+		t.Fatalf("got %v, want WRONGPASS", err)
+	}
+}
+
+func TestQueryUnbalancedQuotes(t *testing.T) {
+	s := newFakeServer(t, true, "")
+	session := s.open(t, config.Connection{Type: "redis"})
+
+	if _, err := db.Exec(context.Background(), session, `GET "oops`); err != query.ErrUnbalancedQuotes {
+		t.Fatalf("got %v, want %v", err, query.ErrUnbalancedQuotes)
+	}
+}
+
+func TestQueryCancel(t *testing.T) {
+	s := newFakeServer(t, true, "")
+	session := s.open(t, config.Connection{Type: "redis"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := db.Exec(ctx, session, "BLPOP queue 0"); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if _, err := db.Exec(context.Background(), session, "GET a"); err != ErrSessionBroken {
+		t.Fatalf("got %v, want %v", err, ErrSessionBroken)
+	}
+}
+
+// TestIntegration runs against a real server when DBVI_TEST_REDIS_ADDR
+// (host:port) is set, using DBVI_TEST_REDIS_PASSWORD if needed.
+func TestIntegration(t *testing.T) {
+	addr := os.Getenv("DBVI_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("DBVI_TEST_REDIS_ADDR not set")
+	}
+
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	c := config.Connection{Name: "Integration", Type: "redis", Host: host, Port: p, Password: os.Getenv("DBVI_TEST_REDIS_PASSWORD")}
+
+	ctx := context.Background()
+	session, err := Driver{}.Open(ctx, c, c.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	for _, line := range []string{`DEL dbvi:test`, `HSET dbvi:test name "Ada Lovelace"`} {
+		if _, err := db.Exec(ctx, session, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reply, _ := exec(t, session, "HGETALL dbvi:test")
+	if got, want := reply.String(), `1# "name" => "Ada Lovelace"`; got != want && got != "1) \"name\"\n2) \"Ada Lovelace\"" {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+// maxBulkLen guards against allocating absurd amounts of memory on a
+// corrupted length, it is Redis' own proto-max-bulk-len default.
+const maxBulkLen = 512 << 20
+
+var errProtocol = errors.New("redis: protocol error")
+
+// writeCommand buffers args as a RESP array of bulk strings, it is sent on
+// the next flush.
+func writeCommand(w *bufio.Writer, args []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(a), a)
+	}
+}
+
+// readReply reads a RESP2 or RESP3 reply.
+func readReply(r *bufio.Reader) (db.Reply, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return db.Reply{}, err
+	}
+	if line == "" {
+		return db.Reply{}, errProtocol
+	}
+
+	kind, payload := line[0], line[1:]
+
+	switch kind {
+	case '+':
+		return db.Reply{Kind: db.ReplyStatus, Str: payload}, nil
+	case '-':
+		return db.Reply{Kind: db.ReplyError, Str: payload}, nil
+	case ':':
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return db.Reply{}, errProtocol
+		}
+		return db.Reply{Kind: db.ReplyInteger, Int: n}, nil
+	case '_':
+		return db.Reply{Kind: db.ReplyNil}, nil
+	case ',':
+		return db.Reply{Kind: db.ReplyDouble, Str: payload}, nil
+	case '(':
+		return db.Reply{Kind: db.ReplyBigNumber, Str: payload}, nil
+	case '#':
+		return db.Reply{Kind: db.ReplyBoolean, Bool: payload == "t"}, nil
+	case '$', '!', '=':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n > maxBulkLen {
+			return db.Reply{}, errProtocol
+		}
+		if n < 0 {
+			return db.Reply{Kind: db.ReplyNil}, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return db.Reply{}, err
+		}
+		s := string(buf[:n])
+
+		switch kind {
+		case '!':
+			return db.Reply{Kind: db.ReplyError, Str: s}, nil
+		case '=':
+			// Verbatim strings start with their format, e.g. "txt:".
+			if len(s) >= 4 && s[3] == ':' {
+				s = s[4:]
+			}
+			return db.Reply{Kind: db.ReplyVerbatim, Str: s}, nil
+		default:
+			return db.Reply{Kind: db.ReplyBulk, Str: s}, nil
+		}
+	case '*', '~', '>', '%', '|':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n > maxBulkLen {
+			return db.Reply{}, errProtocol
+		}
+		if n < 0 {
+			return db.Reply{Kind: db.ReplyNil}, nil
+		}
+		if kind == '%' || kind == '|' {
+			n *= 2
+		}
+
+		elems := make([]db.Reply, n)
+		for i := range elems {
+			if elems[i], err = readReply(r); err != nil {
+				return db.Reply{}, err
+			}
+		}
+
+		switch kind {
+		case '~':
+			return db.Reply{Kind: db.ReplySet, Elems: elems}, nil
+		case '>':
+			return db.Reply{Kind: db.ReplyPush, Elems: elems}, nil
+		case '%':
+			return db.Reply{Kind: db.ReplyMap, Elems: elems}, nil
+		case '|':
+			// Attributes describe the reply that follows them.
+			return readReply(r)
+		default:
+			return db.Reply{Kind: db.ReplyArray, Elems: elems}, nil
+		}
+	default:
+		return db.Reply{}, fmt.Errorf("%w: unexpected %q", errProtocol, kind)
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
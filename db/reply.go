@@ -0,0 +1,187 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReplyKind is the RESP type of a Reply.
+type ReplyKind int
+
+const (
+	ReplyStatus ReplyKind = iota
+	ReplyError
+	ReplyInteger
+	ReplyBulk
+	ReplyArray
+	ReplyNil
+	ReplyMap
+	ReplySet
+	ReplyDouble
+	ReplyBoolean
+	ReplyVerbatim
+	ReplyBigNumber
+	ReplyPush
+)
+
+// Reply is a reply of a key-value store like Redis. It is kept in its own
+// shape rather than flattened into a table.
+type Reply struct {
+	Kind ReplyKind
+
+	// Str holds the text of status, error, bulk, verbatim, double and big
+	// number replies.
+	Str string
+	Int int64
+	// Bool is the value of boolean replies.
+	Bool bool
+	// Elems are the elements of arrays, sets and pushes. Maps alternate
+	// keys and values.
+	Elems []Reply
+}
+
+// ReplyLine is a line of a rendered reply. Kind is the type of the value
+// the line shows, for coloring.
+type ReplyLine struct {
+	Text string
+	Kind ReplyKind
+}
+
+// Lines renders the reply the way redis-cli does, with nested aggregates
+// indented under their index.
+func (r Reply) Lines() []ReplyLine {
+	switch r.Kind {
+	case ReplyStatus:
+		return []ReplyLine{{Text: r.Str, Kind: r.Kind}}
+	case ReplyError:
+		return []ReplyLine{{Text: "(error) " + r.Str, Kind: r.Kind}}
+	case ReplyInteger:
+		return []ReplyLine{{Text: "(integer) " + strconv.FormatInt(r.Int, 10), Kind: r.Kind}}
+	case ReplyBulk:
+		return []ReplyLine{{Text: QuoteReply(r.Str), Kind: r.Kind}}
+	case ReplyNil:
+		return []ReplyLine{{Text: "(nil)", Kind: r.Kind}}
+	case ReplyDouble:
+		return []ReplyLine{{Text: "(double) " + r.Str, Kind: r.Kind}}
+	case ReplyBoolean:
+		return []ReplyLine{{Text: fmt.Sprintf("(%t)", r.Bool), Kind: r.Kind}}
+	case ReplyBigNumber:
+		return []ReplyLine{{Text: "(big number) " + r.Str, Kind: r.Kind}}
+	case ReplyVerbatim:
+		var lines []ReplyLine
+		for _, l := range strings.Split(strings.TrimSuffix(r.Str, "\n"), "\n") {
+			lines = append(lines, ReplyLine{Text: l, Kind: r.Kind})
+		}
+		return lines
+	case ReplyMap:
+		return r.mapLines()
+	default:
+		return r.aggregateLines()
+	}
+}
+
+func (r Reply) aggregateLines() []ReplyLine {
+	if len(r.Elems) == 0 {
+		switch r.Kind {
+		case ReplySet:
+			return []ReplyLine{{Text: "(empty set)", Kind: r.Kind}}
+		default:
+			return []ReplyLine{{Text: "(empty array)", Kind: r.Kind}}
+		}
+	}
+
+	sep := ")"
+	if r.Kind == ReplySet {
+		sep = "~"
+	}
+
+	width := len(strconv.Itoa(len(r.Elems)))
+
+	var lines []ReplyLine
+	for i, elem := range r.Elems {
+		prefix := fmt.Sprintf("%*d%s ", width, i+1, sep)
+		lines = appendIndented(lines, prefix, elem.Lines())
+	}
+
+	return lines
+}
+
+func (r Reply) mapLines() []ReplyLine {
+	if len(r.Elems) == 0 {
+		return []ReplyLine{{Text: "(empty hash)", Kind: r.Kind}}
+	}
+
+	pairs := len(r.Elems) / 2
+	width := len(strconv.Itoa(pairs))
+
+	var lines []ReplyLine
+	for i := 0; i < pairs; i++ {
+		key := r.Elems[2*i].Lines()
+		prefix := fmt.Sprintf("%*d# %s => ", width, i+1, key[0].Text)
+		lines = appendIndented(lines, prefix, r.Elems[2*i+1].Lines())
+	}
+
+	return lines
+}
+
+// appendIndented appends sub with prefix on its first line and the
+// following lines aligned under it.
+func appendIndented(lines []ReplyLine, prefix string, sub []ReplyLine) []ReplyLine {
+	pad := strings.Repeat(" ", len([]rune(prefix)))
+	for j, l := range sub {
+		if j == 0 {
+			l.Text = prefix + l.Text
+		} else {
+			l.Text = pad + l.Text
+		}
+		lines = append(lines, l)
+	}
+
+	return lines
+}
+
+func (r Reply) String() string {
+	lines := r.Lines()
+
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.Text
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// QuoteReply quotes a bulk string like redis-cli, escaping quotes,
+// backslashes, control characters and non-ASCII bytes.
+func QuoteReply(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\a':
+			sb.WriteString(`\a`)
+		case '\b':
+			sb.WriteString(`\b`)
+		default:
+			if c >= ' ' && c <= '~' {
+				sb.WriteByte(c)
+			} else {
+				fmt.Fprintf(&sb, `\x%02x`, c)
+			}
+		}
+	}
+
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplyLines(t *testing.T) {
+	bulk := func(s string) Reply { return Reply{Kind: ReplyBulk, Str: s} }
+
+	many := Reply{Kind: ReplyArray}
+	for i := 0; i < 10; i++ {
+		many.Elems = append(many.Elems, Reply{Kind: ReplyInteger, Int: int64(i)})
+	}
+
+	tests := []struct {
+		name  string
+		reply Reply
+		want  []string
+	}{
+		{name: "status", reply: Reply{Kind: ReplyStatus, Str: "OK"}, want: []string{"OK"}},
+		{name: "error", reply: Reply{Kind: ReplyError, Str: "WRONGTYPE Operation against a key"}, want: []string{"(error) WRONGTYPE Operation against a key"}},
+		{name: "nil", reply: Reply{Kind: ReplyNil}, want: []string{"(nil)"}},
+		{name: "bulk", reply: bulk("caf\xc3\xa9 \"1\"\n"), want: []string{`"caf\xc3\xa9 \"1\"\n"`}},
+		{name: "boolean", reply: Reply{Kind: ReplyBoolean, Bool: true}, want: []string{"(true)"}},
+		{name: "double", reply: Reply{Kind: ReplyDouble, Str: "3.14"}, want: []string{"(double) 3.14"}},
+		{name: "empty array", reply: Reply{Kind: ReplyArray}, want: []string{"(empty array)"}},
+		{name: "empty set", reply: Reply{Kind: ReplySet}, want: []string{"(empty set)"}},
+		{
+			name: "nested",
+			reply: Reply{Kind: ReplyArray, Elems: []Reply{
+				bulk("a"),
+				{Kind: ReplyArray, Elems: []Reply{bulk("b"), {Kind: ReplyNil}}},
+			}},
+			want: []string{`1) "a"`, `2) 1) "b"`, `   2) (nil)`},
+		},
+		{
+			name: "map",
+			reply: Reply{Kind: ReplyMap, Elems: []Reply{
+				bulk("name"), bulk("Ada"),
+				bulk("langs"), {Kind: ReplySet, Elems: []Reply{bulk("en"), bulk("fr")}},
+			}},
+			want: []string{`1# "name" => "Ada"`, `2# "langs" => 1~ "en"`, `              2~ "fr"`},
+		},
+		{
+			name:  "index padding",
+			reply: many,
+			want:  []string{" 1) (integer) 0", " 2) (integer) 1", " 3) (integer) 2", " 4) (integer) 3", " 5) (integer) 4", " 6) (integer) 5", " 7) (integer) 6", " 8) (integer) 7", " 9) (integer) 8", "10) (integer) 9"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.reply.String()
+			if want := strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestReplyLinesKinds(t *testing.T) {
+	reply := Reply{Kind: ReplyArray, Elems: []Reply{
+		{Kind: ReplyInteger, Int: 1},
+		{Kind: ReplyError, Str: "ERR boom"},
+	}}
+
+	lines := reply.Lines()
+	if lines[0].Kind != ReplyInteger || lines[1].Kind != ReplyError {
+		t.Errorf("got kinds %v and %v, want the kinds of the elements", lines[0].Kind, lines[1].Kind)
+	}
+}
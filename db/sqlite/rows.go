@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+type rows struct {
+	ctx      context.Context
+	session  *Session
+	rows     *sql.Rows
+	columns  []db.Column
+	write    bool // an INSERT, UPDATE or DELETE, its changes are counted
+	count    int64
+	affected int64
+	err      error
+	closed   bool
+}
+
+func newRows(ctx context.Context, s *Session, r *sql.Rows, write bool) (*rows, error) {
+	types, err := r.ColumnTypes()
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	columns := make([]db.Column, len(types))
+	for i, t := range types {
+		columns[i] = db.Column{Name: t.Name(), Type: t.DatabaseTypeName()}
+	}
+
+	return &rows{ctx: ctx, session: s, rows: r, columns: columns, write: write}, nil
+}
+
+func (r *rows) Columns() []db.Column {
+	return r.columns
+}
+
+func (r *rows) Next() bool {
+	if r.err != nil || !r.rows.Next() {
+		return false
+	}
+
+	r.count++
+	return true
+}
+
+func (r *rows) Values() []any {
+	values := make([]any, len(r.columns))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := r.rows.Scan(dest...); err != nil {
+		r.err = err
+		return values
+	}
+
+	for i, v := range values {
+		values[i] = convertValue(r.columns[i].Type, v)
+	}
+
+	return values
+}
+
+func (r *rows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return r.rows.Err()
+}
+
+// Close counts the changes of writes once the statement ran.
+func (r *rows) Close() error {
+	if r.closed {
+		return r.Err()
+	}
+	r.closed = true
+
+	if err := r.rows.Close(); err != nil {
+		return err
+	}
+
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	if r.write && len(r.columns) == 0 {
+		n, err := r.session.changes(r.ctx)
+		if err != nil {
+			return err
+		}
+		r.affected = n
+	}
+
+	return nil
+}
+
+func (r *rows) Tag() string {
+	return ""
+}
+
+func (r *rows) RowsAffected() int64 {
+	if len(r.columns) == 0 {
+		return r.affected
+	}
+
+	return r.count
+}
+
+// convertValue maps the values of the driver to the displayable types
+// documented on db.Rows. SQLite values only have a storage class, the
+// declared type of the column tells what they mean. Numbers in DECIMAL
+// columns are stored as integers or reals already.
+func convertValue(typeName string, v any) any {
+	typeName = strings.ToUpper(typeName)
+
+	switch v := v.(type) {
+	case string:
+		if typeName == "JSON" {
+			return db.JSON(v)
+		}
+		return v
+	case time.Time:
+		if typeName == "DATE" {
+			return db.Date{Time: v}
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,140 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// busyTimeout is how long statements wait on a database locked by another
+// process, like the app whose cache is being inspected, in milliseconds.
+const busyTimeout = 5000
+
+// Memory is the path of a private in-memory database.
+const Memory = ":memory:"
+
+func init() {
+	db.Register("sqlite", Driver{})
+}
+
+type Driver struct{}
+
+// Open opens the database file at path, which is the connection's path. The
+// file has to exist, a typo shouldn't silently create an empty database.
+func (Driver) Open(ctx context.Context, c config.Connection, path string) (db.Session, error) {
+	pool, err := sql.Open("sqlite3", dsn(path))
+	if err != nil {
+		return nil, err
+	}
+
+	// An in-memory database only lives as long as its connection, the
+	// session keeps hold of a single one.
+	pool.SetMaxOpenConns(1)
+
+	conn, err := pool.Conn(ctx)
+	if err == nil {
+		err = conn.PingContext(ctx)
+	}
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	return &Session{pool: pool, conn: conn}, nil
+}
+
+func dsn(path string) string {
+	params := fmt.Sprintf("_busy_timeout=%d&_foreign_keys=on", busyTimeout)
+	if path == Memory {
+		return "file::memory:?" + params
+	}
+
+	u := url.URL{Path: filepath.ToSlash(expandHome(path))}
+	return "file:" + u.EscapedPath() + "?mode=rw&" + params
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
+
+// Session is a connection to a SQLite database.
+type Session struct {
+	pool *sql.DB
+	conn *sql.Conn
+}
+
+// Query runs the statements in stmt one after another and returns the result
+// of the last one.
+func (s *Session) Query(ctx context.Context, stmt string, args ...any) (db.Rows, error) {
+	stmts := query.Split(stmt, query.SQLite)
+	if len(stmts) == 0 {
+		stmts = []query.Statement{{Text: stmt}}
+	}
+
+	if len(stmts) > 1 && len(args) > 0 {
+		return nil, errors.New("bind parameters need a single statement")
+	}
+
+	for _, st := range stmts[:len(stmts)-1] {
+		rows, err := s.query(ctx, st.Text)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := db.Collect(rows, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.query(ctx, stmts[len(stmts)-1].Text, args...)
+}
+
+// query runs a single statement. Everything goes through QueryContext, it
+// tells from the columns if there is a result set, the SQLite driver only
+// executes the statement on the first Next.
+func (s *Session) query(ctx context.Context, stmt string, args ...any) (db.Rows, error) {
+	r, err := s.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	write := query.Classify(stmt, query.SQLite).Kind == query.KindWrite
+	return newRows(ctx, s, r, write)
+}
+
+// changes is the number of rows changed by the last INSERT, UPDATE or
+// DELETE.
+func (s *Session) changes(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.conn.QueryRowContext(ctx, "SELECT changes()").Scan(&n)
+	return n, err
+}
+
+// Ping checks the database is still open.
+func (s *Session) Ping(ctx context.Context) error {
+	return s.conn.PingContext(ctx)
+}
+
+func (s *Session) Close() error {
+	s.conn.Close()
+	return s.pool.Close()
+}
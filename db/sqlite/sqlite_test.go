@@ -0,0 +1,217 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+)
+
+// open opens a session through db.Open, like the editor does.
+func open(t *testing.T, c config.Connection) db.Session {
+	t.Helper()
+
+	session, err := db.Open(context.Background(), c, c.Address())
+	if err != nil {
+		t.Fatalf("opening %s: %v", c.Path, err)
+	}
+	t.Cleanup(func() { session.Close() })
+
+	return session
+}
+
+func exec(t *testing.T, session db.Session, stmt string) *db.Result {
+	t.Helper()
+
+	res, err := db.Exec(context.Background(), session, stmt)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", stmt, err)
+	}
+
+	return res
+}
+
+func TestQueryTypeMapping(t *testing.T) {
+	session := open(t, config.Connection{Type: "sqlite", Path: Memory})
+
+	exec(t, session, `CREATE TABLE t (
+		id INTEGER PRIMARY KEY,
+		price DECIMAL(10, 2),
+		ratio REAL,
+		name TEXT,
+		avatar BLOB,
+		doc JSON,
+		day DATE,
+		created DATETIME,
+		active BOOLEAN,
+		missing TEXT
+	)`)
+	exec(t, session, `INSERT INTO t VALUES (1, '12.50', 1.1, 'héllo', x'0102ff', '{"a": 1}', '2024-03-01', '2024-03-01 12:34:56.789', 1, NULL)`)
+
+	res := exec(t, session, "SELECT * FROM t")
+
+	wantTypes := []string{"INTEGER", "DECIMAL", "REAL", "TEXT", "BLOB", "JSON", "DATE", "DATETIME", "BOOLEAN", "TEXT"}
+	for i, c := range res.Columns {
+		if !strings.HasPrefix(c.Type, wantTypes[i]) {
+			t.Errorf("%s: got type %q, want %q", c.Name, c.Type, wantTypes[i])
+		}
+	}
+
+	if len(res.Rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(res.Rows))
+	}
+
+	want := []string{
+		"1",
+		"12.5",
+		"1.1",
+		"héllo",
+		`\x0102ff`,
+		`{"a": 1}`,
+		"2024-03-01",
+		"2024-03-01 12:34:56.789",
+		"true",
+		"NULL",
+	}
+
+	for i, v := range res.Rows[0] {
+		if got := db.FormatValue(v); got != want[i] {
+			t.Errorf("%s: got %q (%T), want %q", res.Columns[i].Name, got, v, want[i])
+		}
+	}
+
+	if _, ok := res.Rows[0][5].(db.JSON); !ok {
+		t.Errorf("expected JSON to be a db.JSON, got %T", res.Rows[0][5])
+	}
+	if _, ok := res.Rows[0][6].(db.Date); !ok {
+		t.Errorf("expected DATE to be a db.Date, got %T", res.Rows[0][6])
+	}
+	if _, ok := res.Rows[0][7].(time.Time); !ok {
+		t.Errorf("expected DATETIME to be a time.Time, got %T", res.Rows[0][7])
+	}
+}
+
+func TestQueryRowsAffected(t *testing.T) {
+	session := open(t, config.Connection{Type: "sqlite", Path: Memory})
+
+	res := exec(t, session, "CREATE TABLE t (n INTEGER); INSERT INTO t VALUES (1), (2), (3);")
+	if len(res.Columns) != 0 || res.RowsAffected != 3 {
+		t.Errorf("got %d columns and %d rows affected, want the 3 inserted rows", len(res.Columns), res.RowsAffected)
+	}
+
+	if res := exec(t, session, "UPDATE t SET n = n + 1 WHERE n > 1"); res.RowsAffected != 2 {
+		t.Errorf("got %d rows affected, want 2", res.RowsAffected)
+	}
+
+	if res := exec(t, session, "CREATE INDEX t_n ON t (n)"); res.RowsAffected != 0 {
+		t.Errorf("got %d rows affected by DDL, want 0", res.RowsAffected)
+	}
+
+	res = exec(t, session, "DELETE FROM t WHERE n = 4 RETURNING n")
+	if len(res.Rows) != 1 || res.Rows[0][0] != int64(4) || res.RowsAffected != 1 {
+		t.Errorf("got rows %v and %d affected, want the deleted row", res.Rows, res.RowsAffected)
+	}
+}
+
+func TestQueryBindParameters(t *testing.T) {
+	session := open(t, config.Connection{Type: "sqlite", Path: Memory})
+
+	res, err := db.Exec(context.Background(), session, "SELECT ? + 1, :name", 41, "Ada")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Rows[0][0] != int64(42) || res.Rows[0][1] != "Ada" {
+		t.Errorf("got %v, want [42 Ada]", res.Rows[0])
+	}
+
+	if _, err := db.Exec(context.Background(), session, "SELECT 1; SELECT ?", 1); err == nil {
+		t.Error("expected an error binding several statements")
+	}
+}
+
+func TestPrimaryKey(t *testing.T) {
+	session := open(t, config.Connection{Type: "sqlite", Path: Memory})
+	exec(t, session, "CREATE TABLE grants (role TEXT, user_id INTEGER, note TEXT, PRIMARY KEY (user_id, role)); CREATE TABLE log (line TEXT)")
+
+	ctx := context.Background()
+	for _, tt := range []struct {
+		schema, table string
+		want          []string
+	}{
+		{table: "grants", want: []string{"user_id", "role"}},
+		{schema: "main", table: "grants", want: []string{"user_id", "role"}},
+		{table: "log", want: []string{}},
+	} {
+		got, err := db.PrimaryKey(ctx, session, "sqlite", tt.schema, tt.table)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.table, err)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %v, want %v", tt.table, got, tt.want)
+		}
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	session := open(t, config.Connection{Type: "sqlite", Path: Memory})
+
+	_, err := db.Exec(context.Background(), session, "SELEC 1")
+	if err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Fatalf("got %v, want a syntax error", err)
+	}
+
+	// The session stays usable after an error.
+	if res := exec(t, session, "SELECT 2"); res.Rows[0][0] != int64(2) {
+		t.Errorf("got %v, want 2", res.Rows[0][0])
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.db")
+
+	if _, err := db.Open(context.Background(), config.Connection{Type: "sqlite", Path: path}, path); err == nil {
+		t.Fatal("expected an error opening a missing file")
+	}
+
+	// Create the file the way an app would, then inspect it.
+	pool, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec("CREATE TABLE users (name TEXT); INSERT INTO users VALUES ('Ada')"); err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+
+	session := open(t, config.Connection{Type: "sqlite", Path: path})
+	if res := exec(t, session, "SELECT name FROM users"); len(res.Rows) != 1 || res.Rows[0][0] != "Ada" {
+		t.Errorf("got rows %v, want Ada", res.Rows)
+	}
+
+	// Read-only connections refuse writes in the database too.
+	readOnly := open(t, config.Connection{Type: "sqlite", Path: path, ReadOnly: true})
+	if _, err := db.Exec(context.Background(), readOnly, "DELETE FROM users"); err == nil {
+		t.Error("expected a read-only connection to refuse writes")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	session := open(t, config.Connection{Type: "sqlite", Path: Memory})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	endless := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n"
+	if _, err := db.Exec(ctx, session, endless); err == nil {
+		t.Fatal("expected the statement to be interrupted")
+	}
+
+	if res := exec(t, session, "SELECT 1"); res.Rows[0][0] != int64(1) {
+		t.Errorf("got %v, want 1", res.Rows[0][0])
+	}
+}
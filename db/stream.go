@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+)
+
+// Pager is implemented by sessions that can run a read through a server side
+// cursor, so a large result leaves the server a page at a time instead of
+// being pushed down the connection all at once. Sessions fall back to Query
+// for statements that can't be paged.
+type Pager interface {
+	QueryPages(ctx context.Context, stmt string, pageSize int) (Rows, error)
+}
+
+// Stream reads the result of a statement a page at a time, so only the rows
+// that were asked for are ever held in memory. The rows stay open, and the
+// session busy, until the last page was read or the stream is closed.
+type Stream struct {
+	rows Rows
+	done bool
+	next bool // another result set follows, the rows are still open
+
+	// What the rows said of the result set that was read before they
+	// moved on to the next one.
+	columns      []Column
+	tag          string
+	rowsAffected int64
+}
+
+// OpenStream runs stmt on s, through a server side cursor of pageSize rows
+// when s supports one. Statements with bind parameters args are read
+// through Query.
+func OpenStream(ctx context.Context, s Session, stmt string, pageSize int, args ...any) (*Stream, error) {
+	var rows Rows
+	var err error
+
+	if p, ok := s.(Pager); ok && len(args) == 0 {
+		rows, err = p.QueryPages(ctx, stmt, pageSize)
+	} else {
+		rows, err = s.Query(ctx, stmt, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stream{rows: rows, columns: rows.Columns()}, nil
+}
+
+func (st *Stream) Columns() []Column {
+	return st.columns
+}
+
+// Fetch reads up to n more rows. Once the rows run out Done reports true,
+// and the stream closes itself unless another result set follows.
+func (st *Stream) Fetch(n int) ([][]any, error) {
+	if st.done {
+		return nil, nil
+	}
+
+	var page [][]any
+	for len(page) < n {
+		if !st.rows.Next() {
+			st.done = true
+			break
+		}
+		page = append(page, st.rows.Values())
+	}
+
+	if err := st.rows.Err(); err != nil {
+		st.done = true
+		st.rows.Close()
+		return nil, err
+	}
+
+	if st.done {
+		tag, rowsAffected := st.rows.Tag(), st.rows.RowsAffected()
+		if sets, ok := st.rows.(ResultSets); ok && sets.NextResultSet() {
+			st.tag, st.rowsAffected = tag, rowsAffected
+			st.next = true
+			return page, nil
+		}
+
+		if err := st.rows.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// NextResultSet moves on to the result set following the one that was read
+// to the end, reporting false when there is none.
+func (st *Stream) NextResultSet() bool {
+	if !st.next {
+		return false
+	}
+
+	st.next, st.done = false, false
+	st.columns = st.rows.Columns()
+	return true
+}
+
+// Done reports if every row was read.
+func (st *Stream) Done() bool {
+	return st.done
+}
+
+// Tag and RowsAffected describe the result set once Done.
+func (st *Stream) Tag() string {
+	if st.next {
+		return st.tag
+	}
+
+	return st.rows.Tag()
+}
+
+func (st *Stream) RowsAffected() int64 {
+	if st.next {
+		return st.rowsAffected
+	}
+
+	return st.rows.RowsAffected()
+}
+
+// Close stops reading, leaving the rows that weren't fetched on the server.
+func (st *Stream) Close() error {
+	if st.done && !st.next {
+		return nil
+	}
+	st.done, st.next = true, false
+
+	return st.rows.Close()
+}
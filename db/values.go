@@ -0,0 +1,166 @@
+package db
+
+import (
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decimal is an exact numeric kept in its textual form so no precision is
+// lost on the way to the screen.
+type Decimal string
+
+// JSON is a JSON document as sent by the server.
+type JSON []byte
+
+// Interval is a duration in the server's own notation, e.g. "1 day 02:00:00".
+type Interval string
+
+// Date is a calendar date without a time of day.
+type Date struct {
+	time.Time
+}
+
+func (d Date) String() string {
+	return d.Format("2006-01-02")
+}
+
+// Bits is a bit string such as the ones held by Postgres BIT or MySQL BIT
+// columns, one '0' or '1' per bit.
+type Bits string
+
+// FormatValue renders a value from Rows.Values for display.
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return formatTime(v)
+	case Decimal:
+		return string(v)
+	case JSON:
+		return string(v)
+	case Interval:
+		return string(v)
+	case Bits:
+		return "b'" + string(v) + "'"
+	case []any:
+		parts := make([]string, len(v))
+		for i, x := range v {
+			parts[i] = FormatValue(x)
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.Location() == time.UTC {
+		return t.Format("2006-01-02 15:04:05.999999")
+	}
+
+	return t.Format("2006-01-02 15:04:05.999999-07:00")
+}
+
+// Compare orders two values from Rows.Values, returning -1, 0 or 1. NULL
+// sorts after everything else, numbers by value, times chronologically and
+// anything else by its text. Text that reads as a number compares by value
+// with numbers.
+func Compare(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+
+	_, aText := a.(string)
+	_, bText := b.(string)
+	if x, ok := number(a, !bText); ok {
+		if y, ok := number(b, !aText); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+
+	if x, ok := timeOf(a); ok {
+		if y, ok := timeOf(b); ok {
+			return x.Compare(y)
+		}
+	}
+
+	if x, ok := a.(bool); ok {
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0
+			case y:
+				return -1
+			default:
+				return 1
+			}
+		}
+	}
+
+	return strings.Compare(FormatValue(a), FormatValue(b))
+}
+
+// number returns the value of a numeric v, or of text reading as a number
+// when text is set.
+func number(v any, text bool) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case Decimal:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil
+	case string:
+		if !text {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+func timeOf(v any) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case Date:
+		return v.Time, true
+	}
+
+	return time.Time{}, false
+}
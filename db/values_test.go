@@ -0,0 +1,37 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		a, b any
+		want int
+	}{
+		{a: nil, b: nil, want: 0},
+		{a: nil, b: int64(1), want: 1},
+		{a: "", b: nil, want: -1},
+		{a: int64(9), b: int64(10), want: -1},
+		{a: int64(9), b: 9.5, want: -1},
+		{a: Decimal("10.50"), b: int64(10), want: 1},
+		{a: "10", b: int64(9), want: 1},
+		{a: "10", b: "9", want: -1},
+		{a: "apple", b: "banana", want: -1},
+		{a: day, b: day.Add(time.Hour), want: -1},
+		{a: Date{day}, b: day, want: 0},
+		{a: true, b: false, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v vs %v", tt.a, tt.b), func(t *testing.T) {
+			if got := Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
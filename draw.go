@@ -0,0 +1,84 @@
+package main
+
+import "github.com/gdamore/tcell"
+
+// drawText writes text starting at x,y, clipped to width cells. It returns
+// the number of cells written.
+func drawText(screen tcell.Screen, x, y, width int, text string, style tcell.Style) int {
+	i := 0
+	for _, ch := range text {
+		if i >= width {
+			break
+		}
+
+		screen.SetContent(x+i, y, ch, nil, style)
+		i++
+	}
+
+	return i
+}
+
+// wrapText breaks text into rows of at most width characters, after a
+// space when there is one to break at.
+func wrapText(text string, width int) []string {
+	runes := []rune(text)
+	if width <= 0 || len(runes) <= width {
+		return []string{text}
+	}
+
+	var rows []string
+	for len(runes) > width {
+		n := width
+		for i := width; i > 0; i-- {
+			if runes[i] == ' ' {
+				n = i
+				break
+			}
+		}
+
+		rows = append(rows, string(runes[:n]))
+		runes = runes[n:]
+		for len(runes) > 0 && runes[0] == ' ' {
+			runes = runes[1:]
+		}
+	}
+	if len(runes) > 0 {
+		rows = append(rows, string(runes))
+	}
+
+	return rows
+}
+
+// fillRow paints width cells starting at x,y with blanks.
+func fillRow(screen tcell.Screen, x, y, width int, style tcell.Style) {
+	for i := 0; i < width; i++ {
+		screen.SetContent(x+i, y, ' ', nil, style)
+	}
+}
+
+// drawBox draws a bordered box with an optional title and clears its inside
+// with fill.
+func drawBox(screen tcell.Screen, x, y, width, height int, title string, style, fill tcell.Style) {
+	for row := y + 1; row < y+height-1; row++ {
+		fillRow(screen, x+1, row, width-2, fill)
+	}
+
+	for i := x + 1; i < x+width-1; i++ {
+		screen.SetContent(i, y, tcell.RuneHLine, nil, style)
+		screen.SetContent(i, y+height-1, tcell.RuneHLine, nil, style)
+	}
+
+	for j := y + 1; j < y+height-1; j++ {
+		screen.SetContent(x, j, tcell.RuneVLine, nil, style)
+		screen.SetContent(x+width-1, j, tcell.RuneVLine, nil, style)
+	}
+
+	screen.SetContent(x, y, tcell.RuneULCorner, nil, style)
+	screen.SetContent(x+width-1, y, tcell.RuneURCorner, nil, style)
+	screen.SetContent(x, y+height-1, tcell.RuneLLCorner, nil, style)
+	screen.SetContent(x+width-1, y+height-1, tcell.RuneLRCorner, nil, style)
+
+	if title != "" {
+		drawText(screen, x+2, y, width-4, " "+title+" ", style)
+	}
+}
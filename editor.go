@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
 	"github.com/ajm113/dbvi/utils"
 	"github.com/gdamore/tcell"
 )
@@ -18,8 +21,30 @@ const (
 	VisualLineMode
 	CommandMode
 	ExecuteMode
+	ResultsMode
 )
 
+func (m EditorMode) String() string {
+	switch m {
+	case NormalMode:
+		return "Normal"
+	case InsertMode:
+		return "Insert"
+	case VisualMode:
+		return "Visual"
+	case VisualLineMode:
+		return "Visual line"
+	case CommandMode:
+		return "Command"
+	case ExecuteMode:
+		return "Execute"
+	case ResultsMode:
+		return "Results"
+	default:
+		return fmt.Sprintf("EditorMode(%d)", int(m))
+	}
+}
+
 type Editor struct {
 	Lines              []string
 	Clipboard          []string
@@ -34,54 +59,92 @@ type Editor struct {
 	Width              int
 	Height             int
 	StatusBar          *StatusBar
-
+	Results            *ResultsPane
+	Popup              Popup
+	Connection         *config.Connection
+	// Autocommit is off when statements should run in a transaction that
+	// is only ended by :commit or :rollback.
+	Autocommit bool
+
+	app           *App
 	screen        tcell.Screen
 	normalStyle   tcell.Style
 	selectedStyle tcell.Style
 	executedStyle tcell.Style
 	bufferedKeys  string
+
+	sessionMu sync.Mutex
+	session   db.Session
+	running   bool
+	cancel    context.CancelFunc // cancels the running statement
+	notice    *db.Notice         // last notice of the running statement
+	txn       *transaction       // open transaction, nil in autocommit
+	stream    *resultStream      // result with rows left to fetch
+	fetching  bool               // running is set for a fetch
+	edits     *tableEdits        // changes made to the rows of the result
+	script    *scriptRun         // last script run
+	errorMark *errorMark         // where the last error points
+	messages  []message          // shown this session, for :messages
+
+	vars  map[string]string // set with :set var, bound without asking
+	binds map[string]string // last values given to placeholders
 }
 
-func NewEditor(screen tcell.Screen) *Editor {
+func NewEditor(app *App) *Editor {
 
 	editor := &Editor{
 		Lines:         []string{""},
 		CursorX:       0,
 		CursorY:       0,
 		EditorMode:    NormalMode,
-		screen:        screen,
+		Autocommit:    true,
+		app:           app,
+		screen:        app.screen,
 		normalStyle:   tcell.StyleDefault,
 		selectedStyle: tcell.StyleDefault.Foreground(tcell.ColorGrey).Background(tcell.ColorWhite),
 	}
 
-	editor.StatusBar = NewStatusBar(screen, editor)
+	editor.StatusBar = NewStatusBar(app.screen, editor)
+	editor.Results = NewResultsPane(app.screen, editor)
 	setDefaultHotkeys(editor)
+	setDefaultCommands(editor)
 
 	return editor
 }
 
 func (e *Editor) HandleEventKey(ek *tcell.EventKey) {
+	// Prompts and the results pane can be opened from a popup, they get
+	// the keys while they are active.
 	if e.EditorMode == CommandMode {
 		e.StatusBar.HandleEventKey(ek)
 		return
 	}
 
+	if e.Popup != nil && e.EditorMode != ResultsMode {
+		e.Popup.HandleEventKey(ek)
+		return
+	}
+
 	moveByWord := ek.Modifiers()&tcell.ModCtrl != 0
 
+	if ek.Key() == tcell.KeyRune && (ek.Rune() == ':' || ek.Rune() == '/') && e.EditorMode != InsertMode {
+		e.enterCommandLine(ek.Rune())
+		return
+	}
+
+	if e.EditorMode == ResultsMode {
+		if ek.Key() == tcell.KeyEscape {
+			e.SetEditorMode(NormalMode)
+			return
+		}
+
+		e.Results.HandleEventKey(ek)
+		e.handleHotkeys(ek)
+		return
+	}
+
 	// General navigation that should work on all modes.
 	switch ek.Key() {
-	case ':':
-		if e.EditorMode != InsertMode && e.EditorMode != CommandMode {
-			e.SetEditorMode(CommandMode)
-			e.StatusBar.Command = ":"
-			e.StatusBar.CursorX++
-		}
-	case '/':
-		if e.EditorMode != InsertMode && e.EditorMode != CommandMode {
-			e.SetEditorMode(CommandMode)
-			e.StatusBar.Command = "/"
-			e.StatusBar.CursorX++
-		}
 	case tcell.KeyEscape:
 		e.SetEditorMode(NormalMode)
 	case tcell.KeyLeft:
@@ -135,37 +198,104 @@ func (e *Editor) HandleEventKey(ek *tcell.EventKey) {
 func (e *Editor) SetEditorMode(editorMode EditorMode) {
 	e.EditorMode = editorMode
 	e.bufferedKeys = ""
+	e.StatusBar.level = MessageInfo
 
 	switch e.EditorMode {
 	case InsertMode:
 		e.StatusBar.Command = "-- INSERT --"
+		e.errorMark = nil
 	case VisualMode:
 		e.StatusBar.Command = "-- VISUAL --"
 	case VisualLineMode:
 		e.StatusBar.Command = "-- VISUAL LINE --"
 	case ExecuteMode:
 		e.StatusBar.Command = "-- EXECUTE --"
+	case ResultsMode:
+		e.StatusBar.Command = "-- RESULTS --"
 	default:
 		e.StatusBar.Command = ""
 	}
 }
 
+// enterCommandLine starts typing a ":" command or a "/" search.
+func (e *Editor) enterCommandLine(prefix rune) {
+	e.StatusBar.returnMode = e.EditorMode
+	e.SetEditorMode(CommandMode)
+	e.StatusBar.Command = string(prefix)
+	e.StatusBar.CursorX = 1
+}
+
+// ExecuteCommand runs a ":" command line such as "connect Local".
+func (e *Editor) ExecuteCommand(line string) {
+	name, args := splitCommandLine(line)
+	if name == "" {
+		return
+	}
+
+	ctx := withCommandArgs(context.Background(), args)
+
+	cmd, ok := CommandRegistry[name]
+	if !ok && strings.HasSuffix(name, "!") {
+		cmd, ok = CommandRegistry[strings.TrimSuffix(name, "!")]
+		ctx = withCommandBang(ctx)
+	}
+	if !ok {
+		e.fail("Not an editor command: %s", name)
+		return
+	}
+
+	cmd.Handler(ctx, e)
+}
+
+// Popup is drawn over the editor and gets the keys while it is open.
+type Popup interface {
+	HandleEventKey(ek *tcell.EventKey)
+	Draw()
+}
+
+func (e *Editor) OpenPopup(p Popup) {
+	e.Popup = p
+}
+
+func (e *Editor) ClosePopup() {
+	e.Popup = nil
+}
+
+// Connect binds the buffer to c, closing the session of the previous
+// connection. A nil connection disconnects it. It reports false, leaving the
+// buffer as it is, while a statement runs on the session.
+func (e *Editor) Connect(c *config.Connection) bool {
+	if e.Connection != c {
+		if e.running {
+			e.notify("A statement is already running")
+			return false
+		}
+
+		e.closeSession()
+		e.txn = nil
+	}
+
+	e.Connection = c
+	return true
+}
+
+// Close releases everything the buffer holds on to.
+func (e *Editor) Close() {
+	e.closeSession()
+}
+
 func (e *Editor) handleHotkeys(ek *tcell.EventKey) {
 	if e.EditorMode == InsertMode {
 		return
 	}
 
 	e.bufferedKeys += eventKeyToString(ek)
-	if cmd, ok := HotkeyCommandRegistry[e.bufferedKeys]; ok {
-
-		if len(cmd.EditorModes) == 0 {
-			cmd.Handler(context.Background(), e)
-		} else {
-			for _, m := range cmd.EditorModes {
-				if m == e.EditorMode {
-					cmd.Handler(context.Background(), e)
-					break
-				}
+	if cmds, ok := HotkeyCommandRegistry[e.bufferedKeys]; ok {
+		// The last command registered for the mode wins.
+		for i := len(cmds) - 1; i >= 0; i-- {
+			if cmds[i].hasMode(e.EditorMode) {
+				cmds[i].Handler(context.Background(), e)
+				break
 			}
 		}
 		e.bufferedKeys = ""
@@ -252,6 +382,12 @@ func (e *Editor) Draw() {
 	e.Height = screenHeight - 2 // leave space for status bar
 	e.Width = screenWidth
 
+	if e.Results.Visible() {
+		resultsHeight := max(4, e.Height*2/5)
+		e.Height -= resultsHeight
+		e.Results.Draw(e.Height, resultsHeight)
+	}
+
 	for y := 0; y < e.Height; y++ {
 		lineIndex := e.ScrollOffsetY + y
 		if lineIndex >= len(e.Lines) {
@@ -266,12 +402,19 @@ func (e *Editor) Draw() {
 			if e.isSelected(x, y) {
 				style = e.selectedStyle
 			}
+			if e.errorMark.covers(x, lineIndex) {
+				style = style.Underline(true).Foreground(tcell.ColorRed)
+			}
 
 			e.screen.SetContent(x, y, ch, nil, style)
 		}
 	}
 
 	e.StatusBar.Draw()
+
+	if e.Popup != nil {
+		e.Popup.Draw()
+	}
 }
 
 func (e *Editor) isSelected(x, y int) bool {
@@ -357,6 +500,15 @@ func eventKeyToString(ev *tcell.EventKey) string {
 		return prefix + "PageDown"
 	case tcell.KeyCtrlSpace:
 		return "Ctrl+Space"
+	}
+
+	// Control characters share their codes with the letters, Ctrl+W comes
+	// in as KeyCtrlW.
+	if ev.Key() >= tcell.KeyCtrlA && ev.Key() <= tcell.KeyCtrlZ {
+		return "Ctrl+" + string(rune('a'+ev.Key()-tcell.KeyCtrlA))
+	}
+
+	switch ev.Key() {
 	default:
 		return fmt.Sprintf("%s[%v]", prefix, ev.Key())
 	}
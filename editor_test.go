@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/tunnel"
+	"github.com/gdamore/tcell"
+	"go.uber.org/zap"
+)
+
+func newTestEditor(t *testing.T, cfg *config.Config) *Editor {
+	t.Helper()
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(80, 24)
+	t.Cleanup(screen.Fini)
+
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+
+	app := &App{
+		screen:  screen,
+		log:     zap.NewNop().Sugar(),
+		config:  cfg,
+		tunnels: tunnel.NewManager(),
+	}
+	app.sessions = db.NewManager(app.openSession)
+	t.Cleanup(func() {
+		app.sessions.Close()
+		app.tunnels.Close()
+	})
+
+	return NewEditor(app)
+}
+
+func typeKeys(e *Editor, keys string) {
+	for _, r := range keys {
+		e.HandleEventKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+}
+
+func pressKey(e *Editor, key tcell.Key) {
+	e.HandleEventKey(tcell.NewEventKey(key, 0, tcell.ModNone))
+}
+
+var testConnections = &config.Config{
+	Connections: []config.Connection{
+		{Name: "Local", Type: "postgres", Host: "localhost"},
+		{Name: "Production", Type: "mysql", Host: "db.example.com"},
+		{Name: "Cache", Type: "redis", Host: "localhost"},
+	},
+}
+
+func TestConnectCommand(t *testing.T) {
+	e := newTestEditor(t, testConnections)
+
+	typeKeys(e, ":connect production")
+	pressKey(e, tcell.KeyEnter)
+
+	if e.Connection == nil || e.Connection.Name != "Production" {
+		t.Fatalf("got %+v, want Production", e.Connection)
+	}
+
+	typeKeys(e, ":connect nope")
+	pressKey(e, tcell.KeyEnter)
+
+	if e.Connection.Name != "Production" {
+		t.Errorf("unknown connection should keep the current one, got %s", e.Connection.Name)
+	}
+
+	if e.StatusBar.Command != "Unknown connection: nope" {
+		t.Errorf("got message %q", e.StatusBar.Command)
+	}
+
+	// The session can't be closed under a running statement.
+	e.running = true
+	typeKeys(e, ":disconnect")
+	pressKey(e, tcell.KeyEnter)
+
+	if e.Connection == nil || e.StatusBar.Command != "A statement is already running" {
+		t.Fatalf("got %q, want the disconnect refused while running", e.StatusBar.Command)
+	}
+
+	e.running = false
+	typeKeys(e, ":disconnect")
+	pressKey(e, tcell.KeyEnter)
+
+	if e.Connection != nil {
+		t.Errorf("expected buffer to be disconnected, got %+v", e.Connection)
+	}
+}
+
+func TestConnectionPicker(t *testing.T) {
+	e := newTestEditor(t, testConnections)
+
+	typeKeys(e, ":connect")
+	pressKey(e, tcell.KeyEnter)
+
+	if e.Popup == nil {
+		t.Fatal("expected the connection picker to open")
+	}
+
+	typeKeys(e, "cac")
+
+	matches := e.Popup.(*Picker).Matches()
+	if len(matches) != 1 || matches[0].Label != "Cache" {
+		t.Fatalf("got %+v, want only Cache", matches)
+	}
+
+	pressKey(e, tcell.KeyEnter)
+
+	if e.Popup != nil {
+		t.Error("expected the picker to close after selecting")
+	}
+
+	if e.Connection == nil || e.Connection.Name != "Cache" {
+		t.Errorf("got %+v, want Cache", e.Connection)
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/export"
+)
+
+func setDefaultCommands(e *Editor) {
+	// Connections
+	registerCommand(newCommand(
+		"Connect",
+		"Binds the buffer to a connection by name, or opens the connection picker",
+		"connect",
+		func(ctx context.Context, e *Editor) {
+			name := CommandArgs(ctx)
+			if name == "" {
+				e.OpenConnectionPicker()
+				return
+			}
+
+			c, ok := e.app.config.Connection(name)
+			if !ok {
+				e.fail("Unknown connection: %s", name)
+				return
+			}
+
+			e.switchConnection(c)
+		},
+	).withUsage("connect [name]"))
+	registerCommand(newCommand(
+		"Disconnect",
+		"Unbinds the buffer from its connection",
+		"disconnect",
+		func(_ context.Context, e *Editor) {
+			if e.Connection == nil {
+				e.notify("Not connected")
+				return
+			}
+
+			name := e.Connection.Name
+			e.confirmEndTransaction("disconnect", func() {
+				if e.Connect(nil) {
+					e.notify("Disconnected from %s", name)
+				}
+			})
+		},
+	))
+	registerCommand(newCommand(
+		"Quit",
+		"Exits, asking first if a transaction is open unless run as :quit!",
+		"quit",
+		func(ctx context.Context, e *Editor) {
+			e.Quit(CommandBang(ctx))
+		},
+	).withUsage("quit[!]"))
+	registerCommand(newCommand(
+		"Quit Short",
+		"Same as :quit",
+		"q",
+		func(ctx context.Context, e *Editor) {
+			e.Quit(CommandBang(ctx))
+		},
+	).withUsage("q[!]"))
+
+	// Execution
+	registerCommand(newCommand(
+		"Run",
+		"Executes the statement under the cursor on the buffer's connection",
+		"run",
+		func(_ context.Context, e *Editor) {
+			stmt, ok := e.StatementUnderCursor()
+			if !ok {
+				e.notify("No statement under cursor")
+				return
+			}
+
+			e.RunStatement(stmt)
+		},
+	))
+	registerCommand(newCommand(
+		"Script",
+		"Runs the buffer, lines first,last or the visual selection statement by statement, script! keeps going past errors",
+		"script",
+		func(ctx context.Context, e *Editor) {
+			first, last, err := e.scriptRange(CommandArgs(ctx))
+			if err != nil {
+				e.fail("%s", err)
+				return
+			}
+
+			e.RunScript(first, last, CommandBang(ctx))
+		},
+	).withUsage("script[!] [first,last|%]"))
+	registerCommand(newCommand(
+		"Report",
+		"Shows the report of the last script run",
+		"report",
+		func(_ context.Context, e *Editor) {
+			e.OpenScriptReport()
+		},
+	))
+	registerCommand(newCommand(
+		"Next Error",
+		"Moves to where the next statement that failed in the last script run went wrong",
+		"cnext",
+		func(_ context.Context, e *Editor) {
+			e.NextScriptError(1)
+		},
+	))
+	registerCommand(newCommand(
+		"Previous Error",
+		"Moves to where the previous statement that failed in the last script run went wrong",
+		"cprev",
+		func(_ context.Context, e *Editor) {
+			e.NextScriptError(-1)
+		},
+	))
+	registerCommand(newCommand(
+		"Explain",
+		"Shows the plan of the statement under the cursor, explain analyze runs it for actual figures",
+		"explain",
+		func(ctx context.Context, e *Editor) {
+			switch strings.ToLower(CommandArgs(ctx)) {
+			case "":
+				e.Explain(false)
+			case "analyze":
+				e.Explain(true)
+			default:
+				e.fail("Usage: explain [analyze]")
+			}
+		},
+	).withUsage("explain [analyze]"))
+	registerCommand(newCommand(
+		"Cancel",
+		"Cancels the running statement",
+		"cancel",
+		func(_ context.Context, e *Editor) {
+			e.CancelStatement()
+		},
+	))
+	registerCommand(newCommand(
+		"Fetch all",
+		"Fetches the rest of the result, up to results.max_rows",
+		"fetchall",
+		func(_ context.Context, e *Editor) {
+			e.FetchAll()
+		},
+	))
+	registerCommand(newCommand(
+		"Apply",
+		"Reviews the statements writing the edits of the results back, and runs them in one transaction",
+		"apply",
+		func(_ context.Context, e *Editor) {
+			e.ApplyEdits()
+		},
+	))
+	registerCommand(newCommand(
+		"Discard",
+		"Drops the edits made to the results",
+		"discard",
+		func(_ context.Context, e *Editor) {
+			e.DiscardEdits()
+		},
+	))
+	registerCommand(newCommand(
+		"Export",
+		"Writes the result to a file: export[!] csv|tsv|json|ndjson|markdown|sql <path> [table]",
+		"export",
+		func(ctx context.Context, e *Editor) {
+			args := strings.Fields(CommandArgs(ctx))
+			if len(args) < 2 || len(args) > 3 {
+				e.fail("Usage: export[!] <format> <path> [table]")
+				return
+			}
+
+			format, ok := export.ParseFormat(args[0])
+			if !ok {
+				e.fail("Unknown format %q, use csv, tsv, json, ndjson, markdown or sql", args[0])
+				return
+			}
+
+			table := ""
+			if len(args) == 3 {
+				table = args[2]
+			}
+			if format == export.SQL && table == "" {
+				e.fail("Usage: export[!] sql <path> <table>")
+				return
+			}
+
+			e.Export(format, args[1], table, CommandBang(ctx))
+		},
+	).withUsage("export[!] <format> <path> [table]"))
+	registerCommand(newCommand(
+		"History",
+		"Lists the statements run to paste one into the buffer or run it again",
+		"history",
+		func(_ context.Context, e *Editor) {
+			e.OpenHistoryPicker()
+		},
+	))
+	registerCommand(newCommand(
+		"Help",
+		"Lists the keys, commands and config options, only those mentioning the topic when one is given",
+		"help",
+		func(ctx context.Context, e *Editor) {
+			e.OpenHelp(CommandArgs(ctx))
+		},
+	).withUsage("help [topic]"))
+	registerCommand(newCommand(
+		"Messages",
+		"Lists the messages shown this session, errors and notices included",
+		"messages",
+		func(_ context.Context, e *Editor) {
+			e.OpenMessages()
+		},
+	))
+	registerCommand(newCommand(
+		"Set",
+		"Sets a variable bound to the placeholders of that name without asking: set var name=value, set var name shows it, set var lists them",
+		"set",
+		func(ctx context.Context, e *Editor) {
+			kind, spec, _ := strings.Cut(CommandArgs(ctx), " ")
+			if kind != "var" {
+				e.fail("Usage: set var [name[=value]]")
+				return
+			}
+
+			e.SetVariable(strings.TrimSpace(spec))
+		},
+	).withUsage("set var [name[=value]]"))
+	registerCommand(newCommand(
+		"Unset",
+		"Removes a variable set with set var, its placeholders are asked for again",
+		"unset",
+		func(ctx context.Context, e *Editor) {
+			args := strings.Fields(CommandArgs(ctx))
+			if len(args) != 2 || args[0] != "var" {
+				e.fail("Usage: unset var <name>")
+				return
+			}
+
+			e.UnsetVariable(args[1])
+		},
+	).withUsage("unset var <name>"))
+
+	// Results view
+	registerCommand(newCommand(
+		"Sort",
+		"Sorts the results by columns without running the statement again: sort col [asc|desc], ...",
+		"sort",
+		func(ctx context.Context, e *Editor) {
+			e.changeView(e.Results.SortBy(CommandArgs(ctx)))
+		},
+	).withUsage("sort [col [asc|desc], ...]"))
+	registerCommand(newCommand(
+		"Filter",
+		"Shows the rows of the results matching an expression, e.g. filter status = 'failed' and amount > 100",
+		"filter",
+		func(ctx context.Context, e *Editor) {
+			e.changeView(e.Results.FilterBy(CommandArgs(ctx)))
+		},
+	).withUsage("filter [expression]"))
+	registerCommand(newCommand(
+		"Hide",
+		"Hides columns of the results, the selected one without argument",
+		"hide",
+		func(ctx context.Context, e *Editor) {
+			e.changeView(e.Results.HideColumns(columnNames(CommandArgs(ctx))))
+		},
+	).withUsage("hide [col ...]"))
+	registerCommand(newCommand(
+		"Show",
+		"Shows hidden columns of the results again, all of them without argument",
+		"show",
+		func(ctx context.Context, e *Editor) {
+			e.changeView(e.Results.ShowColumns(columnNames(CommandArgs(ctx))))
+		},
+	).withUsage("show [col ...]"))
+	registerCommand(newCommand(
+		"Pin",
+		"Keeps columns of the results in view as they scroll sideways, the selected one without argument",
+		"pin",
+		func(ctx context.Context, e *Editor) {
+			e.changeView(e.Results.PinColumns(columnNames(CommandArgs(ctx))))
+		},
+	).withUsage("pin [col ...]"))
+	registerCommand(newCommand(
+		"Unpin",
+		"Lets pinned columns of the results scroll again, all of them without argument",
+		"unpin",
+		func(ctx context.Context, e *Editor) {
+			e.changeView(e.Results.UnpinColumns(columnNames(CommandArgs(ctx))))
+		},
+	).withUsage("unpin [col ...]"))
+	registerCommand(newCommand(
+		"Tab Pin",
+		"Keeps the result set shown in its tab when the next statement runs, or lets it go again",
+		"tabpin",
+		func(_ context.Context, e *Editor) {
+			e.PinResultTab()
+		},
+	))
+	registerCommand(newCommand(
+		"Tab Close",
+		"Closes the tab of the result set shown",
+		"tabclose",
+		func(_ context.Context, e *Editor) {
+			e.CloseResultTab()
+		},
+	))
+
+	// Transactions
+	registerCommand(newCommand(
+		"Begin",
+		"Opens a transaction on the buffer's session",
+		"begin",
+		func(_ context.Context, e *Editor) {
+			e.Begin()
+		},
+	))
+	registerCommand(newCommand(
+		"Commit",
+		"Commits the open transaction",
+		"commit",
+		func(_ context.Context, e *Editor) {
+			e.Commit()
+		},
+	))
+	registerCommand(newCommand(
+		"Rollback",
+		"Rolls back the open transaction, or to the savepoint given",
+		"rollback",
+		func(ctx context.Context, e *Editor) {
+			e.Rollback(CommandArgs(ctx))
+		},
+	).withUsage("rollback [savepoint]"))
+	registerCommand(newCommand(
+		"Savepoint",
+		"Sets a savepoint in the open transaction, named sp1, sp2... by default",
+		"savepoint",
+		func(ctx context.Context, e *Editor) {
+			e.Savepoint(CommandArgs(ctx))
+		},
+	).withUsage("savepoint [name]"))
+	registerCommand(newCommand(
+		"Savepoints",
+		"Lists the savepoints of the open transaction to roll back to one",
+		"savepoints",
+		func(_ context.Context, e *Editor) {
+			e.OpenSavepointPicker()
+		},
+	))
+	registerCommand(newCommand(
+		"Autocommit",
+		"Turns autocommit on or off for the buffer, toggles it without argument",
+		"autocommit",
+		func(ctx context.Context, e *Editor) {
+			switch strings.ToLower(CommandArgs(ctx)) {
+			case "":
+				e.SetAutocommit(!e.Autocommit)
+			case "on":
+				e.SetAutocommit(true)
+			case "off":
+				e.SetAutocommit(false)
+			default:
+				e.fail("Usage: autocommit [on|off]")
+			}
+		},
+	).withUsage("autocommit [on|off]"))
+
+	// Redis
+	registerCommand(newCommand(
+		"Keys",
+		"Browses the keys of the Redis connection matching a pattern, * by default",
+		"keys",
+		func(ctx context.Context, e *Editor) {
+			e.OpenKeyBrowser(CommandArgs(ctx))
+		},
+	).withUsage("keys [pattern]"))
+}
+
+// OpenConnectionPicker lists every configured connection in a popup and
+// connects the buffer to the chosen one.
+func (e *Editor) OpenConnectionPicker() {
+	if len(e.app.config.Connections) == 0 {
+		e.notify("No connections configured")
+		return
+	}
+
+	items := make([]PickerItem, len(e.app.config.Connections))
+	for i := range e.app.config.Connections {
+		c := &e.app.config.Connections[i]
+		where := c.Host
+		if c.IsFile() {
+			where = c.Path
+		}
+
+		items[i] = PickerItem{
+			Label:  c.Name,
+			Detail: fmt.Sprintf("%s %s", c.Type, where),
+			Value:  c,
+		}
+	}
+
+	e.OpenPopup(NewPicker(e, "Connections", items, func(item PickerItem) {
+		e.switchConnection(item.Value.(*config.Connection))
+	}))
+}
+
+// switchConnection connects the buffer to c, asking first if that gives up
+// an open transaction.
+func (e *Editor) switchConnection(c *config.Connection) {
+	connect := func() {
+		if e.Connect(c) {
+			e.notify("Connected to %s", c.Name)
+		}
+	}
+
+	if c == e.Connection {
+		connect()
+		return
+	}
+
+	e.confirmEndTransaction("switch to "+c.Name, connect)
+}
@@ -1,6 +1,10 @@
 package main
 
-import "context"
+import (
+	"context"
+
+	"github.com/ajm113/dbvi/query"
+)
 
 func setDefaultHotkeys(e *Editor) {
 	// Insert hotkeys
@@ -125,4 +129,261 @@ func setDefaultHotkeys(e *Editor) {
 			e.SetCursor(0, len(e.Lines)-1)
 		},
 	))
+
+	// execution
+	registerHotkeyCommand(newHotkeyCommand(
+		"Run Redis Command",
+		"Runs the command on the cursor's line of a buffer bound to a Redis connection",
+		[]EditorMode{NormalMode},
+		[]string{"Enter"},
+		func(_ context.Context, e *Editor) {
+			if e.Dialect() != query.Redis {
+				return
+			}
+
+			stmt, ok := e.StatementUnderCursor()
+			if !ok {
+				e.notify("No command on this line")
+				return
+			}
+
+			e.RunStatement(stmt)
+		},
+	))
+
+	// results
+	registerHotkeyCommand(newHotkeyCommand(
+		"Focus Results",
+		"Moves focus to the results pane, or back to the editor",
+		[]EditorMode{NormalMode, ResultsMode},
+		[]string{"Ctrl+w"},
+		func(_ context.Context, e *Editor) {
+			if e.EditorMode == ResultsMode {
+				e.SetEditorMode(NormalMode)
+				return
+			}
+
+			if !e.Results.Visible() {
+				e.notify("No results")
+				return
+			}
+
+			e.SetEditorMode(ResultsMode)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Next Result Row",
+		"Selects the next row of the results",
+		[]EditorMode{ResultsMode},
+		[]string{"j"},
+		func(_ context.Context, e *Editor) {
+			e.Results.MoveSelection(1, 0)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Previous Result Row",
+		"Selects the previous row of the results",
+		[]EditorMode{ResultsMode},
+		[]string{"k"},
+		func(_ context.Context, e *Editor) {
+			e.Results.MoveSelection(-1, 0)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Previous Result Column",
+		"Selects the column left of the current one",
+		[]EditorMode{ResultsMode},
+		[]string{"h"},
+		func(_ context.Context, e *Editor) {
+			e.Results.MoveSelection(0, -1)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Next Result Column",
+		"Selects the column right of the current one",
+		[]EditorMode{ResultsMode},
+		[]string{"l"},
+		func(_ context.Context, e *Editor) {
+			e.Results.MoveSelection(0, 1)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"First Result Column",
+		"Selects the first column of the results",
+		[]EditorMode{ResultsMode},
+		[]string{"0"},
+		func(_ context.Context, e *Editor) {
+			e.Results.SetSelection(e.Results.Row, 0)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Last Result Column",
+		"Selects the last column of the results",
+		[]EditorMode{ResultsMode},
+		[]string{"$"},
+		func(_ context.Context, e *Editor) {
+			e.Results.SetSelection(e.Results.Row, e.Results.ColumnCount()-1)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Last Result Row",
+		"Selects the last row of the results",
+		[]EditorMode{ResultsMode},
+		[]string{"G"},
+		func(_ context.Context, e *Editor) {
+			e.Results.SetSelection(e.Results.RowCount()-1, e.Results.Col)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Next Result Tab",
+		"Shows the result set of the next tab of the results pane",
+		[]EditorMode{NormalMode, ResultsMode},
+		[]string{"gt"},
+		func(_ context.Context, e *Editor) {
+			e.SwitchResultTab(1)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Previous Result Tab",
+		"Shows the result set of the previous tab of the results pane",
+		[]EditorMode{NormalMode, ResultsMode},
+		[]string{"gT"},
+		func(_ context.Context, e *Editor) {
+			e.SwitchResultTab(-1)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Toggle Record View",
+		"Shows the selected row as a list of fields, or the results as a grid again",
+		[]EditorMode{ResultsMode},
+		[]string{"x"},
+		func(_ context.Context, e *Editor) {
+			if !e.Results.ToggleRecord() {
+				e.notify("No rows to show as records")
+			}
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Toggle Record Fold",
+		"Collapses the selected JSON field of the record view to one line, or expands it",
+		[]EditorMode{ResultsMode},
+		[]string{"za"},
+		func(_ context.Context, e *Editor) {
+			if !e.Results.ToggleFold() {
+				e.notify("Only JSON fields of a record can be folded")
+			}
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Edit Result Cell",
+		"Edits the selected cell of the results, when they can be written back",
+		[]EditorMode{ResultsMode},
+		[]string{"i"},
+		func(_ context.Context, e *Editor) {
+			if e.Results.OnEdit == nil {
+				e.notify("These results can't be edited")
+				return
+			}
+
+			e.Results.OnEdit(e.Results.Selected())
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Change Result Cell",
+		"Replaces the value of the selected cell, starting from an empty one",
+		[]EditorMode{ResultsMode},
+		[]string{"cw"},
+		func(_ context.Context, e *Editor) {
+			row, col := e.Results.Selected()
+			e.EditCell(row, col, true)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Add Result Row",
+		"Adds an empty row to the results, inserted into their table once applied",
+		[]EditorMode{ResultsMode},
+		[]string{"o"},
+		func(_ context.Context, e *Editor) {
+			e.AddRow()
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Delete Result Row",
+		"Marks the selected row to be deleted from its table once applied, or keeps it again",
+		[]EditorMode{ResultsMode},
+		[]string{"dd"},
+		func(_ context.Context, e *Editor) {
+			row, _ := e.Results.Selected()
+			e.ToggleDeleteRow(row)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Sort Results",
+		"Sorts the results by the selected column, then the other way around, then as they came",
+		[]EditorMode{ResultsMode},
+		[]string{"s"},
+		func(_ context.Context, e *Editor) {
+			if !e.Results.ToggleSort() {
+				e.notify("Only rows with columns can be sorted")
+			}
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Move Result Column Left",
+		"Moves the selected column of the results one place to the left",
+		[]EditorMode{ResultsMode},
+		[]string{"<"},
+		func(_ context.Context, e *Editor) {
+			e.Results.MoveColumn(-1)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Move Result Column Right",
+		"Moves the selected column of the results one place to the right",
+		[]EditorMode{ResultsMode},
+		[]string{">"},
+		func(_ context.Context, e *Editor) {
+			e.Results.MoveColumn(1)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Next Result Match",
+		"Selects the next cell of the results matching the last / search",
+		[]EditorMode{ResultsMode},
+		[]string{"n"},
+		func(_ context.Context, e *Editor) {
+			e.SearchResults("", false)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Previous Result Match",
+		"Selects the previous cell of the results matching the last / search",
+		[]EditorMode{ResultsMode},
+		[]string{"N"},
+		func(_ context.Context, e *Editor) {
+			e.SearchResults("", true)
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Next Result Page",
+		"Loads the next range of the results, when they are paged",
+		[]EditorMode{ResultsMode},
+		[]string{"]"},
+		func(_ context.Context, e *Editor) {
+			if e.Results.OnPage != nil {
+				e.Results.OnPage(1)
+			}
+		},
+	))
+	registerHotkeyCommand(newHotkeyCommand(
+		"Previous Result Page",
+		"Loads the previous range of the results, when they are paged",
+		[]EditorMode{ResultsMode},
+		[]string{"["},
+		func(_ context.Context, e *Editor) {
+			if e.Results.OnPage != nil {
+				e.Results.OnPage(-1)
+			}
+		},
+	))
 }
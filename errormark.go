@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+// errorMark is the token of the buffer the error of the last statement run
+// points at, drawn underlined until something else runs or the buffer is
+// edited.
+type errorMark struct {
+	x, y int
+	len  int
+}
+
+// covers reports if the buffer position x, y is part of the mark.
+func (m *errorMark) covers(x, y int) bool {
+	return m != nil && y == m.y && x >= m.x && x < m.x+m.len
+}
+
+// describeError is err as shown to the user, with the SQLSTATE the server
+// sent along and its detail and hint on lines of their own.
+func describeError(err error) string {
+	text := err.Error()
+
+	var serverErr *db.ServerError
+	if !errors.As(err, &serverErr) {
+		return text
+	}
+
+	if serverErr.Code != "" && !strings.Contains(text, serverErr.Code) {
+		text += fmt.Sprintf(" (SQLSTATE %s)", serverErr.Code)
+	}
+	if serverErr.Detail != "" {
+		text += "\nDetail: " + serverErr.Detail
+	}
+	if serverErr.Hint != "" {
+		text += "\nHint: " + serverErr.Hint
+	}
+
+	return text
+}
+
+// errorOffset returns the offset in the buffer err points at, for stmt run
+// as bound. A statement that isn't from the buffer points nowhere in it.
+func (e *Editor) errorOffset(stmt query.Statement, bound boundStatement, err error) (int, bool) {
+	var serverErr *db.ServerError
+	if stmt.Detached || !errors.As(err, &serverErr) || serverErr.Offset < 0 {
+		return 0, false
+	}
+
+	offset := bound.unbind(e.Dialect(), serverErr.Offset)
+	if offset > len(stmt.Text) {
+		return 0, false
+	}
+
+	return stmt.Offset + offset, true
+}
+
+// markError moves the cursor to where err, the error of stmt run as bound,
+// points in the buffer and underlines the token there. It reports false
+// when the error doesn't point anywhere.
+func (e *Editor) markError(stmt query.Statement, bound boundStatement, err error) bool {
+	e.errorMark = nil
+
+	offset, ok := e.errorOffset(stmt, bound, err)
+	if !ok {
+		return false
+	}
+
+	x, y := e.OffsetPosition(offset)
+	e.SetCursor(x, y)
+
+	// The token starting there, or the character.
+	n := 1
+	line := e.Lines[y]
+	if tokens := query.Lex(line[min(x, len(line)):], e.Dialect()); len(tokens) > 0 && tokens[0].Offset == 0 && tokens[0].Significant() {
+		n = len(tokens[0].Text)
+	}
+	e.errorMark = &errorMark{x: x, y: y, len: n}
+
+	return true
+}
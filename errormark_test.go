@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/history"
+	"github.com/gdamore/tcell"
+)
+
+func init() {
+	db.Register("faulty", faultyDriver{})
+}
+
+// faultyDriver fails every statement with a syntax error pointing at the
+// first FORM in it.
+type faultyDriver struct{}
+
+func (faultyDriver) Open(context.Context, config.Connection, string) (db.Session, error) {
+	return faultySession{}, nil
+}
+
+type faultySession struct{}
+
+func (faultySession) Query(_ context.Context, stmt string, _ ...any) (db.Rows, error) {
+	return nil, &db.ServerError{
+		Err:     errors.New(`syntax error at or near "FORM"`),
+		Code:    "42601",
+		Message: `syntax error at or near "FORM"`,
+		Hint:    "Did you mean FROM?",
+		Offset:  strings.Index(stmt, "FORM"),
+	}
+}
+
+func (faultySession) Close() error { return nil }
+
+func TestStatementErrorPosition(t *testing.T) {
+	e := newTestEditor(t, nil)
+	e.Connect(&config.Connection{Name: "Faulty", Type: "faulty", Host: "localhost"})
+	e.vars = map[string]string{"id": "1"}
+	e.Lines = []string{
+		"SELECT 1;",
+		"SELECT :id, name",
+		"  FORM users;",
+	}
+	e.SetCursor(0, 1)
+
+	typeKeys(e, ":run")
+	pressKey(e, tcell.KeyEnter)
+	runPosted(t, e)
+
+	// The placeholder was rewritten shorter, the position still lands on
+	// the token in the buffer.
+	if e.CursorX != 2 || e.CursorY != 2 {
+		t.Errorf("got cursor at %d,%d, want it on FORM at 2,2", e.CursorX, e.CursorY)
+	}
+	if m := e.errorMark; m == nil || m.x != 2 || m.y != 2 || m.len != 4 {
+		t.Errorf("got mark %+v, want FORM underlined", m)
+	}
+
+	want := `Error: syntax error at or near "FORM" (SQLSTATE 42601)`
+	if e.StatusBar.Command != want || e.StatusBar.level != MessageError {
+		t.Errorf("got %q, want %q", e.StatusBar.Command, want)
+	}
+
+	// The hint is on a line of its own, the whole error opens in a popup.
+	view, ok := e.Popup.(*MessageView)
+	if !ok || len(view.lines) != 2 || view.lines[1] != "Hint: Did you mean FROM?" {
+		t.Fatalf("got popup %T, want the error with its hint", e.Popup)
+	}
+	typeKeys(e, "q")
+
+	typeKeys(e, "i")
+	if e.errorMark != nil {
+		t.Errorf("got the mark kept while editing")
+	}
+}
+
+func TestHistoryErrorNotMarked(t *testing.T) {
+	e := newTestEditor(t, nil)
+	e.Connect(&config.Connection{Name: "Faulty", Type: "faulty", Host: "localhost"})
+
+	store, err := history.Open(filepath.Join(t.TempDir(), history.FileName), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.app.history = store
+	if err := store.Add(history.Entry{Statement: "SELECT name FORM users", Connection: "Faulty"}); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Lines = []string{"SELECT 1;", "SELECT 2;"}
+	e.SetCursor(0, 1)
+
+	// The statement run again from the history isn't in the buffer, its
+	// error can't point into it.
+	typeKeys(e, ":history")
+	pressKey(e, tcell.KeyEnter)
+	pressKey(e, tcell.KeyCtrlR)
+	runPosted(t, e)
+
+	if e.StatusBar.level != MessageError {
+		t.Fatalf("got %q, want the error", e.StatusBar.Command)
+	}
+	if e.errorMark != nil || e.CursorX != 0 || e.CursorY != 1 {
+		t.Errorf("got mark %+v and cursor at %d,%d, want the buffer left alone", e.errorMark, e.CursorX, e.CursorY)
+	}
+}
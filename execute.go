@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+// cancelTimeout bounds how long a server side cancel may take.
+const cancelTimeout = 5 * time.Second
+
+// Dialect is the query dialect of the buffer's connection.
+func (e *Editor) Dialect() query.Dialect {
+	if e.Connection == nil {
+		return ""
+	}
+
+	return query.Dialect(e.Connection.Type)
+}
+
+// CursorOffset converts the cursor position into a byte offset within the
+// buffer's text.
+func (e *Editor) CursorOffset() int {
+	offset := 0
+	for y := 0; y < e.CursorY && y < len(e.Lines); y++ {
+		offset += len(e.Lines[y]) + 1
+	}
+
+	return offset + e.CursorX
+}
+
+// OffsetPosition converts a byte offset within the buffer's text into a
+// cursor position, the inverse of CursorOffset.
+func (e *Editor) OffsetPosition(offset int) (x, y int) {
+	for y < len(e.Lines)-1 && offset > len(e.Lines[y]) {
+		offset -= len(e.Lines[y]) + 1
+		y++
+	}
+
+	return offset, y
+}
+
+// StatementUnderCursor returns the statement the cursor is on.
+func (e *Editor) StatementUnderCursor() (query.Statement, bool) {
+	stmts := query.Split(strings.Join(e.Lines, "\n"), e.Dialect())
+	return query.StatementAt(stmts, e.CursorOffset())
+}
+
+// RunStatement checks stmt against the connection's safety settings and
+// executes it in the background. Destructive statements are only run once
+// confirmed.
+func (e *Editor) RunStatement(stmt query.Statement) {
+	c := e.Connection
+	if c == nil {
+		e.notify("Not connected, use :connect first")
+		return
+	}
+
+	if e.discardEditsFirst(func() { e.RunStatement(stmt) }) {
+		return
+	}
+
+	if c.ReadOnly {
+		if reason, refused := query.ReadOnlyViolation(stmt.Text, e.Dialect()); refused {
+			e.warn("Refused on read-only %s, line %d \"%s\": %s", c.Name, stmt.Line+1, snippet(stmt.Text, 40), reason)
+			return
+		}
+	}
+
+	e.bindPlaceholders([]query.Statement{stmt}, func(bound []boundStatement) {
+		if reason, ok := query.Destructive(stmt.Text, e.Dialect()); ok && c.ConfirmsDestructive() {
+			e.confirmDestructive(c, stmt, reason, func() {
+				e.startStatement(c, stmt, bound[0])
+			})
+			return
+		}
+
+		e.startStatement(c, stmt, bound[0])
+	})
+}
+
+// discardEditsFirst asks to drop the edits made to the results before they
+// are replaced by running something else, and calls run once they are. It
+// reports false, without calling run, when there are no edits.
+func (e *Editor) discardEditsFirst(run func()) bool {
+	n := e.pendingEdits()
+	if n == 0 {
+		return false
+	}
+
+	e.confirmYes(fmt.Sprintf("Discard %d changes to the results and run? [y/N]: ", n), func() {
+		e.edits.discard()
+		e.Results.refreshRows()
+		run()
+	})
+
+	return true
+}
+
+// confirmDestructive asks before running a destructive statement with run.
+// Production connections need the connection name typed out instead of a y.
+func (e *Editor) confirmDestructive(c *config.Connection, stmt query.Statement, reason string, run func()) {
+	what := fmt.Sprintf("%s at line %d", reason, stmt.Line+1)
+	question := fmt.Sprintf("%s on line %d of %s, run anyway? [y/N]: ", reason, stmt.Line+1, c.Name)
+	e.confirm(c, what, question, run)
+}
+
+// startStatement executes stmt in the background, showing its result in the
+// results pane and the outcome in the command line. bound is what is sent
+// for stmt.
+func (e *Editor) startStatement(c *config.Connection, stmt query.Statement, bound boundStatement) {
+	if e.running {
+		e.notify("A statement is already running")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.cancel = cancel
+	e.notice = nil
+	e.errorMark = nil
+	e.notify("Running on %s...", c.Name)
+
+	begin := ""
+	if e.needsImplicitBegin(stmt.Text) {
+		begin = beginStatement(e.Dialect())
+	}
+
+	retry := begin == "" && e.mayRetry(stmt.Text)
+	prev := e.takeStream()
+	pageSize := e.app.config.Results.Page()
+	rowCap := e.app.config.Results.RowCap()
+
+	go func() {
+		e.closeStream(prev)
+
+		var began bool
+		var err error
+		if begin != "" {
+			_, err = e.execute(ctx, c, begin, false)
+			began = err == nil
+		}
+
+		var st *db.Stream
+		var res *db.Result
+		var sets []*db.Result
+		start := time.Now()
+		if err == nil {
+			var rows [][]any
+			st, rows, err = e.openStream(ctx, c, bound.Text, retry, pageSize, bound.Args...)
+			if err == nil {
+				res = &db.Result{Columns: st.Columns(), Rows: rows}
+			}
+		}
+		if err == nil && st.Done() {
+			// The stream describes the first set only until it moves on
+			// to the next.
+			res.Tag, res.RowsAffected = st.Tag(), st.RowsAffected()
+			sets, err = moreResultSets(st, rowCap)
+		}
+		elapsed := time.Since(start)
+
+		e.app.post(func() {
+			e.running = false
+			e.cancel = nil
+
+			if began {
+				e.trackTransaction(begin)
+			}
+
+			if err != nil {
+				cancel()
+				e.recordStatement(c, stmt.Text, elapsed, 0, err)
+				e.markError(stmt, bound, err)
+				e.statementFailed(c, err)
+				return
+			}
+
+			label := snippet(stmt.Text, 30)
+			if st.Done() {
+				cancel()
+			} else {
+				// The statement's context lives as long as its rows.
+				res.More = true
+				e.stream = &resultStream{Stream: st, cancel: cancel, result: res, label: label}
+			}
+
+			count := int64(len(res.Rows))
+			if len(res.Columns) == 0 {
+				count = res.RowsAffected
+			}
+			e.recordStatement(c, stmt.Text, elapsed, count, nil)
+
+			e.trackTransaction(stmt.Text)
+			e.Results.SetResult(label, res, elapsed)
+			if res.More {
+				e.Results.OnMore = e.FetchMore
+			}
+
+			// Rows can be edited if they turn out to come from a table
+			// with a primary key, which is looked up on the first edit.
+			e.edits = nil
+			if len(res.Columns) > 0 && e.Dialect() != query.Redis {
+				e.edits = &tableEdits{result: res, stmt: stmt.Text}
+				e.Results.OnEdit = func(row, col int) {
+					e.EditCell(row, col, false)
+				}
+			}
+
+			summary := e.addResultSets(label, sets, elapsed)
+			if e.notice != nil {
+				e.notify("%s  %s", summary, e.notice)
+				return
+			}
+
+			e.notify("%s", summary)
+		})
+	}()
+}
+
+// CancelStatement asks the server to stop the running statement. Sessions
+// that can't cancel server side get their context cancelled instead.
+func (e *Editor) CancelStatement() {
+	if !e.running {
+		e.notify("No statement running")
+		return
+	}
+
+	// Scripts stop after the running statement.
+	if e.script != nil && e.script.running {
+		e.script.stopped = true
+	}
+
+	// Fetches stop between pages.
+	if e.fetching {
+		e.cancel()
+		e.notify("Cancelled")
+		return
+	}
+
+	e.sessionMu.Lock()
+	s := e.session
+	e.sessionMu.Unlock()
+
+	canceler, ok := s.(db.Canceler)
+	if !ok {
+		e.cancel()
+		e.notify("Cancelled")
+		return
+	}
+
+	e.notify("Cancelling...")
+	cancelStatement := e.cancel
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+		defer cancel()
+
+		if err := canceler.Cancel(ctx); err != nil {
+			e.app.log.Errorf("failed cancelling statement: %v", err)
+			cancelStatement()
+		}
+	}()
+}
+
+// execute runs stmt on the buffer's session and fetches its whole result.
+// retry is passed on to onSession.
+func (e *Editor) execute(ctx context.Context, c *config.Connection, stmt string, retry bool) (*db.Result, error) {
+	var res *db.Result
+	err := e.onSession(ctx, c, retry, func(s db.Session) (err error) {
+		res, err = db.Exec(ctx, s, stmt)
+		return err
+	})
+
+	return res, err
+}
+
+// openStream runs stmt with the bind parameters args on the buffer's session
+// and fetches the first page of its result. retry is passed on to onSession.
+func (e *Editor) openStream(ctx context.Context, c *config.Connection, stmt string, retry bool, pageSize int, args ...any) (st *db.Stream, rows [][]any, err error) {
+	err = e.onSession(ctx, c, retry, func(s db.Session) error {
+		st, err = db.OpenStream(ctx, s, stmt, pageSize, args...)
+		if err != nil {
+			return err
+		}
+
+		rows, err = st.Fetch(pageSize)
+		return err
+	})
+
+	return st, rows, err
+}
+
+// onSession calls run with the buffer's session. When the connection turns
+// out to be broken the session is dropped, and run is called once more on a
+// new one if retry is set. Otherwise it fails with db.ErrBroken, a
+// transaction open on the session is lost with it.
+func (e *Editor) onSession(ctx context.Context, c *config.Connection, retry bool, run func(db.Session) error) error {
+	s, err := e.sessionFor(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	err = run(s)
+	if err == nil || !sessionBroken(s, err) {
+		return err
+	}
+
+	e.dropSession(s)
+
+	if !retry || ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", db.ErrBroken, err)
+	}
+
+	e.app.log.Infof("reconnecting to %s: %v", c.Name, err)
+	if s, err = e.sessionFor(ctx, c); err != nil {
+		return fmt.Errorf("%w: %w", db.ErrBroken, err)
+	}
+
+	e.app.post(func() {
+		e.notice = &db.Notice{Message: "Reconnected to " + c.Name}
+		e.notify("Reconnected to %s", c.Name)
+	})
+
+	return run(s)
+}
+
+// mayRetry reports if stmt can be run again on a new session when the
+// connection breaks under it, which only a read outside a transaction can.
+// It reads the transaction state, so it is called on the UI thread and the
+// answer handed to onSession.
+func (e *Editor) mayRetry(stmt string) bool {
+	return e.txn == nil && query.Classify(stmt, e.Dialect()).Kind == query.KindRead
+}
+
+// sessionBroken tells a statement that failed from a session whose
+// connection is gone.
+func sessionBroken(s db.Session, err error) bool {
+	return db.IsBroken(err) || !db.Alive(context.Background(), s)
+}
+
+// sessionFor returns the buffer's session, acquiring one from the pool when
+// it holds none. The buffer keeps it until it disconnects, so what a
+// statement leaves on the session, like SET or USE, temporary tables or a
+// SQLite :memory: database, is there for the next one.
+func (e *Editor) sessionFor(ctx context.Context, c *config.Connection) (db.Session, error) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+
+	if e.session != nil {
+		return e.session, nil
+	}
+
+	s, err := e.app.sessions.Acquire(ctx, *c)
+	if err != nil {
+		return nil, err
+	}
+
+	if r, ok := s.(db.NoticeReporter); ok {
+		r.OnNotice(func(n db.Notice) {
+			e.app.post(func() {
+				e.notice = &n
+				e.message(noticeLevel(n), "%s", n)
+			})
+		})
+	}
+
+	e.session = s
+	return s, nil
+}
+
+// dropSession closes s, a session whose connection broke, so the next
+// statement gets a new one.
+func (e *Editor) dropSession(s db.Session) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+
+	e.app.sessions.Discard(s)
+	if e.session == s {
+		e.session = nil
+	}
+}
+
+// closeSession closes the buffer's session, if it has one, rolling back a
+// transaction left open on it. It isn't handed back to the pool, where the
+// next buffer would find the settings and temporary tables left on it.
+func (e *Editor) closeSession() {
+	e.closeStream(e.takeStream())
+
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+
+	if e.session == nil {
+		return
+	}
+
+	e.app.sessions.Discard(e.session)
+	e.session = nil
+}
+
+// snippet shortens a statement to its first line, clipped to n characters.
+func snippet(stmt string, n int) string {
+	line, _, multiline := strings.Cut(stmt, "\n")
+
+	runes := []rune(line)
+	if len(runes) > n {
+		return string(runes[:n-3]) + "..."
+	}
+
+	if multiline {
+		return line + " ..."
+	}
+
+	return line
+}
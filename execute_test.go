@@ -0,0 +1,163 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/gdamore/tcell"
+)
+
+// runPosted runs the next function posted by background work, like the
+// event loop in App.Run does.
+func runPosted(t *testing.T, e *Editor) {
+	t.Helper()
+
+	events := make(chan tcell.Event, 1)
+	go func() {
+		events <- e.screen.PollEvent()
+	}()
+
+	select {
+	case ev := <-events:
+		interrupt, ok := ev.(*tcell.EventInterrupt)
+		if !ok {
+			t.Fatalf("expected a posted function, got %T", ev)
+		}
+		interrupt.Data().(func())()
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for background work")
+	}
+}
+
+func TestRunStatementReadOnly(t *testing.T) {
+	tests := []struct {
+		connection config.Connection
+		lines      []string
+		want       string
+	}{
+		{
+			connection: config.Connection{Name: "Replica", Type: "postgres", Host: "localhost", ReadOnly: true},
+			lines:      []string{"SELECT 1;", "DELETE FROM orders;"},
+			want:       `Refused on read-only Replica, line 2 "DELETE FROM orders": DELETE modifies data`,
+		},
+		{
+			connection: config.Connection{Name: "Cache", Type: "redis", Host: "localhost", ReadOnly: true},
+			lines:      []string{"GET a", "FLUSHDB"},
+			want:       `Refused on read-only Cache, line 2 "FLUSHDB": FLUSHDB modifies data`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.connection.Name, func(t *testing.T) {
+			e := newTestEditor(t, nil)
+			e.Connect(&tt.connection)
+			e.Lines = tt.lines
+			e.SetCursor(0, 1)
+
+			typeKeys(e, ":run")
+			pressKey(e, tcell.KeyEnter)
+
+			if e.StatusBar.Command != tt.want {
+				t.Errorf("got %q, want %q", e.StatusBar.Command, tt.want)
+			}
+
+			if e.running {
+				t.Error("refused statement shouldn't be running")
+			}
+		})
+	}
+}
+
+func TestRunStatementNoDriver(t *testing.T) {
+	e := newTestEditor(t, nil)
+	e.Connect(&config.Connection{Name: "Mongo", Type: "mongo", Host: "localhost"})
+	e.Lines = []string{"SELECT 1"}
+
+	typeKeys(e, ":run")
+	pressKey(e, tcell.KeyEnter)
+	runPosted(t, e)
+
+	if !strings.Contains(e.StatusBar.Command, "no driver registered for mongo") {
+		t.Errorf("got %q", e.StatusBar.Command)
+	}
+}
+
+func TestRunStatementDestructive(t *testing.T) {
+	off := false
+
+	tests := []struct {
+		name        string
+		connection  config.Connection
+		answer      string
+		wantPrompt  string
+		wantRunning bool
+	}{
+		{
+			name:        "confirmed",
+			connection:  config.Connection{Name: "Local", Type: "postgres", Host: "localhost"},
+			answer:      "y",
+			wantPrompt:  "DELETE without WHERE on line 1 of Local, run anyway? [y/N]: ",
+			wantRunning: true,
+		},
+		{
+			name:        "declined",
+			connection:  config.Connection{Name: "Local", Type: "postgres", Host: "localhost"},
+			answer:      "",
+			wantPrompt:  "DELETE without WHERE on line 1 of Local, run anyway? [y/N]: ",
+			wantRunning: false,
+		},
+		{
+			name:        "production confirmed",
+			connection:  config.Connection{Name: "Orders", Type: "postgres", Host: "db", Tags: []string{"production"}},
+			answer:      "Orders",
+			wantPrompt:  "DELETE without WHERE at line 1 on production Orders, type the connection name to run: ",
+			wantRunning: true,
+		},
+		{
+			name:        "production y isn't enough",
+			connection:  config.Connection{Name: "Orders", Type: "postgres", Host: "db", Tags: []string{"production"}},
+			answer:      "y",
+			wantPrompt:  "DELETE without WHERE at line 1 on production Orders, type the connection name to run: ",
+			wantRunning: false,
+		},
+		{
+			name:        "confirmation disabled",
+			connection:  config.Connection{Name: "Scratch", Type: "postgres", Host: "localhost", ConfirmDestructive: &off},
+			wantRunning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditor(t, nil)
+			e.Connect(&tt.connection)
+			e.Lines = []string{"DELETE FROM orders"}
+
+			typeKeys(e, ":run")
+			pressKey(e, tcell.KeyEnter)
+
+			if tt.wantPrompt != "" {
+				if e.StatusBar.Command != tt.wantPrompt {
+					t.Fatalf("got prompt %q, want %q", e.StatusBar.Command, tt.wantPrompt)
+				}
+
+				if e.running {
+					t.Fatal("statement started before it was confirmed")
+				}
+
+				typeKeys(e, tt.answer)
+				pressKey(e, tcell.KeyEnter)
+			}
+
+			if e.running != tt.wantRunning {
+				t.Errorf("got running=%v, want %v (%q)", e.running, tt.wantRunning, e.StatusBar.Command)
+			}
+
+			if e.running {
+				runPosted(t, e)
+			}
+		})
+	}
+}
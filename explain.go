@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/plan"
+	"github.com/ajm113/dbvi/query"
+)
+
+// Explain shows the plan of the statement under the cursor. Analyzing runs
+// the statement, so it goes through the same checks as running it.
+func (e *Editor) Explain(analyze bool) {
+	c := e.Connection
+	if c == nil {
+		e.notify("Not connected, use :connect first")
+		return
+	}
+
+	stmt, ok := e.StatementUnderCursor()
+	if !ok {
+		e.notify("No statement under cursor")
+		return
+	}
+
+	text, err := plan.Statement(e.Dialect(), stmt.Text, analyze)
+	if err != nil {
+		e.fail("%s", err)
+		return
+	}
+
+	if !analyze {
+		e.startExplain(c, stmt, text, false)
+		return
+	}
+
+	if c.ReadOnly {
+		if reason, refused := query.ReadOnlyViolation(stmt.Text, e.Dialect()); refused {
+			e.warn("Refused on read-only %s, line %d \"%s\": %s", c.Name, stmt.Line+1, snippet(stmt.Text, 40), reason)
+			return
+		}
+	}
+
+	if reason, ok := query.Destructive(stmt.Text, e.Dialect()); ok && c.ConfirmsDestructive() {
+		e.confirmDestructive(c, stmt, reason+", EXPLAIN ANALYZE runs it", func() {
+			e.startExplain(c, stmt, text, true)
+		})
+		return
+	}
+
+	e.startExplain(c, stmt, text, true)
+}
+
+// startExplain runs text, the EXPLAIN of stmt, in the background and opens
+// the plan it returns.
+func (e *Editor) startExplain(c *config.Connection, stmt query.Statement, text string, analyze bool) {
+	if e.running {
+		e.notify("A statement is already running")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.cancel = cancel
+	e.notify("Explaining on %s...", c.Name)
+
+	retry := e.mayRetry(text)
+	prev := e.takeStream()
+
+	go func() {
+		defer cancel()
+
+		e.closeStream(prev)
+		res, err := e.execute(ctx, c, text, retry)
+
+		e.app.post(func() {
+			e.running = false
+			e.cancel = nil
+
+			if err != nil {
+				e.statementFailed(c, err)
+				return
+			}
+
+			p, err := plan.Parse(e.Dialect(), res, analyze)
+			if err != nil {
+				e.fail("Error: %s", err)
+				return
+			}
+
+			title := fmt.Sprintf("EXPLAIN line %d", stmt.Line+1)
+			if analyze {
+				title = fmt.Sprintf("EXPLAIN ANALYZE line %d", stmt.Line+1)
+			}
+
+			view := NewPlanView(e, title, p)
+			e.OpenPopup(view)
+			e.notify("%s %s", title, view.summary())
+		})
+	}()
+}
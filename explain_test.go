@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/plan"
+	"github.com/gdamore/tcell"
+)
+
+func TestExplain(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	runLine(t, e, "CREATE INDEX t_n ON t (n)")
+
+	e.Lines = []string{"SELECT * FROM t WHERE n = 1"}
+	runCommand(t, e, "explain")
+
+	v, ok := e.Popup.(*PlanView)
+	if !ok {
+		t.Fatalf("got popup %T and %q, want the plan", e.Popup, e.StatusBar.Command)
+	}
+	if label := v.Plan.Root.Label; !strings.Contains(label, "t_n") {
+		t.Errorf("got plan %q, want it to use the index", label)
+	}
+
+	e.screen.Clear()
+	e.Draw()
+	found := false
+	_, h := e.screen.Size()
+	for y := 0; y < h; y++ {
+		found = found || strings.Contains(screenRow(e, y), "SEARCH t USING COVERING INDEX t_n")
+	}
+	if !found {
+		t.Error("plan isn't drawn")
+	}
+
+	typeKeys(e, "q")
+	typeKeys(e, ":explain analyze")
+	pressKey(e, tcell.KeyEnter)
+	if want := "EXPLAIN ANALYZE isn't available for this connection type"; e.StatusBar.Command != want {
+		t.Errorf("got %q, want %q", e.StatusBar.Command, want)
+	}
+}
+
+func TestPlanView(t *testing.T) {
+	e := newTestEditor(t, nil)
+
+	scan := &plan.Node{Label: "Seq Scan on orders", Analyzed: true, Time: 9, Details: []string{"Filter: (amount > 100)"}}
+	index := &plan.Node{Label: "Index Scan on users", Analyzed: true, Time: 0.5}
+	root := &plan.Node{Label: "Hash Join", Analyzed: true, Time: 10, Children: []*plan.Node{scan, index}}
+	v := NewPlanView(e, "EXPLAIN ANALYZE line 1", &plan.Plan{Root: root, Analyzed: true})
+	e.OpenPopup(v)
+
+	if v.nodeStyle(scan) != v.hot || v.nodeStyle(index) != v.style || v.nodeStyle(root) != v.style {
+		t.Error("only the scan should be highlighted as expensive")
+	}
+
+	if len(v.lines) != 4 {
+		t.Fatalf("got %d lines, want the nodes and the filter", len(v.lines))
+	}
+
+	// Folding the root hides the rest of the tree.
+	pressKey(e, tcell.KeyEnter)
+	if len(v.lines) != 1 {
+		t.Errorf("got %d lines with the root folded, want 1", len(v.lines))
+	}
+	typeKeys(e, "l")
+	typeKeys(e, "jj")
+	if line := v.lines[v.selected]; line.detail != "Filter: (amount > 100)" {
+		t.Errorf("got line %+v selected, want the filter", line)
+	}
+
+	// Folding from a detail folds its node.
+	typeKeys(e, " ")
+	if len(v.lines) != 3 || v.lines[v.selected].node != scan {
+		t.Errorf("got %d lines and %q selected", len(v.lines), v.lines[v.selected].node.Label)
+	}
+}
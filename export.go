@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/export"
+)
+
+// progressInterval is how often a running export reports how far it got.
+const progressInterval = 250 * time.Millisecond
+
+// Export writes the result in the results pane to path in the background.
+// Rows a streamed result has left are read from the server as they are
+// written rather than kept. An existing file is only overwritten with force.
+func (e *Editor) Export(format export.Format, path, table string, force bool) {
+	res := e.Results.Result
+	if res == nil || len(res.Columns) == 0 {
+		e.notify("No result to export")
+		return
+	}
+
+	if _, ok := res.Reply(); ok {
+		e.notify("Replies can't be exported, only table results")
+		return
+	}
+
+	if e.running {
+		e.notify("A statement is already running")
+		return
+	}
+
+	path = expandPath(path)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		e.notify("%s exists, use :export! to overwrite it", path)
+		return
+	}
+	if err != nil {
+		e.fail("Error: %s", err)
+		return
+	}
+
+	w, err := export.NewWriter(format, file, res.Columns, export.Options{Table: table, Dialect: e.Dialect()})
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		e.fail("Error: %s", err)
+		return
+	}
+
+	rows, st := res.Rows, e.takeStream()
+	pageSize := e.app.config.Results.Page()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.fetching = true
+	e.cancel = cancel
+	e.notify("Exporting to %s...", path)
+
+	go func() {
+		defer cancel()
+
+		n, err := e.writeRows(ctx, w, rows, st, pageSize, path)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			os.Remove(path)
+		}
+
+		e.closeStream(st)
+
+		e.app.post(func() {
+			e.running = false
+			e.fetching = false
+			e.cancel = nil
+
+			switch {
+			case errors.Is(err, context.Canceled):
+				e.notify("Export cancelled")
+			case err != nil:
+				e.fail("Error: %s", err)
+			default:
+				e.notify("Exported %d rows to %s", n, path)
+			}
+		})
+	}()
+}
+
+// writeRows writes rows, then what st has left, reporting progress as it
+// goes. It stops when ctx is cancelled.
+func (e *Editor) writeRows(ctx context.Context, w export.Writer, rows [][]any, st *resultStream, pageSize int, path string) (int, error) {
+	n := 0
+	last := time.Now()
+
+	write := func(rows [][]any) error {
+		for _, row := range rows {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := w.WriteRow(row); err != nil {
+				return err
+			}
+			n++
+
+			if time.Since(last) >= progressInterval {
+				last = time.Now()
+				written := n
+				e.app.post(func() {
+					e.notify("Exporting to %s... %d rows", path, written)
+				})
+			}
+		}
+
+		return nil
+	}
+
+	if err := write(rows); err != nil {
+		return n, err
+	}
+
+	for st != nil && !st.Done() {
+		page, err := st.Fetch(pageSize)
+		if err != nil {
+			return n, err
+		}
+
+		if err := write(page); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// expandPath expands a leading ~ to the home directory.
+func expandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
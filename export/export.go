@@ -0,0 +1,335 @@
+// Package export writes result sets to files in the formats other tools
+// read: CSV, TSV, JSON, NDJSON, Markdown and SQL INSERT statements.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+type Format string
+
+const (
+	CSV      Format = "csv"
+	TSV      Format = "tsv"
+	JSON     Format = "json"
+	NDJSON   Format = "ndjson"
+	Markdown Format = "markdown"
+	SQL      Format = "sql"
+)
+
+// Formats lists the formats in the order they are offered.
+var Formats = []Format{CSV, TSV, JSON, NDJSON, Markdown, SQL}
+
+var aliases = map[string]Format{
+	"jsonl":  NDJSON,
+	"md":     Markdown,
+	"insert": SQL,
+}
+
+var ErrNoTable = errors.New("the sql format needs a target table")
+
+// ParseFormat looks up a format by name or alias, ignoring case.
+func ParseFormat(name string) (Format, bool) {
+	name = strings.ToLower(name)
+	if f, ok := aliases[name]; ok {
+		return f, true
+	}
+
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, true
+		}
+	}
+
+	return "", false
+}
+
+// Options are the settings of the SQL format.
+type Options struct {
+	Table   string        // target table of the INSERT statements
+	Dialect query.Dialect // quoting of identifiers and literals
+}
+
+// Writer writes the rows of a result one at a time. Close writes whatever
+// ends the format and flushes, it doesn't close the underlying writer.
+type Writer interface {
+	WriteRow(row []any) error
+	Close() error
+}
+
+// NewWriter returns a writer of format f to w for rows with columns. Formats
+// with a header write it right away.
+func NewWriter(f Format, w io.Writer, columns []db.Column, opts Options) (Writer, error) {
+	bw := bufio.NewWriter(w)
+
+	switch f {
+	case CSV, TSV:
+		cw := csv.NewWriter(bw)
+		cw.UseCRLF = true
+		if f == TSV {
+			cw.Comma = '\t'
+		}
+		return newDelimited(cw, bw, columns)
+	case JSON, NDJSON:
+		return &jsonWriter{w: bw, columns: columns, array: f == JSON}, nil
+	case Markdown:
+		return newMarkdown(bw, columns), nil
+	case SQL:
+		if opts.Table == "" {
+			return nil, ErrNoTable
+		}
+		return newInsert(bw, columns, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", f)
+	}
+}
+
+// text is how a value is written in the text based formats. NULL is left
+// empty so it can be told from the string "NULL".
+func text(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	return db.FormatValue(v)
+}
+
+// delimited writes CSV and TSV, quoting fields as RFC 4180 asks.
+type delimited struct {
+	cw     *csv.Writer
+	bw     *bufio.Writer
+	record []string
+}
+
+func newDelimited(cw *csv.Writer, bw *bufio.Writer, columns []db.Column) (*delimited, error) {
+	d := &delimited{cw: cw, bw: bw, record: make([]string, len(columns))}
+	for i, c := range columns {
+		d.record[i] = c.Name
+	}
+
+	return d, cw.Write(d.record)
+}
+
+func (d *delimited) WriteRow(row []any) error {
+	for i, v := range row {
+		d.record[i] = text(v)
+	}
+
+	return d.cw.Write(d.record)
+}
+
+func (d *delimited) Close() error {
+	d.cw.Flush()
+	if err := d.cw.Error(); err != nil {
+		return err
+	}
+
+	return d.bw.Flush()
+}
+
+// jsonWriter writes an object per row, keyed by column name in column
+// order, either as one array or one object per line.
+type jsonWriter struct {
+	w       *bufio.Writer
+	columns []db.Column
+	array   bool
+	rows    int
+}
+
+func (j *jsonWriter) WriteRow(row []any) error {
+	switch {
+	case j.array && j.rows == 0:
+		j.w.WriteString("[\n  ")
+	case j.array:
+		j.w.WriteString(",\n  ")
+	}
+	j.rows++
+
+	j.w.WriteByte('{')
+	for i, v := range row {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+
+		name, _ := json.Marshal(j.columns[i].Name)
+		j.w.Write(name)
+		j.w.WriteByte(':')
+
+		if err := writeJSONValue(j.w, v); err != nil {
+			return err
+		}
+	}
+	j.w.WriteByte('}')
+
+	if !j.array {
+		j.w.WriteByte('\n')
+	}
+
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	if j.array {
+		if j.rows == 0 {
+			j.w.WriteString("[]\n")
+		} else {
+			j.w.WriteString("\n]\n")
+		}
+	}
+
+	return j.w.Flush()
+}
+
+// writeJSONValue writes v with its JSON type: numbers stay numbers, decimals
+// keep their digits and JSON documents are embedded as they are.
+func writeJSONValue(w *bufio.Writer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		_, err := w.WriteString("null")
+		return err
+	case bool:
+		_, err := w.WriteString(strconv.FormatBool(v))
+		return err
+	case int64:
+		_, err := w.WriteString(strconv.FormatInt(v, 10))
+		return err
+	case uint64:
+		_, err := w.WriteString(strconv.FormatUint(v, 10))
+		return err
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			_, err := w.WriteString("null")
+			return err
+		}
+		_, err := w.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		return err
+	case db.Decimal:
+		_, err := w.WriteString(string(v))
+		return err
+	case db.JSON:
+		if json.Valid(v) {
+			_, err := w.Write(v)
+			return err
+		}
+		return writeJSON(w, string(v))
+	case []byte:
+		// Bytes go as base64, like encoding/json does.
+		return writeJSON(w, v)
+	case []any:
+		w.WriteByte('[')
+		for i, x := range v {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeJSONValue(w, x); err != nil {
+				return err
+			}
+		}
+		_, err := w.WriteString("]")
+		return err
+	default:
+		return writeJSON(w, db.FormatValue(v))
+	}
+}
+
+func writeJSON(w *bufio.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// markdown writes a GitHub flavored table.
+type markdown struct {
+	w     *bufio.Writer
+	cells []string
+}
+
+func newMarkdown(w *bufio.Writer, columns []db.Column) *markdown {
+	m := &markdown{w: w, cells: make([]string, len(columns))}
+
+	for i, c := range columns {
+		m.cells[i] = markdownCell(c.Name)
+	}
+	m.writeCells()
+
+	for i := range m.cells {
+		m.cells[i] = "---"
+	}
+	m.writeCells()
+
+	return m
+}
+
+func (m *markdown) writeCells() {
+	m.w.WriteString("| ")
+	m.w.WriteString(strings.Join(m.cells, " | "))
+	m.w.WriteString(" |\n")
+}
+
+func (m *markdown) WriteRow(row []any) error {
+	for i, v := range row {
+		m.cells[i] = markdownCell(db.FormatValue(v))
+	}
+	m.writeCells()
+
+	return nil
+}
+
+func (m *markdown) Close() error {
+	return m.w.Flush()
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+func markdownCell(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// insert writes an INSERT statement per row.
+type insert struct {
+	w       *bufio.Writer
+	prefix  string
+	dialect query.Dialect
+}
+
+func newInsert(w *bufio.Writer, columns []db.Column, opts Options) *insert {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = query.QuoteIdent(c.Name, opts.Dialect)
+	}
+
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", query.QuoteIdent(opts.Table, opts.Dialect), strings.Join(names, ", "))
+	return &insert{w: w, prefix: prefix, dialect: opts.Dialect}
+}
+
+func (ins *insert) WriteRow(row []any) error {
+	ins.w.WriteString(ins.prefix)
+	for i, v := range row {
+		if i > 0 {
+			ins.w.WriteString(", ")
+		}
+		ins.w.WriteString(query.Literal(v, ins.dialect))
+	}
+	_, err := ins.w.WriteString(");\n")
+
+	return err
+}
+
+func (ins *insert) Close() error {
+	return ins.w.Flush()
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+var (
+	columns = []db.Column{{Name: "id"}, {Name: "name"}, {Name: "doc"}, {Name: "price"}}
+	rows    = [][]any{
+		{int64(1), `Ada "the first", Lovelace`, db.JSON(`{"a": [1, 2]}`), db.Decimal("12.50")},
+		{int64(2), "line\nbreak | pipe", nil, nil},
+	}
+)
+
+func write(t *testing.T, f Format, opts Options) string {
+	t.Helper()
+
+	var b strings.Builder
+	w, err := NewWriter(f, &b, columns, opts)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", f, err)
+	}
+
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("%s: unexpected error: %v", f, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("%s: unexpected error: %v", f, err)
+	}
+
+	return b.String()
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format Format
+		opts   Options
+		want   string
+	}{
+		{
+			format: CSV,
+			want: "id,name,doc,price\r\n" +
+				"1,\"Ada \"\"the first\"\", Lovelace\",\"{\"\"a\"\": [1, 2]}\",12.50\r\n" +
+				"2,\"line\r\nbreak | pipe\",,\r\n",
+		},
+		{
+			format: TSV,
+			want: "id\tname\tdoc\tprice\r\n" +
+				"1\t\"Ada \"\"the first\"\", Lovelace\"\t\"{\"\"a\"\": [1, 2]}\"\t12.50\r\n" +
+				"2\t\"line\r\nbreak | pipe\"\t\t\r\n",
+		},
+		{
+			format: JSON,
+			want: "[\n" +
+				"  {\"id\":1,\"name\":\"Ada \\\"the first\\\", Lovelace\",\"doc\":{\"a\": [1, 2]},\"price\":12.50},\n" +
+				"  {\"id\":2,\"name\":\"line\\nbreak | pipe\",\"doc\":null,\"price\":null}\n" +
+				"]\n",
+		},
+		{
+			format: NDJSON,
+			want: "{\"id\":1,\"name\":\"Ada \\\"the first\\\", Lovelace\",\"doc\":{\"a\": [1, 2]},\"price\":12.50}\n" +
+				"{\"id\":2,\"name\":\"line\\nbreak | pipe\",\"doc\":null,\"price\":null}\n",
+		},
+		{
+			format: Markdown,
+			want: "| id | name | doc | price |\n" +
+				"| --- | --- | --- | --- |\n" +
+				"| 1 | Ada \"the first\", Lovelace | {\"a\": [1, 2]} | 12.50 |\n" +
+				"| 2 | line<br>break \\| pipe | NULL | NULL |\n",
+		},
+		{
+			format: SQL,
+			opts:   Options{Table: "people", Dialect: query.MySQL},
+			want: "INSERT INTO people (id, name, doc, price) VALUES (1, 'Ada \"the first\", Lovelace', '{\"a\": [1, 2]}', 12.50);\n" +
+				"INSERT INTO people (id, name, doc, price) VALUES (2, 'line\nbreak | pipe', NULL, NULL);\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := write(t, tt.format, tt.opts); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestJSONValues(t *testing.T) {
+	var b strings.Builder
+	w, _ := NewWriter(NDJSON, &b, []db.Column{{Name: "v"}}, Options{})
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range []any{true, 1.5, []byte{1, 2}, db.Date{Time: day}, []any{int64(1), nil}, db.JSON("not json")} {
+		w.WriteRow([]any{v})
+	}
+	w.Close()
+
+	want := `{"v":true}
+{"v":1.5}
+{"v":"AQI="}
+{"v":"2024-03-01"}
+{"v":[1,null]}
+{"v":"not json"}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSQLNeedsTable(t *testing.T) {
+	if _, err := NewWriter(SQL, &strings.Builder{}, columns, Options{}); err != ErrNoTable {
+		t.Errorf("got %v, want ErrNoTable", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"CSV": CSV, "jsonl": NDJSON, "md": Markdown, "insert": SQL} {
+		if got, ok := ParseFormat(name); !ok || got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+
+	if _, ok := ParseFormat("xlsx"); ok {
+		t.Error("expected xlsx to be unknown")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/gdamore/tcell"
+)
+
+func TestExport(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	e.app.config.Results = config.Results{PageSize: 10}
+
+	runLine(t, e, "INSERT INTO t WITH RECURSIVE s(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM s WHERE n < 35) SELECT n FROM s")
+	runLine(t, e, "SELECT n FROM t")
+
+	// The rows past the first page come from the stream.
+	path := filepath.Join(t.TempDir(), "t.csv")
+	runCommand(t, e, "export csv "+path)
+	for e.running {
+		runPosted(t, e)
+	}
+
+	if want := "Exported 35 rows to " + path; e.StatusBar.Command != want {
+		t.Fatalf("got %q, want %q", e.StatusBar.Command, want)
+	}
+	if e.stream != nil {
+		t.Error("expected the stream closed once it was read")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\r\n"); len(lines) != 36 || lines[0] != "n" || lines[35] != "35" {
+		t.Errorf("got %d lines, want the header and 35 rows", len(lines))
+	}
+
+	// Existing files need a bang.
+	typeKeys(e, ":export sql "+path+" copy")
+	pressKey(e, tcell.KeyEnter)
+	if !strings.HasSuffix(e.StatusBar.Command, "use :export! to overwrite it") {
+		t.Fatalf("got %q, want the file kept", e.StatusBar.Command)
+	}
+
+	runCommand(t, e, "export! sql "+path+" copy")
+	data, _ = os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "INSERT INTO copy (n) VALUES (1);\n") {
+		t.Errorf("got %q, want INSERT statements", data)
+	}
+
+	typeKeys(e, ":export sql "+path)
+	pressKey(e, tcell.KeyEnter)
+	if e.StatusBar.Command != "Usage: export[!] sql <path> <table>" {
+		t.Errorf("got %q, want the usage", e.StatusBar.Command)
+	}
+}
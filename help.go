@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ajm113/dbvi/config"
+)
+
+// helpEntry documents a hotkey, a command or a config option.
+type helpEntry struct {
+	section string
+	term    string // what is typed or set, e.g. "dd" or ":connect [name]"
+	text    string
+}
+
+// matches reports if the entry is about topic.
+func (h helpEntry) matches(topic string) bool {
+	topic = strings.ToLower(strings.TrimPrefix(topic, ":"))
+	for _, s := range []string{h.section, strings.TrimPrefix(h.term, ":"), h.text} {
+		if strings.Contains(strings.ToLower(s), topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// helpTermWidth is the widest a term gets padded to, longer ones push their
+// text further right.
+const helpTermWidth = 28
+
+// helpEntries generates the help from the hotkey and command registries
+// and the options of the config file, hotkeys grouped by the mode they work
+// in.
+func helpEntries() []helpEntry {
+	// A command bound to several keys is listed once per mode.
+	var hotkeys []*HotkeyCommand
+	for _, key := range slices.Sorted(maps.Keys(HotkeyCommandRegistry)) {
+		for _, cmd := range HotkeyCommandRegistry[key] {
+			if !slices.Contains(hotkeys, cmd) {
+				hotkeys = append(hotkeys, cmd)
+			}
+		}
+	}
+
+	var entries []helpEntry
+	for _, mode := range []EditorMode{NormalMode, InsertMode, VisualMode, VisualLineMode, ResultsMode} {
+		section := fmt.Sprintf("%s mode keys", mode)
+		for _, cmd := range hotkeys {
+			if len(cmd.EditorModes) > 0 && slices.Contains(cmd.EditorModes, mode) {
+				entries = append(entries, helpEntry{section, strings.Join(cmd.Keys, ", "), cmd.Name + ": " + cmd.Description})
+			}
+		}
+	}
+	for _, cmd := range hotkeys {
+		if len(cmd.EditorModes) == 0 {
+			entries = append(entries, helpEntry{"Keys of every mode", strings.Join(cmd.Keys, ", "), cmd.Name + ": " + cmd.Description})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(CommandRegistry)) {
+		cmd := CommandRegistry[name]
+		usage := cmd.Usage
+		if usage == "" {
+			usage = cmd.Command
+		}
+		entries = append(entries, helpEntry{"Commands", ":" + usage, cmd.Name + ": " + cmd.Description})
+	}
+
+	for _, opt := range config.Options() {
+		entries = append(entries, helpEntry{"Config options", fmt.Sprintf("%s (%s)", opt.Key, opt.Type), opt.Description})
+	}
+
+	return entries
+}
+
+// helpLines lays entries out under the heading of their section.
+func helpLines(entries []helpEntry) []string {
+	var lines []string
+	for i, entry := range entries {
+		if i == 0 || entry.section != entries[i-1].section {
+			if i > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, entry.section)
+		}
+
+		lines = append(lines, fmt.Sprintf("  %-*s  %s", helpTermWidth, entry.term, entry.text))
+	}
+
+	return lines
+}
+
+// OpenHelp shows the keys, commands and config options, only those about
+// topic when one is given.
+func (e *Editor) OpenHelp(topic string) {
+	entries := helpEntries()
+	title := "Help"
+	if topic != "" {
+		entries = slices.DeleteFunc(entries, func(h helpEntry) bool { return !h.matches(topic) })
+		title = "Help: " + topic
+	}
+
+	if len(entries) == 0 {
+		e.fail("No help for %s", topic)
+		return
+	}
+
+	e.OpenPopup(NewMessageView(e, title, helpLines(entries), MessageInfo))
+}
+
+// printHelp writes the command line usage, followed by the help of the
+// editor.
+func printHelp(w io.Writer) {
+	// The registries are filled as the editor is made.
+	setDefaultHotkeys(nil)
+	setDefaultCommands(nil)
+
+	fmt.Fprintln(w, "Usage: dbvi [options]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options")
+	fmt.Fprintf(w, "  %-*s  %s\n", helpTermWidth, "-h, --help", "Shows this help and exits")
+	fmt.Fprintln(w)
+
+	for _, line := range helpLines(helpEntries()) {
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell"
+)
+
+func TestHelp(t *testing.T) {
+	e := newTestEditor(t, nil)
+
+	typeKeys(e, ":help")
+	pressKey(e, tcell.KeyEnter)
+	view, ok := e.Popup.(*MessageView)
+	if !ok {
+		t.Fatalf("got popup %T, want the help", e.Popup)
+	}
+
+	text := strings.Join(view.lines, "\n")
+	for _, want := range []string{"Normal mode keys", "Results mode keys", ":connect [name]", "connections[].ssh.port (int)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("got help without %q", want)
+		}
+	}
+	typeKeys(e, "q")
+
+	// A topic keeps the entries mentioning it.
+	typeKeys(e, ":help :tabpin")
+	pressKey(e, tcell.KeyEnter)
+	view, ok = e.Popup.(*MessageView)
+	if !ok || view.title != "Help: :tabpin" {
+		t.Fatalf("got popup %T, want the help on tabpin", e.Popup)
+	}
+	if !slices.Equal(view.lines[:1], []string{"Commands"}) || len(view.lines) != 2 || !strings.Contains(view.lines[1], ":tabpin") {
+		t.Errorf("got %q, want only :tabpin", view.lines)
+	}
+	typeKeys(e, "q")
+
+	typeKeys(e, ":help nothing like it")
+	pressKey(e, tcell.KeyEnter)
+	if e.Popup != nil || e.StatusBar.level != MessageError {
+		t.Errorf("got %q, want no help found", e.StatusBar.Command)
+	}
+}
+
+func TestPrintHelp(t *testing.T) {
+	var out bytes.Buffer
+	printHelp(&out)
+
+	for _, want := range []string{"Usage: dbvi [options]", "-h, --help", ":help [topic]", "use_connection (string)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got help without %q", want)
+		}
+	}
+
+	// Aliases are told apart from the command they stand for.
+	seen := map[string]bool{}
+	for _, entry := range helpEntries() {
+		if entry.section != "Commands" {
+			continue
+		}
+		if seen[entry.text] {
+			t.Errorf("got command %q twice", entry.text)
+		}
+		seen[entry.text] = true
+	}
+}
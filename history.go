@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/history"
+	"github.com/ajm113/dbvi/query"
+	"github.com/gdamore/tcell"
+)
+
+// openHistory opens the history file in the data directory.
+func openHistory(h config.History) (*history.Store, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return history.Open(filepath.Join(dir, history.FileName), h.Entries())
+}
+
+// recordStatement adds a statement that was run on c to the history, unless
+// the connection is left out of it.
+func (e *Editor) recordStatement(c *config.Connection, stmt string, elapsed time.Duration, rows int64, err error) {
+	if e.app.history == nil || !e.app.config.History.Records(c.Name) {
+		return
+	}
+
+	entry := history.Entry{
+		Time:       time.Now(),
+		Connection: c.Name,
+		Statement:  stmt,
+		Duration:   elapsed,
+		Rows:       rows,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if err := e.app.history.Add(entry); err != nil {
+		e.app.log.Errorf("failed adding to history: %v", err)
+	}
+}
+
+// OpenHistoryPicker lists the statements run, most recent first, to paste
+// one into the buffer or run it again.
+func (e *Editor) OpenHistoryPicker() {
+	if e.app.history == nil {
+		e.notify("History is off, see the log")
+		return
+	}
+
+	entries := e.app.history.Entries()
+	if len(entries) == 0 {
+		e.notify("No history yet")
+		return
+	}
+
+	items := make([]PickerItem, len(entries))
+	for i, entry := range entries {
+		items[i] = PickerItem{
+			Label:  strings.Join(strings.Fields(entry.Statement), " "),
+			Detail: historyDetail(entry),
+			Value:  entry,
+		}
+	}
+
+	p := NewPicker(e, "History, Enter to paste, Ctrl+R to run", items, func(item PickerItem) {
+		e.PasteStatement(item.Value.(history.Entry).Statement)
+	})
+	p.Actions = map[tcell.Key]func(PickerItem){
+		tcell.KeyCtrlR: func(item PickerItem) {
+			e.RunStatement(query.Statement{Text: item.Value.(history.Entry).Statement, Detached: true})
+		},
+	}
+	e.OpenPopup(p)
+}
+
+// historyDetail describes where, when and how a statement ran, e.g.
+// "Local 2024-03-01 12:00 12ms 3 rows".
+func historyDetail(entry history.Entry) string {
+	outcome := fmt.Sprintf("%d rows", entry.Rows)
+	if entry.Error != "" {
+		outcome = "failed: " + entry.Error
+	}
+
+	return fmt.Sprintf("%s %s %s %s", entry.Connection, entry.Time.Local().Format("2006-01-02 15:04"), entry.Duration.Round(time.Millisecond), outcome)
+}
+
+// PasteStatement puts stmt on the lines below the cursor, terminated so it
+// runs on its own, and moves the cursor to it. An empty buffer is replaced.
+func (e *Editor) PasteStatement(stmt string) {
+	if e.Dialect() != query.Redis && !strings.HasSuffix(strings.TrimSpace(stmt), ";") {
+		stmt += ";"
+	}
+	lines := strings.Split(stmt, "\n")
+
+	if len(e.Lines) == 1 && e.Lines[0] == "" {
+		e.Lines = lines
+		e.SetCursor(0, 0)
+		return
+	}
+
+	y := e.CursorY + 1
+	e.Lines = slices.Insert(e.Lines, y, lines...)
+	e.SetCursor(0, y)
+}
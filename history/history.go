@@ -0,0 +1,158 @@
+// Package history keeps a record of the statements run, one JSON object
+// per line in a file that outlives the session.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the history file within the data directory.
+const FileName = "history.ndjson"
+
+// Entry is a statement that was run.
+type Entry struct {
+	Time       time.Time     `json:"time"`
+	Connection string        `json:"connection"`
+	Statement  string        `json:"statement"`
+	Duration   time.Duration `json:"duration"`
+	Rows       int64         `json:"rows"` // returned, or affected by statements without a result
+	Error      string        `json:"error,omitempty"`
+}
+
+// Store is the history file with the entries it holds, at most size of
+// them. Entries past the size are dropped oldest first.
+type Store struct {
+	path string
+	size int
+
+	mu      sync.Mutex
+	entries []Entry // oldest first
+	lines   int     // lines in the file, kept entries or not
+	torn    bool    // the file ends in the middle of a line
+}
+
+// Open reads the history at path, which doesn't have to exist yet. Lines
+// that can't be read, e.g. cut short by a crash, are skipped.
+func Open(path string, size int) (*Store, error) {
+	s := &Store{path: path, size: size}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.torn = len(data) > 0 && data[len(data)-1] != '\n'
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for sc.Scan() {
+		s.lines++
+
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			s.entries = append(s.entries, e)
+		}
+	}
+
+	if len(s.entries) > size {
+		s.entries = s.entries[len(s.entries)-size:]
+	}
+
+	return s, nil
+}
+
+// Add appends e to the history file. The file is rewritten once it holds
+// twice the entries kept, to drop the oldest ones, or when its last line was
+// cut short.
+func (s *Store) Add(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, e)
+	if len(s.entries) > s.size {
+		s.entries = slices.Clone(s.entries[len(s.entries)-s.size:])
+	}
+
+	if s.torn || s.lines >= 2*s.size {
+		return s.rewrite()
+	}
+
+	return s.append(e)
+}
+
+func (s *Store) append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	s.lines++
+
+	return f.Close()
+}
+
+// rewrite replaces the history file with the entries kept, through a
+// temporary file so a crash can't lose the history.
+func (s *Store) rewrite() error {
+	var buf bytes.Buffer
+	for _, e := range s.entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(line, '\n'))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed replacing %s: %w", s.path, err)
+	}
+	s.lines, s.torn = len(s.entries), false
+
+	return nil
+}
+
+// Entries returns the history, most recent first.
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := slices.Clone(s.entries)
+	slices.Reverse(entries)
+
+	return entries
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dbvi", FileName)
+
+	s, err := Open(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 7 {
+		e := Entry{
+			Time:       start.Add(time.Duration(i) * time.Minute),
+			Connection: "Local",
+			Statement:  "SELECT " + string(rune('a'+i)),
+			Duration:   time.Millisecond,
+		}
+		if i == 6 {
+			e.Error = "no such column: g"
+		}
+		if err := s.Add(e); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	statements := func(entries []Entry) string {
+		var stmts []string
+		for _, e := range entries {
+			stmts = append(stmts, e.Statement)
+		}
+		return strings.Join(stmts, ", ")
+	}
+
+	if got := statements(s.Entries()); got != "SELECT g, SELECT f, SELECT e" {
+		t.Errorf("Entries() = %s", got)
+	}
+
+	// The file is compacted once it holds twice the entries kept.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n > 6 {
+		t.Errorf("history file has %d lines, want at most 6", n)
+	}
+
+	// A line cut short is skipped when the history is read again.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-03-01T12:`)
+	f.Close()
+
+	s, err = Open(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := s.Entries()
+	if got := statements(entries); got != "SELECT g, SELECT f, SELECT e" {
+		t.Errorf("Entries() after reopening = %s", got)
+	}
+	if entries[0].Error != "no such column: g" || !entries[0].Time.Equal(start.Add(6*time.Minute)) {
+		t.Errorf("entry read back as %+v", entries[0])
+	}
+
+	// The next entry doesn't get lost on the end of the cut line.
+	if err := s.Add(Entry{Statement: "SELECT h"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if s, err = Open(path, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := statements(s.Entries()); got != "SELECT h, SELECT g, SELECT f" {
+		t.Errorf("Entries() after adding to a cut file = %s", got)
+	}
+}
+
+func TestOpenMissing(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), FileName), 10)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if entries := s.Entries(); len(entries) != 0 {
+		t.Errorf("Entries() = %v, want none", entries)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/history"
+	"github.com/gdamore/tcell"
+)
+
+func TestHistory(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+
+	store, err := history.Open(filepath.Join(t.TempDir(), history.FileName), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.app.history = store
+
+	runLine(t, e, "INSERT INTO t VALUES (1), (2)")
+	runLine(t, e, "SELECT n\n  FROM t")
+	runLine(t, e, "SELECT nope FROM t")
+
+	entries := store.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if entries[0].Error == "" || entries[1].Rows != 2 || entries[2].Rows != 2 || entries[2].Connection != "Fixture" {
+		t.Errorf("got entries %+v", entries)
+	}
+
+	// The picker searches the statements, Enter pastes one below the
+	// cursor.
+	typeKeys(e, ":history")
+	pressKey(e, tcell.KeyEnter)
+	typeKeys(e, "sel n")
+	matches := e.Popup.(*Picker).Matches()
+	if len(matches) == 0 || matches[0].Label != "SELECT n FROM t" || !strings.Contains(matches[0].Detail, "2 rows") {
+		t.Fatalf("got matches %+v, want SELECT n FROM t first", matches)
+	}
+	pressKey(e, tcell.KeyEnter)
+
+	if got := strings.Join(e.Lines, "\n"); got != "SELECT nope FROM t\nSELECT n\n  FROM t;" || e.CursorY != 1 {
+		t.Errorf("got buffer %q with the cursor on line %d", got, e.CursorY)
+	}
+
+	// Ctrl+R runs it again.
+	typeKeys(e, ":history")
+	pressKey(e, tcell.KeyEnter)
+	typeKeys(e, "insert")
+	pressKey(e, tcell.KeyCtrlR)
+	runPosted(t, e)
+	if n := countRows(t, e); n != 4 {
+		t.Errorf("got %d rows after running the insert again, want 4", n)
+	}
+}
+
+func TestHistoryExclude(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	e.app.config.History.Exclude = []string{"fixture"}
+
+	store, err := history.Open(filepath.Join(t.TempDir(), history.FileName), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.app.history = store
+
+	runLine(t, e, "SELECT 1")
+	if entries := store.Entries(); len(entries) != 0 {
+		t.Errorf("got entries %+v for an excluded connection", entries)
+	}
+}
@@ -1,19 +1,35 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	_ "github.com/ajm113/dbvi/db/mysql"
+	_ "github.com/ajm113/dbvi/db/postgres"
+	_ "github.com/ajm113/dbvi/db/redis"
+	_ "github.com/ajm113/dbvi/db/sqlite"
+	"github.com/ajm113/dbvi/history"
+	"github.com/ajm113/dbvi/tunnel"
 	"github.com/gdamore/tcell"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 type App struct {
-	screen tcell.Screen
-	log    *zap.SugaredLogger
-	editor *Editor
+	screen   tcell.Screen
+	log      *zap.SugaredLogger
+	editor   *Editor
+	config   *config.Config
+	tunnels  *tunnel.Manager
+	sessions *db.Manager
+	history  *history.Store // nil when it couldn't be opened
+	quit     bool
 }
 
 func NewApp() *App {
@@ -43,13 +59,18 @@ func (a *App) Init() {
 	}
 
 	a.log.Debugf("loading config: %s", configPath)
-	_, err = config.Load(configPath)
+	a.config, err = config.Load(configPath)
 	if err != nil {
 		a.log.Fatal("unexpected error loading config", zap.Any("error", err))
 	}
 
 	a.log.Info("loaded config")
 
+	a.history, err = openHistory(a.config.History)
+	if err != nil {
+		a.log.Warnf("history is off: %v", err)
+	}
+
 	a.screen, err = tcell.NewScreen()
 	if err != nil {
 		a.log.Fatal("unexpected error creating tcell screen", zap.Any("error", err))
@@ -59,31 +80,102 @@ func (a *App) Init() {
 		a.log.Fatal("unexpected error init tcell screen", zap.Any("error", err))
 	}
 
-	a.editor = NewEditor(a.screen)
+	a.tunnels = tunnel.NewManager()
+	a.sessions = db.NewManager(a.openSession)
+	a.editor = NewEditor(a)
+
+	if a.config.UseConnection != "" {
+		c, ok := a.config.Connection(a.config.UseConnection)
+		if !ok {
+			a.log.Warnf("use_connection %q doesn't match any connection", a.config.UseConnection)
+		} else {
+			a.editor.Connect(c)
+		}
+	}
 }
 
 func (a *App) Run() error {
 	defer func() {
 		a.screen.Fini()
+		a.editor.Close()
+
+		if err := a.sessions.Close(); err != nil {
+			a.log.Error("failed closing sessions", zap.Any("error", err))
+		}
+
+		if err := a.tunnels.Close(); err != nil {
+			a.log.Error("failed closing ssh tunnels", zap.Any("error", err))
+		}
 	}()
 
 	a.draw()
 
-	for {
+	// Redraw every second while a transaction is open, so its timer keeps
+	// counting. The event loop tells the ticker whether one is.
+	var inTransaction atomic.Bool
+	done := make(chan struct{})
+	defer close(done)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if inTransaction.Load() {
+					a.post(func() {})
+				}
+			}
+		}
+	}()
+
+	for !a.quit {
 		ev := a.screen.PollEvent()
 		switch ev := ev.(type) {
 		case *tcell.EventKey:
 			switch ev.Key() {
 			case tcell.KeyCtrlC:
-				a.screen.Fini()
-				os.Exit(0)
+				a.editor.Quit(false)
+				a.draw()
+				continue
 			}
 			a.editor.HandleEventKey(ev)
 		case *tcell.EventResize:
 			a.screen.Sync()
+		case *tcell.EventInterrupt:
+			if fn, ok := ev.Data().(func()); ok {
+				fn()
+			}
 		}
 		a.draw()
+		inTransaction.Store(a.editor.InTransaction())
 	}
+
+	return nil
+}
+
+// Quit stops the event loop once the current event is handled.
+func (a *App) Quit() {
+	a.quit = true
+}
+
+// post schedules fn to run on the event loop, so background work can
+// update the UI without racing the drawing.
+func (a *App) post(fn func()) {
+	a.screen.PostEvent(tcell.NewEventInterrupt(fn))
+}
+
+// openSession opens a database session for c, going through its SSH tunnel
+// when it has one.
+func (a *App) openSession(ctx context.Context, c config.Connection) (db.Session, error) {
+	addr, err := a.tunnels.Endpoint(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Open(ctx, c, addr)
 }
 
 func (a *App) draw() {
@@ -92,6 +184,8 @@ func (a *App) draw() {
 
 	if a.editor.EditorMode == CommandMode {
 		a.screen.ShowCursor(a.editor.StatusBar.CursorX, a.editor.Height+1)
+	} else if a.editor.EditorMode == ResultsMode {
+		a.screen.HideCursor()
 	} else {
 		a.screen.ShowCursor(a.editor.CursorX, a.editor.CursorY-a.editor.ScrollOffsetY)
 	}
@@ -100,10 +194,22 @@ func (a *App) draw() {
 }
 
 func main() {
+	args, err := parseFlags(os.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dbvi: %s\nTry dbvi --help\n", err)
+		os.Exit(2)
+	}
+
+	for _, cmd := range args.Commands {
+		if cmd.Name == "help" {
+			printHelp(os.Stdout)
+			return
+		}
+	}
+
 	app := NewApp()
 	app.Init()
-	err := app.Run()
-	if err != nil {
+	if err := app.Run(); err != nil {
 		os.Exit(1)
 	}
 }
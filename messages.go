@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/gdamore/tcell"
+)
+
+type MessageLevel int
+
+const (
+	MessageInfo MessageLevel = iota
+	MessageWarning
+	MessageError
+)
+
+func (l MessageLevel) String() string {
+	switch l {
+	case MessageWarning:
+		return "warning"
+	case MessageError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// heading is the title of a popup showing a message of the level.
+func (l MessageLevel) heading() string {
+	switch l {
+	case MessageWarning:
+		return "Warning"
+	case MessageError:
+		return "Error"
+	default:
+		return "Message"
+	}
+}
+
+// maxMessages is how many messages :messages keeps, the oldest go first.
+const maxMessages = 500
+
+// message is something the editor told the user.
+type message struct {
+	Level MessageLevel
+	Text  string // one or more lines
+	At    time.Time
+}
+
+// notify shows an informational message in the command line.
+func (e *Editor) notify(format string, a ...any) {
+	e.message(MessageInfo, format, a...)
+}
+
+// warn shows a message about something that was refused or might not have
+// gone as expected.
+func (e *Editor) warn(format string, a ...any) {
+	e.message(MessageWarning, format, a...)
+}
+
+// fail shows a message about something that went wrong.
+func (e *Editor) fail(format string, a ...any) {
+	e.message(MessageError, format, a...)
+}
+
+// message logs a message for :messages and shows its first line in the
+// command line. Messages of several lines, or too wide for the command line,
+// open in a popup unless one is open already.
+func (e *Editor) message(level MessageLevel, format string, a ...any) {
+	m := message{Level: level, Text: fmt.Sprintf(format, a...), At: time.Now()}
+	e.messages = append(e.messages, m)
+	if len(e.messages) > maxMessages {
+		e.messages = e.messages[len(e.messages)-maxMessages:]
+	}
+
+	first, _, multiline := strings.Cut(m.Text, "\n")
+	e.StatusBar.Command = first
+	e.StatusBar.CursorX = 0
+	e.StatusBar.level = level
+
+	w, _ := e.screen.Size()
+	if !multiline && utf8.RuneCountInString(first) <= w {
+		return
+	}
+
+	// The results pane keeps the keys while it is focused.
+	if e.Popup != nil || e.EditorMode == ResultsMode {
+		e.StatusBar.Command += "  (:messages for more)"
+		return
+	}
+
+	e.OpenPopup(NewMessageView(e, level.heading(), strings.Split(m.Text, "\n"), level))
+}
+
+// noticeLevel is the level notices of severity are shown at.
+func noticeLevel(n db.Notice) MessageLevel {
+	switch n.Severity {
+	case "WARNING", "ERROR":
+		return MessageWarning
+	default:
+		return MessageInfo
+	}
+}
+
+// OpenMessages shows the messages of the session, the latest at the
+// bottom.
+func (e *Editor) OpenMessages() {
+	if len(e.messages) == 0 {
+		e.notify("No messages")
+		return
+	}
+
+	var lines []string
+	var levels []MessageLevel
+	for _, m := range e.messages {
+		for i, line := range strings.Split(m.Text, "\n") {
+			prefix := m.At.Format("15:04:05") + "  "
+			if i > 0 {
+				prefix = strings.Repeat(" ", len(prefix))
+			}
+			lines = append(lines, prefix+line)
+			levels = append(levels, m.Level)
+		}
+	}
+
+	v := NewMessageView(e, "Messages", lines, MessageInfo)
+	v.levels = levels
+	v.offset = len(lines)
+	e.OpenPopup(v)
+}
+
+// MessageView is a popup showing lines of messages, scrolled with j and k.
+type MessageView struct {
+	title  string
+	lines  []string
+	levels []MessageLevel // of each line, or of all of them when nil
+	level  MessageLevel
+
+	offset int // in rows, lines wider than the popup wrap over several
+	height int // rows visible, set by Draw
+	rows   int // rows the lines wrap to, set by Draw
+
+	style   tcell.Style
+	warning tcell.Style
+	error   tcell.Style
+	dim     tcell.Style
+
+	screen tcell.Screen
+	editor *Editor
+}
+
+func NewMessageView(editor *Editor, title string, lines []string, level MessageLevel) *MessageView {
+	return &MessageView{
+		title:   title,
+		lines:   lines,
+		rows:    len(lines),
+		level:   level,
+		style:   tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		warning: tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack),
+		error:   tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack),
+		dim:     tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack).Dim(true),
+		screen:  editor.screen,
+		editor:  editor,
+	}
+}
+
+func (v *MessageView) HandleEventKey(ek *tcell.EventKey) {
+	switch ek.Key() {
+	case tcell.KeyEscape, tcell.KeyEnter:
+		v.editor.ClosePopup()
+	case tcell.KeyUp:
+		v.scroll(-1)
+	case tcell.KeyDown:
+		v.scroll(1)
+	case tcell.KeyPgUp:
+		v.scroll(-max(1, v.height))
+	case tcell.KeyPgDn:
+		v.scroll(max(1, v.height))
+	case tcell.KeyRune:
+		switch ek.Rune() {
+		case 'q':
+			v.editor.ClosePopup()
+		case 'k':
+			v.scroll(-1)
+		case 'j':
+			v.scroll(1)
+		case 'g':
+			v.offset = 0
+		case 'G':
+			v.offset = v.rows
+		}
+	}
+}
+
+func (v *MessageView) scroll(delta int) {
+	v.offset = max(0, min(v.offset+delta, v.rows-max(1, v.height)))
+}
+
+func (v *MessageView) lineStyle(i int) tcell.Style {
+	level := v.level
+	if v.levels != nil {
+		level = v.levels[i]
+	}
+
+	switch level {
+	case MessageWarning:
+		return v.warning
+	case MessageError:
+		return v.error
+	default:
+		return v.style
+	}
+}
+
+func (v *MessageView) Draw() {
+	w, h := v.screen.Size()
+
+	width := w - 4
+	if width < 20 {
+		return
+	}
+
+	var rows []string
+	var of []int // the line of each row
+	for i, line := range v.lines {
+		for _, row := range wrapText(line, width-4) {
+			rows = append(rows, row)
+			of = append(of, i)
+		}
+	}
+
+	height := min(len(rows)+3, h-4)
+	if height < 4 {
+		return
+	}
+
+	x := (w - width) / 2
+	y := (h - height) / 2
+
+	drawBox(v.screen, x, y, width, height, v.title, v.editor.AccentStyle(), v.style)
+
+	v.rows = len(rows)
+	v.height = height - 3
+	v.offset = max(0, min(v.offset, v.rows-v.height))
+
+	for i := 0; i < v.height && v.offset+i < v.rows; i++ {
+		drawText(v.screen, x+2, y+1+i, width-4, rows[v.offset+i], v.lineStyle(of[v.offset+i]))
+	}
+
+	drawText(v.screen, x+2, y+height-2, width-4, "j/k to scroll, Enter or q to close", v.dim)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell"
+)
+
+func TestMessages(t *testing.T) {
+	e := newTestEditor(t, nil)
+
+	e.notify("Connected")
+	e.warn("Refused on read-only")
+	if e.StatusBar.Command != "Refused on read-only" || e.StatusBar.level != MessageWarning {
+		t.Errorf("got %q at %s, want the warning", e.StatusBar.Command, e.StatusBar.level)
+	}
+
+	// A message of several lines shows its first one and opens in full.
+	e.fail("Error: division by zero\nDetail: in row 3")
+	if e.StatusBar.Command != "Error: division by zero" {
+		t.Errorf("got %q, want the first line", e.StatusBar.Command)
+	}
+	if _, ok := e.Popup.(*MessageView); !ok {
+		t.Fatalf("got popup %T, want the message", e.Popup)
+	}
+	pressKey(e, tcell.KeyEnter)
+
+	// Unknown commands are errors too, and every message is logged.
+	typeKeys(e, ":nope")
+	pressKey(e, tcell.KeyEnter)
+	if e.StatusBar.level != MessageError {
+		t.Errorf("got %s, want an unknown command to be an error", e.StatusBar.level)
+	}
+
+	typeKeys(e, ":messages")
+	pressKey(e, tcell.KeyEnter)
+	view, ok := e.Popup.(*MessageView)
+	if !ok || len(view.lines) != 5 {
+		t.Fatalf("got popup %T, want the 4 messages on 5 lines", e.Popup)
+	}
+	if !strings.HasSuffix(view.lines[3], "  Detail: in row 3") || view.levels[3] != MessageError {
+		t.Errorf("got line %q, want the detail of the error", view.lines[3])
+	}
+	if !strings.HasSuffix(view.lines[4], "Not an editor command: nope") {
+		t.Errorf("got last line %q, want the latest message", view.lines[4])
+	}
+	typeKeys(e, "q")
+
+	for i := range maxMessages + 10 {
+		e.notify("%d", i)
+	}
+	if len(e.messages) != maxMessages || e.messages[0].Text != "10" {
+		t.Errorf("got %d messages from %q, want the latest %d", len(e.messages), e.messages[0].Text, maxMessages)
+	}
+}
+
+func TestWideMessage(t *testing.T) {
+	e := newTestEditor(t, nil)
+
+	// Wider than the command line, it opens in full, wrapped to the popup.
+	text := `Error: duplicate key value violates unique constraint "orders_customer_id_reference_key" on table "orders" (SQLSTATE 23505)`
+	e.fail("%s", text)
+	view, ok := e.Popup.(*MessageView)
+	if !ok {
+		t.Fatalf("got popup %T, want the message", e.Popup)
+	}
+
+	e.Draw()
+	_, h := e.screen.Size()
+	var screen []string
+	for y := range h {
+		screen = append(screen, screenRow(e, y))
+	}
+	if view.rows != 2 || !strings.Contains(strings.Join(screen, "\n"), `"orders" (SQLSTATE 23505)`) {
+		t.Errorf("got %d rows, want the message wrapped on 2", view.rows)
+	}
+	pressKey(e, tcell.KeyEnter)
+
+	e.notify("Short enough")
+	if e.Popup != nil {
+		t.Error("got a popup for a message that fits")
+	}
+}
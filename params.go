@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ajm113/dbvi/query"
+)
+
+// boundStatement is a statement with its placeholders rewritten for the
+// driver, and the values bound to them.
+type boundStatement struct {
+	Text string
+	Args []any
+
+	placeholders []query.Placeholder // rewritten in Text
+}
+
+// unbind maps offset, in the text of the bound statement, back to the
+// statement as written.
+func (b boundStatement) unbind(d query.Dialect, offset int) int {
+	return query.Unbind(d, b.placeholders, offset)
+}
+
+// bindPlaceholders gets the values of the placeholders of stmts, from the
+// variables set with :set var or else by asking for them one after the
+// other, and calls run with the statements rewritten for the driver along
+// with their values. A named placeholder used by several statements is
+// asked for once, numbered and positional ones belong to their statement.
+// Answers are remembered as the defaults of the next run.
+func (e *Editor) bindPlaceholders(stmts []query.Statement, run func([]boundStatement)) {
+	// key tells the placeholders apart across statements.
+	key := func(stmt query.Statement, p query.Placeholder) string {
+		if len(stmts) == 1 || !p.Positional() {
+			return p.Label()
+		}
+		return fmt.Sprintf("%s on line %d", p.Label(), stmt.Line+1)
+	}
+
+	placeholders := make([][]query.Placeholder, len(stmts))
+	values := map[string]any{}
+	var ask []string
+	for i, stmt := range stmts {
+		placeholders[i] = query.Placeholders(stmt.Text, e.Dialect())
+		for _, p := range placeholders[i] {
+			k := key(stmt, p)
+			if _, ok := values[k]; ok || slices.Contains(ask, k) {
+				continue
+			}
+
+			if v, ok := e.vars[p.Name]; ok && k == p.Label() {
+				values[k] = bindValue(v)
+				continue
+			}
+			ask = append(ask, k)
+		}
+	}
+
+	var next func(i int)
+	next = func(i int) {
+		if i == len(ask) {
+			bound := make([]boundStatement, len(stmts))
+			for j, stmt := range stmts {
+				named := map[string]any{}
+				for _, p := range placeholders[j] {
+					named[p.Name] = values[key(stmt, p)]
+				}
+				bound[j].Text, bound[j].Args = query.Bind(stmt.Text, e.Dialect(), placeholders[j], named)
+				bound[j].placeholders = placeholders[j]
+			}
+			run(bound)
+			return
+		}
+
+		k := ask[i]
+		question := fmt.Sprintf("%s (%s for NULL): ", k, nullInput)
+		e.StatusBar.PromptWith(question, e.binds[k], func(answer string) {
+			if e.binds == nil {
+				e.binds = map[string]string{}
+			}
+			e.binds[k] = answer
+			values[k] = bindValue(answer)
+			next(i + 1)
+		})
+	}
+	next(0)
+}
+
+// bindValue is the value bound for what was typed for a placeholder.
+func bindValue(input string) any {
+	if input == nullInput {
+		return nil
+	}
+
+	return input
+}
+
+// SetVariable handles :set var. "name=value" sets the variable bound to the
+// placeholders named name without asking, "name" shows it and "" lists them
+// all.
+func (e *Editor) SetVariable(spec string) {
+	if spec == "" {
+		if len(e.vars) == 0 {
+			e.notify("No variables set")
+			return
+		}
+
+		var vars []string
+		for _, name := range slices.Sorted(maps.Keys(e.vars)) {
+			vars = append(vars, name+"="+e.vars[name])
+		}
+		e.notify("%s", strings.Join(vars, "  "))
+		return
+	}
+
+	name, value, ok := strings.Cut(spec, "=")
+	name = strings.TrimPrefix(strings.TrimSpace(name), ":")
+	if name == "" {
+		e.fail("Usage: set var [name[=value]]")
+		return
+	}
+
+	if !ok {
+		v, set := e.vars[name]
+		if !set {
+			e.notify("Variable %s isn't set", name)
+			return
+		}
+		e.notify("%s=%s", name, v)
+		return
+	}
+
+	if e.vars == nil {
+		e.vars = map[string]string{}
+	}
+	e.vars[name] = strings.TrimSpace(value)
+	e.notify("%s=%s", name, e.vars[name])
+}
+
+// UnsetVariable handles :unset var, after which the placeholders named name
+// are asked for again.
+func (e *Editor) UnsetVariable(name string) {
+	name = strings.TrimPrefix(name, ":")
+	if _, ok := e.vars[name]; !ok {
+		e.notify("Variable %s isn't set", name)
+		return
+	}
+
+	delete(e.vars, name)
+	e.notify("Unset %s", name)
+}
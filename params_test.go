@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/gdamore/tcell"
+)
+
+func TestBindPlaceholders(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	runLine(t, e, "INSERT INTO t VALUES (1), (2), (3)")
+
+	e.Lines = []string{"SELECT n FROM t WHERE n >= :lo AND n < ? AND ':x' = ':x' ORDER BY n"}
+	run := func() {
+		typeKeys(e, ":run")
+		pressKey(e, tcell.KeyEnter)
+	}
+
+	run()
+	if want := `:lo (\N for NULL): `; e.StatusBar.Command != want {
+		t.Fatalf("got prompt %q, want %q", e.StatusBar.Command, want)
+	}
+	answer(e, "2")
+	if want := `?1 (\N for NULL): `; e.StatusBar.Command != want {
+		t.Fatalf("got prompt %q, want %q", e.StatusBar.Command, want)
+	}
+	answer(e, "3")
+	runPosted(t, e)
+	if rows := e.Results.Result.Rows; len(rows) != 1 || rows[0][0] != int64(2) {
+		t.Fatalf("got rows %v, want [[2]]", rows)
+	}
+
+	// The answers are the defaults of the next run.
+	run()
+	if want := `:lo (\N for NULL): 2`; e.StatusBar.Command != want {
+		t.Fatalf("got prompt %q, want %q", e.StatusBar.Command, want)
+	}
+	pressKey(e, tcell.KeyEscape)
+
+	// Variables are bound without asking.
+	typeKeys(e, ":set var lo = 1")
+	pressKey(e, tcell.KeyEnter)
+	if e.StatusBar.Command != "lo=1" {
+		t.Fatalf("got %q, want the variable set", e.StatusBar.Command)
+	}
+
+	run()
+	if want := `?1 (\N for NULL): 3`; e.StatusBar.Command != want {
+		t.Fatalf("got prompt %q, want %q", e.StatusBar.Command, want)
+	}
+	pressKey(e, tcell.KeyEnter)
+	runPosted(t, e)
+	if rows := e.Results.Result.Rows; len(rows) != 2 {
+		t.Fatalf("got rows %v, want 1 and 2", rows)
+	}
+
+	typeKeys(e, ":unset var lo")
+	pressKey(e, tcell.KeyEnter)
+	run()
+	if want := `:lo (\N for NULL): 2`; e.StatusBar.Command != want {
+		t.Fatalf("got prompt %q, want %q", e.StatusBar.Command, want)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/ajm113/dbvi/utils"
+	"github.com/gdamore/tcell"
+)
+
+type PickerItem struct {
+	Label  string
+	Detail string
+	Value  any
+}
+
+// Picker is a popup listing items that are fuzzy filtered as the user types.
+type Picker struct {
+	Title    string
+	Items    []PickerItem
+	Query    string
+	Selected int
+	OnSelect func(PickerItem)
+
+	// Actions choose an item another way than Enter, with other keys.
+	Actions map[tcell.Key]func(PickerItem)
+
+	filtered []int
+	style    tcell.Style
+	selStyle tcell.Style
+
+	screen tcell.Screen
+	editor *Editor
+}
+
+func NewPicker(editor *Editor, title string, items []PickerItem, onSelect func(PickerItem)) *Picker {
+	p := &Picker{
+		Title:    title,
+		Items:    items,
+		OnSelect: onSelect,
+		style:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		selStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+		screen:   editor.screen,
+		editor:   editor,
+	}
+	p.filter()
+
+	return p
+}
+
+// Matches returns the items matching the current query, best match first.
+func (p *Picker) Matches() []PickerItem {
+	items := make([]PickerItem, len(p.filtered))
+	for i, idx := range p.filtered {
+		items[i] = p.Items[idx]
+	}
+
+	return items
+}
+
+func (p *Picker) filter() {
+	type match struct {
+		index int
+		score int
+	}
+
+	var matches []match
+	for i, item := range p.Items {
+		if score, ok := utils.FuzzyScore(p.Query, item.Label); ok {
+			matches = append(matches, match{index: i, score: score})
+		}
+	}
+
+	// An empty query keeps the original order.
+	if p.Query != "" {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].score > matches[j].score
+		})
+	}
+
+	p.filtered = p.filtered[:0]
+	for _, m := range matches {
+		p.filtered = append(p.filtered, m.index)
+	}
+
+	p.Selected = 0
+}
+
+func (p *Picker) HandleEventKey(ek *tcell.EventKey) {
+	if action, ok := p.Actions[ek.Key()]; ok {
+		p.editor.ClosePopup()
+		if p.Selected < len(p.filtered) {
+			action(p.Items[p.filtered[p.Selected]])
+		}
+		return
+	}
+
+	switch ek.Key() {
+	case tcell.KeyEscape:
+		p.editor.ClosePopup()
+	case tcell.KeyEnter:
+		p.editor.ClosePopup()
+		if p.Selected < len(p.filtered) && p.OnSelect != nil {
+			p.OnSelect(p.Items[p.filtered[p.Selected]])
+		}
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		if p.Selected > 0 {
+			p.Selected--
+		}
+	case tcell.KeyDown, tcell.KeyCtrlN, tcell.KeyTab:
+		if p.Selected < len(p.filtered)-1 {
+			p.Selected++
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(p.Query) > 0 {
+			runes := []rune(p.Query)
+			p.Query = string(runes[:len(runes)-1])
+			p.filter()
+		}
+	case tcell.KeyRune:
+		p.Query += string(ek.Rune())
+		p.filter()
+	}
+}
+
+func (p *Picker) Draw() {
+	w, h := p.screen.Size()
+
+	width := min(60, w-4)
+	height := min(len(p.Items)+4, h-4)
+	if width < 10 || height < 4 {
+		return
+	}
+
+	x := (w - width) / 2
+	y := (h - height) / 2
+
+	drawBox(p.screen, x, y, width, height, p.Title, p.editor.AccentStyle(), p.style)
+	drawText(p.screen, x+2, y+1, width-4, "> "+p.Query, p.style)
+
+	rows := height - 3
+	offset := 0
+	if p.Selected >= rows {
+		offset = p.Selected - rows + 1
+	}
+
+	for i := 0; i < rows && offset+i < len(p.filtered); i++ {
+		item := p.Items[p.filtered[offset+i]]
+
+		style := p.style
+		if offset+i == p.Selected {
+			style = p.selStyle
+		}
+
+		fillRow(p.screen, x+1, y+2+i, width-2, style)
+		n := drawText(p.screen, x+2, y+2+i, width-4, item.Label, style)
+		if item.Detail != "" {
+			drawText(p.screen, x+3+n, y+2+i, width-5-n, item.Detail, style.Dim(true))
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// mysqlOperations are the keys of MySQL's FORMAT=JSON plans that stand for
+// a step of the plan, in the order they are read from an object.
+var mysqlOperations = []struct{ key, label string }{
+	{"query_block", "Query block"},
+	{"union_result", "Union"},
+	{"windowing", "Window"},
+	{"grouping_operation", "Group"},
+	{"duplicates_removal", "Distinct"},
+	{"ordering_operation", "Order"},
+	{"buffer_result", "Buffer"},
+	{"nested_loop", "Nested loop"},
+	{"table", ""},
+	{"materialized_from_subquery", "Materialize"},
+}
+
+// mysqlNested are keys holding more steps without being a step themselves.
+var mysqlNested = []string{"query_specifications", "attached_subqueries", "optimized_away_subqueries", "having_subqueries", "order_by_subqueries", "select_list_subqueries"}
+
+var mysqlAccess = map[string]string{
+	"ALL":         "Table scan",
+	"index":       "Full index scan",
+	"range":       "Index range scan",
+	"ref":         "Index lookup",
+	"eq_ref":      "Unique index lookup",
+	"ref_or_null": "Index lookup or null",
+	"const":       "Constant row",
+	"system":      "System row",
+	"fulltext":    "Fulltext index",
+	"index_merge": "Index merge",
+}
+
+func parseMySQL(data string) (*Plan, error) {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, err
+	}
+
+	nodes := mysqlNodes(doc)
+	if len(nodes) == 0 {
+		return nil, ErrEmpty
+	}
+
+	return &Plan{Root: nodes[0]}, nil
+}
+
+// mysqlNodes reads the steps an object of the plan holds.
+func mysqlNodes(obj map[string]any) []*Node {
+	var nodes []*Node
+	for _, op := range mysqlOperations {
+		v := obj[op.key]
+		if v == nil {
+			continue
+		}
+
+		switch {
+		case op.key == "table":
+			if t, ok := v.(map[string]any); ok {
+				nodes = append(nodes, mysqlTable(t))
+			}
+		case op.key == "nested_loop":
+			n := &Node{Label: op.label}
+			for _, item := range list(v) {
+				n.Children = append(n.Children, mysqlNodes(item)...)
+			}
+			n.Cost = childCost(n)
+			nodes = append(nodes, n)
+		default:
+			child, _ := v.(map[string]any)
+			n := &Node{Label: op.label, Children: mysqlNodes(child)}
+			mysqlOperation(n, op.key, child)
+			nodes = append(nodes, n)
+		}
+	}
+
+	for _, key := range mysqlNested {
+		for _, item := range list(obj[key]) {
+			nodes = append(nodes, mysqlNodes(item)...)
+		}
+	}
+
+	return nodes
+}
+
+// mysqlOperation fills in what a step other than a table access tells.
+func mysqlOperation(n *Node, key string, obj map[string]any) {
+	n.Cost = childCost(n)
+
+	switch key {
+	case "query_block":
+		if id, ok := obj["select_id"]; ok {
+			n.Label += fmt.Sprintf(" #%v", id)
+		}
+		if cost, ok := number(costInfo(obj)["query_cost"]); ok {
+			n.Cost = max(cost, n.Cost)
+		}
+	case "ordering_operation", "grouping_operation":
+		if obj["using_filesort"] == true {
+			n.Label = "Sort"
+		}
+	}
+
+	if obj["using_temporary_table"] == true {
+		n.Details = append(n.Details, "Using temporary table")
+	}
+}
+
+func mysqlTable(t map[string]any) *Node {
+	access, _ := t["access_type"].(string)
+	label, ok := mysqlAccess[access]
+	if !ok {
+		label = access + " access"
+	}
+
+	n := &Node{Label: fmt.Sprintf("%s on %v", label, t["table_name"])}
+	if key, ok := t["key"].(string); ok {
+		n.Label += " using " + key
+	}
+
+	n.Rows, _ = number(t["rows_produced_per_join"])
+
+	if rows, ok := number(t["rows_examined_per_scan"]); ok {
+		n.Details = append(n.Details, fmt.Sprintf("Rows examined per scan: %.0f", rows))
+	}
+	if filtered, ok := number(t["filtered"]); ok && filtered < 100 {
+		n.Details = append(n.Details, fmt.Sprintf("Filtered: %g%%", filtered))
+	}
+	if cond, ok := t["attached_condition"].(string); ok {
+		n.Details = append(n.Details, "Condition: "+cond)
+	}
+
+	n.Children = mysqlNodes(map[string]any{
+		"materialized_from_subquery": t["materialized_from_subquery"],
+		"attached_subqueries":        t["attached_subqueries"],
+	})
+
+	read, _ := number(costInfo(t)["read_cost"])
+	eval, _ := number(costInfo(t)["eval_cost"])
+	n.Cost = read + eval + childCost(n)
+
+	return n
+}
+
+func costInfo(obj map[string]any) map[string]any {
+	info, _ := obj["cost_info"].(map[string]any)
+	return info
+}
+
+// childCost adds up the costs of the children of n.
+func childCost(n *Node) float64 {
+	cost := 0.0
+	for _, c := range n.Children {
+		cost += c.Cost
+	}
+
+	return cost
+}
+
+// list returns the objects of a JSON array, or v itself if it is an object.
+func list(v any) []map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		return []map[string]any{v}
+	case []any:
+		var objs []map[string]any
+		for _, item := range v {
+			if obj, ok := item.(map[string]any); ok {
+				objs = append(objs, obj)
+			}
+		}
+		return objs
+	}
+
+	return nil
+}
+
+// number reads a JSON number, or a string holding one as MySQL writes its
+// costs.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
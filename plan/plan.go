@@ -0,0 +1,135 @@
+// Package plan runs statements through the EXPLAIN of their dialect and
+// reads the plans back as trees of nodes with their costs and timings.
+package plan
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+// Node is a step of a plan. Costs and times include the node's children.
+type Node struct {
+	Label   string
+	Details []string // conditions, keys and the like
+
+	Cost float64 // estimated, in the planner's units, 0 when unknown
+	Rows float64 // estimated rows out
+
+	// Set when the plan was analyzed, i.e. the statement ran.
+	Analyzed   bool
+	ActualRows float64 // rows out, per loop
+	Time       float64 // milliseconds, over all loops
+	Loops      float64
+
+	Buffers    bool // the block counts are known
+	HitBlocks  int64
+	ReadBlocks int64
+
+	Children []*Node
+}
+
+// Plan is what EXPLAIN returned for a statement.
+type Plan struct {
+	Root     *Node
+	Analyzed bool
+
+	// Milliseconds spent planning and running the statement, when the
+	// server reports them.
+	PlanningTime  float64
+	ExecutionTime float64
+}
+
+var (
+	ErrUnsupported = errors.New("EXPLAIN isn't available for this connection type")
+	ErrNoAnalyze   = errors.New("EXPLAIN ANALYZE isn't available for this connection type")
+	ErrEmpty       = errors.New("EXPLAIN returned no plan")
+)
+
+// Statement wraps stmt in the EXPLAIN of dialect d, asking for a plan that
+// can be parsed. Analyzing runs the statement.
+func Statement(d query.Dialect, stmt string, analyze bool) (string, error) {
+	switch {
+	case d == query.Postgres && analyze:
+		return "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) " + stmt, nil
+	case d == query.Postgres:
+		return "EXPLAIN (FORMAT JSON) " + stmt, nil
+	case d == query.MySQL && analyze:
+		// MySQL only analyzes into its tree format.
+		return "EXPLAIN ANALYZE " + stmt, nil
+	case d == query.MySQL:
+		return "EXPLAIN FORMAT=JSON " + stmt, nil
+	case d == query.SQLite && analyze:
+		return "", ErrNoAnalyze
+	case d == query.SQLite:
+		return "EXPLAIN QUERY PLAN " + stmt, nil
+	default:
+		return "", ErrUnsupported
+	}
+}
+
+// Parse reads the result of the statement made by Statement.
+func Parse(d query.Dialect, res *db.Result, analyze bool) (*Plan, error) {
+	if res == nil || len(res.Rows) == 0 || len(res.Rows[0]) == 0 {
+		return nil, ErrEmpty
+	}
+
+	var p *Plan
+	var err error
+	switch {
+	case d == query.Postgres:
+		p, err = parsePostgres(text(res.Rows[0][0]))
+	case d == query.MySQL && analyze:
+		p, err = parseText(text(res.Rows[0][0]))
+	case d == query.MySQL:
+		p, err = parseMySQL(text(res.Rows[0][0]))
+	case d == query.SQLite:
+		p, err = parseSQLite(res)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading the plan: %w", err)
+	}
+
+	p.Analyzed = analyze
+	return p, nil
+}
+
+func text(v any) string {
+	if j, ok := v.(db.JSON); ok {
+		return string(j)
+	}
+
+	return db.FormatValue(v)
+}
+
+// Total is what the plan's nodes are weighed by: the time of the root when
+// analyzed, its cost otherwise.
+func (p *Plan) Total() float64 {
+	if p.Analyzed {
+		return p.Root.Time
+	}
+
+	return p.Root.Cost
+}
+
+// Self is the time, or cost when not analyzed, spent in the node itself,
+// leaving out its children.
+func (n *Node) Self(analyzed bool) float64 {
+	weight := func(n *Node) float64 {
+		if analyzed {
+			return n.Time
+		}
+		return n.Cost
+	}
+
+	self := weight(n)
+	for _, c := range n.Children {
+		self -= weight(c)
+	}
+
+	return max(0, self)
+}
@@ -0,0 +1,165 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+// outline lists the labels of the tree, indented by depth.
+func outline(n *Node, depth int) string {
+	s := strings.Repeat("  ", depth) + n.Label + "\n"
+	for _, c := range n.Children {
+		s += outline(c, depth+1)
+	}
+
+	return s
+}
+
+func TestStatement(t *testing.T) {
+	tests := []struct {
+		dialect query.Dialect
+		analyze bool
+		want    string
+		err     error
+	}{
+		{dialect: query.Postgres, want: "EXPLAIN (FORMAT JSON) SELECT 1"},
+		{dialect: query.Postgres, analyze: true, want: "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) SELECT 1"},
+		{dialect: query.MySQL, want: "EXPLAIN FORMAT=JSON SELECT 1"},
+		{dialect: query.MySQL, analyze: true, want: "EXPLAIN ANALYZE SELECT 1"},
+		{dialect: query.SQLite, want: "EXPLAIN QUERY PLAN SELECT 1"},
+		{dialect: query.SQLite, analyze: true, err: ErrNoAnalyze},
+		{dialect: query.Redis, err: ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		got, err := Statement(tt.dialect, "SELECT 1", tt.analyze)
+		if got != tt.want || err != tt.err {
+			t.Errorf("Statement(%s, %v) = %q, %v, want %q, %v", tt.dialect, tt.analyze, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParsePostgres(t *testing.T) {
+	data := db.JSON(`[{
+		"Plan": {
+			"Node Type": "Hash Join", "Join Type": "Left", "Total Cost": 60.5, "Plan Rows": 100,
+			"Actual Total Time": 2.5, "Actual Rows": 90, "Actual Loops": 1,
+			"Shared Hit Blocks": 12, "Shared Read Blocks": 3,
+			"Hash Cond": "(o.user_id = u.id)",
+			"Plans": [
+				{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Total Cost": 40, "Plan Rows": 100,
+				 "Actual Total Time": 1.5, "Actual Rows": 90, "Actual Loops": 1, "Filter": "(amount > 100)", "Rows Removed by Filter": 10},
+				{"Node Type": "Hash", "Total Cost": 10, "Plan Rows": 5, "Actual Total Time": 0.1, "Actual Rows": 5, "Actual Loops": 1,
+				 "Plans": [{"Node Type": "Index Scan", "Index Name": "users_pkey", "Relation Name": "users", "Alias": "users",
+				            "Total Cost": 10, "Plan Rows": 5, "Actual Total Time": 0.02, "Actual Rows": 1, "Actual Loops": 5}]}
+			]
+		},
+		"Planning Time": 0.2,
+		"Execution Time": 2.7
+	}]`)
+
+	p, err := Parse(query.Postgres, &db.Result{Rows: [][]any{{data}}}, true)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := "Hash Left Join\n  Seq Scan on orders o\n  Hash\n    Index Scan using users_pkey on users\n"
+	if got := outline(p.Root, 0); got != want {
+		t.Errorf("got plan\n%s\nwant\n%s", got, want)
+	}
+
+	if p.PlanningTime != 0.2 || p.ExecutionTime != 2.7 || p.Total() != 2.5 {
+		t.Errorf("got planning %v, execution %v and total %v", p.PlanningTime, p.ExecutionTime, p.Total())
+	}
+
+	root, scan := p.Root, p.Root.Children[0]
+	if !root.Buffers || root.HitBlocks != 12 || root.ReadBlocks != 3 || root.Details[0] != "Hash Cond: (o.user_id = u.id)" {
+		t.Errorf("got root %+v", root)
+	}
+	if scan.Self(true) != 1.5 || root.Self(true) != 0.9 || strings.Join(scan.Details, "; ") != "Filter: (amount > 100); Rows Removed by Filter: 10" {
+		t.Errorf("got self %v and %v, details %q", scan.Self(true), root.Self(true), scan.Details)
+	}
+
+	// Time is over all loops.
+	if index := p.Root.Children[1].Children[0]; index.Time != 0.1 || index.Loops != 5 {
+		t.Errorf("got index scan time %v over %v loops", index.Time, index.Loops)
+	}
+}
+
+func TestParseMySQL(t *testing.T) {
+	data := `{
+		"query_block": {
+			"select_id": 1,
+			"cost_info": {"query_cost": "12.50"},
+			"ordering_operation": {
+				"using_filesort": true,
+				"nested_loop": [
+					{"table": {"table_name": "o", "access_type": "ALL", "rows_examined_per_scan": 100, "rows_produced_per_join": 33,
+					           "filtered": "33.33", "cost_info": {"read_cost": "8.00", "eval_cost": "1.00"}, "attached_condition": "(o.amount > 100)"}},
+					{"table": {"table_name": "u", "access_type": "eq_ref", "key": "PRIMARY", "rows_examined_per_scan": 1,
+					           "rows_produced_per_join": 33, "cost_info": {"read_cost": "2.00", "eval_cost": "1.50"}}}
+				]
+			}
+		}
+	}`
+
+	p, err := Parse(query.MySQL, &db.Result{Rows: [][]any{{data}}}, false)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := "Query block #1\n  Sort\n    Nested loop\n      Table scan on o\n      Unique index lookup on u using PRIMARY\n"
+	if got := outline(p.Root, 0); got != want {
+		t.Errorf("got plan\n%s\nwant\n%s", got, want)
+	}
+
+	scan := p.Root.Children[0].Children[0].Children[0]
+	if p.Total() != 12.5 || scan.Cost != 9 || scan.Rows != 33 || len(scan.Details) != 3 {
+		t.Errorf("got total %v, scan %+v", p.Total(), scan)
+	}
+}
+
+func TestParseMySQLAnalyze(t *testing.T) {
+	tree := "-> Filter: (t.n > 1)  (cost=1.25 rows=3) (actual time=0.050..0.200 rows=2 loops=1)\n" +
+		"    -> Table scan on t  (cost=1.25 rows=10) (actual time=0.040..0.150 rows=10 loops=1)\n"
+
+	p, err := Parse(query.MySQL, &db.Result{Rows: [][]any{{tree}}}, true)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if got := outline(p.Root, 0); got != "Filter: (t.n > 1)\n  Table scan on t\n" {
+		t.Errorf("got plan\n%s", got)
+	}
+
+	scan := p.Root.Children[0]
+	if !scan.Analyzed || scan.Time != 0.15 || scan.ActualRows != 10 || scan.Cost != 1.25 || scan.Rows != 10 {
+		t.Errorf("got scan %+v", scan)
+	}
+}
+
+func TestParseSQLite(t *testing.T) {
+	res := &db.Result{Rows: [][]any{
+		{int64(2), int64(0), int64(0), "SCAN o"},
+		{int64(5), int64(0), int64(0), "SEARCH u USING INTEGER PRIMARY KEY (rowid=?)"},
+		{int64(9), int64(0), int64(0), "USE TEMP B-TREE FOR ORDER BY"},
+		{int64(12), int64(5), int64(0), "CORRELATED SCALAR SUBQUERY 1"},
+	}}
+
+	p, err := Parse(query.SQLite, res, false)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := "Query plan\n  SCAN o\n  SEARCH u USING INTEGER PRIMARY KEY (rowid=?)\n    CORRELATED SCALAR SUBQUERY 1\n  USE TEMP B-TREE FOR ORDER BY\n"
+	if got := outline(p.Root, 0); got != want {
+		t.Errorf("got plan\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := Parse(query.SQLite, &db.Result{}, false); err != ErrEmpty {
+		t.Errorf("got error %v for no rows, want ErrEmpty", err)
+	}
+}
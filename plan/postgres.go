@@ -0,0 +1,143 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// pgNode is a node of a Postgres plan in FORMAT JSON.
+type pgNode struct {
+	NodeType     string  `json:"Node Type"`
+	JoinType     string  `json:"Join Type"`
+	Strategy     string  `json:"Strategy"`
+	RelationName string  `json:"Relation Name"`
+	Alias        string  `json:"Alias"`
+	IndexName    string  `json:"Index Name"`
+	CTEName      string  `json:"CTE Name"`
+	TotalCost    float64 `json:"Total Cost"`
+	PlanRows     float64 `json:"Plan Rows"`
+
+	ActualTotalTime *float64 `json:"Actual Total Time"`
+	ActualRows      float64  `json:"Actual Rows"`
+	ActualLoops     float64  `json:"Actual Loops"`
+
+	SharedHitBlocks  *int64 `json:"Shared Hit Blocks"`
+	SharedReadBlocks int64  `json:"Shared Read Blocks"`
+
+	Filter          string   `json:"Filter"`
+	IndexCond       string   `json:"Index Cond"`
+	RecheckCond     string   `json:"Recheck Cond"`
+	HashCond        string   `json:"Hash Cond"`
+	MergeCond       string   `json:"Merge Cond"`
+	JoinFilter      string   `json:"Join Filter"`
+	SortKey         []string `json:"Sort Key"`
+	GroupKey        []string `json:"Group Key"`
+	RemovedByFilter *float64 `json:"Rows Removed by Filter"`
+
+	Plans []pgNode `json:"Plans"`
+}
+
+type pgPlan struct {
+	Plan          pgNode  `json:"Plan"`
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+}
+
+func parsePostgres(data string) (*Plan, error) {
+	var plans []pgPlan
+	if err := json.Unmarshal([]byte(data), &plans); err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, ErrEmpty
+	}
+
+	return &Plan{
+		Root:          plans[0].Plan.node(),
+		PlanningTime:  plans[0].PlanningTime,
+		ExecutionTime: plans[0].ExecutionTime,
+	}, nil
+}
+
+func (p pgNode) node() *Node {
+	n := &Node{
+		Label: p.label(),
+		Cost:  p.TotalCost,
+		Rows:  p.PlanRows,
+	}
+
+	if p.ActualTotalTime != nil {
+		n.Analyzed = true
+		n.ActualRows = p.ActualRows
+		n.Loops = p.ActualLoops
+		n.Time = *p.ActualTotalTime * max(1, p.ActualLoops)
+	}
+
+	if p.SharedHitBlocks != nil {
+		n.Buffers = true
+		n.HitBlocks, n.ReadBlocks = *p.SharedHitBlocks, p.SharedReadBlocks
+	}
+
+	for _, d := range []struct{ name, value string }{
+		{"Index Cond", p.IndexCond},
+		{"Recheck Cond", p.RecheckCond},
+		{"Hash Cond", p.HashCond},
+		{"Merge Cond", p.MergeCond},
+		{"Join Filter", p.JoinFilter},
+		{"Filter", p.Filter},
+		{"Sort Key", strings.Join(p.SortKey, ", ")},
+		{"Group Key", strings.Join(p.GroupKey, ", ")},
+	} {
+		if d.value != "" {
+			n.Details = append(n.Details, d.name+": "+d.value)
+		}
+	}
+	if p.RemovedByFilter != nil {
+		n.Details = append(n.Details, fmt.Sprintf("Rows Removed by Filter: %.0f", *p.RemovedByFilter))
+	}
+
+	for _, c := range p.Plans {
+		n.Children = append(n.Children, c.node())
+	}
+
+	return n
+}
+
+// label names the node the way Postgres' text format does, e.g.
+// "Hash Left Join" or "Index Scan using users_pkey on users u".
+func (p pgNode) label() string {
+	label := p.NodeType
+	if p.Strategy != "" && p.NodeType == "Aggregate" {
+		switch p.Strategy {
+		case "Hashed":
+			label = "HashAggregate"
+		case "Sorted":
+			label = "GroupAggregate"
+		}
+	}
+
+	if p.JoinType != "" && p.JoinType != "Inner" {
+		if strings.HasSuffix(label, "Join") {
+			label = strings.TrimSuffix(label, "Join") + p.JoinType + " Join"
+		} else {
+			label += " " + p.JoinType + " Join"
+		}
+	}
+
+	if p.IndexName != "" {
+		label += " using " + p.IndexName
+	}
+
+	switch {
+	case p.RelationName != "":
+		label += " on " + p.RelationName
+		if p.Alias != "" && p.Alias != p.RelationName {
+			label += " " + p.Alias
+		}
+	case p.CTEName != "":
+		label += " on " + p.CTEName
+	}
+
+	return label
+}
@@ -0,0 +1,130 @@
+package plan
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+var (
+	textCost   = regexp.MustCompile(`\(cost=([\d.]+)(?:\.\.([\d.]+))? rows=([\d.e+]+)\)`)
+	textActual = regexp.MustCompile(`\(actual time=([\d.]+)\.\.([\d.]+) rows=([\d.e+]+) loops=(\d+)\)`)
+)
+
+// parseText reads a plan written as an indented tree, as MySQL's EXPLAIN
+// ANALYZE does, where each step starts with "-> " and is indented under the
+// step it feeds:
+//
+//	-> Filter: (t.n > 1)  (cost=1.25 rows=3) (actual time=0.1..0.2 rows=2 loops=1)
+//	    -> Table scan on t  (cost=1.25 rows=10) (actual time=0.1..0.2 rows=10 loops=1)
+func parseText(text string) (*Plan, error) {
+	type open struct {
+		indent int
+		node   *Node
+	}
+
+	var roots []*Node
+	var stack []open
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		// Lines not starting a step carry on the one above.
+		if !strings.HasPrefix(trimmed, "->") && len(stack) > 0 {
+			parent := stack[len(stack)-1].node
+			parent.Details = append(parent.Details, trimmed)
+			continue
+		}
+
+		n := textNode(strings.TrimSpace(strings.TrimPrefix(trimmed, "->")))
+		if len(stack) == 0 {
+			roots = append(roots, n)
+		} else {
+			parent := stack[len(stack)-1].node
+			parent.Children = append(parent.Children, n)
+		}
+		stack = append(stack, open{indent: indent, node: n})
+	}
+
+	switch len(roots) {
+	case 0:
+		return nil, ErrEmpty
+	case 1:
+		return &Plan{Root: roots[0]}, nil
+	}
+
+	root := &Node{Label: "Plan", Children: roots}
+	for _, r := range roots {
+		root.Cost += r.Cost
+		root.Time += r.Time
+	}
+	return &Plan{Root: root}, nil
+}
+
+// textNode reads a step of a text plan, taking its figures out of the
+// label.
+func textNode(line string) *Node {
+	n := &Node{}
+
+	if m := textActual.FindStringSubmatch(line); m != nil {
+		n.Analyzed = true
+		end, _ := strconv.ParseFloat(m[2], 64)
+		n.ActualRows, _ = strconv.ParseFloat(m[3], 64)
+		n.Loops, _ = strconv.ParseFloat(m[4], 64)
+		n.Time = end * max(1, n.Loops)
+		line = strings.Replace(line, m[0], "", 1)
+	}
+
+	if m := textCost.FindStringSubmatch(line); m != nil {
+		total := m[1]
+		if m[2] != "" {
+			total = m[2]
+		}
+		n.Cost, _ = strconv.ParseFloat(total, 64)
+		n.Rows, _ = strconv.ParseFloat(m[3], 64)
+		line = strings.Replace(line, m[0], "", 1)
+	}
+
+	n.Label = strings.TrimSpace(line)
+	return n
+}
+
+// parseSQLite builds the tree of EXPLAIN QUERY PLAN, whose rows are the
+// id of a step, the id of its parent, an unused column and the step.
+func parseSQLite(res *db.Result) (*Plan, error) {
+	root := &Node{Label: "Query plan"}
+	nodes := map[int64]*Node{0: root}
+
+	for _, row := range res.Rows {
+		if len(row) < 4 {
+			return nil, ErrEmpty
+		}
+
+		id, _ := row[0].(int64)
+		parent, _ := row[1].(int64)
+
+		n := &Node{Label: db.FormatValue(row[3])}
+		nodes[id] = n
+
+		p, ok := nodes[parent]
+		if !ok {
+			p = root
+		}
+		p.Children = append(p.Children, n)
+	}
+
+	if len(root.Children) == 1 {
+		root = root.Children[0]
+	}
+
+	return &Plan{Root: root}, nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ajm113/dbvi/plan"
+	"github.com/gdamore/tcell"
+)
+
+// Shares of the plan's time, or cost, above which a node is highlighted as
+// expensive.
+const (
+	hotShare  = 0.5
+	warmShare = 0.2
+)
+
+// planLine is a screen line of the plan view, a node or one of its details.
+type planLine struct {
+	node   *plan.Node
+	depth  int
+	detail string // set for the lines under a node
+}
+
+// PlanView is a popup showing the plan of a statement as a tree whose nodes
+// fold, with the most expensive ones highlighted.
+type PlanView struct {
+	Title string
+	Plan  *plan.Plan
+
+	folded   map[*plan.Node]bool
+	lines    []planLine
+	selected int
+	offset   int
+	height   int // lines visible, set by Draw
+
+	style    tcell.Style
+	dim      tcell.Style
+	selStyle tcell.Style
+	hot      tcell.Style
+	warm     tcell.Style
+
+	screen tcell.Screen
+	editor *Editor
+}
+
+func NewPlanView(editor *Editor, title string, p *plan.Plan) *PlanView {
+	v := &PlanView{
+		Title:    title,
+		Plan:     p,
+		folded:   map[*plan.Node]bool{},
+		style:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		dim:      tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack).Dim(true),
+		selStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+		hot:      tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack).Bold(true),
+		warm:     tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack),
+		screen:   editor.screen,
+		editor:   editor,
+	}
+	v.layout()
+
+	return v
+}
+
+// layout lists the lines of the nodes not folded away.
+func (v *PlanView) layout() {
+	v.lines = v.lines[:0]
+
+	var walk func(n *plan.Node, depth int)
+	walk = func(n *plan.Node, depth int) {
+		v.lines = append(v.lines, planLine{node: n, depth: depth})
+		if v.folded[n] {
+			return
+		}
+
+		for _, d := range n.Details {
+			v.lines = append(v.lines, planLine{node: n, depth: depth, detail: d})
+		}
+		for _, c := range n.Children {
+			walk(c, depth+1)
+		}
+	}
+	walk(v.Plan.Root, 0)
+
+	v.selected = max(0, min(v.selected, len(v.lines)-1))
+}
+
+func (v *PlanView) HandleEventKey(ek *tcell.EventKey) {
+	switch ek.Key() {
+	case tcell.KeyEscape:
+		v.editor.ClosePopup()
+	case tcell.KeyEnter:
+		v.toggle()
+	case tcell.KeyUp:
+		v.move(-1)
+	case tcell.KeyDown:
+		v.move(1)
+	case tcell.KeyPgUp:
+		v.move(-max(1, v.height))
+	case tcell.KeyPgDn:
+		v.move(max(1, v.height))
+	case tcell.KeyRune:
+		switch ek.Rune() {
+		case 'q':
+			v.editor.ClosePopup()
+		case 'k':
+			v.move(-1)
+		case 'j':
+			v.move(1)
+		case ' ', 'z':
+			v.toggle()
+		case 'h':
+			v.fold(true)
+		case 'l':
+			v.fold(false)
+		}
+	}
+}
+
+func (v *PlanView) move(delta int) {
+	v.selected = max(0, min(v.selected+delta, len(v.lines)-1))
+}
+
+// toggle folds the selected node away under its line, or unfolds it.
+func (v *PlanView) toggle() {
+	n := v.lines[v.selected].node
+	v.fold(!v.folded[n])
+}
+
+func (v *PlanView) fold(folded bool) {
+	n := v.lines[v.selected].node
+	if len(n.Children) == 0 && len(n.Details) == 0 {
+		return
+	}
+
+	v.folded[n] = folded
+	for v.lines[v.selected].detail != "" {
+		v.selected--
+	}
+	v.layout()
+}
+
+// summary describes the plan as a whole, e.g. "Planning 0.2ms  Execution 2.7ms".
+func (v *PlanView) summary() string {
+	var parts []string
+	if v.Plan.PlanningTime > 0 {
+		parts = append(parts, "Planning "+milliseconds(v.Plan.PlanningTime))
+	}
+	if v.Plan.ExecutionTime > 0 {
+		parts = append(parts, "Execution "+milliseconds(v.Plan.ExecutionTime))
+	}
+	if !v.Plan.Analyzed && v.Plan.Root.Cost > 0 {
+		parts = append(parts, fmt.Sprintf("Cost %.2f", v.Plan.Root.Cost))
+	}
+
+	return strings.Join(parts, "  ")
+}
+
+// nodeFigures are the estimated and actual figures of a node.
+func nodeFigures(n *plan.Node) string {
+	var parts []string
+	if n.Cost > 0 || n.Rows > 0 {
+		parts = append(parts, fmt.Sprintf("cost=%.2f rows=%.0f", n.Cost, n.Rows))
+	}
+	if n.Analyzed {
+		parts = append(parts, fmt.Sprintf("actual rows=%.0f loops=%.0f time=%s", n.ActualRows, n.Loops, milliseconds(n.Time)))
+	}
+	if n.Buffers {
+		parts = append(parts, fmt.Sprintf("buffers hit=%d read=%d", n.HitBlocks, n.ReadBlocks))
+	}
+
+	return strings.Join(parts, "  ")
+}
+
+func milliseconds(ms float64) string {
+	return fmt.Sprintf("%.3gms", ms)
+}
+
+// nodeStyle highlights the nodes taking up a large share of the plan.
+func (v *PlanView) nodeStyle(n *plan.Node) tcell.Style {
+	total := v.Plan.Total()
+	if total <= 0 {
+		return v.style
+	}
+
+	switch share := n.Self(v.Plan.Analyzed) / total; {
+	case share >= hotShare:
+		return v.hot
+	case share >= warmShare:
+		return v.warm
+	default:
+		return v.style
+	}
+}
+
+func (v *PlanView) Draw() {
+	w, h := v.screen.Size()
+
+	width := w - 4
+	height := min(len(v.lines)+5, h-4)
+	if width < 20 || height < 6 {
+		return
+	}
+
+	x := (w - width) / 2
+	y := (h - height) / 2
+
+	drawBox(v.screen, x, y, width, height, v.Title, v.editor.AccentStyle(), v.style)
+	drawText(v.screen, x+2, y+1, width-4, v.summary(), v.dim)
+
+	v.height = height - 4
+	if v.selected < v.offset {
+		v.offset = v.selected
+	}
+	if v.selected >= v.offset+v.height {
+		v.offset = v.selected - v.height + 1
+	}
+
+	for i := 0; i < v.height && v.offset+i < len(v.lines); i++ {
+		line := v.lines[v.offset+i]
+		row := y + 2 + i
+		indent := x + 2 + line.depth*2
+
+		style, dim := v.nodeStyle(line.node), v.dim
+		if v.offset+i == v.selected {
+			style, dim = v.selStyle, v.selStyle
+			fillRow(v.screen, x+1, row, width-2, style)
+		}
+
+		if line.detail != "" {
+			drawText(v.screen, indent+4, row, x+width-2-indent-4, line.detail, dim)
+			continue
+		}
+
+		marker := "  "
+		if len(line.node.Children) > 0 || len(line.node.Details) > 0 {
+			marker = "▾ "
+			if v.folded[line.node] {
+				marker = "▸ "
+			}
+		}
+
+		n := drawText(v.screen, indent, row, x+width-2-indent, marker+line.node.Label, style)
+		if figures := nodeFigures(line.node); figures != "" {
+			drawText(v.screen, indent+n+2, row, x+width-4-indent-n, figures, dim)
+		}
+	}
+
+	drawText(v.screen, x+2, y+height-2, width-4, "Enter to fold, j/k to move, q to close", v.dim)
+}
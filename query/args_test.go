@@ -0,0 +1,39 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitRedisArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+		err  error
+	}{
+		{line: "GET user:1", want: []string{"GET", "user:1"}},
+		{line: "  SET   a  b  ", want: []string{"SET", "a", "b"}},
+		{line: `SET greeting "hello world"`, want: []string{"SET", "greeting", "hello world"}},
+		{line: `SET k "line\nbreak\t\x41\"q\""`, want: []string{"SET", "k", "line\nbreak\tA\"q\""}},
+		{line: `SET k 'it\'s \n raw'`, want: []string{"SET", "k", `it's \n raw`}},
+		{line: `SET k ""`, want: []string{"SET", "k", ""}},
+		{line: `SET k pre"quoted part"`, want: []string{"SET", "k", "prequoted part"}},
+		{line: `SET k "\xZZ"`, want: []string{"SET", "k", "xZZ"}},
+		{line: "", want: nil},
+		{line: `SET k "unterminated`, err: ErrUnbalancedQuotes},
+		{line: `SET k 'unterminated`, err: ErrUnbalancedQuotes},
+		{line: `SET k "closed"trailing`, err: ErrUnbalancedQuotes},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitRedisArgs(tt.line)
+		if err != tt.err {
+			t.Errorf("SplitRedisArgs(%q): got error %v, want %v", tt.line, err, tt.err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitRedisArgs(%q): got %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package query
+
+import (
+	"fmt"
+)
+
+// Kind is what a statement does to the database.
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindRead
+	KindWrite
+	KindDDL
+	KindTransaction
+	KindSession
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindRead:
+		return "read"
+	case KindWrite:
+		return "write"
+	case KindDDL:
+		return "ddl"
+	case KindTransaction:
+		return "transaction"
+	case KindSession:
+		return "session"
+	default:
+		return "unknown"
+	}
+}
+
+var statementKinds = map[string]Kind{
+	"SELECT":   KindRead,
+	"SHOW":     KindRead,
+	"EXPLAIN":  KindRead,
+	"DESCRIBE": KindRead,
+	"DESC":     KindRead,
+	"VALUES":   KindRead,
+	"TABLE":    KindRead,
+	"FETCH":    KindRead,
+	"HELP":     KindRead,
+
+	"INSERT":  KindWrite,
+	"UPDATE":  KindWrite,
+	"DELETE":  KindWrite,
+	"MERGE":   KindWrite,
+	"REPLACE": KindWrite,
+	"UPSERT":  KindWrite,
+	"COPY":    KindWrite,
+	"LOAD":    KindWrite,
+	"LOCK":    KindWrite,
+	"HANDLER": KindWrite,
+
+	"CREATE":   KindDDL,
+	"ALTER":    KindDDL,
+	"DROP":     KindDDL,
+	"TRUNCATE": KindDDL,
+	"RENAME":   KindDDL,
+	"GRANT":    KindDDL,
+	"REVOKE":   KindDDL,
+	"COMMENT":  KindDDL,
+	"REINDEX":  KindDDL,
+	"VACUUM":   KindDDL,
+	"ANALYZE":  KindDDL,
+	"CLUSTER":  KindDDL,
+	"REFRESH":  KindDDL,
+	"OPTIMIZE": KindDDL,
+	"REPAIR":   KindDDL,
+	"IMPORT":   KindDDL,
+
+	"BEGIN":     KindTransaction,
+	"START":     KindTransaction,
+	"COMMIT":    KindTransaction,
+	"ROLLBACK":  KindTransaction,
+	"SAVEPOINT": KindTransaction,
+	"RELEASE":   KindTransaction,
+	"END":       KindTransaction,
+	"ABORT":     KindTransaction,
+
+	"SET":     KindSession,
+	"RESET":   KindSession,
+	"USE":     KindSession,
+	"DISCARD": KindSession,
+	"LISTEN":  KindSession,
+	"CLOSE":   KindSession,
+}
+
+// Classification is the result of classifying a statement.
+type Classification struct {
+	Kind    Kind
+	Keyword string // the keyword the kind was decided from
+}
+
+// Classify decides what a statement does from its leading keywords. CTEs are
+// looked through so "WITH x AS (...) DELETE ..." is a write.
+func Classify(stmt string, d Dialect) Classification {
+	if d == Redis {
+		return classifyRedis(stmt)
+	}
+
+	words := topLevelKeywords(stmt, d)
+	if len(words) == 0 {
+		return Classification{}
+	}
+
+	first := words[0]
+	if first == "WITH" {
+		return classifyWith(words)
+	}
+
+	kind, ok := statementKinds[first]
+	if !ok {
+		return Classification{Keyword: first}
+	}
+
+	switch first {
+	case "SELECT":
+		// SELECT ... INTO creates a table in Postgres and writes to
+		// variables or files in MySQL, FOR UPDATE/SHARE takes row locks.
+		for i, w := range words {
+			if w == "INTO" || (w == "FOR" && i+1 < len(words) && (words[i+1] == "UPDATE" || words[i+1] == "SHARE" || words[i+1] == "NO" || words[i+1] == "KEY")) {
+				return Classification{Kind: KindWrite, Keyword: "SELECT " + w}
+			}
+		}
+	case "COPY":
+		if contains(words, "TO") {
+			return Classification{Kind: KindRead, Keyword: first}
+		}
+	case "EXPLAIN":
+		// EXPLAIN ANALYZE runs the statement it explains.
+		if contains(words, "ANALYZE") || contains(words, "ANALYSE") {
+			inner := Classify(stripExplain(stmt, d), d)
+			if inner.Kind != KindRead {
+				return Classification{Kind: inner.Kind, Keyword: "EXPLAIN ANALYZE " + inner.Keyword}
+			}
+		}
+	case "SHOW", "DESCRIBE", "DESC":
+		// These are always safe, don't read into their arguments.
+	}
+
+	return Classification{Kind: kind, Keyword: first}
+}
+
+func classifyWith(words []string) Classification {
+	for _, w := range words[1:] {
+		switch statementKinds[w] {
+		case KindWrite, KindDDL:
+			return Classification{Kind: statementKinds[w], Keyword: "WITH ... " + w}
+		}
+	}
+
+	return Classification{Kind: KindRead, Keyword: "WITH"}
+}
+
+// topLevelKeywords returns the upper cased word tokens of stmt that aren't
+// nested in parentheses, except for the first word after an opening
+// parenthesis so data modifying CTEs are still visible.
+func topLevelKeywords(stmt string, d Dialect) []string {
+	var words []string
+
+	depth := 0
+	afterParen := false
+	for _, tok := range Lex(stmt, d) {
+		if !tok.Significant() {
+			continue
+		}
+
+		switch {
+		case tok.Kind == TokenPunct && tok.Text == "(":
+			depth++
+			afterParen = true
+			continue
+		case tok.Kind == TokenPunct && tok.Text == ")":
+			depth--
+		case tok.Kind == TokenWord && (depth == 0 || afterParen):
+			words = append(words, tok.Keyword())
+		}
+
+		afterParen = false
+	}
+
+	return words
+}
+
+func stripExplain(stmt string, d Dialect) string {
+	for _, tok := range Lex(stmt, d) {
+		switch tok.Keyword() {
+		case "SELECT", "INSERT", "UPDATE", "DELETE", "MERGE", "WITH", "VALUES", "CREATE", "REPLACE", "TABLE":
+			return stmt[tok.Offset:]
+		}
+	}
+
+	return ""
+}
+
+func contains(words []string, w string) bool {
+	for _, x := range words {
+		if x == w {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadOnlyViolation reports why stmt can't be sent on a read-only
+// connection. ok is false when the statement is allowed.
+func ReadOnlyViolation(stmt string, d Dialect) (reason string, ok bool) {
+	c := Classify(stmt, d)
+
+	// set_config changes settings from within any statement, a SELECT
+	// included.
+	if d != Redis {
+		for _, tok := range Lex(stmt, d) {
+			if tok.Kind == TokenWord && tok.Keyword() == "SET_CONFIG" {
+				return "set_config may turn the read-only mode off", true
+			}
+		}
+	}
+
+	switch c.Kind {
+	case KindRead:
+		return "", false
+	case KindTransaction:
+		// Opening a transaction is fine, but not one that turns the
+		// read-only mode of the session back off.
+		words := topLevelKeywords(stmt, d)
+		for i := 1; i < len(words); i++ {
+			if words[i-1] == "READ" && words[i] == "WRITE" {
+				return "changing the transaction read mode isn't allowed", true
+			}
+		}
+		return "", false
+	case KindWrite:
+		return fmt.Sprintf("%s modifies data", c.Keyword), true
+	case KindDDL:
+		return fmt.Sprintf("%s changes the schema", c.Keyword), true
+	case KindSession:
+		// SET, RESET and DISCARD can all put the read-only transaction
+		// mode back to off, directly or by resetting every setting.
+		switch c.Keyword {
+		case "SET", "RESET", "DISCARD":
+			return fmt.Sprintf("%s may turn the read-only mode off", c.Keyword), true
+		}
+		return "", false
+	}
+
+	if c.Keyword == "" {
+		return "statement couldn't be classified", true
+	}
+
+	return fmt.Sprintf("%s may modify data", c.Keyword), true
+}
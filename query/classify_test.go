@@ -0,0 +1,96 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		dialect Dialect
+		want    Kind
+	}{
+		{stmt: "select * from orders", dialect: Postgres, want: KindRead},
+		{stmt: "-- comment\nSELECT 1", dialect: Postgres, want: KindRead},
+		{stmt: "(SELECT 1) UNION (SELECT 2)", dialect: Postgres, want: KindRead},
+		{stmt: "SELECT * INTO backup FROM orders", dialect: Postgres, want: KindWrite},
+		{stmt: "SELECT * FROM orders FOR UPDATE", dialect: Postgres, want: KindWrite},
+		{stmt: "WITH recent AS (SELECT 1) SELECT * FROM recent", dialect: Postgres, want: KindRead},
+		{stmt: "WITH gone AS (DELETE FROM orders RETURNING *) SELECT * FROM gone", dialect: Postgres, want: KindWrite},
+		{stmt: "WITH x AS (SELECT id FROM a) UPDATE b SET y = 1", dialect: Postgres, want: KindWrite},
+		{stmt: "INSERT INTO orders VALUES (1)", dialect: MySQL, want: KindWrite},
+		{stmt: "replace into orders values (1)", dialect: MySQL, want: KindWrite},
+		{stmt: "COPY orders TO STDOUT", dialect: Postgres, want: KindRead},
+		{stmt: "COPY orders FROM STDIN", dialect: Postgres, want: KindWrite},
+		{stmt: "EXPLAIN SELECT 1", dialect: Postgres, want: KindRead},
+		{stmt: "EXPLAIN ANALYZE DELETE FROM orders", dialect: Postgres, want: KindWrite},
+		{stmt: "DROP TABLE orders", dialect: Postgres, want: KindDDL},
+		{stmt: "TRUNCATE orders", dialect: MySQL, want: KindDDL},
+		{stmt: "BEGIN", dialect: Postgres, want: KindTransaction},
+		{stmt: "SET search_path = public", dialect: Postgres, want: KindSession},
+		{stmt: "CALL archive_orders()", dialect: MySQL, want: KindUnknown},
+		{stmt: "GET user:1", dialect: Redis, want: KindRead},
+		{stmt: "hset user:1 name bob", dialect: Redis, want: KindWrite},
+		{stmt: "CONFIG GET maxmemory", dialect: Redis, want: KindRead},
+		{stmt: "CONFIG SET maxmemory 1gb", dialect: Redis, want: KindWrite},
+		{stmt: "SORT list", dialect: Redis, want: KindRead},
+		{stmt: "SORT list STORE dest", dialect: Redis, want: KindWrite},
+		{stmt: `"FLUSHALL"`, dialect: Redis, want: KindWrite},
+		{stmt: "'flushdb'", dialect: Redis, want: KindWrite},
+		{stmt: "GEORADIUS shops 15 37 200 km", dialect: Redis, want: KindRead},
+		{stmt: "GEORADIUS shops 15 37 200 km STORE near", dialect: Redis, want: KindWrite},
+		{stmt: "HGETDEL user:1 FIELDS 1 name", dialect: Redis, want: KindUnknown},
+		{stmt: "BF.ADD seen user:1", dialect: Redis, want: KindUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("classify: %s", tt.stmt), func(t *testing.T) {
+			if got := Classify(tt.stmt, tt.dialect).Kind; got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOnlyViolation(t *testing.T) {
+	tests := []struct {
+		stmt       string
+		dialect    Dialect
+		wantReason string
+		wantOk     bool
+	}{
+		{stmt: "SELECT 1", dialect: Postgres},
+		{stmt: "BEGIN", dialect: Postgres},
+		{stmt: "START TRANSACTION READ ONLY", dialect: MySQL},
+		{stmt: "BEGIN READ WRITE", dialect: Postgres, wantReason: "changing the transaction read mode isn't allowed", wantOk: true},
+		{stmt: "begin isolation level serializable, read write", dialect: Postgres, wantReason: "changing the transaction read mode isn't allowed", wantOk: true},
+		{stmt: "START TRANSACTION READ WRITE", dialect: MySQL, wantReason: "changing the transaction read mode isn't allowed", wantOk: true},
+		{stmt: "SET search_path = public", dialect: Postgres, wantReason: "SET may turn the read-only mode off", wantOk: true},
+		{stmt: "USE shop", dialect: MySQL},
+		{stmt: "DELETE FROM orders", dialect: Postgres, wantReason: "DELETE modifies data", wantOk: true},
+		{stmt: "ALTER TABLE orders ADD x int", dialect: MySQL, wantReason: "ALTER changes the schema", wantOk: true},
+		{stmt: "SET default_transaction_read_only = off", dialect: Postgres, wantReason: "SET may turn the read-only mode off", wantOk: true},
+		{stmt: "SET SESSION TRANSACTION READ WRITE", dialect: MySQL, wantReason: "SET may turn the read-only mode off", wantOk: true},
+		{stmt: "RESET ALL", dialect: Postgres, wantReason: "RESET may turn the read-only mode off", wantOk: true},
+		{stmt: "DISCARD ALL", dialect: Postgres, wantReason: "DISCARD may turn the read-only mode off", wantOk: true},
+		{stmt: "SELECT set_config('default_transaction_read_only', 'off', false)", dialect: Postgres, wantReason: "set_config may turn the read-only mode off", wantOk: true},
+		{stmt: "SELECT * FROM (SELECT SET_CONFIG('default_transaction_read_only', 'off', false)) s", dialect: Postgres, wantReason: "set_config may turn the read-only mode off", wantOk: true},
+		{stmt: "GET set_config", dialect: Redis},
+		{stmt: "CALL archive_orders()", dialect: MySQL, wantReason: "CALL may modify data", wantOk: true},
+		{stmt: "FLUSHALL", dialect: Redis, wantReason: "FLUSHALL modifies data", wantOk: true},
+		{stmt: "SCAN 0 MATCH user:*", dialect: Redis},
+		{stmt: `"FLUSHALL"`, dialect: Redis, wantReason: "FLUSHALL modifies data", wantOk: true},
+		{stmt: "BF.ADD seen user:1", dialect: Redis, wantReason: "BF.ADD may modify data", wantOk: true},
+		{stmt: `GET "user:1`, dialect: Redis, wantReason: "statement couldn't be classified", wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("read only: %s", tt.stmt), func(t *testing.T) {
+			reason, ok := ReadOnlyViolation(tt.stmt, tt.dialect)
+			if ok != tt.wantOk || reason != tt.wantReason {
+				t.Errorf("got (%q, %v), want (%q, %v)", reason, ok, tt.wantReason, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Destructive reports if stmt can wipe out data in one go and why: DELETE or
+// UPDATE without a WHERE clause, DROP, TRUNCATE, ALTER ... DROP, and Redis
+// FLUSHALL, FLUSHDB or DEL/UNLINK of wildcard patterns.
+func Destructive(stmt string, d Dialect) (reason string, ok bool) {
+	if d == Redis {
+		return destructiveRedis(stmt)
+	}
+
+	words := topLevelKeywords(stmt, d)
+	if len(words) == 0 {
+		return "", false
+	}
+
+	verb := words[0]
+	if verb == "WITH" {
+		// The statement after the CTEs is what runs against the table.
+		for _, w := range words[1:] {
+			if w == "DELETE" || w == "UPDATE" {
+				verb = w
+				break
+			}
+		}
+	}
+
+	switch verb {
+	case "DELETE", "UPDATE":
+		if !contains(words, "WHERE") {
+			return verb + " without WHERE", true
+		}
+	case "DROP":
+		if len(words) > 1 {
+			return "DROP " + words[1], true
+		}
+		return "DROP", true
+	case "TRUNCATE":
+		return "TRUNCATE", true
+	case "ALTER":
+		if contains(words, "DROP") {
+			return fmt.Sprintf("ALTER %s ... DROP", strings.Join(words[1:2], "")), true
+		}
+	}
+
+	return "", false
+}
+
+func destructiveRedis(line string) (string, bool) {
+	// A line the driver can't split isn't sent at all.
+	fields, err := SplitRedisArgs(line)
+	if err != nil || len(fields) == 0 {
+		return "", false
+	}
+
+	cmd := strings.ToUpper(fields[0])
+	switch cmd {
+	case "FLUSHALL", "FLUSHDB":
+		return cmd, true
+	case "DEL", "UNLINK":
+		for _, key := range fields[1:] {
+			if strings.ContainsAny(key, "*?[") {
+				return fmt.Sprintf("%s with wildcard %s", cmd, key), true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,43 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDestructive(t *testing.T) {
+	tests := []struct {
+		stmt       string
+		dialect    Dialect
+		wantReason string
+	}{
+		{stmt: "DELETE FROM orders", dialect: Postgres, wantReason: "DELETE without WHERE"},
+		{stmt: "delete from orders where id = 1", dialect: Postgres},
+		{stmt: "DELETE FROM orders WHERE id IN (SELECT id FROM old)", dialect: Postgres},
+		{stmt: "UPDATE orders SET total = (SELECT 1 WHERE true)", dialect: MySQL, wantReason: "UPDATE without WHERE"},
+		{stmt: "UPDATE orders SET total = 0 -- WHERE id = 1", dialect: MySQL, wantReason: "UPDATE without WHERE"},
+		{stmt: "UPDATE orders SET note = 'WHERE' WHERE id = 1", dialect: MySQL},
+		{stmt: "WITH old AS (SELECT id FROM orders WHERE x) DELETE FROM orders", dialect: Postgres, wantReason: "DELETE without WHERE"},
+		{stmt: "DROP TABLE orders", dialect: Postgres, wantReason: "DROP TABLE"},
+		{stmt: "TRUNCATE orders", dialect: MySQL, wantReason: "TRUNCATE"},
+		{stmt: "ALTER TABLE orders DROP COLUMN total", dialect: Postgres, wantReason: "ALTER TABLE ... DROP"},
+		{stmt: "ALTER TABLE orders ADD COLUMN total int", dialect: Postgres},
+		{stmt: "SELECT * FROM orders", dialect: Postgres},
+		{stmt: "FLUSHALL", dialect: Redis, wantReason: "FLUSHALL"},
+		{stmt: "flushdb async", dialect: Redis, wantReason: "FLUSHDB"},
+		{stmt: `"FLUSHALL"`, dialect: Redis, wantReason: "FLUSHALL"},
+		{stmt: "'flushdb' async", dialect: Redis, wantReason: "FLUSHDB"},
+		{stmt: `DEL "session:*"`, dialect: Redis, wantReason: "DEL with wildcard session:*"},
+		{stmt: "DEL session:*", dialect: Redis, wantReason: "DEL with wildcard session:*"},
+		{stmt: "DEL session:1 session:2", dialect: Redis},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("destructive: %s", tt.stmt), func(t *testing.T) {
+			reason, ok := Destructive(tt.stmt, tt.dialect)
+			if ok != (tt.wantReason != "") || reason != tt.wantReason {
+				t.Errorf("got (%q, %v), want %q", reason, ok, tt.wantReason)
+			}
+		})
+	}
+}
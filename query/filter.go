@@ -0,0 +1,369 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+// Filter is a condition on the rows of a result, written like a WHERE
+// clause: status = 'failed' and amount > 100. It supports comparisons,
+// [NOT] LIKE and ILIKE, IS [NOT] NULL, AND, OR, NOT and parentheses.
+type Filter struct {
+	text string
+	root filterNode
+}
+
+// filterNode is a part of a filter evaluated against a row.
+type filterNode interface {
+	eval(row []any) bool
+}
+
+// operand is a column of the row or a literal.
+type operand struct {
+	col   int // -1 for literals
+	value any
+}
+
+func (o operand) get(row []any) any {
+	if o.col < 0 {
+		return o.value
+	}
+
+	return row[o.col]
+}
+
+type andNode struct{ left, right filterNode }
+type orNode struct{ left, right filterNode }
+type notNode struct{ node filterNode }
+
+func (n andNode) eval(row []any) bool { return n.left.eval(row) && n.right.eval(row) }
+func (n orNode) eval(row []any) bool  { return n.left.eval(row) || n.right.eval(row) }
+func (n notNode) eval(row []any) bool { return !n.node.eval(row) }
+
+// compareNode compares two operands, comparisons with NULL are false.
+type compareNode struct {
+	left, right operand
+	op          string
+}
+
+func (n compareNode) eval(row []any) bool {
+	a, b := n.left.get(row), n.right.get(row)
+	if a == nil || b == nil {
+		return false
+	}
+
+	c := db.Compare(a, b)
+	switch n.op {
+	case "=":
+		return c == 0
+	case "!=", "<>":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+type nullNode struct {
+	operand operand
+	not     bool
+}
+
+func (n nullNode) eval(row []any) bool {
+	return (n.operand.get(row) == nil) != n.not
+}
+
+type likeNode struct {
+	operand operand
+	pattern *regexp.Regexp
+	not     bool
+}
+
+func (n likeNode) eval(row []any) bool {
+	v := n.operand.get(row)
+	if v == nil {
+		return false
+	}
+
+	return n.pattern.MatchString(db.FormatValue(v)) != n.not
+}
+
+// ParseFilter parses text as a filter on rows with columns. Columns are
+// matched by name, ignoring case.
+func ParseFilter(text string, columns []db.Column) (*Filter, error) {
+	p := &filterParser{columns: columns}
+	for _, tok := range Lex(text, Postgres) {
+		if tok.Significant() {
+			p.tokens = append(p.tokens, tok)
+		}
+	}
+	p.joinOperators()
+
+	if len(p.tokens) == 0 {
+		return nil, errors.New("empty filter")
+	}
+
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.tokens[p.pos].Text)
+	}
+
+	return &Filter{text: strings.TrimSpace(text), root: root}, nil
+}
+
+// Match reports if row passes the filter.
+func (f *Filter) Match(row []any) bool {
+	return f.root.eval(row)
+}
+
+func (f *Filter) String() string {
+	return f.text
+}
+
+type filterParser struct {
+	tokens  []Token
+	pos     int
+	columns []db.Column
+}
+
+// joinOperators merges the punctuation of two character operators like <=
+// that the lexer emits a character at a time.
+func (p *filterParser) joinOperators() {
+	var tokens []Token
+	for _, tok := range p.tokens {
+		if n := len(tokens); n > 0 && tok.Kind == TokenPunct && tokens[n-1].Kind == TokenPunct && tokens[n-1].Offset+len(tokens[n-1].Text) == tok.Offset {
+			switch tokens[n-1].Text + tok.Text {
+			case "<=", ">=", "<>", "!=":
+				tokens[n-1].Text += tok.Text
+				continue
+			}
+		}
+		tokens = append(tokens, tok)
+	}
+
+	p.tokens = tokens
+}
+
+func (p *filterParser) peek() Token {
+	if p.pos >= len(p.tokens) {
+		return Token{}
+	}
+
+	return p.tokens[p.pos]
+}
+
+// accept consumes the next token if it is the keyword or punctuation want.
+func (p *filterParser) accept(want string) bool {
+	tok := p.peek()
+	if tok.Keyword() == want || (tok.Kind == TokenPunct && tok.Text == want) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *filterParser) or() (filterNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) and() (filterNode, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) not() (filterNode, error) {
+	if p.accept("NOT") {
+		node, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+
+	if p.accept("(") {
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("missing )")
+		}
+		return node, nil
+	}
+
+	return p.condition()
+}
+
+// condition parses a comparison, LIKE or IS NULL test of an operand.
+func (p *filterParser) condition() (filterNode, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.accept("IS") {
+		not := p.accept("NOT")
+		if !p.accept("NULL") {
+			return nil, errors.New("expected NULL after IS")
+		}
+		return nullNode{operand: left, not: not}, nil
+	}
+
+	not := p.accept("NOT")
+	switch {
+	case p.accept("LIKE"):
+		return p.like(left, not, false)
+	case p.accept("ILIKE"):
+		return p.like(left, not, true)
+	case not:
+		return nil, errors.New("expected LIKE after NOT")
+	}
+
+	op := p.peek()
+	switch op.Text {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+		p.pos++
+	case "":
+		return nil, errors.New("expected a comparison at the end")
+	default:
+		return nil, fmt.Errorf("expected a comparison, got %s", op.Text)
+	}
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	return compareNode{left: left, right: right, op: op.Text}, nil
+}
+
+func (p *filterParser) like(left operand, not, fold bool) (filterNode, error) {
+	tok := p.peek()
+	if tok.Kind != TokenString {
+		return nil, errors.New("expected a pattern after LIKE")
+	}
+	p.pos++
+
+	pattern := likePattern(unquoteString(tok.Text))
+	if fold {
+		pattern = "(?i)" + pattern
+	}
+
+	return likeNode{operand: left, pattern: regexp.MustCompile(pattern), not: not}, nil
+}
+
+// likePattern translates a LIKE pattern, where % matches any text and _ a
+// single character, to an anchored regular expression.
+func likePattern(like string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range like {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+
+	return "(?s)" + sb.String()
+}
+
+func (p *filterParser) operand() (operand, error) {
+	tok := p.peek()
+	p.pos++
+
+	switch {
+	case tok.Kind == TokenString:
+		return operand{col: -1, value: unquoteString(tok.Text)}, nil
+	case tok.Kind == TokenNumber:
+		if n, err := strconv.ParseInt(tok.Text, 10, 64); err == nil {
+			return operand{col: -1, value: n}, nil
+		}
+		f, err := strconv.ParseFloat(tok.Text, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid number %s", tok.Text)
+		}
+		return operand{col: -1, value: f}, nil
+	case tok.Kind == TokenPunct && tok.Text == "-" && p.peek().Kind == TokenNumber:
+		o, err := p.operand()
+		switch v := o.value.(type) {
+		case int64:
+			o.value = -v
+		case float64:
+			o.value = -v
+		}
+		return o, err
+	case tok.Keyword() == "NULL":
+		return operand{col: -1}, nil
+	case tok.Keyword() == "TRUE", tok.Keyword() == "FALSE":
+		return operand{col: -1, value: tok.Keyword() == "TRUE"}, nil
+	case tok.Kind == TokenWord, tok.Kind == TokenQuotedIdent:
+		name := tok.Text
+		if tok.Kind == TokenQuotedIdent {
+			name = unquoteIdent(tok, Postgres)
+		}
+		for i, c := range p.columns {
+			if strings.EqualFold(c.Name, name) {
+				return operand{col: i}, nil
+			}
+		}
+		return operand{}, fmt.Errorf("no column %s", name)
+	case tok.Text == "":
+		return operand{}, errors.New("unexpected end of filter")
+	default:
+		return operand{}, fmt.Errorf("unexpected %s", tok.Text)
+	}
+}
+
+// unquoteString returns the text of a quoted string literal.
+func unquoteString(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		s = s[1 : len(s)-1]
+	}
+
+	return strings.ReplaceAll(s, "''", "'")
+}
@@ -0,0 +1,87 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+func TestParseFilter(t *testing.T) {
+	columns := []db.Column{{Name: "id"}, {Name: "Status"}, {Name: "amount"}, {Name: "note"}}
+	rows := [][]any{
+		{int64(1), "failed", db.Decimal("250.00"), "card declined"},
+		{int64(2), "failed", db.Decimal("20.00"), nil},
+		{int64(3), "paid", db.Decimal("120.50"), "It's fine"},
+		{int64(4), nil, int64(-5), ""},
+	}
+
+	tests := []struct {
+		filter string
+		want   []int64 // ids of the rows matching
+	}{
+		{filter: "status = 'failed' and amount > 100", want: []int64{1}},
+		{filter: "STATUS = 'failed' OR amount >= 120.5", want: []int64{1, 2, 3}},
+		{filter: `"Status" <> 'failed'`, want: []int64{3}},
+		{filter: "status != 'failed'", want: []int64{3}},
+		{filter: "status is null", want: []int64{4}},
+		{filter: "note is not null and not (id <= 1)", want: []int64{3, 4}},
+		{filter: "amount < -1", want: []int64{4}},
+		{filter: "amount < '100'", want: []int64{2, 4}},
+		{filter: "note like 'card%'", want: []int64{1}},
+		{filter: "note not like '%d%'", want: []int64{3, 4}},
+		{filter: "note ilike 'it''s _ine'", want: []int64{3}},
+		{filter: "note = null", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := ParseFilter(tt.filter, columns)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+
+			var got []int64
+			for _, row := range rows {
+				if f.Match(row) {
+					got = append(got, row[0].(int64))
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("matched %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("matched %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	columns := []db.Column{{Name: "id"}}
+
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{filter: "", want: "empty filter"},
+		{filter: "name = 'a'", want: "no column name"},
+		{filter: "id", want: "expected a comparison at the end"},
+		{filter: "id = 1 and", want: "unexpected end of filter"},
+		{filter: "(id = 1", want: "missing )"},
+		{filter: "id = 1 id", want: "unexpected id"},
+		{filter: "id is 1", want: "expected NULL after IS"},
+		{filter: "id like 1", want: "expected a pattern after LIKE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := ParseFilter(tt.filter, columns)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("ParseFilter() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
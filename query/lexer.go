@@ -0,0 +1,222 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Dialect decides how statements are lexed, it matches the connection type
+// they are sent to.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	MySQL    Dialect = "mysql"
+	Redis    Dialect = "redis"
+	SQLite   Dialect = "sqlite"
+)
+
+type TokenKind int
+
+const (
+	TokenWhitespace TokenKind = iota
+	TokenComment
+	TokenWord
+	TokenQuotedIdent
+	TokenString
+	TokenNumber
+	TokenPunct
+)
+
+type Token struct {
+	Kind   TokenKind
+	Text   string
+	Offset int
+}
+
+// Keyword returns the upper cased text of a word token, or "" for any other
+// kind of token.
+func (t Token) Keyword() string {
+	if t.Kind != TokenWord {
+		return ""
+	}
+
+	return strings.ToUpper(t.Text)
+}
+
+// Significant reports if the token is something other than whitespace or a
+// comment.
+func (t Token) Significant() bool {
+	return t.Kind != TokenWhitespace && t.Kind != TokenComment
+}
+
+// Lex splits src into tokens. It never fails; unterminated strings and
+// comments run to the end of the input.
+func Lex(src string, d Dialect) []Token {
+	l := &lexer{src: src, dialect: d}
+	for l.pos < len(l.src) {
+		l.next()
+	}
+
+	return l.tokens
+}
+
+type lexer struct {
+	src     string
+	pos     int
+	dialect Dialect
+	tokens  []Token
+}
+
+func (l *lexer) emit(kind TokenKind, start int) {
+	l.tokens = append(l.tokens, Token{Kind: kind, Text: l.src[start:l.pos], Offset: start})
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+
+	return 0
+}
+
+func (l *lexer) next() {
+	start := l.pos
+	c := l.src[l.pos]
+
+	switch {
+	case isSpace(c):
+		for l.pos < len(l.src) && isSpace(l.src[l.pos]) {
+			l.pos++
+		}
+		l.emit(TokenWhitespace, start)
+	case c == '-' && l.peek(1) == '-', c == '#' && l.dialect == MySQL:
+		l.skipUntil("\n", false)
+		l.emit(TokenComment, start)
+	case c == '/' && l.peek(1) == '*':
+		l.pos += 2
+		l.skipUntil("*/", true)
+		l.emit(TokenComment, start)
+	case c == '\'':
+		l.lexQuoted('\'', l.dialect == MySQL)
+		l.emit(TokenString, start)
+	case (c == 'E' || c == 'e') && l.peek(1) == '\'' && l.dialect == Postgres:
+		l.pos++
+		l.lexQuoted('\'', true)
+		l.emit(TokenString, start)
+	case c == '"':
+		l.lexQuoted('"', l.dialect == MySQL)
+		if l.dialect == MySQL {
+			l.emit(TokenString, start)
+		} else {
+			l.emit(TokenQuotedIdent, start)
+		}
+	case c == '`':
+		l.lexQuoted('`', false)
+		l.emit(TokenQuotedIdent, start)
+	case c == '[' && l.dialect == SQLite:
+		// SQLite also accepts the [name] quoting of SQL Server.
+		l.skipUntil("]", true)
+		l.emit(TokenQuotedIdent, start)
+	case c == '$' && l.dialect == Postgres && l.lexDollarQuoted():
+		l.emit(TokenString, start)
+	case isDigit(c) || (c == '.' && isDigit(l.peek(1))):
+		l.lexNumber()
+		l.emit(TokenNumber, start)
+	case isWordStart(rune(c)) || c >= 0x80:
+		for l.pos < len(l.src) && (isWordPart(rune(l.src[l.pos])) || l.src[l.pos] >= 0x80) {
+			l.pos++
+		}
+		l.emit(TokenWord, start)
+	default:
+		l.pos++
+		l.emit(TokenPunct, start)
+	}
+}
+
+// skipUntil advances past the next occurrence of end, or to the end of the
+// input. include decides if end is consumed.
+func (l *lexer) skipUntil(end string, include bool) {
+	i := strings.Index(l.src[l.pos:], end)
+	if i < 0 {
+		l.pos = len(l.src)
+		return
+	}
+
+	l.pos += i
+	if include {
+		l.pos += len(end)
+	}
+}
+
+func (l *lexer) lexQuoted(quote byte, backslashEscapes bool) {
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\' && backslashEscapes:
+			l.pos += 2
+		case c == quote && l.peek(1) == quote:
+			l.pos += 2
+		case c == quote:
+			l.pos++
+			return
+		default:
+			l.pos++
+		}
+	}
+
+	l.pos = min(l.pos, len(l.src))
+}
+
+// lexDollarQuoted lexes a Postgres $tag$...$tag$ string. It returns false
+// and consumes nothing when the $ doesn't open one (e.g. a $1 parameter).
+func (l *lexer) lexDollarQuoted() bool {
+	end := l.pos + 1
+	for end < len(l.src) && l.src[end] != '$' {
+		if !isWordPart(rune(l.src[end])) || (end == l.pos+1 && isDigit(l.src[end])) {
+			return false
+		}
+		end++
+	}
+
+	if end >= len(l.src) {
+		return false
+	}
+
+	tag := l.src[l.pos : end+1]
+	l.pos = end + 1
+	l.skipUntil(tag, true)
+
+	return true
+}
+
+func (l *lexer) lexNumber() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case isDigit(c), c == '.':
+			l.pos++
+		case (c == 'e' || c == 'E') && (isDigit(l.peek(1)) || ((l.peek(1) == '-' || l.peek(1) == '+') && isDigit(l.peek(2)))):
+			l.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isWordPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		src     string
+		dialect Dialect
+		want    []string // significant tokens
+	}{
+		{
+			src:     "SELECT a, 'b;c' FROM t -- trailing; comment",
+			dialect: Postgres,
+			want:    []string{"SELECT", "a", ",", "'b;c'", "FROM", "t"},
+		},
+		{
+			src:     "SELECT 'it''s', E'\\'q', \"Col\"",
+			dialect: Postgres,
+			want:    []string{"SELECT", "'it''s'", ",", "E'\\'q'", ",", "\"Col\""},
+		},
+		{
+			src:     "DO $body$ BEGIN; END $body$",
+			dialect: Postgres,
+			want:    []string{"DO", "$body$ BEGIN; END $body$"},
+		},
+		{
+			src:     "SELECT $1::int, 1.5e-3",
+			dialect: Postgres,
+			want:    []string{"SELECT", "$", "1", ":", ":", "int", ",", "1.5e-3"},
+		},
+		{
+			src:     "SELECT `order`, 'a\\'b' # comment",
+			dialect: MySQL,
+			want:    []string{"SELECT", "`order`", ",", "'a\\'b'"},
+		},
+		{
+			src:     "SELECT /* ; */ 1",
+			dialect: MySQL,
+			want:    []string{"SELECT", "1"},
+		},
+		{
+			src:     "SELECT [order;id], `x` FROM t",
+			dialect: SQLite,
+			want:    []string{"SELECT", "[order;id]", ",", "`x`", "FROM", "t"},
+		},
+		{
+			src:     "SELECT 'unterminated",
+			dialect: Postgres,
+			want:    []string{"SELECT", "'unterminated"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("lex: %s", tt.src), func(t *testing.T) {
+			var got []string
+			for _, tok := range Lex(tt.src, tt.dialect) {
+				if tok.Significant() {
+					got = append(got, tok.Text)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Placeholder is a bind parameter written in a statement: a named
+// :customer_id, a numbered $1 or a positional ?.
+type Placeholder struct {
+	// Name identifies the value bound to the placeholder: "customer_id"
+	// for :customer_id, "$1" for $1, and "?1", "?2"... for the question
+	// marks in order. Placeholders sharing a name share a value.
+	Name   string
+	Offset int // of the placeholder in the statement
+	Len    int
+}
+
+// Positional reports if the placeholder is a $1 or a ?, which only have a
+// meaning within their statement.
+func (p Placeholder) Positional() bool {
+	return strings.HasPrefix(p.Name, "$") || strings.HasPrefix(p.Name, "?")
+}
+
+// Label is how the placeholder is shown when asking for its value, e.g.
+// ":customer_id", "$1" or "?2".
+func (p Placeholder) Label() string {
+	if p.Positional() {
+		return p.Name
+	}
+
+	return ":" + p.Name
+}
+
+// Placeholders finds the bind parameters of stmt, leaving out anything
+// inside strings, quoted identifiers and comments as well as the look-alikes
+// of dialect d: casts like ::int, slices like arr[lo:hi] and the ? operators
+// of Postgres' jsonb.
+func Placeholders(stmt string, d Dialect) []Placeholder {
+	if d == Redis {
+		return nil
+	}
+
+	var ps []Placeholder
+	questions := 0
+
+	tokens := Lex(stmt, d)
+	for i, tok := range tokens {
+		if tok.Kind != TokenPunct {
+			continue
+		}
+
+		var next Token
+		if i+1 < len(tokens) && tokens[i+1].Offset == tok.Offset+1 {
+			next = tokens[i+1]
+		}
+
+		switch tok.Text {
+		case ":":
+			if next.Kind != TokenWord || (i > 0 && adjoinsOperand(tokens[i-1])) {
+				continue
+			}
+			ps = append(ps, Placeholder{Name: next.Text, Offset: tok.Offset, Len: 1 + len(next.Text)})
+		case "$":
+			if next.Kind != TokenNumber || strings.Trim(next.Text, "0123456789") != "" {
+				continue
+			}
+			ps = append(ps, Placeholder{Name: "$" + next.Text, Offset: tok.Offset, Len: 1 + len(next.Text)})
+		case "?":
+			// In Postgres ? is also a jsonb operator, which follows an
+			// operand where a parameter can't.
+			if d == Postgres && followsOperand(tokens[:i]) {
+				continue
+			}
+			questions++
+			ps = append(ps, Placeholder{Name: fmt.Sprintf("?%d", questions), Offset: tok.Offset, Len: 1})
+		}
+	}
+
+	return ps
+}
+
+// adjoinsOperand reports if tok, right before a ":", makes it part of a cast
+// or a slice rather than a named parameter.
+func adjoinsOperand(tok Token) bool {
+	switch tok.Kind {
+	case TokenWord, TokenQuotedIdent, TokenString, TokenNumber:
+		return true
+	case TokenPunct:
+		return tok.Text == ":" || tok.Text == ")" || tok.Text == "]"
+	default:
+		return false
+	}
+}
+
+// followsOperand reports if the last significant token of tokens ends an
+// operand: a column, a value or a parenthesized expression.
+func followsOperand(tokens []Token) bool {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		tok := tokens[i]
+		switch {
+		case !tok.Significant():
+			continue
+		case tok.Kind == TokenWord:
+			return !reserved[tok.Keyword()]
+		case tok.Kind == TokenPunct:
+			return tok.Text == ")" || tok.Text == "]"
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+// Bind rewrites the placeholders ps of stmt into the ones the driver of
+// dialect d takes, $1, $2... for Postgres and ? for the others, and returns
+// the values to send along in their order. values holds the value of each
+// placeholder by name, missing ones are sent as NULL.
+func Bind(stmt string, d Dialect, ps []Placeholder, values map[string]any) (string, []any) {
+	var b strings.Builder
+	var args []any
+
+	last := 0
+	for i, bound := range bindings(d, ps) {
+		p := ps[i]
+		b.WriteString(stmt[last:p.Offset])
+		b.WriteString(bound)
+		last = p.Offset + p.Len
+
+		if len(args) < bindingNumber(d, ps, i) {
+			args = append(args, values[p.Name])
+		}
+	}
+	b.WriteString(stmt[last:])
+
+	return b.String(), args
+}
+
+// Unbind maps offset, in the statement Bind rewrote stmt into, back to
+// stmt. Offsets within a rewritten placeholder map to its start.
+func Unbind(d Dialect, ps []Placeholder, offset int) int {
+	shift := 0
+	for i, bound := range bindings(d, ps) {
+		p := ps[i]
+		start := p.Offset + shift
+		if offset < start {
+			break
+		}
+		if offset < start+len(bound) {
+			return p.Offset
+		}
+		shift += len(bound) - p.Len
+	}
+
+	return offset - shift
+}
+
+// bindings returns what Bind writes in place of each of ps.
+func bindings(d Dialect, ps []Placeholder) []string {
+	bound := make([]string, len(ps))
+	for i := range ps {
+		bound[i] = "?"
+		if d == Postgres {
+			bound[i] = fmt.Sprintf("$%d", bindingNumber(d, ps, i))
+		}
+	}
+
+	return bound
+}
+
+// bindingNumber is the position, counted from 1, of the value sent for the
+// i-th of ps. Postgres sends one value per name, the others one per
+// placeholder.
+func bindingNumber(d Dialect, ps []Placeholder, i int) int {
+	if d != Postgres {
+		return i + 1
+	}
+
+	var names []string
+	for _, p := range ps[:i+1] {
+		if !slices.Contains(names, p.Name) {
+			names = append(names, p.Name)
+		}
+	}
+
+	return slices.Index(names, ps[i].Name) + 1
+}
@@ -0,0 +1,89 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		stmt      string
+		dialect   Dialect
+		wantNames []string
+	}{
+		{stmt: "SELECT * FROM orders WHERE customer_id = :customer_id AND status = :status", dialect: Postgres, wantNames: []string{"customer_id", "status"}},
+		{stmt: "SELECT * FROM orders WHERE id = $1 OR parent = $1 LIMIT $2", dialect: Postgres, wantNames: []string{"$1", "$1", "$2"}},
+		{stmt: "INSERT INTO t VALUES (?, ?)", dialect: MySQL, wantNames: []string{"?1", "?2"}},
+		{stmt: "SELECT ':a', \"?\" -- :b ?\n/* $1 */ FROM t WHERE n = :n", dialect: SQLite, wantNames: []string{"n"}},
+		{stmt: "SELECT total::numeric, tags[1:2], tags[lo:hi] FROM t", dialect: Postgres},
+		{stmt: "SELECT $$ :a $1 $$, data ? 'key', (data) ?| array['a'] FROM t WHERE id = ?", dialect: Postgres, wantNames: []string{"?1"}},
+		{stmt: "SELECT data ? 'key' FROM t", dialect: MySQL, wantNames: []string{"?1"}},
+		{stmt: "SET @a := 1", dialect: MySQL},
+		{stmt: "GET user:1", dialect: Redis},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("placeholders: %s", tt.stmt), func(t *testing.T) {
+			var names []string
+			for _, p := range Placeholders(tt.stmt, tt.dialect) {
+				names = append(names, p.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("got %q, want %q", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	values := map[string]any{"id": "7", "$1": "a", "?1": "x", "?2": nil}
+
+	tests := []struct {
+		stmt     string
+		dialect  Dialect
+		wantText string
+		wantArgs []any
+	}{
+		{stmt: "SELECT :id, :id, $1", dialect: Postgres, wantText: "SELECT $1, $1, $2", wantArgs: []any{"7", "a"}},
+		{stmt: "SELECT ?, ?", dialect: Postgres, wantText: "SELECT $1, $2", wantArgs: []any{"x", nil}},
+		{stmt: "SELECT :id, :id, ?", dialect: MySQL, wantText: "SELECT ?, ?, ?", wantArgs: []any{"7", "7", "x"}},
+		{stmt: "SELECT :id||':id'", dialect: SQLite, wantText: "SELECT ?||':id'", wantArgs: []any{"7"}},
+		{stmt: "SELECT 1", dialect: SQLite, wantText: "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("bind: %s", tt.stmt), func(t *testing.T) {
+			text, args := Bind(tt.stmt, tt.dialect, Placeholders(tt.stmt, tt.dialect), values)
+			if text != tt.wantText || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("got (%q, %v), want (%q, %v)", text, args, tt.wantText, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestUnbind(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		dialect Dialect
+		bound   string // the text at the offset in the bound statement
+		want    int
+	}{
+		{stmt: "SELECT :id, :id FROM t", dialect: Postgres, bound: "FROM", want: 16},
+		{stmt: "SELECT :id, :id FROM t", dialect: Postgres, bound: "1, $1", want: 7},
+		{stmt: "SELECT * FROM t WHERE a = :a AND b = ? ORDER", dialect: MySQL, bound: "ORDER", want: 39},
+		{stmt: "SELECT 1", dialect: SQLite, bound: "1", want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			ps := Placeholders(tt.stmt, tt.dialect)
+			text, _ := Bind(tt.stmt, tt.dialect, ps, nil)
+
+			if got := Unbind(tt.dialect, ps, strings.Index(text, tt.bound)); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
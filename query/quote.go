@@ -0,0 +1,133 @@
+package query
+
+import (
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+// reserved are the keywords most likely to be used as column names that
+// can't appear unquoted as identifiers.
+var reserved = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "BETWEEN": true,
+	"BY": true, "CASE": true, "CHECK": true, "COLUMN": true, "CONSTRAINT": true,
+	"CREATE": true, "CROSS": true, "DEFAULT": true, "DELETE": true, "DESC": true,
+	"DISTINCT": true, "DROP": true, "ELSE": true, "END": true, "EXISTS": true,
+	"FALSE": true, "FOR": true, "FOREIGN": true, "FROM": true, "GROUP": true,
+	"HAVING": true, "IN": true, "INDEX": true, "INNER": true, "INSERT": true,
+	"INTO": true, "IS": true, "JOIN": true, "KEY": true, "LEFT": true,
+	"LIKE": true, "LIMIT": true, "NOT": true, "NULL": true, "OFFSET": true,
+	"ON": true, "OR": true, "ORDER": true, "OUTER": true, "PRIMARY": true,
+	"REFERENCES": true, "RIGHT": true, "SELECT": true, "SET": true, "TABLE": true,
+	"THEN": true, "TO": true, "TRUE": true, "UNION": true, "UNIQUE": true,
+	"UPDATE": true, "USER": true, "USING": true, "VALUES": true, "WHEN": true,
+	"WHERE": true, "WITH": true,
+}
+
+// QuoteIdent quotes name as an identifier of dialect d when it has to be:
+// it isn't a plain word, is a reserved keyword, or, in Postgres, has upper
+// case letters that would be folded away. Dotted names like schema.table
+// are quoted part by part.
+func QuoteIdent(name string, d Dialect) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = quoteIdentPart(p, d)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func quoteIdentPart(name string, d Dialect) string {
+	if plainIdent(name, d) {
+		return name
+	}
+
+	if d == MySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func plainIdent(name string, d Dialect) bool {
+	if name == "" || reserved[strings.ToUpper(name)] {
+		return false
+	}
+
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+			if d == Postgres {
+				return false
+			}
+		case r >= '0' && r <= '9', r == '$':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Literal renders v, a value from db.Rows, as a SQL literal of dialect d.
+func Literal(v any, d Dialect) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return QuoteString(v, d)
+	case []byte:
+		if d == Postgres {
+			return `'\x` + hex.EncodeToString(v) + `'::bytea`
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	case bool:
+		switch {
+		case d == SQLite && v:
+			return "1"
+		case d == SQLite:
+			return "0"
+		case v:
+			return "TRUE"
+		default:
+			return "FALSE"
+		}
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return QuoteString(db.FormatValue(v), d)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case db.Decimal:
+		return string(v)
+	case db.Bits:
+		if d == SQLite {
+			return QuoteString(string(v), d)
+		}
+		return "b'" + string(v) + "'"
+	default:
+		// Dates, times, JSON and intervals are cast from their text.
+		return QuoteString(db.FormatValue(v), d)
+	}
+}
+
+// QuoteString quotes s as a string literal. MySQL treats backslashes as
+// escapes unless NO_BACKSLASH_ESCAPES is set, so they are doubled there.
+func QuoteString(s string, d Dialect) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	if d == MySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+
+	return "'" + s + "'"
+}
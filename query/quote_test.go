@@ -0,0 +1,59 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	tests := []struct {
+		name string
+		d    Dialect
+		want string
+	}{
+		{"users", Postgres, "users"},
+		{"public.users", Postgres, "public.users"},
+		{"Users", Postgres, `"Users"`},
+		{"Users", MySQL, "Users"},
+		{"order", MySQL, "`order`"},
+		{"first name", SQLite, `"first name"`},
+		{`we"ird`, Postgres, `"we""ird"`},
+		{"we`ird", MySQL, "`we``ird`"},
+		{"1st", SQLite, `"1st"`},
+	}
+
+	for _, tt := range tests {
+		if got := QuoteIdent(tt.name, tt.d); got != tt.want {
+			t.Errorf("%s %s: got %s, want %s", tt.d, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		v    any
+		d    Dialect
+		want string
+	}{
+		{nil, Postgres, "NULL"},
+		{"it's", Postgres, "'it''s'"},
+		{`C:\temp`, Postgres, `'C:\temp'`},
+		{`C:\temp`, MySQL, `'C:\\temp'`},
+		{[]byte{0xde, 0xad}, Postgres, `'\xdead'::bytea`},
+		{[]byte{0xde, 0xad}, MySQL, "X'dead'"},
+		{true, Postgres, "TRUE"},
+		{true, SQLite, "1"},
+		{int64(-3), MySQL, "-3"},
+		{1.5, SQLite, "1.5"},
+		{db.Decimal("12.50"), Postgres, "12.50"},
+		{db.Bits("101"), MySQL, "b'101'"},
+		{db.JSON(`{"a": "b'c"}`), Postgres, `'{"a": "b''c"}'`},
+	}
+
+	for _, tt := range tests {
+		if got := Literal(tt.v, tt.d); got != tt.want {
+			t.Errorf("%s %#v: got %s, want %s", tt.d, tt.v, got, tt.want)
+		}
+	}
+}
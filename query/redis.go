@@ -0,0 +1,169 @@
+package query
+
+import (
+	"strings"
+)
+
+// redisWriteCommands are commands that modify the keyspace or server state.
+var redisWriteCommands = map[string]bool{
+	"APPEND": true, "COPY": true, "DECR": true, "DECRBY": true, "DEL": true,
+	"EXPIRE": true, "EXPIREAT": true, "GETDEL": true, "GETEX": true, "GETSET": true,
+	"INCR": true, "INCRBY": true, "INCRBYFLOAT": true, "MOVE": true, "MSET": true,
+	"MSETNX": true, "PERSIST": true, "PEXPIRE": true, "PEXPIREAT": true, "PSETEX": true,
+	"RENAME": true, "RENAMENX": true, "RESTORE": true, "SET": true, "SETBIT": true,
+	"SETEX": true, "SETNX": true, "SETRANGE": true, "UNLINK": true, "MIGRATE": true,
+
+	"HDEL": true, "HINCRBY": true, "HINCRBYFLOAT": true, "HMSET": true, "HSET": true,
+	"HSETNX": true, "HEXPIRE": true, "HPERSIST": true,
+
+	"BLMOVE": true, "BLMPOP": true, "BLPOP": true, "BRPOP": true, "BRPOPLPUSH": true,
+	"LINSERT": true, "LMOVE": true, "LMPOP": true, "LPOP": true, "LPUSH": true,
+	"LPUSHX": true, "LREM": true, "LSET": true, "LTRIM": true, "RPOP": true,
+	"RPOPLPUSH": true, "RPUSH": true, "RPUSHX": true,
+
+	"SADD": true, "SDIFFSTORE": true, "SINTERSTORE": true, "SMOVE": true, "SPOP": true,
+	"SREM": true, "SUNIONSTORE": true,
+
+	"BZMPOP": true, "BZPOPMAX": true, "BZPOPMIN": true, "ZADD": true, "ZDIFFSTORE": true,
+	"ZINCRBY": true, "ZINTERSTORE": true, "ZMPOP": true, "ZPOPMAX": true, "ZPOPMIN": true,
+	"ZRANGESTORE": true, "ZREM": true, "ZREMRANGEBYLEX": true, "ZREMRANGEBYRANK": true,
+	"ZREMRANGEBYSCORE": true, "ZUNIONSTORE": true,
+
+	"XACK": true, "XADD": true, "XAUTOCLAIM": true, "XCLAIM": true, "XDEL": true,
+	"XGROUP": true, "XTRIM": true, "XREADGROUP": true, "XSETID": true,
+
+	"PFADD": true, "PFMERGE": true, "GEOADD": true, "GEOSEARCHSTORE": true,
+	"BITFIELD": true, "BITOP": true,
+
+	"JSON.SET": true, "JSON.DEL": true, "JSON.FORGET": true, "JSON.MSET": true,
+	"JSON.ARRAPPEND": true, "JSON.ARRINSERT": true, "JSON.ARRPOP": true,
+	"JSON.ARRTRIM": true, "JSON.NUMINCRBY": true, "JSON.STRAPPEND": true,
+	"JSON.CLEAR": true, "JSON.TOGGLE": true, "JSON.MERGE": true,
+
+	"FLUSHALL": true, "FLUSHDB": true, "SWAPDB": true, "PUBLISH": true, "SPUBLISH": true,
+	"EVAL": true, "EVALSHA": true, "FCALL": true, "FUNCTION": true, "SCRIPT": true,
+	"CONFIG": true, "DEBUG": true, "SHUTDOWN": true, "REPLICAOF": true, "SLAVEOF": true,
+	"FAILOVER": true, "BGSAVE": true, "BGREWRITEAOF": true, "SAVE": true, "MODULE": true,
+	"ACL": true, "CLUSTER": true, "CLIENT": true, "MULTI": true, "EXEC": true,
+}
+
+// redisReadSubcommands are read-only forms of otherwise writing commands.
+var redisReadSubcommands = map[string]bool{
+	"CONFIG GET":      true,
+	"SCRIPT EXISTS":   true,
+	"FUNCTION LIST":   true,
+	"FUNCTION DUMP":   true,
+	"FUNCTION STATS":  true,
+	"CLIENT LIST":     true,
+	"CLIENT INFO":     true,
+	"CLIENT GETNAME":  true,
+	"CLIENT ID":       true,
+	"CLUSTER INFO":    true,
+	"CLUSTER NODES":   true,
+	"CLUSTER SLOTS":   true,
+	"CLUSTER SHARDS":  true,
+	"ACL WHOAMI":      true,
+	"ACL LIST":        true,
+	"ACL USERS":       true,
+	"ACL CAT":         true,
+	"XGROUP HELP":     true,
+	"FUNCTION HELP":   true,
+	"SCRIPT HELP":     true,
+	"CONFIG HELP":     true,
+	"CLIENT HELP":     true,
+	"CLUSTER HELP":    true,
+	"ACL HELP":        true,
+	"DEBUG HELP":      true,
+	"CLUSTER MYID":    true,
+	"CLUSTER KEYSLOT": true,
+	"MEMORY USAGE":    true,
+	"MEMORY STATS":    true,
+	"MEMORY DOCTOR":   true,
+	"SLOWLOG GET":     true,
+	"SLOWLOG LEN":     true,
+}
+
+// redisReadCommands are the commands known not to modify the keyspace or
+// server state. Anything not listed here or in redisReadSubcommands may
+// write, module commands included.
+var redisReadCommands = map[string]bool{
+	"DBSIZE": true, "DUMP": true, "EXISTS": true, "EXPIRETIME": true, "KEYS": true,
+	"OBJECT": true, "PEXPIRETIME": true, "PTTL": true, "RANDOMKEY": true, "SCAN": true,
+	"TOUCH": true, "TTL": true, "TYPE": true,
+
+	"BITCOUNT": true, "BITFIELD_RO": true, "BITPOS": true, "GET": true, "GETBIT": true,
+	"GETRANGE": true, "LCS": true, "MGET": true, "STRLEN": true, "SUBSTR": true,
+
+	"HEXISTS": true, "HEXPIRETIME": true, "HGET": true, "HGETALL": true, "HKEYS": true,
+	"HLEN": true, "HMGET": true, "HPEXPIRETIME": true, "HPTTL": true, "HRANDFIELD": true,
+	"HSCAN": true, "HSTRLEN": true, "HTTL": true, "HVALS": true,
+
+	"LINDEX": true, "LLEN": true, "LPOS": true, "LRANGE": true,
+
+	"SCARD": true, "SDIFF": true, "SINTER": true, "SINTERCARD": true, "SISMEMBER": true,
+	"SMEMBERS": true, "SMISMEMBER": true, "SRANDMEMBER": true, "SSCAN": true, "SUNION": true,
+
+	"ZCARD": true, "ZCOUNT": true, "ZDIFF": true, "ZINTER": true, "ZINTERCARD": true,
+	"ZLEXCOUNT": true, "ZMSCORE": true, "ZRANDMEMBER": true, "ZRANGE": true,
+	"ZRANGEBYLEX": true, "ZRANGEBYSCORE": true, "ZRANK": true, "ZREVRANGE": true,
+	"ZREVRANGEBYLEX": true, "ZREVRANGEBYSCORE": true, "ZREVRANK": true, "ZSCAN": true,
+	"ZSCORE": true, "ZUNION": true,
+
+	"XINFO": true, "XLEN": true, "XPENDING": true, "XRANGE": true, "XREAD": true,
+	"XREVRANGE": true,
+
+	"PFCOUNT": true, "GEODIST": true, "GEOHASH": true, "GEOPOS": true, "GEOSEARCH": true,
+	"GEORADIUS_RO": true, "GEORADIUSBYMEMBER_RO": true, "SORT_RO": true,
+	"EVAL_RO": true, "EVALSHA_RO": true, "FCALL_RO": true,
+
+	"JSON.ARRINDEX": true, "JSON.ARRLEN": true, "JSON.GET": true, "JSON.MGET": true,
+	"JSON.OBJKEYS": true, "JSON.OBJLEN": true, "JSON.RESP": true, "JSON.STRLEN": true,
+	"JSON.TYPE": true,
+
+	"AUTH": true, "COMMAND": true, "ECHO": true, "HELLO": true, "INFO": true,
+	"LASTSAVE": true, "PING": true, "ROLE": true, "SELECT": true, "TIME": true,
+}
+
+// redisStoringCommands read unless given a STORE option, which writes the
+// result to a key.
+var redisStoringCommands = map[string]bool{
+	"SORT": true, "GEORADIUS": true, "GEORADIUSBYMEMBER": true,
+}
+
+func classifyRedis(line string) Classification {
+	// Split the way the driver does, so quoted commands are seen as what
+	// is sent. A line it can't split isn't sent either.
+	args, err := SplitRedisArgs(line)
+	if err != nil || len(args) == 0 {
+		return Classification{}
+	}
+
+	cmd := strings.ToUpper(args[0])
+	if len(args) > 1 && redisReadSubcommands[cmd+" "+strings.ToUpper(args[1])] {
+		return Classification{Kind: KindRead, Keyword: cmd + " " + strings.ToUpper(args[1])}
+	}
+
+	switch {
+	case redisStoringCommands[cmd]:
+		if containsFold(args[1:], "STORE") || containsFold(args[1:], "STOREDIST") {
+			return Classification{Kind: KindWrite, Keyword: cmd + " STORE"}
+		}
+		return Classification{Kind: KindRead, Keyword: cmd}
+	case redisReadCommands[cmd]:
+		return Classification{Kind: KindRead, Keyword: cmd}
+	case redisWriteCommands[cmd]:
+		return Classification{Kind: KindWrite, Keyword: cmd}
+	}
+
+	return Classification{Keyword: cmd}
+}
+
+func containsFold(words []string, w string) bool {
+	for _, x := range words {
+		if strings.EqualFold(x, w) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,226 @@
+package query
+
+import "strings"
+
+// Source is the table a SELECT reads its rows from, when every row of the
+// result is a row of that table.
+type Source struct {
+	Schema string // "" when the table isn't qualified
+	Table  string
+
+	// Columns holds the table column each result column shows, "" for
+	// expressions. It is nil for SELECT *, whose result columns are the
+	// table's.
+	Columns []string
+}
+
+// Name is the table's name quoted for dialect d, qualified by its schema
+// when it was.
+func (s Source) Name(d Dialect) string {
+	if s.Schema == "" {
+		return quoteIdentPart(s.Table, d)
+	}
+
+	return quoteIdentPart(s.Schema, d) + "." + quoteIdentPart(s.Table, d)
+}
+
+// rowChanging are the keywords that make the rows of a SELECT something
+// other than rows of the table it reads.
+var rowChanging = map[string]bool{
+	"DISTINCT": true, "GROUP": true, "HAVING": true, "WINDOW": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "INTO": true,
+	"JOIN": true, "NATURAL": true, "LATERAL": true,
+}
+
+// fromEnds are the clauses that may follow the table of a SELECT.
+var fromEnds = map[string]bool{
+	"WHERE": true, "ORDER": true, "LIMIT": true, "OFFSET": true,
+	"FETCH": true, "FOR": true,
+}
+
+// SourceTable finds the table stmt selects from, for statements whose rows
+// can be written back: a single table without joins, grouping, DISTINCT or
+// set operations.
+func SourceTable(stmt string, d Dialect) (Source, bool) {
+	if d == Redis {
+		return Source{}, false
+	}
+
+	var tokens []Token
+	for _, tok := range Lex(stmt, d) {
+		if tok.Significant() {
+			tokens = append(tokens, tok)
+		}
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].Text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	if len(tokens) == 0 || tokens[0].Keyword() != "SELECT" {
+		return Source{}, false
+	}
+
+	// Split the statement into the select list and what follows FROM,
+	// looking only at the top level.
+	from, end := -1, len(tokens)
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.Text == "(":
+			depth++
+		case tok.Text == ")":
+			depth--
+		case depth > 0:
+		case rowChanging[tok.Keyword()], tok.Text == ";":
+			return Source{}, false
+		case tok.Keyword() == "FROM" && from < 0:
+			from = i
+		case fromEnds[tok.Keyword()] && from >= 0 && end == len(tokens):
+			end = i
+		}
+	}
+
+	if from < 0 {
+		return Source{}, false
+	}
+
+	src, ok := sourceName(tokens[from+1:end], d)
+	if !ok {
+		return Source{}, false
+	}
+
+	items := splitItems(tokens[1:from])
+	for i, item := range items {
+		if star(item) {
+			if len(items) > 1 {
+				return Source{}, false
+			}
+			return src, true
+		}
+
+		if src.Columns == nil {
+			src.Columns = make([]string, len(items))
+		}
+		src.Columns[i] = columnName(item, d)
+	}
+
+	return src, true
+}
+
+// sourceName reads "[schema.]table [[AS] alias]".
+func sourceName(tokens []Token, d Dialect) (Source, bool) {
+	var src Source
+	switch {
+	case len(tokens) > 0 && tokens[0].Keyword() == "ONLY":
+		return Source{}, false
+	case len(tokens) >= 3 && ident(tokens[0]) && tokens[1].Text == "." && ident(tokens[2]):
+		src = Source{Schema: unquoteIdent(tokens[0], d), Table: unquoteIdent(tokens[2], d)}
+		tokens = tokens[3:]
+	case len(tokens) >= 1 && ident(tokens[0]):
+		src = Source{Table: unquoteIdent(tokens[0], d)}
+		tokens = tokens[1:]
+	default:
+		return Source{}, false
+	}
+
+	if len(tokens) > 0 && tokens[0].Keyword() == "AS" {
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && ident(tokens[0]) {
+		tokens = tokens[1:]
+	}
+
+	return src, len(tokens) == 0
+}
+
+// splitItems splits a select list at its top level commas.
+func splitItems(tokens []Token) [][]Token {
+	var items [][]Token
+
+	depth, start := 0, 0
+	for i, tok := range tokens {
+		switch tok.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				items = append(items, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(items, tokens[start:])
+}
+
+// star reports if a select list item is * or alias.*.
+func star(item []Token) bool {
+	switch len(item) {
+	case 1:
+		return item[0].Text == "*"
+	case 3:
+		return ident(item[0]) && item[1].Text == "." && item[2].Text == "*"
+	}
+
+	return false
+}
+
+// columnName returns the table column a select list item shows, "" if it
+// is an expression. Aliases don't matter, results are matched by position.
+func columnName(item []Token, d Dialect) string {
+	if len(item) >= 3 && ident(item[0]) && item[1].Text == "." {
+		item = item[2:]
+	}
+
+	if len(item) == 0 || !ident(item[0]) {
+		return ""
+	}
+
+	switch rest := item[1:]; {
+	case len(rest) == 0:
+	case len(rest) == 1 && ident(rest[0]):
+	case len(rest) == 2 && rest[0].Keyword() == "AS" && ident(rest[1]):
+	default:
+		return ""
+	}
+
+	return unquoteIdent(item[0], d)
+}
+
+// ident reports if tok can be an identifier, which leaves out the keywords
+// that can't.
+func ident(tok Token) bool {
+	switch tok.Kind {
+	case TokenQuotedIdent:
+		return true
+	case TokenWord:
+		return !reserved[tok.Keyword()]
+	}
+
+	return false
+}
+
+// unquoteIdent returns the name an identifier token stands for. Postgres
+// folds unquoted names to lower case.
+func unquoteIdent(tok Token, d Dialect) string {
+	text := tok.Text
+	if tok.Kind != TokenQuotedIdent {
+		if d == Postgres {
+			return strings.ToLower(text)
+		}
+		return text
+	}
+
+	if len(text) < 2 {
+		return text
+	}
+
+	open, inner := text[0], text[1:len(text)-1]
+	if open == '[' {
+		return inner
+	}
+
+	return strings.ReplaceAll(inner, string(open)+string(open), string(open))
+}
@@ -0,0 +1,55 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSourceTable(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		dialect Dialect
+		want    Source
+		wantOk  bool
+	}{
+		{stmt: "SELECT * FROM users", dialect: Postgres, want: Source{Table: "users"}, wantOk: true},
+		{stmt: "select u.* from Users u where id > 3 order by id limit 10;", dialect: Postgres, want: Source{Table: "users"}, wantOk: true},
+		{stmt: `SELECT * FROM public."Users"`, dialect: Postgres, want: Source{Schema: "public", Table: "Users"}, wantOk: true},
+		{stmt: "SELECT id, `e``mail` AS address, lower(name) FROM shop.users AS u", dialect: MySQL, want: Source{Schema: "shop", Table: "users", Columns: []string{"id", "e`mail", ""}}, wantOk: true},
+		{stmt: "SELECT u.id, [name] FROM users u", dialect: SQLite, want: Source{Table: "users", Columns: []string{"id", "name"}}, wantOk: true},
+		{stmt: "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders GROUP BY user_id)", dialect: Postgres, want: Source{Table: "users"}, wantOk: true},
+		{stmt: "SELECT * FROM users FOR UPDATE", dialect: Postgres, want: Source{Table: "users"}, wantOk: true},
+		{stmt: "SELECT * FROM users u JOIN orders o ON o.user_id = u.id", dialect: Postgres},
+		{stmt: "SELECT * FROM users, orders", dialect: Postgres},
+		{stmt: "SELECT DISTINCT name FROM users", dialect: Postgres},
+		{stmt: "SELECT name, count(*) FROM users GROUP BY name", dialect: Postgres},
+		{stmt: "SELECT * FROM users UNION SELECT * FROM admins", dialect: Postgres},
+		{stmt: "SELECT * FROM (SELECT 1) x", dialect: Postgres},
+		{stmt: "SELECT *, 1 FROM users", dialect: Postgres},
+		{stmt: "SELECT * FROM ONLY users", dialect: Postgres},
+		{stmt: "WITH x AS (SELECT 1) SELECT * FROM x", dialect: Postgres},
+		{stmt: "SELECT 1", dialect: Postgres},
+		{stmt: "UPDATE users SET name = 'a'", dialect: Postgres},
+		{stmt: "SELECT * FROM a; SELECT * FROM b", dialect: Postgres},
+		{stmt: "GET users", dialect: Redis},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			got, ok := SourceTable(tt.stmt, tt.dialect)
+			if ok != tt.wantOk || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestSourceName(t *testing.T) {
+	src := Source{Schema: "public", Table: "Users"}
+	if got := src.Name(Postgres); got != `public."Users"` {
+		t.Errorf("got %s, want public.\"Users\"", got)
+	}
+	if got := (Source{Table: "order"}).Name(MySQL); got != "`order`" {
+		t.Errorf("got %s, want `order`", got)
+	}
+}
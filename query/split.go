@@ -0,0 +1,171 @@
+package query
+
+import "strings"
+
+// Statement is a single statement of a larger text, without its terminator.
+type Statement struct {
+	Text   string
+	Offset int // byte offset of Text within the source
+	Line   int // zero based line Text starts on
+
+	// Detached is set for a statement that isn't part of the source, e.g.
+	// one run again from the history. Offset and Line mean nothing then.
+	Detached bool
+}
+
+// Split breaks src into statements separated by semicolons, ignoring those
+// inside strings, quoted identifiers and comments. Postgres' $$ bodies lex
+// as strings, the BEGIN ... END blocks of CREATE statements are kept whole,
+// and MySQL scripts can change the terminator with a DELIMITER line. Redis
+// has no terminator, every non-blank line is a command. Blank statements
+// are dropped.
+func Split(src string, d Dialect) []Statement {
+	if d == Redis {
+		return splitLines(src)
+	}
+
+	var stmts []Statement
+	start := 0
+	delimiter := ";"
+
+	// The first keyword of the statement, and the blocks it has open.
+	first := ""
+	begun := false
+	depth := 0
+
+	tokens := Lex(src, d)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if !tok.Significant() || tok.Offset < start {
+			continue
+		}
+
+		if !begun {
+			begun, first = true, tok.Keyword()
+
+			if d == MySQL && first == "DELIMITER" {
+				end := len(src)
+				if n := strings.IndexByte(src[tok.Offset:], '\n'); n >= 0 {
+					end = tok.Offset + n
+				}
+				if delim := strings.TrimSpace(src[tok.Offset+len(tok.Text) : end]); delim != "" {
+					delimiter = delim
+				}
+
+				start, begun = end, false
+				continue
+			}
+		}
+
+		if at := delimiterAt(src, tok, delimiter); at >= 0 && (depth == 0 || delimiter != ";") {
+			stmts = appendStatement(stmts, src, start, at, d)
+			start = at + len(delimiter)
+			first, begun, depth = "", false, 0
+			continue
+		}
+
+		switch kw := tok.Keyword(); {
+		case first == "CREATE" && kw == "BEGIN", depth > 0 && kw == "CASE":
+			depth++
+		case depth > 0 && kw == "END" && !endsControlFlow(tokens[i+1:]):
+			depth--
+		}
+	}
+
+	return appendStatement(stmts, src, start, len(src), d)
+}
+
+// delimiterAt returns the offset of the statement delimiter starting at, or
+// in, tok, or -1. Custom delimiters can be glued to a word, as in END$$.
+func delimiterAt(src string, tok Token, delimiter string) int {
+	switch tok.Kind {
+	case TokenPunct:
+		if strings.HasPrefix(src[tok.Offset:], delimiter) {
+			return tok.Offset
+		}
+	case TokenWord:
+		if i := strings.Index(tok.Text, delimiter); i >= 0 {
+			return tok.Offset + i
+		}
+	}
+
+	return -1
+}
+
+// endsControlFlow reports if the END before tokens closes an IF, LOOP, WHILE
+// or REPEAT of a procedure rather than a block.
+func endsControlFlow(tokens []Token) bool {
+	for _, tok := range tokens {
+		if tok.Significant() {
+			switch tok.Keyword() {
+			case "IF", "LOOP", "WHILE", "REPEAT":
+				return true
+			}
+			return false
+		}
+	}
+
+	return false
+}
+
+func splitLines(src string) []Statement {
+	var stmts []Statement
+
+	offset := 0
+	for _, line := range strings.SplitAfter(src, "\n") {
+		stmts = appendStatement(stmts, src, offset, offset+len(line), Redis)
+		offset += len(line)
+	}
+
+	return stmts
+}
+
+func appendStatement(stmts []Statement, src string, start, end int, d Dialect) []Statement {
+	text := src[start:end]
+	trimmed := strings.TrimLeft(text, " \t\r\n")
+	offset := start + len(text) - len(trimmed)
+	trimmed = strings.TrimRight(trimmed, " \t\r\n")
+
+	if trimmed == "" || (d != Redis && !hasSignificantTokens(trimmed, d)) {
+		return stmts
+	}
+
+	return append(stmts, Statement{
+		Text:   trimmed,
+		Offset: offset,
+		Line:   strings.Count(src[:offset], "\n"),
+	})
+}
+
+func hasSignificantTokens(s string, d Dialect) bool {
+	for _, tok := range Lex(s, d) {
+		if tok.Significant() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StatementAt returns the statement containing offset. When offset falls
+// between statements the one before it is used, so a cursor resting after a
+// terminating semicolon still picks the statement it ends.
+func StatementAt(stmts []Statement, offset int) (Statement, bool) {
+	var found Statement
+	ok := false
+
+	for _, s := range stmts {
+		if s.Offset > offset {
+			break
+		}
+
+		found = s
+		ok = true
+	}
+
+	if !ok && len(stmts) > 0 {
+		return stmts[0], true
+	}
+
+	return found, ok
+}
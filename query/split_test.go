@@ -0,0 +1,105 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		src     string
+		dialect Dialect
+		want    []Statement
+	}{
+		{
+			src:     "SELECT 1;\n\nSELECT ';';  \n-- only a comment;\n",
+			dialect: Postgres,
+			want: []Statement{
+				{Text: "SELECT 1", Offset: 0, Line: 0},
+				{Text: "SELECT ';'", Offset: 11, Line: 2},
+			},
+		},
+		{
+			src:     "  UPDATE t SET a = 1\n  WHERE id = 2",
+			dialect: MySQL,
+			want: []Statement{
+				{Text: "UPDATE t SET a = 1\n  WHERE id = 2", Offset: 2, Line: 0},
+			},
+		},
+		{
+			src:     "GET a\n\n  SET b \"x;y\"\n",
+			dialect: Redis,
+			want: []Statement{
+				{Text: "GET a", Offset: 0, Line: 0},
+				{Text: "SET b \"x;y\"", Offset: 9, Line: 2},
+			},
+		},
+		{
+			src:     "DELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 1; END//\nDELIMITER $$\nCREATE FUNCTION f() RETURNS int BEGIN RETURN 1; END$$\nDELIMITER ;\nCALL p();",
+			dialect: MySQL,
+			want: []Statement{
+				{Text: "CREATE PROCEDURE p() BEGIN SELECT 1; END", Offset: 13, Line: 1},
+				{Text: "CREATE FUNCTION f() RETURNS int BEGIN RETURN 1; END", Offset: 69, Line: 3},
+				{Text: "CALL p()", Offset: 135, Line: 5},
+			},
+		},
+		{
+			src:     "CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; SELECT CASE WHEN 1 THEN 2 END; END; BEGIN; COMMIT",
+			dialect: MySQL,
+			want: []Statement{
+				{Text: "CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; SELECT CASE WHEN 1 THEN 2 END; END", Offset: 0, Line: 0},
+				{Text: "BEGIN", Offset: 91, Line: 0},
+				{Text: "COMMIT", Offset: 98, Line: 0},
+			},
+		},
+		{
+			src:     "CREATE TRIGGER tr AFTER INSERT ON t BEGIN\n  INSERT INTO log VALUES (new.n);\nEND;\nSELECT 1",
+			dialect: SQLite,
+			want: []Statement{
+				{Text: "CREATE TRIGGER tr AFTER INSERT ON t BEGIN\n  INSERT INTO log VALUES (new.n);\nEND", Offset: 0, Line: 0},
+				{Text: "SELECT 1", Offset: 81, Line: 3},
+			},
+		},
+		{
+			src:     "CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END $$ LANGUAGE plpgsql;\nDO $body$ BEGIN PERFORM 1; END $body$;",
+			dialect: Postgres,
+			want: []Statement{
+				{Text: "CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END $$ LANGUAGE plpgsql", Offset: 0, Line: 0},
+				{Text: "DO $body$ BEGIN PERFORM 1; END $body$", Offset: 79, Line: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("split: %q", tt.src), func(t *testing.T) {
+			got := Split(tt.src, tt.dialect)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementAt(t *testing.T) {
+	src := "SELECT 1;\nSELECT 2;\n\nSELECT 3"
+	stmts := Split(src, Postgres)
+
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{offset: 0, want: "SELECT 1"},
+		{offset: 8, want: "SELECT 1"},
+		{offset: 10, want: "SELECT 2"},
+		{offset: 20, want: "SELECT 2"},
+		{offset: len(src), want: "SELECT 3"},
+	}
+
+	for _, tt := range tests {
+		got, ok := StatementAt(stmts, tt.offset)
+		if !ok || got.Text != tt.want {
+			t.Errorf("offset %d: got %q, want %q", tt.offset, got.Text, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/utils"
+	"github.com/gdamore/tcell"
+)
+
+// recordLine is a screen line of the record view, which lists the fields of
+// the selected row one under the other.
+type recordLine struct {
+	field int    // column the line belongs to
+	name  string // column name, set on the field's first line only
+	text  string
+	dim   bool // text stands in for NULL or an empty string
+}
+
+// ToggleRecord switches between the grid and the record view, it reports
+// false for results without columns to show.
+func (r *ResultsPane) ToggleRecord() bool {
+	if r.Result == nil || r.lines != nil || len(r.Result.Columns) == 0 {
+		return false
+	}
+
+	r.Record = !r.Record
+	r.recordScroll = 0
+
+	return true
+}
+
+// ToggleFold collapses the selected field of the record view to a single
+// line when it holds JSON, or expands it again. Fields stay folded as the
+// selection moves between records.
+func (r *ResultsPane) ToggleFold() bool {
+	row, col := r.Selected()
+	if !r.Record || row >= len(r.Result.Rows) {
+		return false
+	}
+
+	if _, ok := jsonText(r.Result.Rows[row][col]); !ok {
+		return false
+	}
+
+	if r.folded == nil {
+		r.folded = map[int]bool{}
+	}
+	r.folded[col] = !r.folded[col]
+
+	return true
+}
+
+// nameWidth is the width of the column names in the record view.
+func (r *ResultsPane) nameWidth(width int) int {
+	n := 0
+	for i := 0; i < r.ColumnCount(); i++ {
+		n = max(n, len([]rune(r.Result.Columns[r.dataCol(i)].Name)))
+	}
+
+	return max(1, min(n, maxColumnWidth, width/3))
+}
+
+// recordLines lays the selected row out as fields with values wrapped to
+// width, in the order the columns are shown.
+func (r *ResultsPane) recordLines(width int) []recordLine {
+	row, _ := r.Selected()
+	if row >= len(r.Result.Rows) {
+		return nil
+	}
+
+	var lines []recordLine
+	for shown := 0; shown < r.ColumnCount(); shown++ {
+		i := r.dataCol(shown)
+		text, dim := fieldText(r.Result.Rows[row][i], r.folded[i], width)
+
+		for j, l := range text {
+			line := recordLine{field: i, text: l, dim: dim}
+			if j == 0 {
+				line.name = r.Result.Columns[i].Name
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// fieldText is how a value shows up in the record view. NULL and empty
+// strings are spelled out to tell them apart, JSON is pretty-printed unless
+// folded.
+func fieldText(v any, folded bool, width int) ([]string, bool) {
+	switch v {
+	case nil:
+		return []string{"NULL"}, true
+	case "":
+		return []string{`""`}, true
+	}
+
+	if s, ok := jsonText(v); ok {
+		if !folded {
+			return utils.Wrap(s, width), false
+		}
+
+		var compact bytes.Buffer
+		json.Compact(&compact, []byte(s))
+
+		return []string{"▸ " + truncate(compact.String(), width-2)}, false
+	}
+
+	return utils.Wrap(db.FormatValue(v), width), false
+}
+
+// jsonText pretty-prints v if it is a JSON document, or a string holding a
+// JSON object or array.
+func jsonText(v any) (string, bool) {
+	var b []byte
+	switch v := v.(type) {
+	case db.JSON:
+		b = v
+	case string:
+		if t := strings.TrimSpace(v); !strings.HasPrefix(t, "{") && !strings.HasPrefix(t, "[") {
+			return "", false
+		}
+		b = []byte(v)
+	default:
+		return "", false
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		return "", false
+	}
+
+	return out.String(), true
+}
+
+// truncate shortens s to width runes, ending it with "…" when cut.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 1 {
+		return "…"
+	}
+
+	return string(runes[:width-1]) + "…"
+}
+
+// drawRecord lists the fields of the selected row in the screen rows
+// [y, y+height), scrolled to keep the selected field in view.
+func (r *ResultsPane) drawRecord(y, height int) {
+	w, _ := r.screen.Size()
+
+	r.height = height
+	focused := r.editor.EditorMode == ResultsMode
+
+	nameWidth := r.nameWidth(w)
+	lines := r.recordLines(w - nameWidth - 3)
+	row, col := r.Selected()
+
+	first, last := -1, -1
+	for i, l := range lines {
+		if l.field == col {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	switch {
+	case first < 0:
+		r.recordScroll = 0
+	case first < r.recordScroll || last-first >= height:
+		r.recordScroll = first
+	case last >= r.recordScroll+height:
+		r.recordScroll = last - height + 1
+	}
+
+	nameStyle := r.style.Bold(true)
+	for i := 0; i < height && r.recordScroll+i < len(lines); i++ {
+		line := lines[r.recordScroll+i]
+
+		style, fieldNameStyle := r.cellStyle(row, line.field, line.dim), nameStyle
+		if focused && line.field == col {
+			style, fieldNameStyle = r.selectedStyle, r.selectedStyle
+			fillRow(r.screen, 0, y+i, w, style)
+		}
+
+		drawText(r.screen, 0, y+i, nameWidth, line.name, fieldNameStyle)
+		r.screen.SetContent(nameWidth+1, y+i, tcell.RuneVLine, nil, r.nullStyle)
+		drawText(r.screen, nameWidth+3, y+i, w-nameWidth-3, line.text, style)
+	}
+}
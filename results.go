@@ -0,0 +1,432 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+	"github.com/gdamore/tcell"
+)
+
+const maxColumnWidth = 40
+
+// CellState is how a cell of the results differs from what the server
+// returned.
+type CellState int
+
+const (
+	CellClean CellState = iota
+	CellChanged
+	CellAdded
+	CellDeleted
+)
+
+// ResultsPane shows the results of the last run below the editor, a tab
+// per result set, as a grid or as a list of lines for replies of key-value
+// stores. Pinned tabs survive the next run.
+type ResultsPane struct {
+	resultState // of the active tab
+
+	tabs   []*resultTab
+	active int
+	height int // rows of data visible, set by Draw
+
+	style         tcell.Style
+	columnStyle   tcell.Style
+	selectedStyle tcell.Style
+	nullStyle     tcell.Style
+	errorStyle    tcell.Style
+	changedStyle  tcell.Style
+	addedStyle    tcell.Style
+	deletedStyle  tcell.Style
+
+	screen tcell.Screen
+	editor *Editor
+}
+
+// resultState is what the pane shows of a result set, kept by its tab while
+// another one is active.
+type resultState struct {
+	Result  *db.Result
+	Elapsed time.Duration
+	Row     int // selected row and column as shown, see Selected
+	Col     int
+	ScrollY int
+	ScrollX int // first visible column
+
+	// Record shows the selected row as a list of fields instead of the
+	// grid, for rows too wide to read across.
+	Record bool
+
+	// OnEdit and OnPage are set by whoever filled the pane when the
+	// result can be edited or paged through, OnMore when more rows can be
+	// fetched as the selection nears the last one and State when rows were
+	// edited. A new result clears them.
+	OnEdit func(row, col int)
+	OnPage func(delta int)
+	OnMore func()
+	State  func(row, col int) CellState
+
+	widths []int
+	lines  []db.ReplyLine // set instead of widths for replies
+
+	folded       map[int]bool // JSON fields collapsed in the record view
+	recordScroll int          // first line of the record view visible
+
+	// The view sorts, filters and rearranges the result without running
+	// it again. rows and cols map what is shown to the result, they are nil
+	// while it is shown as it came.
+	rows     []int
+	cols     []int
+	pins     int   // columns pinned at the start of cols
+	order    []int // columns in the order they were moved to
+	hidden   map[int]bool
+	pinned   map[int]bool
+	sortKeys []sortKey
+	filter   *query.Filter
+	search   string
+}
+
+func NewResultsPane(screen tcell.Screen, editor *Editor) *ResultsPane {
+	return &ResultsPane{
+		style:         tcell.StyleDefault,
+		columnStyle:   tcell.StyleDefault.Bold(true).Underline(true),
+		selectedStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+		nullStyle:     tcell.StyleDefault.Dim(true),
+		errorStyle:    tcell.StyleDefault.Foreground(tcell.ColorRed),
+		changedStyle:  tcell.StyleDefault.Foreground(tcell.ColorYellow),
+		addedStyle:    tcell.StyleDefault.Foreground(tcell.ColorGreen),
+		deletedStyle:  tcell.StyleDefault.Foreground(tcell.ColorRed).Dim(true),
+		screen:        screen,
+		editor:        editor,
+	}
+}
+
+func (r *ResultsPane) Visible() bool {
+	return r.Result != nil
+}
+
+// fill shows res, a result that was just run, as it came.
+func (s *resultState) fill(res *db.Result, elapsed time.Duration) {
+	s.Result = res
+	s.Elapsed = elapsed
+
+	if reply, ok := res.Reply(); ok {
+		s.lines = reply.Lines()
+		return
+	}
+
+	s.widths = make([]int, len(res.Columns))
+	for i, c := range res.Columns {
+		s.widths[i] = len([]rune(c.Name))
+	}
+	s.fitWidths(res.Rows)
+}
+
+// AppendRows adds a page of rows fetched after the result was set.
+func (r *ResultsPane) AppendRows(rows [][]any, elapsed time.Duration) {
+	if r.Result == nil || r.lines != nil {
+		return
+	}
+
+	r.Result.Rows = append(r.Result.Rows, rows...)
+	r.Elapsed += elapsed
+	r.fitWidths(rows)
+
+	if r.rows != nil {
+		r.refreshRows()
+	}
+}
+
+// fitWidths widens the columns to fit rows.
+func (s *resultState) fitWidths(rows [][]any) {
+	for _, row := range rows {
+		for i, v := range row {
+			if w := len([]rune(cellText(v))); w > s.widths[i] {
+				s.widths[i] = min(w, maxColumnWidth)
+			}
+		}
+	}
+}
+
+// Summary describes the result in one line, e.g. "3 rows  SELECT 3  12ms".
+func (r *ResultsPane) Summary() string {
+	if r.Result == nil {
+		return ""
+	}
+
+	elapsed := r.Elapsed.Round(time.Millisecond).String()
+
+	// Replies speak for themselves, the tag is the command that was run.
+	if r.lines != nil {
+		return r.Result.Tag + "  " + elapsed
+	}
+
+	parts := []string{}
+	switch {
+	case r.rows != nil && r.filter != nil:
+		parts = append(parts, fmt.Sprintf("%d of %d rows", len(r.rows), len(r.Result.Rows)))
+	case r.Result.More:
+		parts = append(parts, fmt.Sprintf("%d rows fetched, more available", len(r.Result.Rows)))
+	case len(r.Result.Columns) > 0:
+		parts = append(parts, fmt.Sprintf("%d rows", len(r.Result.Rows)))
+	}
+
+	if r.Result.Tag != "" {
+		parts = append(parts, r.Result.Tag)
+	} else if len(r.Result.Columns) == 0 {
+		parts = append(parts, fmt.Sprintf("%d rows affected", r.Result.RowsAffected))
+	}
+
+	parts = append(parts, elapsed)
+
+	return strings.Join(parts, "  ")
+}
+
+// MoveSelection moves the selected cell, scrolling to keep it visible.
+func (r *ResultsPane) MoveSelection(rows, cols int) {
+	r.SetSelection(r.Row+rows, r.Col+cols)
+}
+
+func (r *ResultsPane) SetSelection(row, col int) {
+	if r.Result == nil {
+		return
+	}
+
+	r.Row = max(0, min(row, r.RowCount()-1))
+	r.Col = max(0, min(col, r.ColumnCount()-1))
+
+	if r.Row < r.ScrollY {
+		r.ScrollY = r.Row
+	}
+
+	if r.height > 0 && r.Row >= r.ScrollY+r.height {
+		r.ScrollY = r.Row - r.height + 1
+	}
+
+	// Pinned columns are always in view.
+	if r.Col >= r.pins && r.Col < max(r.ScrollX, r.pins) {
+		r.ScrollX = r.Col
+	}
+
+	for r.ScrollX < r.Col && !r.columnVisible(r.Col) {
+		r.ScrollX++
+	}
+
+	// Fetch the next page before the selection reaches the end.
+	if r.OnMore != nil && r.Row >= r.RowCount()-max(1, r.height) {
+		r.OnMore()
+	}
+}
+
+// RowCount is the number of rows, or lines of a reply.
+func (r *ResultsPane) RowCount() int {
+	if r.lines != nil {
+		return len(r.lines)
+	}
+
+	if r.rows != nil {
+		return len(r.rows)
+	}
+
+	return len(r.Result.Rows)
+}
+
+// ColumnCount is the number of columns, replies have a single one.
+func (r *ResultsPane) ColumnCount() int {
+	if r.lines != nil {
+		return 1
+	}
+
+	if r.cols != nil {
+		return len(r.cols)
+	}
+
+	return len(r.Result.Columns)
+}
+
+// columnVisible reports if the column shown at col fits on the screen.
+func (r *ResultsPane) columnVisible(col int) bool {
+	if col < r.pins {
+		return true
+	}
+
+	w, _ := r.screen.Size()
+
+	x := 0
+	for _, i := range r.visibleColumns() {
+		if i > col {
+			break
+		}
+		x += r.widths[r.dataCol(i)] + 3
+	}
+
+	return x <= w
+}
+
+// visibleColumns lists the columns drawn from the left, as shown: the
+// pinned ones, then the rest scrolled to ScrollX.
+func (r *ResultsPane) visibleColumns() []int {
+	var cols []int
+	for i := 0; i < r.ColumnCount(); i++ {
+		if i < r.pins || i >= r.ScrollX {
+			cols = append(cols, i)
+		}
+	}
+
+	return cols
+}
+
+func (r *ResultsPane) HandleEventKey(ek *tcell.EventKey) {
+	switch ek.Key() {
+	case tcell.KeyUp:
+		r.MoveSelection(-1, 0)
+	case tcell.KeyDown:
+		r.MoveSelection(1, 0)
+	case tcell.KeyLeft:
+		r.MoveSelection(0, -1)
+	case tcell.KeyRight:
+		r.MoveSelection(0, 1)
+	case tcell.KeyPgUp:
+		r.MoveSelection(-max(1, r.height), 0)
+	case tcell.KeyPgDn:
+		r.MoveSelection(max(1, r.height), 0)
+	}
+}
+
+// Draw renders the pane in the screen rows [y, y+height).
+func (r *ResultsPane) Draw(y, height int) {
+	w, _ := r.screen.Size()
+
+	summary := r.Summary()
+	if r.Record && r.lines == nil && r.RowCount() > 0 {
+		summary = fmt.Sprintf("Record %d of %d  %s", r.Row+1, r.RowCount(), summary)
+	}
+	if view := r.viewSummary(); view != "" {
+		summary += "  " + view
+	}
+
+	accent := r.editor.AccentStyle()
+	fillRow(r.screen, 0, y, w, accent)
+	drawText(r.screen, 1, y, w-2, summary, accent)
+
+	if r.showTabs() {
+		r.drawTabs(y + 1)
+		y, height = y+1, height-1
+	}
+
+	if r.lines != nil {
+		r.drawReply(y+1, height-1)
+		return
+	}
+
+	if len(r.Result.Columns) == 0 || height < 3 {
+		r.height = 0
+		return
+	}
+
+	if r.Record {
+		r.drawRecord(y+1, height-1)
+		return
+	}
+
+	r.height = height - 2
+	focused := r.editor.EditorMode == ResultsMode
+
+	x := 0
+	for _, shown := range r.visibleColumns() {
+		if x >= w {
+			break
+		}
+
+		col := r.dataCol(shown)
+		width := r.widths[col]
+
+		drawText(r.screen, x, y+1, min(width, w-x), r.Result.Columns[col].Name, r.columnStyle)
+
+		for i := 0; i < r.height; i++ {
+			if r.ScrollY+i >= r.RowCount() {
+				break
+			}
+
+			row := r.dataRow(r.ScrollY + i)
+			v := r.Result.Rows[row][col]
+
+			style := r.cellStyle(row, col, v == nil)
+			if focused && r.ScrollY+i == r.Row && shown == r.Col {
+				style = r.selectedStyle
+				fillRow(r.screen, x, y+2+i, min(width, w-x), style)
+			}
+
+			drawText(r.screen, x, y+2+i, min(width, w-x), cellText(v), style)
+		}
+
+		x += width
+		if x+3 <= w {
+			for i := 0; i < height-1; i++ {
+				r.screen.SetContent(x+1, y+1+i, tcell.RuneVLine, nil, r.nullStyle)
+			}
+		}
+		x += 3
+	}
+}
+
+// cellStyle is the style of a cell of the result that isn't selected,
+// showing NULLs dimmed and edits in color.
+func (r *ResultsPane) cellStyle(row, col int, null bool) tcell.Style {
+	state := CellClean
+	if r.State != nil {
+		state = r.State(row, col)
+	}
+
+	switch {
+	case state == CellDeleted:
+		return r.deletedStyle
+	case state == CellChanged:
+		return r.changedStyle
+	case state == CellAdded:
+		return r.addedStyle
+	case null:
+		return r.nullStyle
+	default:
+		return r.style
+	}
+}
+
+// drawReply lists the lines of a reply, errors in red and nils dimmed.
+func (r *ResultsPane) drawReply(y, height int) {
+	w, _ := r.screen.Size()
+
+	r.height = height
+	focused := r.editor.EditorMode == ResultsMode
+
+	for i := 0; i < height && r.ScrollY+i < len(r.lines); i++ {
+		line := r.lines[r.ScrollY+i]
+
+		style := r.style
+		switch line.Kind {
+		case db.ReplyError:
+			style = r.errorStyle
+		case db.ReplyNil:
+			style = r.nullStyle
+		}
+		if focused && r.ScrollY+i == r.Row {
+			style = r.selectedStyle
+			fillRow(r.screen, 0, y+i, w, style)
+		}
+
+		drawText(r.screen, 0, y+i, w, line.Text, style)
+	}
+}
+
+// cellText is how a value shows up in a grid cell, on a single line.
+func cellText(v any) string {
+	s := db.FormatValue(v)
+	if strings.ContainsAny(s, "\n\r\t") {
+		s = strings.NewReplacer("\n", "↵", "\r", "", "\t", " ").Replace(s)
+	}
+
+	return s
+}
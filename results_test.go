@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/gdamore/tcell"
+)
+
+func init() {
+	db.Register("fake", fakeDriver{})
+}
+
+// fakeDriver answers every statement with the same two rows.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(context.Context, config.Connection, string) (db.Session, error) {
+	return fakeSession{}, nil
+}
+
+type fakeSession struct{}
+
+func (fakeSession) Query(context.Context, string, ...any) (db.Rows, error) {
+	return &fakeRows{
+		columns: []db.Column{{Name: "id", Type: "int8"}, {Name: "email", Type: "text"}},
+		rows:    [][]any{{int64(1), "ada@example.com"}, {int64(2), nil}},
+	}, nil
+}
+
+func (fakeSession) Close() error { return nil }
+
+type fakeRows struct {
+	columns []db.Column
+	rows    [][]any
+	i       int
+}
+
+func (r *fakeRows) Columns() []db.Column { return r.columns }
+func (r *fakeRows) Next() bool           { r.i++; return r.i <= len(r.rows) }
+func (r *fakeRows) Values() []any        { return r.rows[r.i-1] }
+func (r *fakeRows) Err() error           { return nil }
+func (r *fakeRows) Close() error         { return nil }
+func (r *fakeRows) Tag() string          { return "SELECT 2" }
+func (r *fakeRows) RowsAffected() int64  { return 2 }
+
+// screenRow returns the text of a screen row.
+func screenRow(e *Editor, y int) string {
+	w, _ := e.screen.Size()
+
+	var sb strings.Builder
+	for x := 0; x < w; x++ {
+		r, _, _, _ := e.screen.GetContent(x, y)
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+func TestRunStatementResults(t *testing.T) {
+	e := newTestEditor(t, nil)
+	e.Connect(&config.Connection{Name: "Local", Type: "fake", Host: "localhost"})
+	e.Lines = []string{"SELECT id, email FROM users"}
+
+	typeKeys(e, ":run")
+	pressKey(e, tcell.KeyEnter)
+	runPosted(t, e)
+
+	if !strings.HasPrefix(e.StatusBar.Command, "2 rows  SELECT 2  ") {
+		t.Errorf("got status %q, want the result summary", e.StatusBar.Command)
+	}
+
+	e.Draw()
+	e.screen.Show()
+
+	y := e.Height
+	if summary := screenRow(e, y); !strings.Contains(summary, "2 rows") {
+		t.Fatalf("results summary row %q not drawn", summary)
+	}
+	if header := screenRow(e, y+1); !strings.Contains(header, "id") || !strings.Contains(header, "email") {
+		t.Errorf("header %q doesn't show the columns", header)
+	}
+	if first := screenRow(e, y+2); !strings.Contains(first, "ada@example.com") {
+		t.Errorf("first row %q doesn't show the email", first)
+	}
+	if second := screenRow(e, y+3); !strings.Contains(second, "NULL") {
+		t.Errorf("second row %q doesn't show NULL", second)
+	}
+
+	pressKey(e, tcell.KeyCtrlW)
+	if e.EditorMode != ResultsMode {
+		t.Fatalf("Ctrl+w didn't focus the results, mode %v", e.EditorMode)
+	}
+
+	typeKeys(e, "jl")
+	if e.Results.Row != 1 || e.Results.Col != 1 {
+		t.Errorf("selection at %d,%d, want 1,1", e.Results.Row, e.Results.Col)
+	}
+
+	pressKey(e, tcell.KeyEscape)
+	if e.EditorMode != NormalMode {
+		t.Errorf("Escape didn't return to the editor, mode %v", e.EditorMode)
+	}
+}
+
+// TestRunStatementSQLite runs statements end to end on a real, in-memory
+// database.
+func TestRunStatementSQLite(t *testing.T) {
+	e := newTestEditor(t, nil)
+	e.Connect(&config.Connection{Name: "Fixture", Type: "sqlite", Path: ":memory:"})
+	t.Cleanup(e.Close)
+
+	e.Lines = []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);",
+		"INSERT INTO users (email) VALUES ('ada@example.com'), (NULL);",
+		"SELECT id, email FROM users ORDER BY id;",
+	}
+
+	for y, want := range []string{"0 rows affected", "2 rows affected", "2 rows  "} {
+		e.SetCursor(0, y)
+		typeKeys(e, ":run")
+		pressKey(e, tcell.KeyEnter)
+		runPosted(t, e)
+
+		if !strings.HasPrefix(e.StatusBar.Command, want) {
+			t.Fatalf("line %d: got status %q, want %q", y+1, e.StatusBar.Command, want)
+		}
+	}
+
+	e.Draw()
+	y := e.Height
+	if first := screenRow(e, y+2); !strings.Contains(first, "1") || !strings.Contains(first, "ada@example.com") {
+		t.Errorf("first row %q doesn't show the user", first)
+	}
+	if second := screenRow(e, y+3); !strings.Contains(second, "NULL") {
+		t.Errorf("second row %q doesn't show NULL", second)
+	}
+}
+
+func TestResultsPaneReply(t *testing.T) {
+	e := newTestEditor(t, nil)
+
+	reply := db.Reply{Kind: db.ReplyArray, Elems: []db.Reply{
+		{Kind: db.ReplyBulk, Str: "a"},
+		{Kind: db.ReplyError, Str: "ERR boom"},
+		{Kind: db.ReplyNil},
+	}}
+	e.Results.SetResult("SELECT", &db.Result{
+		Columns: []db.Column{{Name: "reply", Type: "reply"}},
+		Rows:    [][]any{{reply}},
+		Tag:     "EXEC",
+	}, 0)
+
+	if got := e.Results.Summary(); got != "EXEC  0s" {
+		t.Errorf("got summary %q, want EXEC  0s", got)
+	}
+
+	e.Draw()
+	e.screen.Show()
+
+	y := e.Height + 1
+	for i, want := range []string{`1) "a"`, "2) (error) ERR boom", "3) (nil)"} {
+		if got := strings.TrimRight(screenRow(e, y+i), " "); got != want {
+			t.Errorf("line %d: got %q, want %q", i, got, want)
+		}
+	}
+
+	_, _, style, _ := e.screen.GetContent(0, y+1)
+	if fg, _, _ := style.Decompose(); fg != tcell.ColorRed {
+		t.Errorf("error line drawn in %v, want red", fg)
+	}
+
+	pressKey(e, tcell.KeyCtrlW)
+	typeKeys(e, "G")
+	if e.Results.Row != 2 {
+		t.Errorf("G selected line %d, want 2", e.Results.Row)
+	}
+}
+
+func TestStreamedResults(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	e.app.config.Results = config.Results{PageSize: 10, MaxRows: 25}
+
+	runLine(t, e, "INSERT INTO t WITH RECURSIVE s(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM s WHERE n < 100) SELECT n FROM s")
+	runLine(t, e, "SELECT n FROM t")
+
+	if got := e.Results.Summary(); !strings.HasPrefix(got, "10 rows fetched, more available") {
+		t.Fatalf("got summary %q, want the first page", got)
+	}
+	if e.session == nil {
+		t.Fatal("expected the buffer to keep its session while rows are left")
+	}
+
+	// Scrolling to the last row fetches the next page.
+	e.SetEditorMode(ResultsMode)
+	e.Results.SetSelection(9, 0)
+	runPosted(t, e)
+	if n := len(e.Results.Result.Rows); n != 20 || e.Results.Result.Rows[19][0] != int64(20) {
+		t.Fatalf("got %d rows, want 20", n)
+	}
+
+	// :fetchall stops at the row cap.
+	runCommand(t, e, "fetchall")
+	if want := "25 rows fetched, more available"; !strings.HasPrefix(e.StatusBar.Command, want) || !strings.HasSuffix(e.StatusBar.Command, "stopped at the row cap") {
+		t.Fatalf("got %q, want %q and the row cap", e.StatusBar.Command, want)
+	}
+	if e.stream != nil {
+		t.Error("expected the stream closed")
+	}
+
+	// A result that fits in a page isn't streamed.
+	runLine(t, e, "SELECT n FROM t WHERE n <= 3")
+	if got := e.Results.Summary(); !strings.HasPrefix(got, "3 rows") || e.stream != nil {
+		t.Errorf("got summary %q, want the whole result", got)
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	e.app.config.Results = config.Results{PageSize: 10}
+
+	runLine(t, e, "INSERT INTO t WITH RECURSIVE s(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM s WHERE n < 35) SELECT n FROM s")
+	runLine(t, e, "SELECT n FROM t")
+
+	typeKeys(e, ":fetchall")
+	pressKey(e, tcell.KeyEnter)
+	for e.running {
+		runPosted(t, e)
+	}
+
+	if got := e.Results.Summary(); !strings.HasPrefix(got, "35 rows") || e.Results.Result.More || e.stream != nil {
+		t.Errorf("got summary %q, want every row", got)
+	}
+}
+
+func TestResultsPaneRecord(t *testing.T) {
+	e := newTestEditor(t, nil)
+
+	e.Results.SetResult("SELECT", &db.Result{
+		Columns: []db.Column{{Name: "id"}, {Name: "payload"}, {Name: "note"}, {Name: "nickname"}},
+		Rows: [][]any{
+			{int64(1), db.JSON(`{"a":1,"b":[true]}`), nil, ""},
+			{int64(2), db.JSON(`[]`), strings.Repeat("word ", 30), "ada"},
+		},
+	}, 0)
+
+	pressKey(e, tcell.KeyCtrlW)
+	typeKeys(e, "x")
+	if !e.Results.Record {
+		t.Fatal("x didn't switch to the record view")
+	}
+
+	e.screen.Clear()
+	e.Draw()
+	y := e.Height
+	if summary := screenRow(e, y); !strings.Contains(summary, "Record 1 of 2") {
+		t.Errorf("summary %q doesn't show the record", summary)
+	}
+
+	want := []string{
+		"id       │ 1",
+		"payload  │ {",
+		`         │   "a": 1,`,
+		`         │   "b": [`,
+		"         │     true",
+		"         │   ]",
+		"         │ }",
+	}
+	for i, w := range want {
+		if got := strings.TrimRight(screenRow(e, y+1+i), " "); got != w {
+			t.Errorf("line %d: got %q, want %q", i, got, w)
+		}
+	}
+
+	// Folding the JSON field leaves room for NULL and the empty string.
+	typeKeys(e, "lza")
+	e.screen.Clear()
+	e.Draw()
+	for i, w := range []string{`payload  │ ▸ {"a":1,"b":[true]}`, "note     │ NULL", `nickname │ ""`} {
+		if got := strings.TrimRight(screenRow(e, y+2+i), " "); got != w {
+			t.Errorf("folded line %d: got %q, want %q", i, got, w)
+		}
+	}
+
+	// j moves to the next record, long values wrap.
+	typeKeys(e, "jl")
+	e.screen.Clear()
+	e.Draw()
+	if summary := screenRow(e, y); !strings.Contains(summary, "Record 2 of 2") {
+		t.Errorf("summary %q doesn't show the second record", summary)
+	}
+	if note := screenRow(e, y+3); !strings.HasPrefix(note, "note     │ word word") || strings.Count(screenRow(e, y+4), "word") == 0 {
+		t.Errorf("note %q isn't wrapped", note)
+	}
+
+	typeKeys(e, "x")
+	if e.Results.Record {
+		t.Error("x didn't switch back to the grid")
+	}
+}
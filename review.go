@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// Review is a popup showing statements about to run, which run once
+// confirmed with y or Enter.
+type Review struct {
+	Title     string
+	Lines     []string
+	OnConfirm func()
+
+	offset int
+	height int // lines visible, set by Draw
+
+	style tcell.Style
+	dim   tcell.Style
+
+	screen tcell.Screen
+	editor *Editor
+}
+
+func NewReview(editor *Editor, title string, stmts []string, onConfirm func()) *Review {
+	var lines []string
+	for _, stmt := range stmts {
+		lines = append(lines, strings.Split(stmt+";", "\n")...)
+	}
+
+	return &Review{
+		Title:     title,
+		Lines:     lines,
+		OnConfirm: onConfirm,
+		style:     tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		dim:       tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack).Dim(true),
+		screen:    editor.screen,
+		editor:    editor,
+	}
+}
+
+func (r *Review) HandleEventKey(ek *tcell.EventKey) {
+	switch ek.Key() {
+	case tcell.KeyEscape:
+		r.editor.ClosePopup()
+		r.editor.notify("Cancelled")
+	case tcell.KeyEnter:
+		r.confirm()
+	case tcell.KeyUp:
+		r.scroll(-1)
+	case tcell.KeyDown:
+		r.scroll(1)
+	case tcell.KeyPgUp:
+		r.scroll(-max(1, r.height))
+	case tcell.KeyPgDn:
+		r.scroll(max(1, r.height))
+	case tcell.KeyRune:
+		switch ek.Rune() {
+		case 'y', 'Y':
+			r.confirm()
+		case 'n', 'N', 'q':
+			r.editor.ClosePopup()
+			r.editor.notify("Cancelled")
+		case 'k':
+			r.scroll(-1)
+		case 'j':
+			r.scroll(1)
+		}
+	}
+}
+
+func (r *Review) confirm() {
+	r.editor.ClosePopup()
+	if r.OnConfirm != nil {
+		r.OnConfirm()
+	}
+}
+
+func (r *Review) scroll(delta int) {
+	r.offset = max(0, min(r.offset+delta, len(r.Lines)-max(1, r.height)))
+}
+
+func (r *Review) Draw() {
+	w, h := r.screen.Size()
+
+	width := min(100, w-4)
+	height := min(len(r.Lines)+4, h-4)
+	if width < 20 || height < 5 {
+		return
+	}
+
+	x := (w - width) / 2
+	y := (h - height) / 2
+
+	drawBox(r.screen, x, y, width, height, r.Title, r.editor.AccentStyle(), r.style)
+
+	r.height = height - 3
+	for i := 0; i < r.height && r.offset+i < len(r.Lines); i++ {
+		drawText(r.screen, x+2, y+1+i, width-4, r.Lines[r.offset+i], r.style)
+	}
+
+	drawText(r.screen, x+2, y+height-2, width-4, "Apply? [y/N], j/k to scroll", r.dim)
+}
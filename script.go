@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+type scriptStatus int
+
+const (
+	scriptPending scriptStatus = iota
+	scriptDone
+	scriptFailed
+	scriptSkipped // not run, the script stopped before it
+)
+
+// scriptEntry is a statement of a script and how running it went.
+type scriptEntry struct {
+	stmt    query.Statement // positioned in the buffer
+	bound   boundStatement
+	status  scriptStatus
+	elapsed time.Duration
+	rows    int64 // returned, or affected
+	result  *db.Result
+	err     error
+}
+
+// scriptRun is a script run one statement after the other on the buffer's
+// session.
+type scriptRun struct {
+	conn      *config.Connection
+	entries   []*scriptEntry
+	keepGoing bool // run the statements after a failed one
+	running   bool
+	stopped   bool // cancelled, the statements left are skipped
+	elapsed   time.Duration
+	current   int // entry last moved to with :cnext and :cprev, -1 for none
+}
+
+// counts returns the number of statements that ran, failed and were
+// skipped.
+func (r *scriptRun) counts() (done, failed, skipped int) {
+	for _, entry := range r.entries {
+		switch entry.status {
+		case scriptDone:
+			done++
+		case scriptFailed:
+			failed++
+		case scriptSkipped:
+			skipped++
+		}
+	}
+
+	return done, failed, skipped
+}
+
+// failed returns the first statement that failed, or nil.
+func (r *scriptRun) failed() *scriptEntry {
+	for _, entry := range r.entries {
+		if entry.status == scriptFailed {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// summary describes the run, e.g. "3 ok, 1 failed, 2 skipped in 40ms".
+func (r *scriptRun) summary() string {
+	done, failed, skipped := r.counts()
+
+	parts := []string{fmt.Sprintf("%d ok", done)}
+	if failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", failed))
+	}
+	if skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d skipped", skipped))
+	}
+
+	return strings.Join(parts, ", ") + " in " + r.elapsed.Round(time.Millisecond).String()
+}
+
+// scriptRange reads the lines a script runs over from the arguments of
+// :script, "first,last" counted from 1 or "%" for the whole buffer. Without
+// arguments it is the visual selection the command line was entered from,
+// or the whole buffer.
+func (e *Editor) scriptRange(args string) (first, last int, err error) {
+	switch {
+	case args == "" && (e.StatusBar.returnMode == VisualMode || e.StatusBar.returnMode == VisualLineMode):
+		return min(e.CursorStartY, e.CursorY), max(e.CursorStartY, e.CursorY), nil
+	case args == "" || args == "%":
+		return 0, len(e.Lines) - 1, nil
+	}
+
+	from, to, ok := strings.Cut(args, ",")
+	if !ok {
+		to = from
+	}
+
+	first, err1 := strconv.Atoi(strings.TrimSpace(from))
+	last, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil || first < 1 || last < first || last > len(e.Lines) {
+		return 0, 0, fmt.Errorf("invalid range %q, the buffer has %d lines", args, len(e.Lines))
+	}
+
+	return first - 1, last - 1, nil
+}
+
+// RunScript runs the statements of lines first to last one after the other
+// and reports how each went. A failed statement stops the script unless
+// keepGoing is set.
+func (e *Editor) RunScript(first, last int, keepGoing bool) {
+	c := e.Connection
+	if c == nil {
+		e.notify("Not connected, use :connect first")
+		return
+	}
+
+	if e.discardEditsFirst(func() { e.RunScript(first, last, keepGoing) }) {
+		return
+	}
+
+	offset := 0
+	for _, line := range e.Lines[:first] {
+		offset += len(line) + 1
+	}
+
+	stmts := query.Split(strings.Join(e.Lines[first:last+1], "\n"), e.Dialect())
+	if len(stmts) == 0 {
+		e.notify("No statements to run")
+		return
+	}
+	for i := range stmts {
+		stmts[i].Offset += offset
+		stmts[i].Line += first
+	}
+
+	if c.ReadOnly {
+		for _, stmt := range stmts {
+			if reason, refused := query.ReadOnlyViolation(stmt.Text, e.Dialect()); refused {
+				e.warn("Refused on read-only %s, line %d \"%s\": %s", c.Name, stmt.Line+1, snippet(stmt.Text, 40), reason)
+				return
+			}
+		}
+	}
+
+	e.bindPlaceholders(stmts, func(bound []boundStatement) {
+		run := &scriptRun{conn: c, keepGoing: keepGoing, current: -1}
+		for i, stmt := range stmts {
+			run.entries = append(run.entries, &scriptEntry{stmt: stmt, bound: bound[i]})
+		}
+
+		// One confirmation covers every destructive statement.
+		var destructive []query.Statement
+		var reason string
+		for _, stmt := range stmts {
+			if r, ok := query.Destructive(stmt.Text, e.Dialect()); ok {
+				if destructive == nil {
+					reason = r
+				}
+				destructive = append(destructive, stmt)
+			}
+		}
+
+		if len(destructive) > 0 && c.ConfirmsDestructive() {
+			if len(destructive) > 1 {
+				reason += fmt.Sprintf(" and %d more destructive statements", len(destructive)-1)
+			}
+			e.confirmDestructive(c, destructive[0], reason, func() {
+				e.startScript(run)
+			})
+			return
+		}
+
+		e.startScript(run)
+	})
+}
+
+// startScript runs the statements of run in the background, one at a time.
+func (e *Editor) startScript(run *scriptRun) {
+	if e.running {
+		e.notify("A statement is already running")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.cancel = cancel
+	e.script = run
+	e.errorMark = nil
+	run.running = true
+
+	prev := e.takeStream()
+	go func() {
+		e.closeStream(prev)
+		e.app.post(func() {
+			e.runScriptEntry(ctx, cancel, run, 0)
+		})
+	}()
+}
+
+// runScriptEntry runs the i-th statement of run, and once it is done the
+// next one or the end of the script.
+func (e *Editor) runScriptEntry(ctx context.Context, cancel context.CancelFunc, run *scriptRun, i int) {
+	c, entry := run.conn, run.entries[i]
+	e.notify("Running %d of %d on %s...", i+1, len(run.entries), c.Name)
+
+	begin := ""
+	if e.needsImplicitBegin(entry.stmt.Text) {
+		begin = beginStatement(e.Dialect())
+	}
+	retry := begin == "" && e.mayRetry(entry.stmt.Text)
+	rowCap := e.app.config.Results.RowCap()
+
+	go func() {
+		var began bool
+		var err error
+		if begin != "" {
+			_, err = e.execute(ctx, c, begin, false)
+			began = err == nil
+		}
+
+		var res *db.Result
+		start := time.Now()
+		if err == nil {
+			err = e.onSession(ctx, c, retry, func(s db.Session) error {
+				rows, err := s.Query(ctx, entry.bound.Text, entry.bound.Args...)
+				if err != nil {
+					return err
+				}
+
+				res, err = db.Collect(rows, rowCap)
+				return err
+			})
+		}
+		elapsed := time.Since(start)
+
+		e.app.post(func() {
+			if began {
+				e.trackTransaction(begin)
+			}
+
+			entry.elapsed = elapsed
+			run.elapsed += elapsed
+
+			if err != nil {
+				entry.status, entry.err = scriptFailed, err
+				e.recordStatement(c, entry.stmt.Text, elapsed, 0, err)
+			} else {
+				entry.status, entry.result = scriptDone, res
+				entry.rows = int64(len(res.Rows))
+				if len(res.Columns) == 0 {
+					entry.rows = res.RowsAffected
+				}
+				e.recordStatement(c, entry.stmt.Text, elapsed, entry.rows, nil)
+				e.trackTransaction(entry.stmt.Text)
+			}
+
+			stop := run.stopped || ctx.Err() != nil || (err != nil && (!run.keepGoing || errors.Is(err, db.ErrBroken)))
+			if i+1 < len(run.entries) && !stop {
+				e.runScriptEntry(ctx, cancel, run, i+1)
+				return
+			}
+
+			for _, left := range run.entries[i+1:] {
+				left.status = scriptSkipped
+			}
+			e.finishScript(run, cancel)
+		})
+	}()
+}
+
+// finishScript shows the results the script returned rows for in tabs, and
+// the report of the run.
+func (e *Editor) finishScript(run *scriptRun, cancel context.CancelFunc) {
+	cancel()
+	e.running = false
+	e.cancel = nil
+	run.running = false
+
+	// Each result with rows gets a tab, the last one is shown.
+	shown := false
+	for _, entry := range run.entries {
+		if entry.status != scriptDone || len(entry.result.Columns) == 0 {
+			continue
+		}
+
+		label := snippet(entry.stmt.Text, 30)
+		if !shown {
+			e.Results.SetResult(label, entry.result, entry.elapsed)
+			e.edits = nil
+			shown = true
+			continue
+		}
+		e.Results.AddResult(label, entry.result, entry.elapsed)
+	}
+	if shown {
+		e.Results.ShowTab(e.Results.TabCount() - 1)
+	}
+
+	e.OpenPopup(NewScriptReport(e, run))
+
+	if failed := run.failed(); failed != nil && !run.keepGoing {
+		run.current = slices.Index(run.entries, failed)
+		e.markError(failed.stmt, failed.bound, failed.err)
+		e.statementFailed(run.conn, fmt.Errorf("line %d: %w", failed.stmt.Line+1, failed.err))
+		return
+	}
+
+	e.notify("Script on %s: %s", run.conn.Name, run.summary())
+}
+
+// OpenScriptReport shows the report of the last script run again.
+func (e *Editor) OpenScriptReport() {
+	if e.script == nil {
+		e.notify("No script run")
+		return
+	}
+
+	e.OpenPopup(NewScriptReport(e, e.script))
+}
+
+// JumpTo moves the cursor to the start of a statement of the buffer.
+func (e *Editor) JumpTo(stmt query.Statement) {
+	x, y := e.OffsetPosition(stmt.Offset)
+	e.SetCursor(x, y)
+}
+
+// showScriptEntry moves the cursor to a statement of the script, to where
+// its error points if it failed, and shows the error.
+func (e *Editor) showScriptEntry(entry *scriptEntry) {
+	if entry.err == nil {
+		e.JumpTo(entry.stmt)
+		return
+	}
+
+	if !e.markError(entry.stmt, entry.bound, entry.err) {
+		e.JumpTo(entry.stmt)
+	}
+	e.fail("Error: line %d: %s", entry.stmt.Line+1, describeError(entry.err))
+}
+
+// NextScriptError moves to the statement that failed delta failures away
+// from the one last shown, in the last script run, wrapping around. The
+// failures work as a quickfix list of the buffer.
+func (e *Editor) NextScriptError(delta int) {
+	if e.script == nil {
+		e.notify("No script run")
+		return
+	}
+
+	var failed []int
+	for i, entry := range e.script.entries {
+		if entry.status == scriptFailed {
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) == 0 {
+		e.notify("No errors in the last script run")
+		return
+	}
+
+	// The first move goes to the first failure either way.
+	at := slices.Index(failed, e.script.current)
+	switch {
+	case at < 0:
+		at = 0
+	default:
+		at = ((at+delta)%len(failed) + len(failed)) % len(failed)
+	}
+
+	e.script.current = failed[at]
+	e.showScriptEntry(e.script.entries[e.script.current])
+	e.StatusBar.Command = fmt.Sprintf("(%d of %d) %s", at+1, len(failed), e.StatusBar.Command)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/gdamore/tcell"
+)
+
+// runScript runs a :script command line and waits for each of its
+// statements.
+func runScript(t *testing.T, e *Editor, line string) {
+	t.Helper()
+
+	typeKeys(e, ":"+line)
+	pressKey(e, tcell.KeyEnter)
+	for e.running || e.script == nil {
+		runPosted(t, e)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	e.Lines = []string{
+		"INSERT INTO t VALUES (1);",
+		"INSERT INTO nope VALUES (2);",
+		"INSERT INTO t VALUES (3);",
+		"SELECT n FROM t ORDER BY n;",
+	}
+
+	statuses := func() []scriptStatus {
+		var got []scriptStatus
+		for _, entry := range e.script.entries {
+			got = append(got, entry.status)
+		}
+		return got
+	}
+
+	// A failure stops the script, the report opens on it.
+	runScript(t, e, "script")
+	if got := statuses(); len(got) != 4 || got[0] != scriptDone || got[1] != scriptFailed || got[2] != scriptSkipped || got[3] != scriptSkipped {
+		t.Fatalf("got statuses %v, want done, failed and skipped", got)
+	}
+	if !strings.HasPrefix(e.StatusBar.Command, "Error: line 2: ") {
+		t.Errorf("got %q, want the error of line 2", e.StatusBar.Command)
+	}
+
+	report, ok := e.Popup.(*ScriptReport)
+	if !ok || report.selected != 1 {
+		t.Fatalf("got popup %T, want the report on the failed statement", e.Popup)
+	}
+	pressKey(e, tcell.KeyEnter)
+	if e.Popup != nil || e.CursorY != 1 {
+		t.Fatalf("got cursor on line %d, want the failed statement's", e.CursorY+1)
+	}
+
+	// script! keeps going, the last rows returned are shown.
+	runScript(t, e, "script!")
+	if got := statuses(); got[1] != scriptFailed || got[2] != scriptDone || got[3] != scriptDone {
+		t.Fatalf("got statuses %v, want only the second to fail", got)
+	}
+	if rows := e.Results.Result.Rows; len(rows) != 3 {
+		t.Errorf("got rows %v, want 1, 1 and 3", rows)
+	}
+	if !strings.HasPrefix(e.StatusBar.Command, "Script on Fixture: 3 ok, 1 failed in ") {
+		t.Errorf("got %q, want the summary", e.StatusBar.Command)
+	}
+	typeKeys(e, "q")
+
+	// The failures work as a quickfix list.
+	e.SetCursor(0, 3)
+	typeKeys(e, ":cnext")
+	pressKey(e, tcell.KeyEnter)
+	if e.CursorY != 1 || !strings.HasPrefix(e.StatusBar.Command, "(1 of 1) Error: line 2: ") {
+		t.Errorf("got cursor on line %d and %q, want the failed statement", e.CursorY+1, e.StatusBar.Command)
+	}
+
+	runScript(t, e, "script 4,4")
+	if len(e.script.entries) != 1 || e.script.entries[0].rows != 3 {
+		t.Errorf("got %d statements, want the last line's only", len(e.script.entries))
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell"
+)
+
+// ScriptReport is a popup listing the statements of a script run with how
+// each went. Enter jumps to the selected statement in the buffer.
+type ScriptReport struct {
+	Run *scriptRun
+
+	selected int
+	offset   int
+	height   int // entries visible, set by Draw
+
+	style    tcell.Style
+	dim      tcell.Style
+	selStyle tcell.Style
+	failed   tcell.Style
+
+	screen tcell.Screen
+	editor *Editor
+}
+
+func NewScriptReport(editor *Editor, run *scriptRun) *ScriptReport {
+	r := &ScriptReport{
+		Run:      run,
+		style:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		dim:      tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack).Dim(true),
+		selStyle: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+		failed:   tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack),
+		screen:   editor.screen,
+		editor:   editor,
+	}
+
+	// The first failure is what there is to look at.
+	for i, entry := range run.entries {
+		if entry.status == scriptFailed {
+			r.selected = i
+			break
+		}
+	}
+
+	return r
+}
+
+func (r *ScriptReport) HandleEventKey(ek *tcell.EventKey) {
+	switch ek.Key() {
+	case tcell.KeyEscape:
+		r.editor.ClosePopup()
+	case tcell.KeyEnter:
+		r.jump()
+	case tcell.KeyUp:
+		r.move(-1)
+	case tcell.KeyDown:
+		r.move(1)
+	case tcell.KeyPgUp:
+		r.move(-max(1, r.height))
+	case tcell.KeyPgDn:
+		r.move(max(1, r.height))
+	case tcell.KeyRune:
+		switch ek.Rune() {
+		case 'q':
+			r.editor.ClosePopup()
+		case 'k':
+			r.move(-1)
+		case 'j':
+			r.move(1)
+		case 'n':
+			r.nextFailure()
+		}
+	}
+}
+
+func (r *ScriptReport) move(delta int) {
+	r.selected = max(0, min(r.selected+delta, len(r.Run.entries)-1))
+}
+
+// nextFailure selects the next failed statement, wrapping around.
+func (r *ScriptReport) nextFailure() {
+	n := len(r.Run.entries)
+	for i := 1; i <= n; i++ {
+		if j := (r.selected + i) % n; r.Run.entries[j].status == scriptFailed {
+			r.selected = j
+			return
+		}
+	}
+}
+
+// jump closes the report and moves the cursor to the selected statement,
+// or to where its error points.
+func (r *ScriptReport) jump() {
+	r.Run.current = r.selected
+	r.editor.ClosePopup()
+	r.editor.showScriptEntry(r.Run.entries[r.selected])
+}
+
+// outcome describes how a statement went, e.g. "12ms  3 rows".
+func (entry *scriptEntry) outcome() string {
+	switch entry.status {
+	case scriptDone:
+		return fmt.Sprintf("%s  %d rows", entry.elapsed.Round(time.Millisecond), entry.rows)
+	case scriptFailed:
+		return fmt.Sprintf("%s  Error: %s", entry.elapsed.Round(time.Millisecond), entry.err)
+	case scriptSkipped:
+		return "skipped"
+	default:
+		return "pending"
+	}
+}
+
+func (entry *scriptEntry) mark() string {
+	switch entry.status {
+	case scriptDone:
+		return "✓"
+	case scriptFailed:
+		return "✗"
+	default:
+		return "-"
+	}
+}
+
+func (r *ScriptReport) Draw() {
+	w, h := r.screen.Size()
+
+	width := w - 4
+	height := min(len(r.Run.entries)+5, h-4)
+	if width < 20 || height < 6 {
+		return
+	}
+
+	x := (w - width) / 2
+	y := (h - height) / 2
+
+	title := fmt.Sprintf("Script on %s", r.Run.conn.Name)
+	drawBox(r.screen, x, y, width, height, title, r.editor.AccentStyle(), r.style)
+	drawText(r.screen, x+2, y+1, width-4, r.Run.summary(), r.dim)
+
+	r.height = height - 4
+	if r.selected < r.offset {
+		r.offset = r.selected
+	}
+	if r.selected >= r.offset+r.height {
+		r.offset = r.selected - r.height + 1
+	}
+
+	for i := 0; i < r.height && r.offset+i < len(r.Run.entries); i++ {
+		entry := r.Run.entries[r.offset+i]
+		row := y + 2 + i
+
+		style, dim := r.style, r.dim
+		if entry.status == scriptFailed {
+			style, dim = r.failed, r.failed
+		}
+		if r.offset+i == r.selected {
+			style, dim = r.selStyle, r.selStyle
+			fillRow(r.screen, x+1, row, width-2, style)
+		}
+
+		label := fmt.Sprintf("%s %4d  %s", entry.mark(), entry.stmt.Line+1, snippet(entry.stmt.Text, 40))
+		n := drawText(r.screen, x+2, row, width-4, label, style)
+		drawText(r.screen, x+2+n+2, row, width-6-n, entry.outcome(), dim)
+	}
+
+	drawText(r.screen, x+2, y+height-2, width-4, "Enter to jump to the statement, n to the next failure, q to close", r.dim)
+}
@@ -2,30 +2,75 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gdamore/tcell"
 )
 
 type StatusBar struct {
-	style       tcell.Style
-	insertStyle tcell.Style
-	normalStyle tcell.Style
+	style        tcell.Style
+	warningStyle tcell.Style
+	errorStyle   tcell.Style
+	insertStyle  tcell.Style
+	normalStyle  tcell.Style
+	visualStyle  tcell.Style
+	commandStyle tcell.Style
 
 	screen tcell.Screen
 
 	Command string
 	CursorX int
+	level   MessageLevel // of the message in the command line
+
+	prompt     string
+	onAnswer   func(string)
+	returnMode EditorMode // mode to go back to once the prompt is answered, or the line run
 
 	editor *Editor
 }
 
 func NewStatusBar(screen tcell.Screen, editor *Editor) *StatusBar {
-	return &StatusBar{
-		style:       tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
-		insertStyle: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorGreen),
-		normalStyle: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlue),
-		screen:      screen,
-		editor:      editor,
+	s := &StatusBar{
+		style:        tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		warningStyle: tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack),
+		errorStyle:   tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack),
+		insertStyle:  tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorGreen),
+		normalStyle:  tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlue),
+		visualStyle:  tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlue),
+		commandStyle: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlue),
+		screen:       screen,
+		editor:       editor,
+	}
+
+	modeColors := editor.app.config.Theme.ModeColors
+	for _, m := range []struct {
+		style *tcell.Style
+		color string
+	}{
+		{&s.normalStyle, modeColors.Normal},
+		{&s.insertStyle, modeColors.Insert},
+		{&s.visualStyle, modeColors.Visual},
+		{&s.commandStyle, modeColors.Command},
+	} {
+		if style, ok := backgroundStyle(m.color); ok {
+			*m.style = style
+		}
+	}
+
+	return s
+}
+
+// modeStyle is the style of the mode indicator for the current mode.
+func (s *StatusBar) modeStyle() tcell.Style {
+	switch s.editor.EditorMode {
+	case InsertMode:
+		return s.insertStyle
+	case VisualMode, VisualLineMode:
+		return s.visualStyle
+	case CommandMode:
+		return s.commandStyle
+	default:
+		return s.normalStyle
 	}
 }
 
@@ -36,15 +81,51 @@ func (s *StatusBar) HandleEventKey(ek *tcell.EventKey) {
 
 	switch ek.Key() {
 	case tcell.KeyEscape:
-		s.editor.SetEditorMode(NormalMode)
+		cancelled := s.onAnswer != nil
+		s.prompt = ""
+		s.onAnswer = nil
+
+		if cancelled || s.searchingResults() {
+			s.editor.SetEditorMode(s.returnMode)
+		} else {
+			s.editor.SetEditorMode(NormalMode)
+		}
 		s.Command = ""
 		s.CursorX = 0
+
+		if cancelled {
+			s.editor.notify("Cancelled")
+		}
 	case tcell.KeyEnter:
-		s.editor.SetEditorMode(NormalMode)
+		if s.onAnswer != nil {
+			answer := s.Command[len(s.prompt):]
+			onAnswer := s.onAnswer
+			s.prompt = ""
+			s.onAnswer = nil
+
+			s.editor.SetEditorMode(s.returnMode)
+			onAnswer(answer)
+			break
+		}
+
+		line := s.Command
+		searchingResults := s.searchingResults()
+		if searchingResults {
+			s.editor.SetEditorMode(ResultsMode)
+		} else {
+			s.editor.SetEditorMode(NormalMode)
+		}
 		s.Command = ""
 		s.CursorX = 0
+
+		switch {
+		case strings.HasPrefix(line, ":"):
+			s.editor.ExecuteCommand(line[1:])
+		case searchingResults:
+			s.editor.SearchResults(line[1:], false)
+		}
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
-		if s.CursorX > 0 {
+		if s.CursorX > len(s.prompt) {
 			s.Command = s.Command[:s.CursorX-1] + s.Command[s.CursorX:]
 			s.CursorX--
 			break
@@ -55,6 +136,33 @@ func (s *StatusBar) HandleEventKey(ek *tcell.EventKey) {
 	}
 }
 
+// searchingResults reports if the line is a "/" search started from the
+// results pane.
+func (s *StatusBar) searchingResults() bool {
+	return s.onAnswer == nil && s.returnMode == ResultsMode && strings.HasPrefix(s.Command, "/")
+}
+
+// Prompt asks a question in the command line. onAnswer is called with what
+// the user typed once they press enter, escape cancels the prompt. Either
+// way the editor goes back to the mode it was in.
+func (s *StatusBar) Prompt(question string, onAnswer func(answer string)) {
+	s.PromptWith(question, "", onAnswer)
+}
+
+// PromptWith is Prompt with an answer typed in already, for editing a value.
+func (s *StatusBar) PromptWith(question, answer string, onAnswer func(answer string)) {
+	s.returnMode = NormalMode
+	if s.editor.EditorMode != CommandMode {
+		s.returnMode = s.editor.EditorMode
+	}
+
+	s.editor.SetEditorMode(CommandMode)
+	s.prompt = question
+	s.onAnswer = onAnswer
+	s.Command = question + answer
+	s.CursorX = len(s.Command)
+}
+
 func (s *StatusBar) Draw() {
 	s.drawStatus()
 	s.drawCommand()
@@ -63,13 +171,21 @@ func (s *StatusBar) Draw() {
 func (s *StatusBar) drawCommand() {
 	w, h := s.screen.Size() // Get width and height
 
+	style := s.style
+	switch s.level {
+	case MessageWarning:
+		style = s.warningStyle
+	case MessageError:
+		style = s.errorStyle
+	}
+
 	for x := 0; x < w; x++ {
 		ch := ' '
 		if x < len(s.Command) {
 			ch = rune(s.Command[x])
 		}
 
-		s.screen.SetContent(x, h-1, ch, nil, s.style)
+		s.screen.SetContent(x, h-1, ch, nil, style)
 	}
 }
 
@@ -88,24 +204,34 @@ func (s *StatusBar) drawStatus() {
 		mode = "V-LINE"
 	case CommandMode:
 		mode = "COMMAND"
+	case ResultsMode:
+		mode = "RESULTS"
 	}
 
 	mode = fmt.Sprintf("  %s  ", mode)
 
-	status := fmt.Sprintf("%s %s %d/%d:%d", mode, "[No Name]", s.editor.CursorY+1, len(s.editor.Lines), s.editor.CursorX+1)
+	connection := "[No Connection]"
+	if c := s.editor.Connection; c != nil {
+		connection = fmt.Sprintf("[%s (%s)]", c.Name, c.Type)
+		if c.Environment != "" {
+			connection = fmt.Sprintf("[%s (%s) %s]", c.Name, c.Type, strings.ToUpper(c.Environment))
+		}
+	}
+
+	if t := s.editor.txn; t != nil {
+		connection += " [" + t.Status() + "]"
+	}
+
+	status := fmt.Sprintf("%s %s %s %d/%d:%d", mode, "[No Name]", connection, s.editor.CursorY+1, len(s.editor.Lines), s.editor.CursorX+1)
 	for x := 0; x < w; x++ {
 		ch := ' '
 		if x < len(status) {
 			ch = rune(status[x])
 		}
 
-		style := s.style
-
-		if x < len(mode) && s.editor.EditorMode == InsertMode {
-			style = s.insertStyle
-		}
-		if x < len(mode) && s.editor.EditorMode != InsertMode {
-			style = s.normalStyle
+		style := s.editor.AccentStyle()
+		if x < len(mode) {
+			style = s.modeStyle()
 		}
 
 		s.screen.SetContent(x, h-2, ch, nil, style)
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/gdamore/tcell"
+)
+
+func TestStatusBarColors(t *testing.T) {
+	cfg := &config.Config{
+		Connections: []config.Connection{
+			{Name: "Orders", Type: "postgres", Host: "db", Environment: "production"},
+			{Name: "Local", Type: "postgres", Host: "localhost"},
+		},
+		Theme: config.Theme{ModeColors: config.ModeColors{Insert: "purple"}},
+	}
+
+	tests := []struct {
+		connection string
+		mode       EditorMode
+		wantMode   tcell.Color
+		wantAccent tcell.Color
+	}{
+		{connection: "Local", mode: NormalMode, wantMode: tcell.ColorBlue, wantAccent: tcell.ColorBlack},
+		{connection: "Local", mode: InsertMode, wantMode: tcell.ColorPurple, wantAccent: tcell.ColorBlack},
+		{connection: "Orders", mode: NormalMode, wantMode: tcell.ColorBlue, wantAccent: tcell.ColorRed},
+	}
+
+	for _, tt := range tests {
+		e := newTestEditor(t, cfg)
+		c, _ := cfg.Connection(tt.connection)
+		e.Connect(c)
+		e.SetEditorMode(tt.mode)
+		e.Draw()
+
+		_, h := e.screen.Size()
+
+		_, _, modeStyle, _ := e.screen.GetContent(0, h-2)
+		if _, bg, _ := modeStyle.Decompose(); bg != tt.wantMode {
+			t.Errorf("%s: got mode color %v, want %v", tt.connection, bg, tt.wantMode)
+		}
+
+		_, _, accentStyle, _ := e.screen.GetContent(40, h-2)
+		if _, bg, _ := accentStyle.Decompose(); bg != tt.wantAccent {
+			t.Errorf("%s: got accent color %v, want %v", tt.connection, bg, tt.wantAccent)
+		}
+	}
+}
+
+func TestConfigColorsDrawn(t *testing.T) {
+	// Every color the UI draws by name is accepted by the config.
+	for name := range tcell.ColorNames {
+		if !config.IsValidColor(name) {
+			t.Errorf("got %s refused by the config", name)
+		}
+	}
+
+	for _, name := range []string{"Orange", "#00aa00"} {
+		if !config.IsValidColor(name) {
+			t.Errorf("got %s refused by the config", name)
+		}
+		if _, ok := backgroundStyle(name); !ok {
+			t.Errorf("got no style for %s", name)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+// resultStream is the result in the results pane while it has rows left to
+// fetch. The buffer keeps its session until the stream is closed.
+type resultStream struct {
+	*db.Stream
+	cancel context.CancelFunc // ends the context the statement ran in
+	result *db.Result         // the rows fetched so far, in a tab of the results pane
+	label  string             // of the tab, for the result sets that follow
+}
+
+// takeStream detaches the streamed result, so the statement about to run
+// can close it first. A session reads one result at a time.
+func (e *Editor) takeStream() *resultStream {
+	st := e.stream
+	e.stream = nil
+	e.Results.OnMore = nil
+
+	return st
+}
+
+// closeStream stops reading st, the rows fetched so far stay in the results
+// pane.
+func (e *Editor) closeStream(st *resultStream) {
+	if st == nil {
+		return
+	}
+
+	if err := st.Close(); err != nil {
+		e.app.log.Errorf("failed closing result stream: %v", err)
+	}
+	st.cancel()
+}
+
+// FetchMore fetches the next page of the streamed result, the results pane
+// calls it when the selection gets near the last row.
+func (e *Editor) FetchMore() {
+	if e.running {
+		return
+	}
+
+	e.fetch(false)
+}
+
+// FetchAll fetches the rest of the streamed result, up to the row cap.
+func (e *Editor) FetchAll() {
+	if e.stream == nil {
+		res := e.Results.Result
+		if res != nil && res.More {
+			e.notify("Stopped at %d rows, raise results.max_rows to fetch more", len(res.Rows))
+			return
+		}
+
+		e.notify("All rows fetched")
+		return
+	}
+
+	if e.running {
+		e.notify("A statement is already running")
+		return
+	}
+
+	e.fetch(true)
+}
+
+// fetch reads the next page of the streamed result in the background, or
+// every page when all is set. Pages are added to the results pane as they
+// arrive.
+func (e *Editor) fetch(all bool) {
+	st := e.stream
+	if st == nil {
+		return
+	}
+
+	// Pages go to the tab shown.
+	if st.result != e.Results.Result {
+		e.notify("The rows left to fetch are in another tab")
+		return
+	}
+
+	pageSize := e.app.config.Results.Page()
+	rowCap := e.app.config.Results.RowCap()
+	limit := rowCap - len(e.Results.Result.Rows)
+	if limit <= 0 {
+		e.closeStream(e.takeStream())
+		e.notify("%s, stopped at the row cap", e.Results.Summary())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.fetching = true
+	e.cancel = cancel
+	if all {
+		e.notify("Fetching...")
+	}
+
+	go func() {
+		defer cancel()
+
+		fetched := 0
+		for {
+			start := time.Now()
+			rows, err := st.Fetch(min(pageSize, limit-fetched))
+			elapsed := time.Since(start)
+			fetched += len(rows)
+
+			capped := fetched >= limit
+			last := err != nil || st.Done() || !all || capped || ctx.Err() != nil
+
+			var sets []*db.Result
+			if err == nil && st.Done() {
+				sets, err = moreResultSets(st.Stream, rowCap)
+			}
+
+			e.app.post(func() {
+				e.addPage(st, rows, elapsed, err, last, capped, sets)
+			})
+
+			if last {
+				return
+			}
+		}
+	}()
+}
+
+// addPage adds a page of the streamed result to the results pane. Once the
+// last page of a fetch is in, the stream is finished or closed if it ran
+// out of rows or hit the row cap, and the result sets following it get tabs
+// of their own.
+func (e *Editor) addPage(st *resultStream, rows [][]any, elapsed time.Duration, err error, last, capped bool, sets []*db.Result) {
+	e.Results.AppendRows(rows, elapsed)
+	if !last {
+		e.notify("Fetching... %s", e.Results.Summary())
+		return
+	}
+
+	e.running = false
+	e.fetching = false
+	e.cancel = nil
+
+	if e.stream != st {
+		return
+	}
+
+	switch {
+	case err != nil:
+		e.closeStream(e.takeStream())
+		e.fail("Error: %s", err)
+		return
+	case st.Done():
+		e.closeStream(e.takeStream())
+		e.Results.Result.More = false
+		e.Results.Result.Tag, e.Results.Result.RowsAffected = st.Tag(), st.RowsAffected()
+		e.notify("%s", e.addResultSets(st.label, sets, elapsed))
+		return
+	case capped:
+		e.closeStream(e.takeStream())
+		e.notify("%s, stopped at the row cap", e.Results.Summary())
+		return
+	}
+
+	e.notify("%s", e.Results.Summary())
+}
+
+// moreResultSets reads the result sets following the one st was read to the
+// end of, like those of a stored procedure, up to rowCap rows each. Only the
+// first result set is fetched page by page.
+func moreResultSets(st *db.Stream, rowCap int) ([]*db.Result, error) {
+	var sets []*db.Result
+	for st.NextResultSet() {
+		res := &db.Result{Columns: st.Columns()}
+		for !st.Done() && len(res.Rows) < rowCap {
+			rows, err := st.Fetch(rowCap - len(res.Rows))
+			if err != nil {
+				return sets, err
+			}
+			res.Rows = append(res.Rows, rows...)
+		}
+
+		if !st.Done() {
+			res.More = true
+			return append(sets, res), st.Close()
+		}
+
+		res.Tag, res.RowsAffected = st.Tag(), st.RowsAffected()
+		sets = append(sets, res)
+	}
+
+	return sets, nil
+}
+
+// addResultSets adds a tab for each of the result sets following the first
+// one of a statement, and returns the summary of the results shown.
+func (e *Editor) addResultSets(label string, sets []*db.Result, elapsed time.Duration) string {
+	for _, res := range sets {
+		e.Results.AddResult(label, res, elapsed)
+	}
+
+	if len(sets) == 0 {
+		return e.Results.Summary()
+	}
+
+	return fmt.Sprintf("%s, %d more result sets, gt to see them", e.Results.Summary(), len(sets))
+}
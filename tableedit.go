@@ -0,0 +1,533 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+// nullInput is what to type in a cell to set it to NULL.
+const nullInput = `\N`
+
+// tableEdits are the changes made in the results pane to rows of the table
+// the result was selected from. They are kept until applied or discarded.
+type tableEdits struct {
+	result *db.Result
+	stmt   string // the statement the result came from
+
+	// Set once the table and its primary key were looked up.
+	source  query.Source
+	columns []string // table column of each result column, "" for expressions
+	key     []int    // result columns holding the primary key
+	rows    map[int]*rowEdit
+}
+
+// rowEdit is how a row of the result was edited.
+type rowEdit struct {
+	original []any        // values as selected, nil for added rows
+	changed  map[int]bool // columns given a new value
+	deleted  bool
+}
+
+// ready reports if the table's primary key was looked up.
+func (t *tableEdits) ready() bool {
+	return t.key != nil
+}
+
+// edit returns the edit of row, starting one if the row wasn't edited yet.
+func (t *tableEdits) edit(row int) *rowEdit {
+	if re, ok := t.rows[row]; ok {
+		return re
+	}
+
+	re := &rowEdit{original: slices.Clone(t.result.Rows[row]), changed: map[int]bool{}}
+	t.rows[row] = re
+
+	return re
+}
+
+// State tells the results pane how a cell was edited.
+func (t *tableEdits) State(row, col int) CellState {
+	re, ok := t.rows[row]
+	switch {
+	case !ok:
+		return CellClean
+	case re.deleted:
+		return CellDeleted
+	case re.original == nil:
+		return CellAdded
+	case re.changed[col]:
+		return CellChanged
+	default:
+		return CellClean
+	}
+}
+
+// Statements generates the DML writing the edits back: deletes first so
+// their keys can be reused, then updates and inserts.
+func (t *tableEdits) Statements(d query.Dialect) []string {
+	var deletes, updates, inserts []string
+
+	table := t.source.Name(d)
+	for _, row := range t.editedRows() {
+		re, values := t.rows[row], t.result.Rows[row]
+
+		switch {
+		case re.deleted && re.original == nil:
+		case re.deleted:
+			deletes = append(deletes, fmt.Sprintf("DELETE FROM %s WHERE %s", table, t.where(re, d)))
+		case re.original == nil:
+			inserts = append(inserts, t.insert(table, re, values, d))
+		case len(re.changed) > 0:
+			var set []string
+			for _, col := range sortedColumns(re.changed) {
+				set = append(set, query.QuoteIdent(t.columns[col], d)+" = "+query.Literal(values[col], d))
+			}
+			updates = append(updates, fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(set, ", "), t.where(re, d)))
+		}
+	}
+
+	return append(append(deletes, updates...), inserts...)
+}
+
+func (t *tableEdits) insert(table string, re *rowEdit, values []any, d query.Dialect) string {
+	cols := sortedColumns(re.changed)
+	if len(cols) == 0 {
+		if d == query.MySQL {
+			return fmt.Sprintf("INSERT INTO %s () VALUES ()", table)
+		}
+		return fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", table)
+	}
+
+	names := make([]string, len(cols))
+	literals := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = query.QuoteIdent(t.columns[col], d)
+		literals[i] = query.Literal(values[col], d)
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(names, ", "), strings.Join(literals, ", "))
+}
+
+// where matches the row by the primary key it was selected with.
+func (t *tableEdits) where(re *rowEdit, d query.Dialect) string {
+	conds := make([]string, len(t.key))
+	for i, col := range t.key {
+		conds[i] = query.QuoteIdent(t.columns[col], d) + " = " + query.Literal(re.original[col], d)
+	}
+
+	return strings.Join(conds, " AND ")
+}
+
+func (t *tableEdits) editedRows() []int {
+	rows := make([]int, 0, len(t.rows))
+	for row := range t.rows {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+
+	return rows
+}
+
+func sortedColumns(cols map[int]bool) []int {
+	sorted := make([]int, 0, len(cols))
+	for col := range cols {
+		sorted = append(sorted, col)
+	}
+	sort.Ints(sorted)
+
+	return sorted
+}
+
+// Pending is the number of statements the edits make.
+func (t *tableEdits) Pending() int {
+	n := 0
+	for _, re := range t.rows {
+		switch {
+		case re.deleted && re.original == nil:
+		case re.deleted, re.original == nil, len(re.changed) > 0:
+			n++
+		}
+	}
+
+	return n
+}
+
+// discard puts the rows back the way they were selected.
+func (t *tableEdits) discard() {
+	var added []int
+	for row, re := range t.rows {
+		if re.original == nil {
+			added = append(added, row)
+			continue
+		}
+		t.result.Rows[row] = re.original
+	}
+
+	t.removeRows(added)
+	t.rows = map[int]*rowEdit{}
+}
+
+// applied makes the edits part of the result once they were written.
+func (t *tableEdits) applied() {
+	var deleted []int
+	for row, re := range t.rows {
+		if re.deleted {
+			deleted = append(deleted, row)
+		}
+	}
+
+	t.removeRows(deleted)
+	t.rows = map[int]*rowEdit{}
+}
+
+func (t *tableEdits) removeRows(rows []int) {
+	sort.Sort(sort.Reverse(sort.IntSlice(rows)))
+	for _, row := range rows {
+		t.result.Rows = slices.Delete(t.result.Rows, row, row+1)
+	}
+}
+
+// pendingEdits is the number of statements the edits of the results make.
+func (e *Editor) pendingEdits() int {
+	if e.edits == nil || e.edits.result != e.Results.Result || !e.edits.ready() {
+		return 0
+	}
+
+	return e.edits.Pending()
+}
+
+// withTableEdits calls then with the edits of the result in the results
+// pane, once it is known the rows can be written back to their table. The
+// table's primary key is looked up the first time.
+func (e *Editor) withTableEdits(then func(t *tableEdits)) {
+	t := e.edits
+	if t == nil || t.result != e.Results.Result || e.Results.Result == nil {
+		e.notify("These results can't be edited")
+		return
+	}
+
+	c := e.Connection
+	if c == nil {
+		e.notify("Not connected, use :connect first")
+		return
+	}
+
+	if c.ReadOnly {
+		e.warn("Refused on read-only %s", c.Name)
+		return
+	}
+
+	if t.ready() {
+		then(t)
+		return
+	}
+
+	source, ok := query.SourceTable(t.stmt, e.Dialect())
+	if !ok {
+		e.notify("Only rows selected from a single table, without joins or grouping, can be edited")
+		return
+	}
+
+	columns := source.Columns
+	if columns == nil {
+		columns = make([]string, len(t.result.Columns))
+		for i, col := range t.result.Columns {
+			columns[i] = col.Name
+		}
+	}
+	if len(columns) != len(t.result.Columns) {
+		e.notify("These results can't be edited")
+		return
+	}
+
+	if e.running {
+		e.notify("A statement is already running")
+		return
+	}
+
+	// The session reads one result at a time, rows left to fetch are
+	// given up so it can look up the key and write the edits.
+	prev := e.takeStream()
+
+	// The lookup only reads the catalog.
+	retry := e.txn == nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.cancel = cancel
+	e.notify("Looking up the primary key of %s...", source.Table)
+
+	go func() {
+		defer cancel()
+
+		e.closeStream(prev)
+
+		var key []string
+		err := e.onSession(ctx, c, retry, func(s db.Session) (err error) {
+			key, err = db.PrimaryKey(ctx, s, c.Type, source.Schema, source.Table)
+			return err
+		})
+
+		e.app.post(func() {
+			e.running = false
+			e.cancel = nil
+
+			if err != nil {
+				e.fail("Error: %s", err)
+				return
+			}
+
+			cols, err := keyColumns(key, columns)
+			if err != nil {
+				e.warn("%s can't be edited, %s", source.Name(e.Dialect()), err)
+				return
+			}
+
+			if e.edits != t {
+				return
+			}
+
+			t.source, t.columns, t.key = source, columns, cols
+			t.rows = map[int]*rowEdit{}
+			e.Results.State = t.State
+			then(t)
+		})
+	}()
+}
+
+// keyColumns finds the result columns holding the primary key.
+func keyColumns(key, columns []string) ([]int, error) {
+	if len(key) == 0 {
+		return nil, errors.New("it has no primary key")
+	}
+
+	cols := make([]int, len(key))
+	for i, name := range key {
+		cols[i] = slices.IndexFunc(columns, func(c string) bool {
+			return strings.EqualFold(c, name)
+		})
+		if cols[i] < 0 {
+			return nil, fmt.Errorf("its primary key column %s isn't selected", name)
+		}
+	}
+
+	return cols, nil
+}
+
+// EditCell asks for a new value of a cell and keeps it as an edit of its
+// row. blank starts from an empty answer instead of the current value.
+func (e *Editor) EditCell(row, col int, blank bool) {
+	e.withTableEdits(func(t *tableEdits) {
+		if row >= len(t.result.Rows) || col >= len(t.columns) {
+			return
+		}
+
+		if t.columns[col] == "" {
+			e.notify("%s is an expression, not a column of %s", t.result.Columns[col].Name, t.source.Table)
+			return
+		}
+
+		if re, ok := t.rows[row]; ok && re.deleted {
+			e.notify("The row is deleted, dd to keep it")
+			return
+		}
+
+		current := ""
+		switch v := t.result.Rows[row][col]; {
+		case blank:
+		case v == nil:
+			current = nullInput
+		default:
+			current = db.FormatValue(v)
+		}
+
+		question := fmt.Sprintf("%s (%s for NULL): ", t.result.Columns[col].Name, nullInput)
+		e.StatusBar.PromptWith(question, current, func(answer string) {
+			var v any = answer
+			if answer == nullInput {
+				v = nil
+			}
+
+			re := t.edit(row)
+			re.changed[col] = true
+			t.result.Rows[row][col] = v
+			e.Results.fitWidths([][]any{t.result.Rows[row]})
+			e.notifyPending()
+		})
+	})
+}
+
+// AddRow adds an empty row to the results, inserted into the table once
+// applied.
+func (e *Editor) AddRow() {
+	e.withTableEdits(func(t *tableEdits) {
+		row := len(t.result.Rows)
+		t.result.Rows = append(t.result.Rows, make([]any, len(t.result.Columns)))
+		t.rows[row] = &rowEdit{changed: map[int]bool{}}
+
+		e.Results.ShowRow(row)
+		e.notifyPending()
+	})
+}
+
+// ToggleDeleteRow marks a row of the results to be deleted from the table,
+// or keeps it again.
+func (e *Editor) ToggleDeleteRow(row int) {
+	e.withTableEdits(func(t *tableEdits) {
+		if row >= len(t.result.Rows) {
+			return
+		}
+
+		re := t.edit(row)
+		re.deleted = !re.deleted
+		e.notifyPending()
+	})
+}
+
+func (e *Editor) notifyPending() {
+	switch n := e.pendingEdits(); n {
+	case 0:
+		e.notify("No changes")
+	case 1:
+		e.notify("1 change, :apply to review it")
+	default:
+		e.notify("%d changes, :apply to review them", n)
+	}
+}
+
+// DiscardEdits drops the edits made to the results.
+func (e *Editor) DiscardEdits() {
+	n := e.pendingEdits()
+	if n == 0 {
+		e.notify("No changes")
+		return
+	}
+
+	e.edits.discard()
+	e.Results.refreshRows()
+	e.Results.SetSelection(e.Results.Row, e.Results.Col)
+	e.notify("Discarded %d changes", n)
+}
+
+// ApplyEdits shows the statements writing the edits back and runs them in
+// one transaction once confirmed. Production connections need the
+// connection name typed out as well.
+func (e *Editor) ApplyEdits() {
+	if e.pendingEdits() == 0 {
+		e.notify("No changes")
+		return
+	}
+
+	e.withTableEdits(func(t *tableEdits) {
+		c := e.Connection
+		stmts := t.Statements(e.Dialect())
+
+		title := fmt.Sprintf("%d statements on %s", len(stmts), c.Name)
+		e.OpenPopup(NewReview(e, title, stmts, func() {
+			// The review was the confirmation, production asks again.
+			e.confirm(c, fmt.Sprintf("Apply %d statements", len(stmts)), "", func() {
+				e.applyEdits(c, t, stmts)
+			})
+		}))
+	})
+}
+
+// applyEdits runs stmts in the background, in a transaction of their own
+// unless one is open already. With autocommit off the transaction is left
+// open for :commit.
+func (e *Editor) applyEdits(c *config.Connection, t *tableEdits, stmts []string) {
+	if e.running {
+		e.notify("A statement is already running")
+		return
+	}
+
+	begin := ""
+	if e.txn == nil {
+		begin = beginStatement(e.Dialect())
+	}
+	commit := begin != "" && e.Autocommit
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.cancel = cancel
+	e.notify("Applying %d statements on %s...", len(stmts), c.Name)
+
+	go func() {
+		defer cancel()
+
+		err := e.onSession(ctx, c, false, func(s db.Session) error {
+			return runBatch(ctx, s, begin, stmts, commit)
+		})
+
+		e.app.post(func() {
+			e.running = false
+			e.cancel = nil
+
+			if err != nil {
+				if begin != "" {
+					e.fail("Error: %s, rolled back", err)
+					return
+				}
+				e.statementFailed(c, err)
+				return
+			}
+
+			if begin != "" && !commit {
+				e.trackTransaction(begin)
+			}
+			for _, stmt := range stmts {
+				e.trackTransaction(stmt)
+			}
+
+			t.applied()
+			e.Results.refreshRows()
+			e.Results.SetSelection(e.Results.Row, e.Results.Col)
+
+			if e.txn != nil {
+				e.notify("Applied %d statements, :commit to keep them", len(stmts))
+				return
+			}
+			e.notify("Applied %d statements", len(stmts))
+		})
+	}()
+}
+
+// runBatch runs stmts after begin, if set, committing them when commit is
+// set. A statement that fails or matches no row rolls back the transaction
+// it began.
+func runBatch(ctx context.Context, s db.Session, begin string, stmts []string, commit bool) error {
+	if begin != "" {
+		if _, err := db.Exec(ctx, s, begin); err != nil {
+			return err
+		}
+	}
+
+	for _, stmt := range stmts {
+		res, err := db.Exec(ctx, s, stmt)
+		if err == nil && res.RowsAffected == 0 && !strings.HasPrefix(stmt, "INSERT") {
+			err = errors.New("no row matched, it was changed or deleted since it was selected")
+		}
+
+		if err != nil {
+			if begin != "" {
+				db.Exec(ctx, s, "ROLLBACK")
+			}
+			return fmt.Errorf("%s: %w", snippet(stmt, 60), err)
+		}
+	}
+
+	if commit {
+		_, err := db.Exec(ctx, s, "COMMIT")
+		return err
+	}
+
+	return nil
+}
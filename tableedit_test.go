@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+	"github.com/gdamore/tcell"
+)
+
+// newUsersEditor has a users table keyed by id, and a log table without a
+// primary key.
+func newUsersEditor(t *testing.T) *Editor {
+	t.Helper()
+
+	e := newSQLiteEditor(t, config.Connection{})
+	runLine(t, e, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, nick TEXT)")
+	runLine(t, e, "CREATE TABLE log (line TEXT)")
+	runLine(t, e, "INSERT INTO users VALUES (1, 'ada@example.com', 'ada'), (2, 'bob@example.com', NULL), (3, 'cy@example.com', 'cy')")
+
+	return e
+}
+
+// answer replaces what the open prompt holds with text and submits it.
+func answer(e *Editor, text string) {
+	e.StatusBar.Command = e.StatusBar.prompt
+	e.StatusBar.CursorX = len(e.StatusBar.Command)
+	typeKeys(e, text)
+	pressKey(e, tcell.KeyEnter)
+}
+
+func TestEditResultRows(t *testing.T) {
+	e := newUsersEditor(t)
+	runLine(t, e, "SELECT id, email, nick FROM users ORDER BY id")
+	pressKey(e, tcell.KeyCtrlW)
+
+	// The first edit looks up the primary key.
+	typeKeys(e, "li")
+	runPosted(t, e)
+	if want := `email (\N for NULL): ada@example.com`; e.StatusBar.Command != want {
+		t.Fatalf("got prompt %q, want %q", e.StatusBar.Command, want)
+	}
+	answer(e, "ada@example.org")
+	if got := e.Results.State(0, 1); got != CellChanged {
+		t.Errorf("got state %v for the edited cell, want changed", got)
+	}
+
+	typeKeys(e, "jlcw")
+	if want := `nick (\N for NULL): `; e.StatusBar.Command != want {
+		t.Fatalf("got prompt %q, want %q", e.StatusBar.Command, want)
+	}
+	answer(e, "it's bob")
+
+	typeKeys(e, "jdd")
+	typeKeys(e, "o")
+	typeKeys(e, "hi")
+	answer(e, "dee@example.com")
+	typeKeys(e, "li")
+	answer(e, `\N`)
+
+	want := []string{
+		"DELETE FROM users WHERE id = 3",
+		"UPDATE users SET email = 'ada@example.org' WHERE id = 1",
+		"UPDATE users SET nick = 'it''s bob' WHERE id = 2",
+		"INSERT INTO users (email, nick) VALUES ('dee@example.com', NULL)",
+	}
+	if got := e.edits.Statements(query.SQLite); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got statements\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	typeKeys(e, ":apply")
+	pressKey(e, tcell.KeyEnter)
+	if _, ok := e.Popup.(*Review); !ok {
+		t.Fatalf("expected the statements to review, got %T", e.Popup)
+	}
+	typeKeys(e, "y")
+	runPosted(t, e)
+
+	if e.StatusBar.Command != "Applied 4 statements" {
+		t.Fatalf("got %q, want the statements applied", e.StatusBar.Command)
+	}
+	if n := len(e.Results.Result.Rows); n != 3 || e.pendingEdits() != 0 {
+		t.Errorf("got %d rows and %d edits left, want the deleted row gone", n, e.pendingEdits())
+	}
+
+	runLine(t, e, "SELECT email, nick FROM users ORDER BY id")
+	var got []string
+	for _, row := range e.Results.Result.Rows {
+		got = append(got, db.FormatValue(row[0])+" "+db.FormatValue(row[1]))
+	}
+	if want := "ada@example.org ada, bob@example.com it's bob, dee@example.com NULL"; strings.Join(got, ", ") != want {
+		t.Errorf("got rows %q, want %q", strings.Join(got, ", "), want)
+	}
+}
+
+func TestEditResultRowsRolledBack(t *testing.T) {
+	e := newUsersEditor(t)
+	runLine(t, e, "SELECT * FROM users WHERE id < 3")
+	pressKey(e, tcell.KeyCtrlW)
+
+	typeKeys(e, "dd")
+	runPosted(t, e)
+	typeKeys(e, "jli")
+	answer(e, "bob@example.org")
+
+	// The edited row is deleted behind the results' back in the meantime.
+	if _, err := db.Exec(context.Background(), e.session, "DELETE FROM users WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	typeKeys(e, ":apply")
+	pressKey(e, tcell.KeyEnter)
+	pressKey(e, tcell.KeyEnter)
+	runPosted(t, e)
+
+	if want := "Error: UPDATE users SET email = 'bob@example.org' WHERE id = 2: no row matched"; !strings.HasPrefix(e.StatusBar.Command, want) || !strings.HasSuffix(e.StatusBar.Command, "rolled back") {
+		t.Fatalf("got %q, want %q", e.StatusBar.Command, want)
+	}
+	if e.pendingEdits() != 2 {
+		t.Errorf("got %d edits, want them kept after the failure", e.pendingEdits())
+	}
+	if _, ok := e.Popup.(*MessageView); !ok {
+		t.Fatalf("got popup %T, want the error too wide for the command line", e.Popup)
+	}
+	typeKeys(e, "q")
+
+	// Running another statement asks before dropping the edits.
+	e.Lines = []string{"SELECT count(*) FROM users WHERE id = 1"}
+	typeKeys(e, ":run")
+	pressKey(e, tcell.KeyEnter)
+	if e.StatusBar.Command != "Discard 2 changes to the results and run? [y/N]: " {
+		t.Fatalf("got %q, want to be asked before the edits are lost", e.StatusBar.Command)
+	}
+	answer(e, "y")
+	runPosted(t, e)
+	if got := e.Results.Result.Rows[0][0]; got != int64(1) {
+		t.Errorf("got %v rows with id 1, want the delete rolled back", got)
+	}
+}
+
+func TestEditResultRowsRefused(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		readOnly bool
+		want     string
+	}{
+		{stmt: "SELECT * FROM users", readOnly: true, want: "Refused on read-only Fixture"},
+		{stmt: "SELECT u.* FROM users u JOIN log l ON l.line = u.email", want: "Only rows selected from a single table"},
+		{stmt: "SELECT email, count(*) FROM users GROUP BY email", want: "Only rows selected from a single table"},
+		{stmt: "SELECT email FROM users", want: "users can't be edited, its primary key column id isn't selected"},
+		{stmt: "SELECT * FROM log", want: "log can't be edited, it has no primary key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			e := newUsersEditor(t)
+			runLine(t, e, "INSERT INTO log VALUES ('ada@example.com')")
+			runLine(t, e, tt.stmt)
+			e.Connection.ReadOnly = tt.readOnly
+
+			pressKey(e, tcell.KeyCtrlW)
+			typeKeys(e, "i")
+			if e.running {
+				runPosted(t, e)
+			}
+
+			if !strings.HasPrefix(e.StatusBar.Command, tt.want) {
+				t.Errorf("got %q, want %q", e.StatusBar.Command, tt.want)
+			}
+			if e.EditorMode != ResultsMode {
+				t.Errorf("got mode %v, want no prompt", e.EditorMode)
+			}
+		})
+	}
+}
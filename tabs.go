@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ajm113/dbvi/db"
+)
+
+// resultTab is a result set in a tab of the results pane.
+type resultTab struct {
+	state  resultState // saved while another tab is active
+	label  string      // the statement the result came from, shortened
+	pinned bool        // kept when the next run replaces the others
+}
+
+// SetResult shows res, the result of a new run, in a tab of its own. The
+// tabs of the previous run go away unless they were pinned.
+func (r *ResultsPane) SetResult(label string, res *db.Result, elapsed time.Duration) {
+	r.saveTab()
+	r.tabs = slices.DeleteFunc(r.tabs, func(t *resultTab) bool { return !t.pinned })
+
+	r.AddResult(label, res, elapsed)
+	r.loadTab(len(r.tabs) - 1)
+}
+
+// AddResult adds a tab for another result set of the same run, next to the
+// active one.
+func (r *ResultsPane) AddResult(label string, res *db.Result, elapsed time.Duration) {
+	t := &resultTab{label: label}
+	t.state.fill(res, elapsed)
+	r.tabs = append(r.tabs, t)
+}
+
+// saveTab keeps what the pane shows with the active tab.
+func (r *ResultsPane) saveTab() {
+	if r.active < len(r.tabs) {
+		r.tabs[r.active].state = r.resultState
+	}
+}
+
+func (r *ResultsPane) loadTab(i int) {
+	r.active = i
+	r.resultState = r.tabs[i].state
+	r.SetSelection(r.Row, r.Col)
+}
+
+// TabCount is the number of result sets the pane holds.
+func (r *ResultsPane) TabCount() int {
+	return len(r.tabs)
+}
+
+// ShowTab activates the i-th tab.
+func (r *ResultsPane) ShowTab(i int) {
+	r.saveTab()
+	r.loadTab(i)
+}
+
+// SwitchTab activates the tab delta tabs away from the active one, wrapping
+// around.
+func (r *ResultsPane) SwitchTab(delta int) {
+	if n := len(r.tabs); n > 0 {
+		r.ShowTab(((r.active+delta)%n + n) % n)
+	}
+}
+
+// TogglePin pins the active tab, or unpins it, and reports if it is pinned.
+func (r *ResultsPane) TogglePin() bool {
+	t := r.tabs[r.active]
+	t.pinned = !t.pinned
+
+	return t.pinned
+}
+
+// CloseTab drops the active tab, the one after it becomes active.
+func (r *ResultsPane) CloseTab() {
+	r.tabs = slices.Delete(r.tabs, r.active, r.active+1)
+	if len(r.tabs) == 0 {
+		r.active = 0
+		r.resultState = resultState{}
+		return
+	}
+
+	r.loadTab(min(r.active, len(r.tabs)-1))
+}
+
+// holds reports if res is shown in one of the tabs.
+func (r *ResultsPane) holds(res *db.Result) bool {
+	for i, t := range r.tabs {
+		if i == r.active && r.Result == res || i != r.active && t.state.Result == res {
+			return true
+		}
+	}
+
+	return false
+}
+
+// showTabs reports if the tab bar is drawn, once there is more than one
+// result set or one was pinned.
+func (r *ResultsPane) showTabs() bool {
+	return len(r.tabs) > 1 || (len(r.tabs) == 1 && r.tabs[0].pinned)
+}
+
+// tabTitle describes a tab, e.g. "2 SELECT * FROM users  3 rows  12ms".
+func (r *ResultsPane) tabTitle(i int) string {
+	t := r.tabs[i]
+	s := &t.state
+	if i == r.active {
+		s = &r.resultState
+	}
+
+	title := fmt.Sprintf("%d %s", i+1, t.label)
+	if t.pinned {
+		title = fmt.Sprintf("%d • %s", i+1, t.label)
+	}
+
+	switch {
+	case s.Result == nil || s.lines != nil:
+	case len(s.Result.Columns) > 0:
+		title += fmt.Sprintf("  %d rows", len(s.Result.Rows))
+	default:
+		title += fmt.Sprintf("  %d affected", s.Result.RowsAffected)
+	}
+
+	return title + "  " + s.Elapsed.Round(time.Millisecond).String()
+}
+
+// drawTabs draws the tab bar on screen row y, scrolled to keep the active
+// tab in view.
+func (r *ResultsPane) drawTabs(y int) {
+	w, _ := r.screen.Size()
+	fillRow(r.screen, 0, y, w, r.nullStyle)
+
+	titles := make([]string, len(r.tabs))
+	for i := range r.tabs {
+		titles[i] = " " + r.tabTitle(i) + " "
+	}
+
+	first := 0
+	for x := 0; first < r.active; first++ {
+		x = 0
+		for _, title := range titles[first : r.active+1] {
+			x += len([]rune(title)) + 1
+		}
+		if x <= w {
+			break
+		}
+	}
+
+	x := 0
+	for i := first; i < len(titles) && x < w; i++ {
+		style := r.nullStyle
+		if i == r.active {
+			style = r.editor.AccentStyle()
+		}
+
+		x += drawText(r.screen, x, y, w-x, titles[i], style) + 1
+	}
+}
+
+// SwitchResultTab shows the result set delta tabs away from the one shown.
+func (e *Editor) SwitchResultTab(delta int) {
+	if !e.Results.Visible() {
+		e.notify("No results")
+		return
+	}
+
+	// Pages being fetched go to the tab shown.
+	if e.fetching {
+		e.notify("Fetching rows, wait for them or :cancel")
+		return
+	}
+
+	if e.pendingEdits() > 0 {
+		e.notify("The results have changes, :apply or :discard them first")
+		return
+	}
+
+	e.Results.SwitchTab(delta)
+	e.notify("%s", e.Results.tabTitle(e.Results.active))
+}
+
+// PinResultTab keeps the result set shown when the next statement runs, or
+// lets it go again.
+func (e *Editor) PinResultTab() {
+	if !e.Results.Visible() {
+		e.notify("No results")
+		return
+	}
+
+	if e.Results.TogglePin() {
+		e.notify("Pinned %s", e.Results.tabs[e.Results.active].label)
+		return
+	}
+
+	e.notify("Unpinned %s", e.Results.tabs[e.Results.active].label)
+}
+
+// CloseResultTab drops the result set shown. Rows left to fetch for it are
+// given up, edits have to be applied or discarded first.
+func (e *Editor) CloseResultTab() {
+	if !e.Results.Visible() {
+		e.notify("No results")
+		return
+	}
+
+	if e.pendingEdits() > 0 {
+		e.notify("The results have changes, :apply or :discard them first")
+		return
+	}
+
+	if e.stream != nil && e.stream.result == e.Results.Result {
+		if e.running {
+			e.notify("A statement is already running")
+			return
+		}
+
+		e.closeStream(e.takeStream())
+	}
+
+	e.Results.CloseTab()
+	if !e.Results.Visible() {
+		if e.EditorMode == ResultsMode {
+			e.SetEditorMode(NormalMode)
+		}
+		e.notify("No results")
+		return
+	}
+
+	e.notify("%s", e.Results.tabTitle(e.Results.active))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/gdamore/tcell"
+)
+
+func init() {
+	db.Register("sets", setsDriver{})
+}
+
+// setsDriver answers every statement with two result sets, like a MySQL
+// CALL.
+type setsDriver struct{}
+
+func (setsDriver) Open(context.Context, config.Connection, string) (db.Session, error) {
+	return setsSession{}, nil
+}
+
+type setsSession struct{}
+
+func (setsSession) Query(context.Context, string, ...any) (db.Rows, error) {
+	return &setsRows{sets: []*db.Result{
+		{Columns: []db.Column{{Name: "id"}}, Rows: [][]any{{int64(1)}, {int64(2)}}, Tag: "SELECT 2", RowsAffected: 2},
+		{Columns: []db.Column{{Name: "total"}}, Rows: [][]any{{int64(3)}}, Tag: "SELECT 1", RowsAffected: 1},
+	}}, nil
+}
+
+func (setsSession) Close() error { return nil }
+
+type setsRows struct {
+	sets []*db.Result
+	i    int
+}
+
+func (r *setsRows) Columns() []db.Column { return r.sets[0].Columns }
+func (r *setsRows) Next() bool           { r.i++; return r.i <= len(r.sets[0].Rows) }
+func (r *setsRows) Values() []any        { return r.sets[0].Rows[r.i-1] }
+func (r *setsRows) Err() error           { return nil }
+func (r *setsRows) Close() error         { return nil }
+func (r *setsRows) Tag() string          { return r.sets[0].Tag }
+func (r *setsRows) RowsAffected() int64  { return r.sets[0].RowsAffected }
+
+func (r *setsRows) NextResultSet() bool {
+	if len(r.sets) == 1 {
+		return false
+	}
+
+	r.sets, r.i = r.sets[1:], 0
+	return true
+}
+
+func TestResultTabs(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	e.Lines = []string{
+		"INSERT INTO t VALUES (1), (2);",
+		"SELECT n FROM t;",
+		"SELECT n FROM t WHERE n > 1;",
+	}
+
+	// Each statement of a script returning rows gets a tab, the last is
+	// shown.
+	runScript(t, e, "script")
+	typeKeys(e, "q")
+	if n := e.Results.TabCount(); n != 2 || e.Results.active != 1 || len(e.Results.Result.Rows) != 1 {
+		t.Fatalf("got %d tabs with %d active, want the second of 2", n, e.Results.active)
+	}
+
+	typeKeys(e, "gt")
+	if e.Results.active != 0 || len(e.Results.Result.Rows) != 2 {
+		t.Fatalf("got tab %d, want gt to wrap around to the first", e.Results.active)
+	}
+	typeKeys(e, "gT")
+	if e.Results.active != 1 {
+		t.Fatalf("got tab %d, want gT back on the second", e.Results.active)
+	}
+
+	// A pinned tab stays when the next statement runs.
+	typeKeys(e, ":tabpin")
+	pressKey(e, tcell.KeyEnter)
+	runLine(t, e, "SELECT count(*) FROM t")
+	if n := e.Results.TabCount(); n != 2 || !e.Results.tabs[0].pinned || e.Results.active != 1 {
+		t.Fatalf("got %d tabs, want the pinned one and the new one", n)
+	}
+
+	typeKeys(e, ":tabclose")
+	pressKey(e, tcell.KeyEnter)
+	if n := e.Results.TabCount(); n != 1 || len(e.Results.Result.Rows) != 1 || e.Results.Result.Columns[0].Name != "n" {
+		t.Fatalf("got %d tabs, want the pinned one left", n)
+	}
+
+	typeKeys(e, ":tabclose")
+	pressKey(e, tcell.KeyEnter)
+	if e.Results.Visible() {
+		t.Errorf("got results shown, want none once the last tab is closed")
+	}
+}
+
+func TestResultSetTabs(t *testing.T) {
+	e := newTestEditor(t, nil)
+	e.Connect(&config.Connection{Name: "Shop", Type: "sets", Host: "localhost"})
+	runLine(t, e, "CALL order_totals()")
+
+	// Each tab keeps the tag of its own set, not the one the stream ended
+	// on.
+	for i, want := range []string{"SELECT 2", "SELECT 1"} {
+		e.Results.ShowTab(i)
+		if got := e.Results.Result.Tag; got != want {
+			t.Errorf("tab %d: got tag %q, want %q", i, got, want)
+		}
+	}
+}
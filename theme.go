@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// backgroundStyle returns a style with name as its background and a
+// foreground that stays readable on it. ok is false for empty or unknown
+// colors.
+func backgroundStyle(name string) (tcell.Style, bool) {
+	color := tcell.GetColor(strings.ToLower(name))
+	if name == "" || color == tcell.ColorDefault {
+		return tcell.StyleDefault, false
+	}
+
+	fg := tcell.ColorWhite
+	if r, g, b := color.RGB(); r >= 0 && (299*r+587*g+114*b)/1000 > 150 {
+		fg = tcell.ColorBlack
+	}
+
+	return tcell.StyleDefault.Foreground(fg).Background(color), true
+}
+
+// AccentStyle is the style of the status line, borders and headers. It
+// takes the color of the buffer's connection, so a production connection is
+// hard to miss.
+func (e *Editor) AccentStyle() tcell.Style {
+	if e.Connection != nil {
+		if style, ok := backgroundStyle(e.Connection.AccentColor()); ok {
+			return style
+		}
+	}
+
+	return tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+// transaction is the transaction open on the buffer's session.
+type transaction struct {
+	started    time.Time
+	statements int      // statements run since it started
+	savepoints []string // oldest first
+}
+
+// Status is the status line indicator, e.g. "TXN 1m5s 3 stmts".
+func (t *transaction) Status() string {
+	elapsed := time.Since(t.started).Truncate(time.Second)
+	if t.statements == 1 {
+		return fmt.Sprintf("TXN %s 1 stmt", elapsed)
+	}
+
+	return fmt.Sprintf("TXN %s %d stmts", elapsed, t.statements)
+}
+
+// txnControl is what a statement does to the transaction state.
+type txnControl int
+
+const (
+	txnNone txnControl = iota
+	txnBegin
+	txnCommit
+	txnRollback
+	txnSavepoint
+	txnRollbackTo
+	txnRelease
+)
+
+// transactionControl tells how stmt changes the transaction state, and the
+// savepoint it names if any.
+func transactionControl(stmt string, d query.Dialect) (txnControl, string) {
+	var tokens []query.Token
+	for _, tok := range query.Lex(stmt, d) {
+		if tok.Significant() && tok.Text != ";" {
+			tokens = append(tokens, tok)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return txnNone, ""
+	}
+	last := tokens[len(tokens)-1].Text
+
+	switch tokens[0].Keyword() {
+	case "BEGIN":
+		return txnBegin, ""
+	case "START":
+		if len(tokens) > 1 && tokens[1].Keyword() == "TRANSACTION" {
+			return txnBegin, ""
+		}
+	case "COMMIT", "END":
+		return txnCommit, ""
+	case "ABORT":
+		return txnRollback, ""
+	case "ROLLBACK":
+		for _, tok := range tokens[1:] {
+			if tok.Keyword() == "TO" {
+				return txnRollbackTo, last
+			}
+		}
+		return txnRollback, ""
+	case "SAVEPOINT":
+		if len(tokens) > 1 {
+			return txnSavepoint, last
+		}
+	case "RELEASE":
+		if len(tokens) > 1 {
+			return txnRelease, last
+		}
+	}
+
+	return txnNone, ""
+}
+
+// beginStatement starts a transaction in dialect d.
+func beginStatement(d query.Dialect) string {
+	if d == query.MySQL {
+		return "START TRANSACTION"
+	}
+
+	return "BEGIN"
+}
+
+// InTransaction reports if a transaction is open on the buffer's session.
+func (e *Editor) InTransaction() bool {
+	return e.txn != nil
+}
+
+// trackTransaction updates the transaction state once stmt ran
+// successfully.
+func (e *Editor) trackTransaction(stmt string) {
+	op, name := transactionControl(stmt, e.Dialect())
+
+	switch op {
+	case txnBegin:
+		if e.txn == nil {
+			e.txn = &transaction{started: time.Now()}
+		}
+	case txnCommit, txnRollback:
+		e.txn = nil
+	case txnSavepoint:
+		// SQLite starts a transaction for a savepoint outside of one.
+		if e.txn == nil {
+			e.txn = &transaction{started: time.Now()}
+		}
+		e.txn.savepoints = append(e.txn.savepoints, name)
+	case txnRollbackTo, txnRelease:
+		if e.txn == nil {
+			return
+		}
+
+		// Both drop the savepoints made after name, releasing drops name
+		// too.
+		for i := len(e.txn.savepoints) - 1; i >= 0; i-- {
+			if strings.EqualFold(e.txn.savepoints[i], name) {
+				if op == txnRelease {
+					i--
+				}
+				e.txn.savepoints = e.txn.savepoints[:i+1]
+				break
+			}
+		}
+	default:
+		if e.txn != nil {
+			e.txn.statements++
+		}
+	}
+}
+
+// needsImplicitBegin reports if stmt has to be preceded by a BEGIN because
+// autocommit is off.
+func (e *Editor) needsImplicitBegin(stmt string) bool {
+	if e.Autocommit || e.txn != nil || e.Dialect() == query.Redis {
+		return false
+	}
+
+	op, _ := transactionControl(stmt, e.Dialect())
+	return op == txnNone
+}
+
+// statementFailed reports err, rolling back the open transaction first on
+// connections with auto_rollback.
+func (e *Editor) statementFailed(c *config.Connection, err error) {
+	if e.transactionLost(c, err) {
+		return
+	}
+
+	if e.txn == nil || !c.AutoRollback {
+		e.fail("Error: %s", describeError(err))
+		return
+	}
+
+	e.runTransaction(c, "ROLLBACK", func() {
+		// Detail and hint go below.
+		text, more, ok := strings.Cut(describeError(err), "\n")
+		text += ", rolled back"
+		if ok {
+			text += "\n" + more
+		}
+		e.fail("Error: %s", text)
+	})
+}
+
+// transactionLost forgets the open transaction when err means its session's
+// connection broke, the server rolled it back.
+func (e *Editor) transactionLost(c *config.Connection, err error) bool {
+	if e.txn == nil || !errors.Is(err, db.ErrBroken) {
+		return false
+	}
+
+	e.txn = nil
+	e.fail("Error: %s, the transaction on %s was lost", err, c.Name)
+	return true
+}
+
+// runTransaction runs a transaction control statement in the background,
+// leaving the results pane alone, and calls onDone once it succeeded.
+func (e *Editor) runTransaction(c *config.Connection, stmt string, onDone func()) {
+	if e.running {
+		e.notify("A statement is already running")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.cancel = cancel
+	prev := e.takeStream()
+
+	go func() {
+		defer cancel()
+
+		e.closeStream(prev)
+		_, err := e.execute(ctx, c, stmt, false)
+
+		e.app.post(func() {
+			e.running = false
+			e.cancel = nil
+
+			if err != nil {
+				if !e.transactionLost(c, err) {
+					e.fail("Error: %s", err)
+				}
+				return
+			}
+
+			e.trackTransaction(stmt)
+			onDone()
+		})
+	}()
+}
+
+// transactionConnection returns the buffer's connection if it supports
+// transactions.
+func (e *Editor) transactionConnection() (*config.Connection, bool) {
+	c := e.Connection
+	if c == nil {
+		e.notify("Not connected, use :connect first")
+		return nil, false
+	}
+
+	if e.Dialect() == query.Redis {
+		e.notify("Transactions need a SQL connection, %s is %s", c.Name, c.Type)
+		return nil, false
+	}
+
+	return c, true
+}
+
+// Begin opens a transaction on the buffer's session.
+func (e *Editor) Begin() {
+	c, ok := e.transactionConnection()
+	if !ok {
+		return
+	}
+
+	if e.txn != nil {
+		e.notify("A transaction is already open")
+		return
+	}
+
+	e.runTransaction(c, beginStatement(e.Dialect()), func() {
+		e.notify("Transaction started on %s", c.Name)
+	})
+}
+
+// Commit commits the open transaction.
+func (e *Editor) Commit() {
+	c, ok := e.transactionConnection()
+	if !ok {
+		return
+	}
+
+	if e.txn == nil {
+		e.notify("No transaction open")
+		return
+	}
+
+	status := e.txn.Status()
+	e.runTransaction(c, "COMMIT", func() {
+		e.notify("Committed, %s", strings.TrimPrefix(status, "TXN "))
+	})
+}
+
+// Rollback rolls back the open transaction, or only to savepoint when one is
+// given.
+func (e *Editor) Rollback(savepoint string) {
+	c, ok := e.transactionConnection()
+	if !ok {
+		return
+	}
+
+	if e.txn == nil {
+		e.notify("No transaction open")
+		return
+	}
+
+	if savepoint != "" {
+		if !savepointName(savepoint) {
+			e.fail("Invalid savepoint name: %s", savepoint)
+			return
+		}
+
+		e.runTransaction(c, "ROLLBACK TO SAVEPOINT "+savepoint, func() {
+			e.notify("Rolled back to savepoint %s", savepoint)
+		})
+		return
+	}
+
+	status := e.txn.Status()
+	e.runTransaction(c, "ROLLBACK", func() {
+		e.notify("Rolled back, %s", strings.TrimPrefix(status, "TXN "))
+	})
+}
+
+// Savepoint sets a savepoint in the open transaction, named sp1, sp2 and so
+// on unless a name is given.
+func (e *Editor) Savepoint(name string) {
+	c, ok := e.transactionConnection()
+	if !ok {
+		return
+	}
+
+	if e.txn == nil {
+		e.notify("No transaction open, use :begin first")
+		return
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("sp%d", len(e.txn.savepoints)+1)
+	}
+	if !savepointName(name) {
+		e.fail("Invalid savepoint name: %s", name)
+		return
+	}
+
+	e.runTransaction(c, "SAVEPOINT "+name, func() {
+		e.notify("Savepoint %s set", name)
+	})
+}
+
+// savepointName reports if name is a plain word that can follow SAVEPOINT
+// as it is, so nothing else rides along into the statement.
+func savepointName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return name != ""
+}
+
+// OpenSavepointPicker lists the savepoints of the open transaction, newest
+// first, and rolls back to the chosen one.
+func (e *Editor) OpenSavepointPicker() {
+	if e.txn == nil || len(e.txn.savepoints) == 0 {
+		e.notify("No savepoints")
+		return
+	}
+
+	n := len(e.txn.savepoints)
+	items := make([]PickerItem, n)
+	for i, name := range e.txn.savepoints {
+		items[n-1-i] = PickerItem{Label: name, Detail: fmt.Sprintf("#%d", i+1), Value: name}
+	}
+
+	e.OpenPopup(NewPicker(e, "Savepoints", items, func(item PickerItem) {
+		e.Rollback(item.Value.(string))
+	}))
+}
+
+// SetAutocommit turns autocommit on or off for the buffer. With autocommit
+// off, statements run in a transaction until :commit or :rollback.
+func (e *Editor) SetAutocommit(on bool) {
+	e.Autocommit = on
+
+	switch {
+	case !on:
+		e.notify("Autocommit off, statements run in a transaction until :commit")
+	case e.txn != nil:
+		e.notify("Autocommit on, the open transaction still needs :commit or :rollback")
+	default:
+		e.notify("Autocommit on")
+	}
+}
+
+// confirmEndTransaction runs then right away if no transaction is open,
+// otherwise once the user agreed to give it up. Closing the session rolls
+// it back.
+func (e *Editor) confirmEndTransaction(action string, then func()) {
+	if e.txn == nil {
+		then()
+		return
+	}
+
+	question := fmt.Sprintf("Transaction open on %s (%s), roll back and %s? [y/N]: ", e.Connection.Name, strings.TrimPrefix(e.txn.Status(), "TXN "), action)
+	e.confirmYes(question, then)
+}
+
+// Quit closes the buffer and exits, asking first when a transaction is
+// open unless force is set.
+func (e *Editor) Quit(force bool) {
+	if force {
+		e.app.Quit()
+		return
+	}
+
+	e.confirmEndTransaction("quit", e.app.Quit)
+}
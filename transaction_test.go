@@ -0,0 +1,271 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/config"
+	"github.com/ajm113/dbvi/query"
+	"github.com/gdamore/tcell"
+)
+
+func TestTransactionControl(t *testing.T) {
+	tests := []struct {
+		stmt string
+		op   txnControl
+		name string
+	}{
+		{stmt: "BEGIN", op: txnBegin},
+		{stmt: "begin isolation level serializable;", op: txnBegin},
+		{stmt: "START TRANSACTION READ ONLY", op: txnBegin},
+		{stmt: "COMMIT;", op: txnCommit},
+		{stmt: "END", op: txnCommit},
+		{stmt: "ROLLBACK", op: txnRollback},
+		{stmt: "ABORT", op: txnRollback},
+		{stmt: "SAVEPOINT before_delete", op: txnSavepoint, name: "before_delete"},
+		{stmt: "ROLLBACK TO SAVEPOINT before_delete;", op: txnRollbackTo, name: "before_delete"},
+		{stmt: "rollback to sp1", op: txnRollbackTo, name: "sp1"},
+		{stmt: "RELEASE SAVEPOINT sp1", op: txnRelease, name: "sp1"},
+		{stmt: "START SLAVE", op: txnNone},
+		{stmt: "SELECT 'BEGIN'", op: txnNone},
+		{stmt: "-- BEGIN\nUPDATE t SET a = 1", op: txnNone},
+	}
+
+	for _, tt := range tests {
+		op, name := transactionControl(tt.stmt, query.Postgres)
+		if op != tt.op || name != tt.name {
+			t.Errorf("%q: got %v %q, want %v %q", tt.stmt, op, name, tt.op, tt.name)
+		}
+	}
+}
+
+// newSQLiteEditor returns an editor connected to a fresh in-memory database
+// with an empty table t.
+func newSQLiteEditor(t *testing.T, c config.Connection) *Editor {
+	t.Helper()
+
+	c.Name, c.Type, c.Path = "Fixture", "sqlite", ":memory:"
+
+	e := newTestEditor(t, nil)
+	e.Connect(&c)
+	t.Cleanup(e.Close)
+
+	runLine(t, e, "CREATE TABLE t (n INTEGER)")
+	return e
+}
+
+// runLine runs stmt as the buffer's only line.
+func runLine(t *testing.T, e *Editor, stmt string) {
+	t.Helper()
+
+	e.Lines = []string{stmt}
+	e.SetCursor(0, 0)
+	runCommand(t, e, "run")
+}
+
+// runCommand runs a : command and waits for its background work.
+func runCommand(t *testing.T, e *Editor, line string) {
+	t.Helper()
+
+	typeKeys(e, ":"+line)
+	pressKey(e, tcell.KeyEnter)
+	runPosted(t, e)
+}
+
+func countRows(t *testing.T, e *Editor) int64 {
+	t.Helper()
+
+	runLine(t, e, "SELECT count(*) FROM t")
+	return e.Results.Result.Rows[0][0].(int64)
+}
+
+func TestTransactionCommands(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+
+	runCommand(t, e, "begin")
+	if e.StatusBar.Command != "Transaction started on Fixture" || !e.InTransaction() {
+		t.Fatalf("got %q, want the transaction started", e.StatusBar.Command)
+	}
+
+	runLine(t, e, "INSERT INTO t VALUES (1)")
+	runCommand(t, e, "savepoint")
+	runLine(t, e, "INSERT INTO t VALUES (2)")
+	runCommand(t, e, "savepoint")
+
+	if got := e.txn.savepoints; len(got) != 2 || got[0] != "sp1" || got[1] != "sp2" {
+		t.Fatalf("got savepoints %q, want sp1 and sp2", got)
+	}
+
+	e.Draw()
+	_, h := e.screen.Size()
+	if status := screenRow(e, h-2); !strings.Contains(status, "[TXN 0s 2 stmts]") {
+		t.Errorf("got status line %q, want the transaction indicator", status)
+	}
+
+	// Savepoints are listed newest first, pick sp1.
+	typeKeys(e, ":savepoints")
+	pressKey(e, tcell.KeyEnter)
+	if matches := e.Popup.(*Picker).Matches(); len(matches) != 2 || matches[0].Label != "sp2" {
+		t.Fatalf("got savepoints %+v, want sp2 first", matches)
+	}
+	pressKey(e, tcell.KeyDown)
+	pressKey(e, tcell.KeyEnter)
+	runPosted(t, e)
+
+	if e.StatusBar.Command != "Rolled back to savepoint sp1" || len(e.txn.savepoints) != 1 {
+		t.Fatalf("got %q and savepoints %q", e.StatusBar.Command, e.txn.savepoints)
+	}
+
+	runCommand(t, e, "commit")
+	if !strings.HasPrefix(e.StatusBar.Command, "Committed, ") || e.InTransaction() {
+		t.Fatalf("got %q, want the transaction committed", e.StatusBar.Command)
+	}
+
+	if n := countRows(t, e); n != 1 {
+		t.Errorf("got %d rows, want the row inserted before sp1", n)
+	}
+}
+
+func TestSavepointName(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	runCommand(t, e, "begin")
+
+	// A name can't carry a statement past the read-only and destructive
+	// checks.
+	for _, line := range []string{"savepoint x; DROP TABLE t", "rollback x; DROP TABLE t"} {
+		typeKeys(e, ":"+line)
+		pressKey(e, tcell.KeyEnter)
+
+		if e.StatusBar.level != MessageError || !strings.HasPrefix(e.StatusBar.Command, "Invalid savepoint name") {
+			t.Errorf("%s: got %q, want the name refused", line, e.StatusBar.Command)
+		}
+	}
+
+	if len(e.txn.savepoints) != 0 {
+		t.Errorf("got savepoints %q, want none", e.txn.savepoints)
+	}
+	if n := countRows(t, e); n != 0 {
+		t.Errorf("got %d rows, want table t still there and empty", n)
+	}
+}
+
+func TestAutocommitOff(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+
+	typeKeys(e, ":autocommit")
+	pressKey(e, tcell.KeyEnter)
+	if e.Autocommit {
+		t.Fatal("expected :autocommit to toggle autocommit off")
+	}
+
+	runLine(t, e, "INSERT INTO t VALUES (1)")
+	if !e.InTransaction() || e.txn.statements != 1 {
+		t.Fatalf("expected the insert to open a transaction, got %+v", e.txn)
+	}
+
+	runCommand(t, e, "rollback")
+	if e.InTransaction() {
+		t.Fatal("expected the transaction to be rolled back")
+	}
+
+	// The count opens a transaction of its own.
+	if n := countRows(t, e); n != 0 {
+		t.Errorf("got %d rows, want the insert rolled back", n)
+	}
+}
+
+func TestAutoRollback(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{AutoRollback: true})
+
+	runCommand(t, e, "begin")
+	runLine(t, e, "INSERT INTO t VALUES (1)")
+	runLine(t, e, "INSERT INTO missing VALUES (1)")
+	runPosted(t, e) // the rollback
+
+	if !strings.HasSuffix(e.StatusBar.Command, ", rolled back") || e.InTransaction() {
+		t.Fatalf("got %q, want the transaction rolled back", e.StatusBar.Command)
+	}
+
+	if n := countRows(t, e); n != 0 {
+		t.Errorf("got %d rows, want the insert rolled back", n)
+	}
+}
+
+func TestQuitWithOpenTransaction(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	runCommand(t, e, "begin")
+
+	typeKeys(e, ":q")
+	pressKey(e, tcell.KeyEnter)
+	if want := "Transaction open on Fixture (0s 0 stmts), roll back and quit? [y/N]: "; e.StatusBar.Command != want {
+		t.Fatalf("got %q, want %q", e.StatusBar.Command, want)
+	}
+
+	pressKey(e, tcell.KeyEnter)
+	if e.app.quit {
+		t.Fatal("quit without confirmation")
+	}
+
+	typeKeys(e, ":q!")
+	pressKey(e, tcell.KeyEnter)
+	if !e.app.quit {
+		t.Error("expected :q! to quit right away")
+	}
+}
+
+func TestSessionKept(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+	runLine(t, e, "INSERT INTO t VALUES (1)")
+
+	// Another buffer on the connection takes a session of its own and
+	// holds it, the buffer keeps its :memory: database with t in it.
+	other := NewEditor(e.app)
+	other.Connect(e.Connection)
+	t.Cleanup(other.Close)
+	runCommand(t, other, "begin")
+
+	if n := countRows(t, e); n != 1 {
+		t.Fatalf("got %d rows, want the row inserted on the buffer's session", n)
+	}
+	if e.session == nil || e.session == other.session {
+		t.Fatal("expected the buffer to keep a session of its own")
+	}
+
+	// Disconnecting closes it, a buffer connecting next starts afresh
+	// instead of finding t.
+	c := e.Connection
+	e.Connect(nil)
+	if e.session != nil || e.app.sessions.Idle("Fixture") != 0 {
+		t.Fatal("expected the session to be closed on disconnect")
+	}
+
+	next := NewEditor(e.app)
+	next.Connect(c)
+	t.Cleanup(next.Close)
+	runLine(t, next, "SELECT n FROM t")
+	if !strings.Contains(next.StatusBar.Command, "no such table: t") {
+		t.Errorf("got %q, want a session without the table left by the last buffer", next.StatusBar.Command)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	e := newSQLiteEditor(t, config.Connection{})
+
+	// Break the buffer's session, a read reconnects transparently.
+	e.session.Close()
+
+	runLine(t, e, "SELECT 1")
+	runPosted(t, e) // the result, after the reconnect notice
+	if res := e.Results.Result; !strings.Contains(e.StatusBar.Command, "Reconnected to Fixture") || res == nil || len(res.Rows) != 1 {
+		t.Fatalf("got %q, want the read retried on a new session", e.StatusBar.Command)
+	}
+
+	// A broken session loses its transaction.
+	runCommand(t, e, "begin")
+	e.session.Close()
+
+	runLine(t, e, "SELECT 1")
+	if !strings.HasSuffix(e.StatusBar.Command, "the transaction on Fixture was lost") || e.InTransaction() {
+		t.Fatalf("got %q, want the transaction lost", e.StatusBar.Command)
+	}
+}
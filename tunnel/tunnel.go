@@ -0,0 +1,396 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var ErrManagerClosed = errors.New("tunnel manager closed")
+
+// Manager owns the SSH tunnels opened for connections. Tunnels are keyed by
+// connection name so every session on the same connection shares one
+// port forward.
+type Manager struct {
+	mu      sync.Mutex
+	tunnels map[string]*Tunnel
+	closed  bool
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		tunnels: map[string]*Tunnel{},
+	}
+}
+
+// Endpoint returns the address a driver should dial to reach the database
+// of c. Connections without an ssh block are dialed directly, otherwise a
+// local port forward is opened (or reused) through the bastion.
+func (m *Manager) Endpoint(ctx context.Context, c config.Connection) (string, error) {
+	if c.SSH == nil {
+		return c.Address(), nil
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return "", ErrManagerClosed
+	}
+
+	var dead *Tunnel
+	if t, ok := m.tunnels[c.Name]; ok {
+		if t.Alive() {
+			m.mu.Unlock()
+			return t.LocalAddr(), nil
+		}
+
+		dead = t
+		delete(m.tunnels, c.Name)
+	}
+	m.mu.Unlock()
+
+	if dead != nil {
+		dead.Close()
+	}
+
+	// Dial without holding the lock, a slow host mustn't keep the other
+	// connections waiting.
+	t, err := Open(ctx, c.SSH, c.Address())
+	if err != nil {
+		return "", fmt.Errorf("ssh tunnel for %s: %w", c.Name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		t.Close()
+		return "", ErrManagerClosed
+	}
+
+	// Another session of c may have opened a tunnel in the meantime.
+	if other, ok := m.tunnels[c.Name]; ok {
+		if other.Alive() {
+			t.Close()
+			return other.LocalAddr(), nil
+		}
+		other.Close()
+	}
+
+	m.tunnels[c.Name] = t
+	return t.LocalAddr(), nil
+}
+
+// Close tears down every tunnel. The manager can't be used afterwards.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+
+	var errs []error
+	for name, t := range m.tunnels {
+		if err := t.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		delete(m.tunnels, name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Tunnel forwards a local port to a remote address through a chain of SSH
+// clients.
+type Tunnel struct {
+	remote   string
+	clients  []*ssh.Client
+	listener net.Listener
+	agent    net.Conn // to the SSH agent, shared by the hops using it
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	dead  bool
+	wg    sync.WaitGroup
+}
+
+// Open dials the jump hosts and the bastion described by s, then starts
+// listening on a random loopback port that forwards to remote.
+func Open(ctx context.Context, s *config.SSH, remote string) (*Tunnel, error) {
+	hostKeyCallback, err := hostKeyCallback(s)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tunnel{
+		remote: remote,
+		conns:  map[net.Conn]struct{}{},
+	}
+
+	hops := append(append([]config.SSHHost{}, s.Jump...), s.SSHHost)
+	for _, hop := range hops {
+		client, err := t.dialHop(ctx, hop, hostKeyCallback)
+		if err != nil {
+			t.Close()
+			return nil, fmt.Errorf("%s: %w", hop.Address(), err)
+		}
+
+		t.clients = append(t.clients, client)
+	}
+
+	t.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	last := t.clients[len(t.clients)-1]
+	go func() {
+		last.Wait()
+		t.mu.Lock()
+		t.dead = true
+		t.mu.Unlock()
+	}()
+
+	t.wg.Add(1)
+	go t.serve()
+
+	return t, nil
+}
+
+// LocalAddr is the loopback address drivers should dial instead of the
+// database address.
+func (t *Tunnel) LocalAddr() string {
+	return t.listener.Addr().String()
+}
+
+// Alive reports if the SSH connection to the bastion is still up.
+func (t *Tunnel) Alive() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return !t.dead
+}
+
+// Close stops listening, drops the forwarded connections and closes the
+// SSH clients from the bastion back to the first jump host. It returns once
+// every forward has stopped.
+func (t *Tunnel) Close() error {
+	t.mu.Lock()
+	t.dead = true
+	for c := range t.conns {
+		c.Close()
+	}
+	t.mu.Unlock()
+
+	var err error
+	if t.listener != nil {
+		err = t.listener.Close()
+	}
+
+	// Closing the clients also ends the forwards still dialing through
+	// them.
+	for i := len(t.clients) - 1; i >= 0; i-- {
+		t.clients[i].Close()
+	}
+	t.wg.Wait()
+
+	if t.agent != nil {
+		t.agent.Close()
+	}
+
+	if errors.Is(err, net.ErrClosed) {
+		err = nil
+	}
+
+	return err
+}
+
+func (t *Tunnel) dialHop(ctx context.Context, hop config.SSHHost, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, error) {
+	auth, err := t.authMethods(hop)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User:            hop.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+	}
+
+	addr := hop.Address()
+
+	var conn net.Conn
+	if len(t.clients) == 0 {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = t.clients[len(t.clients)-1].Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func (t *Tunnel) serve() {
+	defer t.wg.Done()
+
+	client := t.clients[len(t.clients)-1]
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		// A tunnel whose bastion went away refuses what it accepts.
+		if !t.track(local) {
+			continue
+		}
+
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.forward(client, local)
+		}()
+	}
+}
+
+func (t *Tunnel) forward(client *ssh.Client, local net.Conn) {
+	defer local.Close()
+	defer t.untrack(local)
+
+	remote, err := client.Dial("tcp", t.remote)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	if !t.track(remote) {
+		return
+	}
+	defer t.untrack(remote)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+
+	// Either side hanging up ends the forward.
+	<-done
+}
+
+// track registers c to be closed with the tunnel. A tunnel that is already
+// closed closes c straight away and reports false.
+func (t *Tunnel) track(c net.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.dead {
+		c.Close()
+		return false
+	}
+
+	t.conns[c] = struct{}{}
+	return true
+}
+
+func (t *Tunnel) untrack(c net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.conns, c)
+}
+
+// authMethods returns how to authenticate to hop. The connection to the
+// agent is opened once and kept until the tunnel closes.
+func (t *Tunnel) authMethods(hop config.SSHHost) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+
+	if hop.Agent {
+		if t.agent == nil {
+			sock := os.Getenv("SSH_AUTH_SOCK")
+			if sock == "" {
+				return nil, errors.New("agent requested but SSH_AUTH_SOCK is not set")
+			}
+
+			conn, err := net.Dial("unix", sock)
+			if err != nil {
+				return nil, fmt.Errorf("ssh agent: %w", err)
+			}
+			t.agent = conn
+		}
+
+		methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(t.agent).Signers))
+	}
+
+	if hop.KeyFile != "" {
+		data, err := os.ReadFile(expandHome(hop.KeyFile))
+		if err != nil {
+			return nil, err
+		}
+
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", hop.KeyFile, err)
+		}
+
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	return methods, nil
+}
+
+func hostKeyCallback(s *config.SSH) (ssh.HostKeyCallback, error) {
+	if s.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	path := s.KnownHosts
+	if path == "" {
+		path = "~/.ssh/known_hosts"
+	}
+
+	return knownhosts.New(expandHome(path))
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,519 @@
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ajm113/dbvi/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testServer is a minimal in-process SSH server that only supports
+// "direct-tcpip" channels, which is all a port forward needs.
+type testServer struct {
+	listener net.Listener
+	hostKey  ssh.Signer
+	forwards atomic.Int32
+}
+
+func newTestServer(t *testing.T, authorized ssh.PublicKey) *testServer {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &testServer{listener: l, hostKey: hostKey}
+
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(authorized.Marshal()) {
+				return nil, io.EOF
+			}
+			return nil, nil
+		},
+	}
+	serverConfig.AddHostKey(hostKey)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn, serverConfig)
+		}
+	}()
+
+	return s
+}
+
+func (s *testServer) handle(conn net.Conn, serverConfig *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+
+		var payload struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		ch, chReqs, err := newChannel.Accept()
+		if err != nil {
+			target.Close()
+			continue
+		}
+		go ssh.DiscardRequests(chReqs)
+		s.forwards.Add(1)
+
+		go func() {
+			defer ch.Close()
+			defer target.Close()
+
+			go io.Copy(target, ch)
+			io.Copy(ch, target)
+		}()
+	}
+}
+
+func (s *testServer) host() config.SSHHost {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return config.SSHHost{Host: host, Port: p, User: "dbvi"}
+}
+
+// newEchoServer stands in for the database behind the bastion.
+func newEchoServer(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func newClientKey(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path, sshPub
+}
+
+func writeKnownHosts(t *testing.T, servers ...*testServer) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, s := range servers {
+		line := knownhosts.Line([]string{knownhosts.Normalize(s.listener.Addr().String())}, s.hostKey.PublicKey())
+		if _, err := f.WriteString(line + "\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+func echo(t *testing.T, addr, msg string) {
+	t.Helper()
+
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Fatalf("dialing tunnel: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte(msg + "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading through tunnel: %v", err)
+	}
+
+	if got != msg+"\n" {
+		t.Errorf("got %q, want %q", got, msg+"\n")
+	}
+}
+
+func testConnection(dbAddr string, s *config.SSH) config.Connection {
+	host, port, _ := net.SplitHostPort(dbAddr)
+	p, _ := strconv.Atoi(port)
+
+	return config.Connection{
+		Name: "Behind Bastion",
+		Type: "postgres",
+		Host: host,
+		Port: p,
+		SSH:  s,
+	}
+}
+
+func TestManagerEndpoint(t *testing.T) {
+	keyFile, pub := newClientKey(t)
+	bastion := newTestServer(t, pub)
+	dbAddr := newEchoServer(t)
+
+	hop := bastion.host()
+	hop.KeyFile = keyFile
+
+	c := testConnection(dbAddr, &config.SSH{
+		SSHHost:    hop,
+		KnownHosts: writeKnownHosts(t, bastion),
+	})
+
+	m := NewManager()
+	defer m.Close()
+
+	ctx := context.Background()
+	addr, err := m.Endpoint(ctx, c)
+	if err != nil {
+		t.Fatalf("opening tunnel: %v", err)
+	}
+
+	if addr == dbAddr {
+		t.Fatalf("expected a local forward, got the database address %s", addr)
+	}
+
+	echo(t, addr, "SELECT 1")
+
+	again, err := m.Endpoint(ctx, c)
+	if err != nil {
+		t.Fatalf("reusing tunnel: %v", err)
+	}
+
+	if again != addr {
+		t.Errorf("expected tunnel to be reused, got %s and %s", addr, again)
+	}
+
+	echo(t, again, "SELECT 2")
+
+	// A forward still open is dropped by Close, not waited for.
+	open, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Fatalf("dialing tunnel: %v", err)
+	}
+	defer open.Close()
+
+	open.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := open.Write([]byte("SELECT 3\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(open).ReadString('\n'); err != nil {
+		t.Fatalf("reading through tunnel: %v", err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("closing manager: %v", err)
+	}
+
+	if _, err := open.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("got %v, want the open forward closed", err)
+	}
+
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Errorf("expected %s to stop listening after Close", addr)
+	}
+
+	if _, err := m.Endpoint(ctx, c); err != ErrManagerClosed {
+		t.Errorf("got %v, want %v", err, ErrManagerClosed)
+	}
+}
+
+func TestManagerEndpointJumpChain(t *testing.T) {
+	keyFile, pub := newClientKey(t)
+	gateway := newTestServer(t, pub)
+	bastion := newTestServer(t, pub)
+	dbAddr := newEchoServer(t)
+
+	gatewayHop := gateway.host()
+	gatewayHop.KeyFile = keyFile
+	bastionHop := bastion.host()
+	bastionHop.KeyFile = keyFile
+
+	c := testConnection(dbAddr, &config.SSH{
+		SSHHost:    bastionHop,
+		KnownHosts: writeKnownHosts(t, gateway, bastion),
+		Jump:       []config.SSHHost{gatewayHop},
+	})
+
+	m := NewManager()
+	defer m.Close()
+
+	addr, err := m.Endpoint(context.Background(), c)
+	if err != nil {
+		t.Fatalf("opening tunnel: %v", err)
+	}
+
+	echo(t, addr, "SELECT 1")
+
+	// The gateway only forwards the SSH connection to the bastion, the
+	// database traffic is forwarded by the bastion.
+	if gateway.forwards.Load() != 1 || bastion.forwards.Load() != 1 {
+		t.Errorf("got gateway=%d bastion=%d forwards, want 1 each", gateway.forwards.Load(), bastion.forwards.Load())
+	}
+}
+
+func TestManagerEndpointUnknownHostKey(t *testing.T) {
+	keyFile, pub := newClientKey(t)
+	bastion := newTestServer(t, pub)
+	other := newTestServer(t, pub)
+
+	hop := bastion.host()
+	hop.KeyFile = keyFile
+
+	c := testConnection(newEchoServer(t), &config.SSH{
+		SSHHost:    hop,
+		KnownHosts: writeKnownHosts(t, other),
+	})
+
+	m := NewManager()
+	defer m.Close()
+
+	if _, err := m.Endpoint(context.Background(), c); err == nil {
+		t.Fatal("expected host key verification to fail")
+	}
+}
+
+func TestManagerEndpointDirect(t *testing.T) {
+	m := NewManager()
+	defer m.Close()
+
+	c := config.Connection{Name: "Local", Type: "redis", Host: "localhost"}
+
+	addr, err := m.Endpoint(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != "localhost:6379" {
+		t.Errorf("got %s, want localhost:6379", addr)
+	}
+}
+
+func TestManagerEndpointAgent(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unix socket paths are short, keep it out of the long test dirs.
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	t.Setenv("SSH_AUTH_SOCK", sock)
+
+	var opened, open atomic.Int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			opened.Add(1)
+			open.Add(1)
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				open.Add(-1)
+			}()
+		}
+	}()
+
+	gateway := newTestServer(t, signer.PublicKey())
+	bastion := newTestServer(t, signer.PublicKey())
+	gatewayHop := gateway.host()
+	gatewayHop.Agent = true
+	bastionHop := bastion.host()
+	bastionHop.Agent = true
+
+	c := testConnection(newEchoServer(t), &config.SSH{
+		SSHHost:    bastionHop,
+		KnownHosts: writeKnownHosts(t, gateway, bastion),
+		Jump:       []config.SSHHost{gatewayHop},
+	})
+
+	m := NewManager()
+	addr, err := m.Endpoint(context.Background(), c)
+	if err != nil {
+		t.Fatalf("opening tunnel: %v", err)
+	}
+	echo(t, addr, "SELECT 1")
+
+	// Both hops share one connection to the agent, closed with the tunnel.
+	if n := opened.Load(); n != 1 {
+		t.Errorf("got %d connections to the agent, want 1", n)
+	}
+
+	m.Close()
+	for deadline := time.Now().Add(time.Second); open.Load() != 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := open.Load(); n != 0 {
+		t.Errorf("got %d connections to the agent left open", n)
+	}
+}
+
+func TestManagerEndpointSlowHost(t *testing.T) {
+	keyFile, pub := newClientKey(t)
+	bastion := newTestServer(t, pub)
+
+	// A host that accepts the connection and never answers.
+	slow, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { slow.Close() })
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := slow.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	slowHost, slowPort, _ := net.SplitHostPort(slow.Addr().String())
+	port, _ := strconv.Atoi(slowPort)
+	slowConn := testConnection(newEchoServer(t), &config.SSH{
+		SSHHost:               config.SSHHost{Host: slowHost, Port: port, User: "dbvi", KeyFile: keyFile},
+		InsecureIgnoreHostKey: true,
+	})
+	slowConn.Name = "Slow"
+
+	hop := bastion.host()
+	hop.KeyFile = keyFile
+	c := testConnection(newEchoServer(t), &config.SSH{
+		SSHHost:    hop,
+		KnownHosts: writeKnownHosts(t, bastion),
+	})
+
+	m := NewManager()
+	defer m.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go m.Endpoint(ctx, slowConn)
+
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow host never dialed")
+	}
+
+	// The other connection doesn't wait for the slow one.
+	start := time.Now()
+	if _, err := m.Endpoint(context.Background(), c); err != nil {
+		t.Fatalf("opening tunnel: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %s, want the tunnel opened while the slow host dials", elapsed)
+	}
+}
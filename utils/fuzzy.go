@@ -0,0 +1,46 @@
+package utils
+
+import "unicode"
+
+// FuzzyScore matches pattern against s as a case-insensitive subsequence.
+// Higher scores mean better matches; consecutive characters and characters
+// at the start of a word score more. ok is false when pattern doesn't match.
+func FuzzyScore(pattern, s string) (score int, ok bool) {
+	p := []rune(pattern)
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	runes := []rune(s)
+	pi := 0
+	prevMatched := false
+
+	for i, r := range runes {
+		if pi == len(p) {
+			break
+		}
+
+		if unicode.ToLower(r) != unicode.ToLower(p[pi]) {
+			prevMatched = false
+			continue
+		}
+
+		score++
+		if prevMatched {
+			score += 2
+		}
+		if i == 0 || !IsWordChar(runes[i-1]) {
+			score += 3
+		}
+
+		prevMatched = true
+		pi++
+	}
+
+	if pi != len(p) {
+		return 0, false
+	}
+
+	// Prefer shorter candidates when everything else is equal.
+	return score*100 - len(runes), true
+}
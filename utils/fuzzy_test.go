@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		wantOk  bool
+	}{
+		{pattern: "", s: "anything", wantOk: true},
+		{pattern: "prd", s: "Production", wantOk: true},
+		{pattern: "PG", s: "local postgres", wantOk: true},
+		{pattern: "redis", s: "Redis Cache", wantOk: true},
+		{pattern: "xyz", s: "Production", wantOk: false},
+		{pattern: "prodd", s: "Production", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("fuzzy: %s in %s", tt.pattern, tt.s), func(t *testing.T) {
+			_, ok := FuzzyScore(tt.pattern, tt.s)
+			if ok != tt.wantOk {
+				t.Errorf("got %v, want %v", ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestFuzzyScoreRanking(t *testing.T) {
+	tests := []struct {
+		pattern string
+		better  string
+		worse   string
+	}{
+		{pattern: "stg", better: "staging", worse: "sales_testing_db"},
+		{pattern: "prod", better: "prod", worse: "production replica"},
+		{pattern: "db", better: "main db", worse: "redis backup"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("ranking: %s", tt.pattern), func(t *testing.T) {
+			better, _ := FuzzyScore(tt.pattern, tt.better)
+			worse, _ := FuzzyScore(tt.pattern, tt.worse)
+
+			if better <= worse {
+				t.Errorf("expected %q (%d) to outrank %q (%d)", tt.better, better, tt.worse, worse)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "unicode"
+import (
+	"strings"
+	"unicode"
+)
 
 func YankFromStrings(lines []string, startX, startY, endX, endY int) []string {
 	var selectedText []string
@@ -88,3 +91,33 @@ func MoveToPrevRune(s string, pos int, r rune) int {
 
 	return pos
 }
+
+// Wrap breaks s into lines of at most width runes, at the last space that
+// fits when there is one. Newlines in s always start a new line.
+func Wrap(s string, width int) []string {
+	var lines []string
+
+	for _, line := range strings.Split(s, "\n") {
+		runes := []rune(strings.TrimRight(line, "\r"))
+		if width <= 0 || len(runes) <= width {
+			lines = append(lines, string(runes))
+			continue
+		}
+
+		for len(runes) > width {
+			cut, next := width, width
+			for i := width; i > 0; i-- {
+				if runes[i] == ' ' {
+					cut, next = i, i+1
+					break
+				}
+			}
+
+			lines = append(lines, string(runes[:cut]))
+			runes = runes[next:]
+		}
+		lines = append(lines, string(runes))
+	}
+
+	return lines
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  []string
+	}{
+		{s: "", width: 10, want: []string{""}},
+		{s: "short", width: 10, want: []string{"short"}},
+		{s: "the quick brown fox", width: 10, want: []string{"the quick", "brown fox"}},
+		{s: "abcdefghijkl", width: 5, want: []string{"abcde", "fghij", "kl"}},
+		{s: "one\ntwo\r\n", width: 10, want: []string{"one", "two", ""}},
+		{s: "héllo wörld", width: 5, want: []string{"héllo", "wörld"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := Wrap(tt.s, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/ajm113/dbvi/query"
+)
+
+// sortKey orders the rows of the results by a column.
+type sortKey struct {
+	col  int
+	desc bool
+}
+
+// Selected returns the row and column of the result the selection is on,
+// which differ from Row and Col once the rows are sorted or filtered or the
+// columns rearranged.
+func (r *ResultsPane) Selected() (row, col int) {
+	return r.dataRow(r.Row), r.dataCol(r.Col)
+}
+
+// dataRow is the row of the result shown at row.
+func (r *ResultsPane) dataRow(row int) int {
+	switch {
+	case r.rows == nil:
+		return row
+	case row >= len(r.rows):
+		return len(r.Result.Rows)
+	}
+
+	return r.rows[row]
+}
+
+// dataCol is the column of the result shown at col.
+func (r *ResultsPane) dataCol(col int) int {
+	if r.cols == nil || col >= len(r.cols) {
+		return col
+	}
+
+	return r.cols[col]
+}
+
+// hasColumns reports if the pane shows a grid the view applies to.
+func (r *ResultsPane) hasColumns() bool {
+	return r.Result != nil && r.lines == nil && len(r.Result.Columns) > 0
+}
+
+// refreshRows filters and sorts the rows again, after the view changed or
+// rows were added or removed. The selection stays on the row it was on when
+// that row is still shown.
+func (r *ResultsPane) refreshRows() {
+	if !r.hasColumns() {
+		return
+	}
+
+	selected := r.dataRow(r.Row)
+
+	if r.filter == nil && r.sortKeys == nil {
+		r.rows = nil
+		r.Row = max(0, min(selected, r.RowCount()-1))
+		return
+	}
+
+	r.rows = make([]int, 0, len(r.Result.Rows))
+	for i, row := range r.Result.Rows {
+		if r.filter == nil || r.filter.Match(row) || r.added(i) {
+			r.rows = append(r.rows, i)
+		}
+	}
+
+	slices.SortStableFunc(r.rows, func(a, b int) int {
+		return r.compareRows(r.Result.Rows[a], r.Result.Rows[b])
+	})
+
+	r.Row = max(0, slices.Index(r.rows, selected))
+}
+
+// added reports if row was added in the pane, added rows are shown whatever
+// the filter so they can be filled in.
+func (r *ResultsPane) added(row int) bool {
+	return r.State != nil && r.State(row, 0) == CellAdded
+}
+
+// compareRows orders two rows by the sort keys, NULLs last either way.
+func (r *ResultsPane) compareRows(a, b []any) int {
+	for _, k := range r.sortKeys {
+		x, y := a[k.col], b[k.col]
+
+		c := db.Compare(x, y)
+		if k.desc && x != nil && y != nil {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// ShowRow makes sure row of the result is shown, at the end of the view if
+// it wasn't, and selects it.
+func (r *ResultsPane) ShowRow(row int) {
+	if r.rows == nil {
+		r.SetSelection(row, r.Col)
+		return
+	}
+
+	i := slices.Index(r.rows, row)
+	if i < 0 {
+		r.rows = append(r.rows, row)
+		i = len(r.rows) - 1
+	}
+	r.SetSelection(i, r.Col)
+}
+
+// refreshColumns works out the columns shown from the column order, pinned
+// columns first, leaving hidden ones out. The selection stays on its column
+// when it is still shown.
+func (r *ResultsPane) refreshColumns() {
+	selected := r.dataCol(r.Col)
+
+	order := r.order
+	if order == nil {
+		order = make([]int, len(r.Result.Columns))
+		for i := range order {
+			order[i] = i
+		}
+	}
+
+	r.cols, r.pins = make([]int, 0, len(order)), 0
+	for _, pinned := range []bool{true, false} {
+		for _, col := range order {
+			if r.pinned[col] == pinned && !r.hidden[col] {
+				r.cols = append(r.cols, col)
+			}
+		}
+		if pinned {
+			r.pins = len(r.cols)
+		}
+	}
+
+	col := max(0, slices.Index(r.cols, selected))
+	r.SetSelection(r.Row, col)
+}
+
+// columnIndex finds a column of the result by name, ignoring case and
+// quotes.
+func (r *ResultsPane) columnIndex(name string) (int, error) {
+	name = strings.Trim(name, "\"`[]")
+	for i, c := range r.Result.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no column %s", name)
+}
+
+func (r *ResultsPane) columnIndexes(names []string) ([]int, error) {
+	cols := make([]int, len(names))
+	for i, name := range names {
+		col, err := r.columnIndex(name)
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = col
+	}
+
+	return cols, nil
+}
+
+var errNoColumns = errors.New("no rows and columns to work with")
+
+// SortBy sorts the rows by a list of columns, each followed by asc or desc:
+// "amount desc, id". An empty list shows the rows in the order they came.
+func (r *ResultsPane) SortBy(spec string) error {
+	if !r.hasColumns() {
+		return errNoColumns
+	}
+
+	var keys []sortKey
+	for _, item := range strings.Split(spec, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+
+		col, err := r.columnIndex(fields[0])
+		if err != nil {
+			return err
+		}
+
+		key := sortKey{col: col}
+		switch {
+		case len(fields) == 1:
+		case len(fields) == 2 && strings.EqualFold(fields[1], "asc"):
+		case len(fields) == 2 && strings.EqualFold(fields[1], "desc"):
+			key.desc = true
+		default:
+			return fmt.Errorf("expected asc or desc after %s", fields[0])
+		}
+		keys = append(keys, key)
+	}
+
+	r.sortKeys = keys
+	r.refreshRows()
+	r.SetSelection(r.Row, r.Col)
+
+	return nil
+}
+
+// ToggleSort sorts the rows by the selected column, then the other way
+// around, then in the order they came.
+func (r *ResultsPane) ToggleSort() bool {
+	if !r.hasColumns() {
+		return false
+	}
+
+	_, col := r.Selected()
+	switch {
+	case len(r.sortKeys) != 1 || r.sortKeys[0].col != col:
+		r.sortKeys = []sortKey{{col: col}}
+	case !r.sortKeys[0].desc:
+		r.sortKeys = []sortKey{{col: col, desc: true}}
+	default:
+		r.sortKeys = nil
+	}
+	r.refreshRows()
+	r.SetSelection(r.Row, r.Col)
+
+	return true
+}
+
+// FilterBy shows only the rows matching a filter such as
+// "status = 'failed' and amount > 100". An empty filter shows every row.
+func (r *ResultsPane) FilterBy(text string) error {
+	if !r.hasColumns() {
+		return errNoColumns
+	}
+
+	var f *query.Filter
+	if strings.TrimSpace(text) != "" {
+		var err error
+		if f, err = query.ParseFilter(text, r.Result.Columns); err != nil {
+			return err
+		}
+	}
+
+	r.filter = f
+	r.refreshRows()
+	r.SetSelection(r.Row, r.Col)
+
+	return nil
+}
+
+// HideColumns leaves columns out of the grid and the record view.
+func (r *ResultsPane) HideColumns(names []string) error {
+	if !r.hasColumns() {
+		return errNoColumns
+	}
+
+	cols, err := r.columnIndexes(names)
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		cols = []int{r.dataCol(r.Col)}
+	}
+
+	hidden := 0
+	for col := range r.Result.Columns {
+		if r.hidden[col] || slices.Contains(cols, col) {
+			hidden++
+		}
+	}
+	if hidden == len(r.Result.Columns) {
+		return errors.New("at least one column has to stay shown")
+	}
+
+	if r.hidden == nil {
+		r.hidden = map[int]bool{}
+	}
+	for _, col := range cols {
+		r.hidden[col] = true
+	}
+	r.refreshColumns()
+
+	return nil
+}
+
+// ShowColumns shows hidden columns again, all of them when names is empty.
+func (r *ResultsPane) ShowColumns(names []string) error {
+	if !r.hasColumns() {
+		return errNoColumns
+	}
+
+	cols, err := r.columnIndexes(names)
+	if err != nil {
+		return err
+	}
+
+	if len(cols) == 0 {
+		r.hidden = nil
+	}
+	for _, col := range cols {
+		delete(r.hidden, col)
+	}
+	r.refreshColumns()
+
+	return nil
+}
+
+// PinColumns keeps columns at the left of the grid as it scrolls sideways,
+// the selected one when names is empty.
+func (r *ResultsPane) PinColumns(names []string) error {
+	if !r.hasColumns() {
+		return errNoColumns
+	}
+
+	cols, err := r.columnIndexes(names)
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		cols = []int{r.dataCol(r.Col)}
+	}
+
+	if r.pinned == nil {
+		r.pinned = map[int]bool{}
+	}
+	for _, col := range cols {
+		r.pinned[col] = true
+	}
+	r.refreshColumns()
+
+	return nil
+}
+
+// UnpinColumns lets columns scroll again, all of them when names is empty.
+func (r *ResultsPane) UnpinColumns(names []string) error {
+	if !r.hasColumns() {
+		return errNoColumns
+	}
+
+	cols, err := r.columnIndexes(names)
+	if err != nil {
+		return err
+	}
+
+	if len(cols) == 0 {
+		r.pinned = nil
+	}
+	for _, col := range cols {
+		delete(r.pinned, col)
+	}
+	r.refreshColumns()
+
+	return nil
+}
+
+// MoveColumn moves the selected column delta places to the right, or left
+// when negative, among the pinned or the other columns.
+func (r *ResultsPane) MoveColumn(delta int) bool {
+	if !r.hasColumns() {
+		return false
+	}
+
+	if r.cols == nil {
+		r.refreshColumns()
+	}
+
+	to := r.Col + delta
+	if to < 0 || to >= len(r.cols) || (r.Col < r.pins) != (to < r.pins) {
+		return false
+	}
+
+	if r.order == nil {
+		r.order = make([]int, len(r.Result.Columns))
+		for i := range r.order {
+			r.order[i] = i
+		}
+	}
+
+	a, b := slices.Index(r.order, r.cols[r.Col]), slices.Index(r.order, r.cols[to])
+	r.order[a], r.order[b] = r.order[b], r.order[a]
+	r.refreshColumns()
+
+	return true
+}
+
+// Search selects the next cell, or the previous one when backwards, whose
+// text contains text, ignoring case. It starts after the selection and wraps
+// around the results. An empty text repeats the last search.
+func (r *ResultsPane) Search(text string, backwards bool) bool {
+	if text != "" {
+		r.search = text
+	}
+	if r.search == "" || !r.hasColumns() {
+		return false
+	}
+
+	needle := strings.ToLower(r.search)
+	rows, cols := r.RowCount(), r.ColumnCount()
+	cells := rows * cols
+
+	step := 1
+	if backwards {
+		step = -1
+	}
+
+	start := r.Row*cols + r.Col
+	for i := 1; i <= cells; i++ {
+		cell := ((start+i*step)%cells + cells) % cells
+		row, col := cell/cols, cell%cols
+
+		v := r.Result.Rows[r.dataRow(row)][r.dataCol(col)]
+		if v != nil && strings.Contains(strings.ToLower(cellText(v)), needle) {
+			r.SetSelection(row, col)
+			return true
+		}
+	}
+
+	return false
+}
+
+// viewSummary describes how the view differs from the result as it came,
+// e.g. "filter: amount > 100  sort: id desc  2 hidden".
+func (r *ResultsPane) viewSummary() string {
+	var parts []string
+
+	if r.filter != nil {
+		parts = append(parts, "filter: "+r.filter.String())
+	}
+
+	if len(r.sortKeys) > 0 {
+		keys := make([]string, len(r.sortKeys))
+		for i, k := range r.sortKeys {
+			keys[i] = r.Result.Columns[k.col].Name
+			if k.desc {
+				keys[i] += " desc"
+			}
+		}
+		parts = append(parts, "sort: "+strings.Join(keys, ", "))
+	}
+
+	if r.pins > 0 {
+		parts = append(parts, fmt.Sprintf("%d pinned", r.pins))
+	}
+
+	if n := len(r.hidden); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d hidden", n))
+	}
+
+	return strings.Join(parts, "  ")
+}
+
+// columnNames splits the arguments of a command taking columns, separated
+// by commas or spaces.
+func columnNames(args string) []string {
+	return strings.FieldsFunc(args, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// changeView reports how a command changing the view of the results went.
+func (e *Editor) changeView(err error) {
+	if err != nil {
+		e.fail("Error: %s", err)
+		return
+	}
+
+	summary := e.Results.Summary()
+	if view := e.Results.viewSummary(); view != "" {
+		summary += "  " + view
+	}
+	e.notify("%s", summary)
+}
+
+// SearchResults selects the next cell of the results containing text, the
+// last text searched for when empty.
+func (e *Editor) SearchResults(text string, backwards bool) {
+	if e.Results.Search(text, backwards) {
+		return
+	}
+
+	switch {
+	case !e.Results.hasColumns():
+		e.notify("No rows to search")
+	case e.Results.search == "":
+		e.notify("No previous search")
+	default:
+		e.notify("Pattern not found: %s", e.Results.search)
+	}
+}
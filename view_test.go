@@ -0,0 +1,118 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ajm113/dbvi/db"
+	"github.com/gdamore/tcell"
+)
+
+func TestResultsPaneView(t *testing.T) {
+	e := newTestEditor(t, nil)
+
+	e.Results.SetResult("SELECT", &db.Result{
+		Columns: []db.Column{{Name: "id"}, {Name: "status"}, {Name: "amount"}},
+		Rows: [][]any{
+			{int64(1), "failed", db.Decimal("250.00")},
+			{int64(2), "paid", db.Decimal("99.50")},
+			{int64(3), "failed", db.Decimal("20")},
+			{int64(4), "failed", nil},
+			{int64(5), "failed", db.Decimal("101")},
+		},
+	}, 0)
+
+	ids := func() []int64 {
+		var ids []int64
+		for i := 0; i < e.Results.RowCount(); i++ {
+			ids = append(ids, e.Results.Result.Rows[e.Results.dataRow(i)][0].(int64))
+		}
+		return ids
+	}
+	wantIDs := func(want ...int64) {
+		t.Helper()
+		if got := ids(); !slices.Equal(got, want) {
+			t.Errorf("rows %v, want %v", got, want)
+		}
+	}
+
+	e.ExecuteCommand("filter status = 'failed' and amount > 100")
+	wantIDs(1, 5)
+
+	e.screen.Clear()
+	e.Draw()
+	y := e.Height
+	if header := screenRow(e, y); !strings.Contains(header, "2 of 5 rows") || !strings.Contains(header, "filter: status = 'failed' and amount > 100") {
+		t.Errorf("header %q doesn't show the filter", header)
+	}
+
+	e.ExecuteCommand("filter amount >")
+	if !strings.Contains(e.StatusBar.Command, "Error: unexpected end of filter") {
+		t.Errorf("bad filter reported %q", e.StatusBar.Command)
+	}
+	wantIDs(1, 5)
+
+	// Sorting is type-aware, NULLs go last either way.
+	e.ExecuteCommand("filter")
+	e.ExecuteCommand("sort amount desc")
+	wantIDs(1, 5, 2, 3, 4)
+	e.ExecuteCommand("sort status, amount")
+	wantIDs(3, 5, 1, 4, 2)
+
+	// The selection stays on its row as the view changes, edits go to the
+	// row of the result selected wherever it is shown.
+	pressKey(e, tcell.KeyCtrlW)
+	typeKeys(e, "j")
+	if row, _ := e.Results.Selected(); row != 3 {
+		t.Errorf("selected row %d, want 3", row)
+	}
+
+	// s sorts by the selected column, then the other way around, then not.
+	typeKeys(e, "s")
+	wantIDs(1, 2, 3, 4, 5)
+	typeKeys(e, "s")
+	wantIDs(5, 4, 3, 2, 1)
+	typeKeys(e, "s")
+	wantIDs(1, 2, 3, 4, 5)
+
+	// / searches cell text from the selection on, n and N repeat it.
+	e.Results.SetSelection(1, 1)
+	typeKeys(e, "/FAIL")
+	pressKey(e, tcell.KeyEnter)
+	if e.EditorMode != ResultsMode {
+		t.Fatalf("search left the results pane for mode %v", e.EditorMode)
+	}
+	if row, col := e.Results.Selected(); row != 2 || col != 1 {
+		t.Errorf("search selected %d,%d, want 2,1", row, col)
+	}
+	typeKeys(e, "n")
+	if row, _ := e.Results.Selected(); row != 3 {
+		t.Errorf("n selected row %d, want 3", row)
+	}
+	typeKeys(e, "N")
+	if row, _ := e.Results.Selected(); row != 2 {
+		t.Errorf("N selected row %d, want 2", row)
+	}
+
+	// Columns can be moved, hidden and pinned.
+	typeKeys(e, ">")
+	e.ExecuteCommand("hide id")
+	e.ExecuteCommand("pin status")
+	e.screen.Clear()
+	e.Draw()
+	if header := screenRow(e, y+1); !strings.HasPrefix(header, "status │ amount") {
+		t.Errorf("columns %q, want status then amount", header)
+	}
+	if header := screenRow(e, y); !strings.Contains(header, "1 pinned  1 hidden") {
+		t.Errorf("header %q doesn't show the pinned and hidden columns", header)
+	}
+
+	e.ExecuteCommand("show")
+	e.ExecuteCommand("unpin")
+	e.screen.Clear()
+	e.Draw()
+	if header := screenRow(e, y+1); !strings.HasPrefix(header, "id │ amount │ status") {
+		t.Errorf("columns %q, want id, amount, status", header)
+	}
+}